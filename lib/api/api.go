@@ -18,7 +18,11 @@ package api
 
 import (
 	"github.com/SENERGY-Platform/device-manager/lib/api/util"
+	"github.com/SENERGY-Platform/device-manager/lib/audit"
+	"github.com/SENERGY-Platform/device-manager/lib/auth"
 	"github.com/SENERGY-Platform/device-manager/lib/config"
+	"github.com/SENERGY-Platform/device-manager/lib/metrics"
+	"github.com/SENERGY-Platform/device-manager/lib/tracing"
 	"github.com/SENERGY-Platform/service-commons/pkg/accesslog"
 	"log"
 	"net/http"
@@ -34,7 +38,10 @@ var endpoints = []interface{}{} //list of objects with EndpointMethod
 
 func Start(config config.Config, control Controller) (srv *http.Server, err error) {
 	log.Println("start api")
-	router := GetRouter(config, control)
+	router, err := NewRouter(config, control)
+	if err != nil {
+		return nil, err
+	}
 	log.Println("listen on port", config.ServerPort)
 	srv = &http.Server{Addr: ":" + config.ServerPort, Handler: router}
 	go func() { log.Println(srv.ListenAndServe()) }()
@@ -52,15 +59,37 @@ func Start(config config.Config, control Controller) (srv *http.Server, err erro
 // @name Authorization
 // @description Type "Bearer" followed by a space and JWT token.
 func GetRouter(config config.Config, control Controller) http.Handler {
+	handler, err := NewRouter(config, control)
+	if err != nil {
+		log.Println("ERROR: unable to create router --> reject all requests", err)
+		return http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+			http.Error(writer, err.Error(), http.StatusInternalServerError)
+		})
+	}
+	return handler
+}
+
+// NewRouter is GetRouter with an error for invalid jwt verification settings
+func NewRouter(config config.Config, control Controller) (http.Handler, error) {
+	verifier, err := auth.NewVerifier(config)
+	if err != nil {
+		return nil, err
+	}
 	handler := GetRouterWithoutMiddleware(config, control)
+	handler = metrics.NewHttpMiddleware(handler)
+	handler = tracing.NewHttpMiddleware(handler)
 	handler = util.NewCors(handler)
 	handler = accesslog.New(handler)
+	handler = audit.NewRequestIdMiddleware(handler)
 	if config.EditForward != "" && config.EditForward != "-" {
-		handler = util.NewConditionalForward(handler, config.EditForward, func(r *http.Request) bool {
+		handler = util.NewConditionalForwardWithOptions(handler, config.EditForward, util.ForwardOptionsFromConfig(config), func(r *http.Request) bool {
 			return r.Method == http.MethodPost || r.Method == http.MethodPut || r.Method == http.MethodDelete
 		})
 	}
-	return handler
+	//verify and check api key scopes before forwarding; forwarded requests carry the signed token of the key
+	handler = NewApiKeyMiddleware(handler, control)
+	handler = auth.NewVerifyMiddleware(handler, verifier)
+	return handler, nil
 }
 
 func GetRouterWithoutMiddleware(config config.Config, command Controller) http.Handler {
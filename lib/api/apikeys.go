@@ -0,0 +1,183 @@
+/*
+ * Copyright 2024 InfAI (CC SES)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package api
+
+import (
+	"encoding/json"
+	"github.com/SENERGY-Platform/device-manager/lib/apikey"
+	"github.com/SENERGY-Platform/device-manager/lib/auth"
+	"github.com/SENERGY-Platform/device-manager/lib/config"
+	"github.com/SENERGY-Platform/device-manager/lib/model"
+	"github.com/SENERGY-Platform/service-commons/pkg/jwt"
+	"log"
+	"net/http"
+	"strings"
+)
+
+func init() {
+	endpoints = append(endpoints, &ApiKeyEndpoints{})
+}
+
+type ApiKeyEndpoints struct{}
+
+// Create godoc
+// @Summary      create api key
+// @Description  creates an api key for machine clients; the secret key is only returned by this request. only admins may create keys for other users or service accounts.
+// @Tags         create, api-keys
+// @Accept       json
+// @Produce      json
+// @Security Bearer
+// @Param        message body model.ApiKeyCreateRequest true "api key"
+// @Success      200 {object}  model.ApiKeyCreated
+// @Failure      400
+// @Failure      401
+// @Failure      403
+// @Failure      404
+// @Failure      500
+// @Router       /api-keys [POST]
+func (this *ApiKeyEndpoints) Create(config config.Config, router *http.ServeMux, control Controller) {
+	router.HandleFunc("POST /api-keys", func(writer http.ResponseWriter, request *http.Request) {
+		control := control.WithContext(request.Context())
+		token, err := auth.GetParsedToken(request)
+		if err != nil {
+			http.Error(writer, err.Error(), http.StatusBadRequest)
+			return
+		}
+		keyRequest := model.ApiKeyCreateRequest{}
+		err = json.NewDecoder(request.Body).Decode(&keyRequest)
+		if err != nil {
+			http.Error(writer, err.Error(), http.StatusBadRequest)
+			return
+		}
+		result, err, errCode := control.CreateApiKey(token, keyRequest)
+		if err != nil {
+			http.Error(writer, err.Error(), errCode)
+			return
+		}
+		writer.Header().Set("Content-Type", "application/json; charset=utf-8")
+		err = json.NewEncoder(writer).Encode(result)
+		if err != nil {
+			log.Println("ERROR: unable to encode response", err)
+		}
+		return
+	})
+}
+
+// List godoc
+// @Summary      list api keys
+// @Description  lists the api keys of the requesting user, without secrets; admins get all keys
+// @Tags         list, api-keys
+// @Produce      json
+// @Security Bearer
+// @Success      200 {array}  model.ApiKey
+// @Failure      400
+// @Failure      401
+// @Failure      404
+// @Failure      500
+// @Router       /api-keys [GET]
+func (this *ApiKeyEndpoints) List(config config.Config, router *http.ServeMux, control Controller) {
+	router.HandleFunc("GET /api-keys", func(writer http.ResponseWriter, request *http.Request) {
+		control := control.WithContext(request.Context())
+		token, err := auth.GetParsedToken(request)
+		if err != nil {
+			http.Error(writer, err.Error(), http.StatusBadRequest)
+			return
+		}
+		result, err, errCode := control.ListApiKeys(token)
+		if err != nil {
+			http.Error(writer, err.Error(), errCode)
+			return
+		}
+		if result == nil {
+			result = []model.ApiKey{}
+		}
+		writer.Header().Set("Content-Type", "application/json; charset=utf-8")
+		err = json.NewEncoder(writer).Encode(result)
+		if err != nil {
+			log.Println("ERROR: unable to encode response", err)
+		}
+		return
+	})
+}
+
+// Revoke godoc
+// @Summary      revoke api key
+// @Description  revokes an api key; allowed for the user of the key, its creator and admins
+// @Tags         delete, api-keys
+// @Security Bearer
+// @Param        id path string true "Api Key Id"
+// @Success      200
+// @Failure      400
+// @Failure      401
+// @Failure      404
+// @Failure      500
+// @Router       /api-keys/{id} [DELETE]
+func (this *ApiKeyEndpoints) Revoke(config config.Config, router *http.ServeMux, control Controller) {
+	router.HandleFunc("DELETE /api-keys/{id}", func(writer http.ResponseWriter, request *http.Request) {
+		control := control.WithContext(request.Context())
+		token, err := auth.GetParsedToken(request)
+		if err != nil {
+			http.Error(writer, err.Error(), http.StatusBadRequest)
+			return
+		}
+		err, errCode := control.RevokeApiKey(token, request.PathValue("id"))
+		if err != nil {
+			http.Error(writer, err.Error(), errCode)
+			return
+		}
+		writer.WriteHeader(http.StatusOK)
+		return
+	})
+}
+
+// NewApiKeyMiddleware authenticates requests with "Authorization: ApiKey <key>".
+// each route usable with api keys maps to explicit operations (see apikey.OperationsOf); the request is rejected with 403
+// if the route is not usable with api keys (e.g. api key management) or no scope of the key allows one of its operations.
+// otherwise it is passed on with a signed token of the key, as if the user of the key had sent it.
+func NewApiKeyMiddleware(handler http.Handler, control Controller) http.Handler {
+	return http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		scheme, key, found := strings.Cut(request.Header.Get("Authorization"), " ")
+		if !found || !strings.EqualFold(scheme, apikey.Scheme) {
+			handler.ServeHTTP(writer, request)
+			return
+		}
+		token, scopes, err, code := control.AuthenticateApiKey(strings.TrimSpace(key))
+		if err != nil {
+			http.Error(writer, err.Error(), code)
+			return
+		}
+		ops, ok, err := apikey.OperationsOf(request)
+		if !ok {
+			http.Error(writer, "api keys may not be used for "+request.Method+" "+request.URL.Path, http.StatusForbidden)
+			return
+		}
+		if err != nil {
+			http.Error(writer, err.Error(), http.StatusBadRequest)
+			return
+		}
+		for _, op := range ops {
+			if !apikey.Allowed(scopes, op) {
+				http.Error(writer, "api key is not allowed to "+op.Action+" "+op.Kind, http.StatusForbidden)
+				return
+			}
+		}
+		request = request.WithContext(jwt.AddTokenToContext(request.Context(), token))
+		//handlers passing the raw header to other services get the token of the key
+		request.Header.Set("Authorization", token.Token)
+		handler.ServeHTTP(writer, request)
+	})
+}
@@ -50,6 +50,7 @@ type AspectEndpoints struct{}
 // @Router       /aspects/{id} [GET]
 func (this *AspectEndpoints) Get(config config.Config, router *http.ServeMux, control Controller) {
 	router.HandleFunc("GET /aspects/{id}", func(writer http.ResponseWriter, request *http.Request) {
+		control := control.WithContext(request.Context())
 		id := request.PathValue("id")
 		token, err := auth.GetParsedToken(request)
 		if err != nil {
@@ -87,6 +88,7 @@ func (this *AspectEndpoints) Get(config config.Config, router *http.ServeMux, co
 // @Router       /aspects [POST]
 func (this *AspectEndpoints) Create(config config.Config, router *http.ServeMux, control Controller) {
 	router.HandleFunc("POST /aspects", func(writer http.ResponseWriter, request *http.Request) {
+		control := control.WithContext(request.Context())
 		aspect := models.Aspect{}
 		err := json.NewDecoder(request.Body).Decode(&aspect)
 		if err != nil {
@@ -140,6 +142,7 @@ func (this *AspectEndpoints) Create(config config.Config, router *http.ServeMux,
 // @Router       /aspects/{id} [PUT]
 func (this *AspectEndpoints) Set(config config.Config, router *http.ServeMux, control Controller) {
 	router.HandleFunc("PUT /aspects/{id}", func(writer http.ResponseWriter, request *http.Request) {
+		control := control.WithContext(request.Context())
 		id := request.PathValue("id")
 		aspect := models.Aspect{}
 		err := json.NewDecoder(request.Body).Decode(&aspect)
@@ -194,6 +197,7 @@ func (this *AspectEndpoints) Set(config config.Config, router *http.ServeMux, co
 // @Router       /aspects/{id} [DELETE]
 func (this *AspectEndpoints) Delete(config config.Config, router *http.ServeMux, control Controller) {
 	router.HandleFunc("DELETE /aspects/{id}", func(writer http.ResponseWriter, request *http.Request) {
+		control := control.WithContext(request.Context())
 		id := request.PathValue("id")
 		token, err := auth.GetParsedToken(request)
 		if err != nil {
@@ -0,0 +1,102 @@
+/*
+ * Copyright 2024 InfAI (CC SES)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package api
+
+import (
+	"encoding/json"
+	"github.com/SENERGY-Platform/device-manager/lib/auth"
+	"github.com/SENERGY-Platform/device-manager/lib/config"
+	"github.com/SENERGY-Platform/device-manager/lib/model"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+func init() {
+	endpoints = append(endpoints, &AuditEndpoints{})
+}
+
+type AuditEndpoints struct{}
+
+// Query godoc
+// @Summary      query audit log
+// @Description  returns the audit records of write operations in chronological order; admins only. needs a configured audit_file.
+// @Tags         audit
+// @Produce      json
+// @Security Bearer
+// @Param        resource query string false "resource id"
+// @Param        kind query string false "resource kind (topic), e.g. devices"
+// @Param        actor query string false "user id of the actor"
+// @Param        from query string false "RFC3339 time, inclusive"
+// @Param        to query string false "RFC3339 time, exclusive"
+// @Param        limit query integer false "default 100"
+// @Param        offset query integer false "default 0"
+// @Success      200 {array}  model.AuditRecord
+// @Failure      400
+// @Failure      401
+// @Failure      403
+// @Failure      404
+// @Failure      500
+// @Router       /audit [GET]
+func (this *AuditEndpoints) Query(config config.Config, router *http.ServeMux, control Controller) {
+	router.HandleFunc("GET /audit", func(writer http.ResponseWriter, request *http.Request) {
+		control := control.WithContext(request.Context())
+		token, err := auth.GetParsedToken(request)
+		if err != nil {
+			http.Error(writer, err.Error(), http.StatusBadRequest)
+			return
+		}
+		query := model.AuditQuery{
+			Resource: request.URL.Query().Get("resource"),
+			Kind:     request.URL.Query().Get("kind"),
+			Actor:    request.URL.Query().Get("actor"),
+		}
+		for param, target := range map[string]*time.Time{"from": &query.From, "to": &query.To} {
+			if value := request.URL.Query().Get(param); value != "" {
+				*target, err = time.Parse(time.RFC3339, value)
+				if err != nil {
+					http.Error(writer, "invalid "+param+": "+err.Error(), http.StatusBadRequest)
+					return
+				}
+			}
+		}
+		for param, target := range map[string]*int64{"limit": &query.Limit, "offset": &query.Offset} {
+			if value := request.URL.Query().Get(param); value != "" {
+				*target, err = strconv.ParseInt(value, 10, 64)
+				if err != nil {
+					http.Error(writer, "invalid "+param+": "+err.Error(), http.StatusBadRequest)
+					return
+				}
+			}
+		}
+		result, err, errCode := control.QueryAudit(token, query)
+		if err != nil {
+			http.Error(writer, err.Error(), errCode)
+			return
+		}
+		if result == nil {
+			result = []model.AuditRecord{}
+		}
+		writer.Header().Set("Content-Type", "application/json; charset=utf-8")
+		err = json.NewEncoder(writer).Encode(result)
+		if err != nil {
+			log.Println("ERROR: unable to encode response", err)
+		}
+		return
+	})
+}
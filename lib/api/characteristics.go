@@ -50,6 +50,7 @@ type CharacteristicsEndpoints struct{}
 // @Router       /characteristics/{id} [GET]
 func (this *CharacteristicsEndpoints) Get(config config.Config, router *http.ServeMux, control Controller) {
 	router.HandleFunc("GET /characteristics/{id}", func(writer http.ResponseWriter, request *http.Request) {
+		control := control.WithContext(request.Context())
 		id := request.PathValue("id")
 		token, err := auth.GetParsedToken(request)
 		if err != nil {
@@ -87,6 +88,7 @@ func (this *CharacteristicsEndpoints) Get(config config.Config, router *http.Ser
 // @Router       /characteristics [POST]
 func (this *CharacteristicsEndpoints) Create(config config.Config, router *http.ServeMux, control Controller) {
 	router.HandleFunc("POST /characteristics", func(writer http.ResponseWriter, request *http.Request) {
+		control := control.WithContext(request.Context())
 		characteristic := models.Characteristic{}
 		err := json.NewDecoder(request.Body).Decode(&characteristic)
 		if err != nil {
@@ -145,6 +147,7 @@ func (this *CharacteristicsEndpoints) Create(config config.Config, router *http.
 // @Router       /characteristics/{id} [PUT]
 func (this *CharacteristicsEndpoints) Set(config config.Config, router *http.ServeMux, control Controller) {
 	router.HandleFunc("PUT /characteristics/{id}", func(writer http.ResponseWriter, request *http.Request) {
+		control := control.WithContext(request.Context())
 		characteristicId := request.PathValue("id")
 		characteristic := models.Characteristic{}
 		err := json.NewDecoder(request.Body).Decode(&characteristic)
@@ -198,6 +201,7 @@ func (this *CharacteristicsEndpoints) Set(config config.Config, router *http.Ser
 // @Router       /characteristics/{id} [DELETE]
 func (this *CharacteristicsEndpoints) Delete(config config.Config, router *http.ServeMux, control Controller) {
 	router.HandleFunc("DELETE /characteristics/{id}", func(writer http.ResponseWriter, request *http.Request) {
+		control := control.WithContext(request.Context())
 		id := request.PathValue("id")
 		token, err := auth.GetParsedToken(request)
 		if err != nil {
@@ -50,6 +50,7 @@ type ConceptsEndpoints struct{}
 // @Router       /concepts/{id} [GET]
 func (this *ConceptsEndpoints) Get(config config.Config, router *http.ServeMux, control Controller) {
 	router.HandleFunc("GET /concepts/{id}", func(writer http.ResponseWriter, request *http.Request) {
+		control := control.WithContext(request.Context())
 		id := request.PathValue("id")
 		token, err := auth.GetParsedToken(request)
 		if err != nil {
@@ -87,6 +88,7 @@ func (this *ConceptsEndpoints) Get(config config.Config, router *http.ServeMux,
 // @Router       /concepts [POST]
 func (this *ConceptsEndpoints) Create(config config.Config, router *http.ServeMux, control Controller) {
 	router.HandleFunc("POST /concepts", func(writer http.ResponseWriter, request *http.Request) {
+		control := control.WithContext(request.Context())
 		concept := models.Concept{}
 		err := json.NewDecoder(request.Body).Decode(&concept)
 		if err != nil {
@@ -145,6 +147,7 @@ func (this *ConceptsEndpoints) Create(config config.Config, router *http.ServeMu
 // @Router       /concepts/{id} [PUT]
 func (this *ConceptsEndpoints) Set(config config.Config, router *http.ServeMux, control Controller) {
 	router.HandleFunc("PUT /concepts/{id}", func(writer http.ResponseWriter, request *http.Request) {
+		control := control.WithContext(request.Context())
 		id := request.PathValue("id")
 		concept := models.Concept{}
 		err := json.NewDecoder(request.Body).Decode(&concept)
@@ -198,6 +201,7 @@ func (this *ConceptsEndpoints) Set(config config.Config, router *http.ServeMux,
 // @Router       /concepts/{id} [DELETE]
 func (this *ConceptsEndpoints) Delete(config config.Config, router *http.ServeMux, control Controller) {
 	router.HandleFunc("DELETE /concepts/{id}", func(writer http.ResponseWriter, request *http.Request) {
+		control := control.WithContext(request.Context())
 		id := request.PathValue("id")
 		token, err := auth.GetParsedToken(request)
 		if err != nil {
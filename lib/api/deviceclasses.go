@@ -50,6 +50,7 @@ type DeviceClassesEndpoints struct{}
 // @Router       /device-classes/{id} [GET]
 func (this *DeviceClassesEndpoints) Get(config config.Config, router *http.ServeMux, control Controller) {
 	router.HandleFunc("GET /device-classes/{id}", func(writer http.ResponseWriter, request *http.Request) {
+		control := control.WithContext(request.Context())
 		id := request.PathValue("id")
 		token, err := auth.GetParsedToken(request)
 		if err != nil {
@@ -87,6 +88,7 @@ func (this *DeviceClassesEndpoints) Get(config config.Config, router *http.Serve
 // @Router       /device-classes [POST]
 func (this *DeviceClassesEndpoints) Create(config config.Config, router *http.ServeMux, control Controller) {
 	router.HandleFunc("POST /device-classes", func(writer http.ResponseWriter, request *http.Request) {
+		control := control.WithContext(request.Context())
 		deviceClass := models.DeviceClass{}
 		err := json.NewDecoder(request.Body).Decode(&deviceClass)
 		if err != nil {
@@ -145,6 +147,7 @@ func (this *DeviceClassesEndpoints) Create(config config.Config, router *http.Se
 // @Router       /device-classes/{id} [PUT]
 func (this *DeviceClassesEndpoints) Set(config config.Config, router *http.ServeMux, control Controller) {
 	router.HandleFunc("PUT /device-classes/{id}", func(writer http.ResponseWriter, request *http.Request) {
+		control := control.WithContext(request.Context())
 		id := request.PathValue("id")
 		deviceClass := models.DeviceClass{}
 		err := json.NewDecoder(request.Body).Decode(&deviceClass)
@@ -198,6 +201,7 @@ func (this *DeviceClassesEndpoints) Set(config config.Config, router *http.Serve
 // @Router       /device-classes/{id} [DELETE]
 func (this *DeviceClassesEndpoints) Delete(config config.Config, router *http.ServeMux, control Controller) {
 	router.HandleFunc("DELETE /device-classes/{id}", func(writer http.ResponseWriter, request *http.Request) {
+		control := control.WithContext(request.Context())
 		id := request.PathValue("id")
 		token, err := auth.GetParsedToken(request)
 		if err != nil {
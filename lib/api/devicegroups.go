@@ -50,6 +50,7 @@ type DeviceGroupsEndpoints struct{}
 // @Router       /device-groups/{id} [GET]
 func (this *DeviceGroupsEndpoints) Get(config config.Config, router *http.ServeMux, control Controller) {
 	router.HandleFunc("GET /device-groups/{id}", func(writer http.ResponseWriter, request *http.Request) {
+		control := control.WithContext(request.Context())
 		id := request.PathValue("id")
 		token, err := auth.GetParsedToken(request)
 		if err != nil {
@@ -87,6 +88,7 @@ func (this *DeviceGroupsEndpoints) Get(config config.Config, router *http.ServeM
 // @Router       /device-groups [POST]
 func (this *DeviceGroupsEndpoints) Create(config config.Config, router *http.ServeMux, control Controller) {
 	router.HandleFunc("POST /device-groups", func(writer http.ResponseWriter, request *http.Request) {
+		control := control.WithContext(request.Context())
 		deviceGroup := models.DeviceGroup{}
 		err := json.NewDecoder(request.Body).Decode(&deviceGroup)
 		if err != nil {
@@ -144,6 +146,7 @@ func (this *DeviceGroupsEndpoints) Create(config config.Config, router *http.Ser
 // @Router       /device-groups/{id} [PUT]
 func (this *DeviceGroupsEndpoints) Set(config config.Config, router *http.ServeMux, control Controller) {
 	router.HandleFunc("PUT /device-groups/{id}", func(writer http.ResponseWriter, request *http.Request) {
+		control := control.WithContext(request.Context())
 		id := request.PathValue("id")
 		deviceGroup := models.DeviceGroup{}
 		err := json.NewDecoder(request.Body).Decode(&deviceGroup)
@@ -197,6 +200,7 @@ func (this *DeviceGroupsEndpoints) Set(config config.Config, router *http.ServeM
 // @Router       /device-groups/{id} [DELETE]
 func (this *DeviceGroupsEndpoints) Delete(config config.Config, router *http.ServeMux, control Controller) {
 	router.HandleFunc("DELETE /device-groups/{id}", func(writer http.ResponseWriter, request *http.Request) {
+		control := control.WithContext(request.Context())
 		id := request.PathValue("id")
 		token, err := auth.GetParsedToken(request)
 		if err != nil {
@@ -42,6 +42,8 @@ const DisplayNameAttributeOrigin = "shared"
 
 const WaitQueryParamName = "wait"
 
+const ForceQueryParamName = "force"
+
 // List godoc
 // @Summary      list devices
 // @Description  list devices
@@ -66,6 +68,7 @@ const WaitQueryParamName = "wait"
 // @Router       /devices [GET]
 func (this *DevicesEndpoints) List(config config.Config, router *http.ServeMux, control Controller) {
 	router.HandleFunc("GET /devices", func(writer http.ResponseWriter, request *http.Request) {
+		control := control.WithContext(request.Context())
 		token, err := jwt.GetParsedToken(request)
 		if err != nil {
 			http.Error(writer, err.Error(), http.StatusBadRequest)
@@ -101,6 +104,7 @@ func (this *DevicesEndpoints) List(config config.Config, router *http.ServeMux,
 // @Router       /devices/{id} [GET]
 func (this *DevicesEndpoints) Get(config config.Config, router *http.ServeMux, control Controller) {
 	router.HandleFunc("GET /devices/{id}", func(writer http.ResponseWriter, request *http.Request) {
+		control := control.WithContext(request.Context())
 		id := request.PathValue("id")
 		token, err := auth.GetParsedToken(request)
 		if err != nil {
@@ -138,6 +142,7 @@ func (this *DevicesEndpoints) Get(config config.Config, router *http.ServeMux, c
 // @Router       /devices [POST]
 func (this *DevicesEndpoints) Create(config config.Config, router *http.ServeMux, control Controller) {
 	router.HandleFunc("POST /devices", func(writer http.ResponseWriter, request *http.Request) {
+		control := control.WithContext(request.Context())
 		device := models.Device{}
 		err := json.NewDecoder(request.Body).Decode(&device)
 		if err != nil {
@@ -178,6 +183,62 @@ func (this *DevicesEndpoints) Create(config config.Config, router *http.ServeMux
 	})
 }
 
+// Clone godoc
+// @Summary      clone device
+// @Description  publishes a copy of the device with new ids, owned by the requesting user.
+// @Description  name, local_id and attributes of the body override the copied values; the attribute "device-manager/clone-source" references the source.
+// @Tags         create, devices
+// @Accept       json
+// @Produce      json
+// @Security Bearer
+// @Param        id path string true "Device Id"
+// @Param        wait query bool false "wait for done message in kafka before responding"
+// @Param        message body model.CloneRequest true "overrides; may be an empty object"
+// @Success      200 {object}  models.Device
+// @Failure      400
+// @Failure      401
+// @Failure      403
+// @Failure      404
+// @Failure      500
+// @Router       /devices/{id}/clone [POST]
+func (this *DevicesEndpoints) Clone(config config.Config, router *http.ServeMux, control Controller) {
+	router.HandleFunc("POST /devices/{id}/clone", func(writer http.ResponseWriter, request *http.Request) {
+		control := control.WithContext(request.Context())
+		clone := model.CloneRequest{}
+		err := json.NewDecoder(request.Body).Decode(&clone)
+		if err != nil {
+			http.Error(writer, err.Error(), http.StatusBadRequest)
+			return
+		}
+		token, err := auth.GetParsedToken(request)
+		if err != nil {
+			http.Error(writer, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		options := model.DeviceCreateOptions{}
+		if waitQueryParam := request.URL.Query().Get(WaitQueryParamName); waitQueryParam != "" {
+			options.Wait, err = strconv.ParseBool(waitQueryParam)
+			if err != nil {
+				http.Error(writer, fmt.Sprintf("invalid %v query parameter %v", WaitQueryParamName, err.Error()), http.StatusBadRequest)
+				return
+			}
+		}
+
+		result, err, errCode := control.CloneDevice(token, request.PathValue("id"), clone, options)
+		if err != nil {
+			http.Error(writer, err.Error(), errCode)
+			return
+		}
+		writer.Header().Set("Content-Type", "application/json; charset=utf-8")
+		err = json.NewEncoder(writer).Encode(result)
+		if err != nil {
+			log.Println("ERROR: unable to encode response", err)
+		}
+		return
+	})
+}
+
 // Set godoc
 // @Summary      set device
 // @Description  set device; admins may create new devices but only without using the UpdateOnlySameOriginAttributesKey query parameter
@@ -197,6 +258,7 @@ func (this *DevicesEndpoints) Create(config config.Config, router *http.ServeMux
 // @Router       /devices/{id} [PUT]
 func (this *DevicesEndpoints) Set(config config.Config, router *http.ServeMux, control Controller) {
 	router.HandleFunc("PUT /devices/{id}", func(writer http.ResponseWriter, request *http.Request) {
+		control := control.WithContext(request.Context())
 		id := request.PathValue("id")
 		device := models.Device{}
 		err := json.NewDecoder(request.Body).Decode(&device)
@@ -258,6 +320,7 @@ func (this *DevicesEndpoints) Set(config config.Config, router *http.ServeMux, c
 // @Router       /devices/{id}/attributes [PUT]
 func (this *DevicesEndpoints) SetAttributes(config config.Config, router *http.ServeMux, control Controller) {
 	router.HandleFunc("PUT /devices/{id}/attributes", func(writer http.ResponseWriter, request *http.Request) {
+		control := control.WithContext(request.Context())
 		id := request.PathValue("id")
 		attributes := []models.Attribute{}
 		err := json.NewDecoder(request.Body).Decode(&attributes)
@@ -325,6 +388,7 @@ func (this *DevicesEndpoints) SetAttributes(config config.Config, router *http.S
 // @Router       /devices/{id}/display_name [PUT]
 func (this *DevicesEndpoints) SetDisplayName(config config.Config, router *http.ServeMux, control Controller) {
 	router.HandleFunc("PUT /devices/{id}/display_name", func(writer http.ResponseWriter, request *http.Request) {
+		control := control.WithContext(request.Context())
 		id := request.PathValue("id")
 		displayName := ""
 
@@ -399,6 +463,7 @@ func (this *DevicesEndpoints) SetDisplayName(config config.Config, router *http.
 // @Router       /devices/{id} [DELETE]
 func (this *DevicesEndpoints) Delete(config config.Config, router *http.ServeMux, control Controller) {
 	router.HandleFunc("DELETE /devices/{id}", func(writer http.ResponseWriter, request *http.Request) {
+		control := control.WithContext(request.Context())
 		id := request.PathValue("id")
 		token, err := auth.GetParsedToken(request)
 		if err != nil {
@@ -446,6 +511,7 @@ func (this *DevicesEndpoints) Delete(config config.Config, router *http.ServeMux
 // @Router       /devices [DELETE]
 func (this *DevicesEndpoints) DeleteMany(config config.Config, router *http.ServeMux, control Controller) {
 	router.HandleFunc("DELETE /devices", func(writer http.ResponseWriter, request *http.Request) {
+		control := control.WithContext(request.Context())
 		ids := []string{}
 		err := json.NewDecoder(request.Body).Decode(&ids)
 		if err != nil {
@@ -0,0 +1,134 @@
+/*
+ * Copyright 2024 InfAI (CC SES)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/SENERGY-Platform/device-manager/lib/auth"
+	"github.com/SENERGY-Platform/device-manager/lib/config"
+	"github.com/SENERGY-Platform/device-manager/lib/model"
+	"log"
+	"net/http"
+)
+
+func init() {
+	endpoints = append(endpoints, &DeviceTypeMigrationEndpoints{})
+}
+
+type DeviceTypeMigrationEndpoints struct{}
+
+// Migrate godoc
+// @Summary      migrate devices to another device-type
+// @Description  sets the device-type of all devices of the device-type, which the user may write, to the target device-type.
+// @Description  the target must have a service for each service local id of the source. devices which are invalid with the target are reported as failed.
+// @Description  with delete_source, the source device-type is deleted, once no devices of it exist and no device failed.
+// @Description  a dry run responds with the listed devices; otherwise the migration runs in the background and the response is the job, readable with GET /device-types/{id}/migrations/{job}
+// @Tags         device-types, devices
+// @Accept       json
+// @Produce      json
+// @Security Bearer
+// @Param        id path string true "source DeviceType Id"
+// @Param        message body model.DeviceTypeMigrationRequest true "migration"
+// @Success      200 {object}  model.DeviceTypeMigrationProgress
+// @Success      202 {object}  model.DeviceTypeMigrationJob
+// @Failure      400
+// @Failure      401
+// @Failure      403
+// @Failure      404
+// @Failure      500
+// @Router       /device-types/{id}/migrate [POST]
+func (this *DeviceTypeMigrationEndpoints) Migrate(config config.Config, router *http.ServeMux, control Controller) {
+	router.HandleFunc("POST /device-types/{id}/migrate", func(writer http.ResponseWriter, request *http.Request) {
+		token, err := auth.GetParsedToken(request)
+		if err != nil {
+			http.Error(writer, err.Error(), http.StatusBadRequest)
+			return
+		}
+		migration := model.DeviceTypeMigrationRequest{}
+		err = json.NewDecoder(request.Body).Decode(&migration)
+		if err != nil {
+			http.Error(writer, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if migration.DryRun {
+			control := control.WithContext(request.Context())
+			result, err, errCode := control.MigrateDeviceType(token, request.PathValue("id"), migration, nil)
+			if err != nil {
+				http.Error(writer, err.Error(), errCode)
+				return
+			}
+			writer.Header().Set("Content-Type", "application/json; charset=utf-8")
+			err = json.NewEncoder(writer).Encode(result)
+			if err != nil {
+				log.Println("ERROR: unable to encode response", err)
+			}
+			return
+		}
+		//the migration outlives the request
+		control := control.WithContext(context.WithoutCancel(request.Context()))
+		result, err, errCode := control.StartDeviceTypeMigration(token, request.PathValue("id"), migration)
+		if err != nil {
+			http.Error(writer, err.Error(), errCode)
+			return
+		}
+		writer.Header().Set("Content-Type", "application/json; charset=utf-8")
+		writer.WriteHeader(http.StatusAccepted)
+		err = json.NewEncoder(writer).Encode(result)
+		if err != nil {
+			log.Println("ERROR: unable to encode response", err)
+		}
+		return
+	})
+}
+
+// ReadMigration godoc
+// @Summary      read device-type migration
+// @Description  returns the progress of a migration started with POST /device-types/{id}/migrate; only the user who started it and admins may read it.
+// @Description  a job, which is not done and was not updated for long, was interrupted (e.g. by a restart) and may be started again.
+// @Tags         device-types, devices
+// @Produce      json
+// @Security Bearer
+// @Param        id path string true "source DeviceType Id"
+// @Param        job path string true "migration job id"
+// @Success      200 {object}  model.DeviceTypeMigrationJob
+// @Failure      400
+// @Failure      401
+// @Failure      404
+// @Failure      500
+// @Router       /device-types/{id}/migrations/{job} [GET]
+func (this *DeviceTypeMigrationEndpoints) ReadMigration(config config.Config, router *http.ServeMux, control Controller) {
+	router.HandleFunc("GET /device-types/{id}/migrations/{job}", func(writer http.ResponseWriter, request *http.Request) {
+		control := control.WithContext(request.Context())
+		token, err := auth.GetParsedToken(request)
+		if err != nil {
+			http.Error(writer, err.Error(), http.StatusBadRequest)
+			return
+		}
+		result, err, errCode := control.ReadDeviceTypeMigration(token, request.PathValue("id"), request.PathValue("job"))
+		if err != nil {
+			http.Error(writer, err.Error(), errCode)
+			return
+		}
+		writer.Header().Set("Content-Type", "application/json; charset=utf-8")
+		err = json.NewEncoder(writer).Encode(result)
+		if err != nil {
+			log.Println("ERROR: unable to encode response", err)
+		}
+		return
+	})
+}
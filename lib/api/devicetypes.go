@@ -51,6 +51,7 @@ type DeviceTypesEndpoints struct{}
 // @Router       /device-types/{id} [GET]
 func (this *DeviceTypesEndpoints) Get(config config.Config, router *http.ServeMux, control Controller) {
 	router.HandleFunc("GET /device-types/{id}", func(writer http.ResponseWriter, request *http.Request) {
+		control := control.WithContext(request.Context())
 		id := request.PathValue("id")
 		token, err := auth.GetParsedToken(request)
 		if err != nil {
@@ -89,6 +90,7 @@ func (this *DeviceTypesEndpoints) Get(config config.Config, router *http.ServeMu
 // @Router       /device-types [POST]
 func (this *DeviceTypesEndpoints) Create(config config.Config, router *http.ServeMux, control Controller) {
 	router.HandleFunc("POST /device-types", func(writer http.ResponseWriter, request *http.Request) {
+		control := control.WithContext(request.Context())
 		devicetype := models.DeviceType{}
 		err := json.NewDecoder(request.Body).Decode(&devicetype)
 		if err != nil {
@@ -146,6 +148,7 @@ func (this *DeviceTypesEndpoints) Create(config config.Config, router *http.Serv
 // @Security Bearer
 // @Param        id path string true "DeviceType Id"
 // @Param        wait query bool false "wait for done message in kafka before responding"
+// @Param        force query bool false "allow breaking changes of a device-type with devices"
 // @Param        distinct_attributes query string false "comma separated list of attribute keys; no other device-type with the same attribute key/value may exist"
 // @Param        message body models.DeviceType true "element"
 // @Success      200 {object}  models.DeviceType
@@ -153,10 +156,12 @@ func (this *DeviceTypesEndpoints) Create(config config.Config, router *http.Serv
 // @Failure      401
 // @Failure      403
 // @Failure      404
+// @Failure      409 "breaking changes of a device-type with devices without force=true"
 // @Failure      500
 // @Router       /device-types/{id} [PUT]
 func (this *DeviceTypesEndpoints) Set(config config.Config, router *http.ServeMux, control Controller) {
 	router.HandleFunc("PUT /device-types/{id}", func(writer http.ResponseWriter, request *http.Request) {
+		control := control.WithContext(request.Context())
 		id := request.PathValue("id")
 		devicetype := models.DeviceType{}
 		err := json.NewDecoder(request.Body).Decode(&devicetype)
@@ -186,6 +191,13 @@ func (this *DeviceTypesEndpoints) Set(config config.Config, router *http.ServeMu
 				return
 			}
 		}
+		if forceQueryParam := request.URL.Query().Get(ForceQueryParamName); forceQueryParam != "" {
+			options.Force, err = strconv.ParseBool(forceQueryParam)
+			if err != nil {
+				http.Error(writer, fmt.Sprintf("invalid %v query parameter %v", ForceQueryParamName, err.Error()), http.StatusBadRequest)
+				return
+			}
+		}
 
 		result, err, errCode := control.PublishDeviceTypeUpdate(token, id, devicetype, options)
 		if err != nil {
@@ -201,6 +213,107 @@ func (this *DeviceTypesEndpoints) Set(config config.Config, router *http.ServeMu
 	})
 }
 
+// Analyze godoc
+// @Summary      analyze device-type update
+// @Description  lists the changes of the device-type compared with the stored version, classified as breaking or non-breaking; nothing is published.
+// @Description  PUT /device-types/{id} rejects breaking changes with 409, if devices of the type exist and force=true is missing.
+// @Tags         device-types
+// @Accept       json
+// @Produce      json
+// @Security Bearer
+// @Param        id path string true "DeviceType Id"
+// @Param        message body models.DeviceType true "element"
+// @Success      200 {object}  model.DeviceTypeUpdateAnalysis
+// @Failure      400
+// @Failure      401
+// @Failure      403
+// @Failure      404
+// @Failure      500
+// @Router       /device-types/{id}/analysis [POST]
+func (this *DeviceTypesEndpoints) Analyze(config config.Config, router *http.ServeMux, control Controller) {
+	router.HandleFunc("POST /device-types/{id}/analysis", func(writer http.ResponseWriter, request *http.Request) {
+		control := control.WithContext(request.Context())
+		devicetype := models.DeviceType{}
+		err := json.NewDecoder(request.Body).Decode(&devicetype)
+		if err != nil {
+			http.Error(writer, err.Error(), http.StatusBadRequest)
+			return
+		}
+		token, err := auth.GetParsedToken(request)
+		if err != nil {
+			http.Error(writer, err.Error(), http.StatusBadRequest)
+			return
+		}
+		result, err, errCode := control.AnalyzeDeviceTypeUpdate(token, request.PathValue("id"), devicetype)
+		if err != nil {
+			http.Error(writer, err.Error(), errCode)
+			return
+		}
+		writer.Header().Set("Content-Type", "application/json; charset=utf-8")
+		err = json.NewEncoder(writer).Encode(result)
+		if err != nil {
+			log.Println("ERROR: unable to encode response", err)
+		}
+		return
+	})
+}
+
+// Clone godoc
+// @Summary      clone device-type
+// @Description  publishes a copy of the device-type with new ids for the device-type, its services, contents and content variables.
+// @Description  name and attributes of the body override the copied values; the attribute "device-manager/clone-source" references the source.
+// @Tags         create, device-types
+// @Accept       json
+// @Produce      json
+// @Security Bearer
+// @Param        id path string true "DeviceType Id"
+// @Param        wait query bool false "wait for done message in kafka before responding"
+// @Param        message body model.CloneRequest true "overrides; may be an empty object"
+// @Success      200 {object}  models.DeviceType
+// @Failure      400
+// @Failure      401
+// @Failure      403
+// @Failure      404
+// @Failure      500
+// @Router       /device-types/{id}/clone [POST]
+func (this *DeviceTypesEndpoints) Clone(config config.Config, router *http.ServeMux, control Controller) {
+	router.HandleFunc("POST /device-types/{id}/clone", func(writer http.ResponseWriter, request *http.Request) {
+		control := control.WithContext(request.Context())
+		clone := model.CloneRequest{}
+		err := json.NewDecoder(request.Body).Decode(&clone)
+		if err != nil {
+			http.Error(writer, err.Error(), http.StatusBadRequest)
+			return
+		}
+		token, err := auth.GetParsedToken(request)
+		if err != nil {
+			http.Error(writer, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		options := model.DeviceTypeUpdateOptions{}
+		if waitQueryParam := request.URL.Query().Get(WaitQueryParamName); waitQueryParam != "" {
+			options.Wait, err = strconv.ParseBool(waitQueryParam)
+			if err != nil {
+				http.Error(writer, fmt.Sprintf("invalid %v query parameter %v", WaitQueryParamName, err.Error()), http.StatusBadRequest)
+				return
+			}
+		}
+
+		result, err, errCode := control.CloneDeviceType(token, request.PathValue("id"), clone, options)
+		if err != nil {
+			http.Error(writer, err.Error(), errCode)
+			return
+		}
+		writer.Header().Set("Content-Type", "application/json; charset=utf-8")
+		err = json.NewEncoder(writer).Encode(result)
+		if err != nil {
+			log.Println("ERROR: unable to encode response", err)
+		}
+		return
+	})
+}
+
 // Delete godoc
 // @Summary      delete device-type
 // @Description  delete device-type
@@ -218,6 +331,7 @@ func (this *DeviceTypesEndpoints) Set(config config.Config, router *http.ServeMu
 // @Router       /device-types/{id} [DELETE]
 func (this *DeviceTypesEndpoints) Delete(config config.Config, router *http.ServeMux, control Controller) {
 	router.HandleFunc("DELETE /device-types/{id}", func(writer http.ResponseWriter, request *http.Request) {
+		control := control.WithContext(request.Context())
 		id := request.PathValue("id")
 		token, err := auth.GetParsedToken(request)
 		if err != nil {
@@ -0,0 +1,226 @@
+/*
+ * Copyright 2024 InfAI (CC SES)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/SENERGY-Platform/device-manager/lib/auth"
+	"github.com/SENERGY-Platform/device-manager/lib/config"
+	"github.com/SENERGY-Platform/device-manager/lib/model"
+	"log"
+	"net/http"
+	"strconv"
+)
+
+func init() {
+	endpoints = append(endpoints, &DeviceTypeVersionEndpoints{})
+}
+
+type DeviceTypeVersionEndpoints struct{}
+
+// List godoc
+// @Summary      list device-type versions
+// @Description  lists the recorded versions of a device-type, oldest first
+// @Tags         list, device-types
+// @Produce      json
+// @Security Bearer
+// @Param        id path string true "DeviceType Id"
+// @Success      200 {array}  model.DeviceTypeVersion
+// @Failure      400
+// @Failure      401
+// @Failure      403
+// @Failure      404
+// @Failure      500
+// @Router       /device-types/{id}/versions [GET]
+func (this *DeviceTypeVersionEndpoints) List(config config.Config, router *http.ServeMux, control Controller) {
+	router.HandleFunc("GET /device-types/{id}/versions", func(writer http.ResponseWriter, request *http.Request) {
+		control := control.WithContext(request.Context())
+		token, err := auth.GetParsedToken(request)
+		if err != nil {
+			http.Error(writer, err.Error(), http.StatusBadRequest)
+			return
+		}
+		result, err, errCode := control.ListDeviceTypeVersions(token, request.PathValue("id"))
+		if err != nil {
+			http.Error(writer, err.Error(), errCode)
+			return
+		}
+		if result == nil {
+			result = []model.DeviceTypeVersion{}
+		}
+		writer.Header().Set("Content-Type", "application/json; charset=utf-8")
+		err = json.NewEncoder(writer).Encode(result)
+		if err != nil {
+			log.Println("ERROR: unable to encode response", err)
+		}
+		return
+	})
+}
+
+// Get godoc
+// @Summary      get device-type version
+// @Description  returns a recorded version of a device-type
+// @Tags         read, device-types
+// @Produce      json
+// @Security Bearer
+// @Param        id path string true "DeviceType Id"
+// @Param        version path integer true "version"
+// @Success      200 {object}  model.DeviceTypeVersion
+// @Failure      400
+// @Failure      401
+// @Failure      403
+// @Failure      404
+// @Failure      500
+// @Router       /device-types/{id}/versions/{version} [GET]
+func (this *DeviceTypeVersionEndpoints) Get(config config.Config, router *http.ServeMux, control Controller) {
+	router.HandleFunc("GET /device-types/{id}/versions/{version}", func(writer http.ResponseWriter, request *http.Request) {
+		control := control.WithContext(request.Context())
+		token, err := auth.GetParsedToken(request)
+		if err != nil {
+			http.Error(writer, err.Error(), http.StatusBadRequest)
+			return
+		}
+		version, err := strconv.Atoi(request.PathValue("version"))
+		if err != nil {
+			http.Error(writer, "invalid version: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+		result, err, errCode := control.ReadDeviceTypeVersion(token, request.PathValue("id"), version)
+		if err != nil {
+			http.Error(writer, err.Error(), errCode)
+			return
+		}
+		writer.Header().Set("Content-Type", "application/json; charset=utf-8")
+		err = json.NewEncoder(writer).Encode(result)
+		if err != nil {
+			log.Println("ERROR: unable to encode response", err)
+		}
+		return
+	})
+}
+
+// Diff godoc
+// @Summary      diff device-type versions
+// @Description  lists the changed fields between two versions of a device-type; services and other lists of elements with ids are compared per id
+// @Tags         read, device-types
+// @Produce      json
+// @Security Bearer
+// @Param        id path string true "DeviceType Id"
+// @Param        version path integer true "version to compare from"
+// @Param        to query integer false "version to compare to; defaults to the latest version"
+// @Success      200 {object}  model.DeviceTypeVersionDiff
+// @Failure      400
+// @Failure      401
+// @Failure      403
+// @Failure      404
+// @Failure      500
+// @Router       /device-types/{id}/versions/{version}/diff [GET]
+func (this *DeviceTypeVersionEndpoints) Diff(config config.Config, router *http.ServeMux, control Controller) {
+	router.HandleFunc("GET /device-types/{id}/versions/{version}/diff", func(writer http.ResponseWriter, request *http.Request) {
+		control := control.WithContext(request.Context())
+		token, err := auth.GetParsedToken(request)
+		if err != nil {
+			http.Error(writer, err.Error(), http.StatusBadRequest)
+			return
+		}
+		from, err := strconv.Atoi(request.PathValue("version"))
+		if err != nil {
+			http.Error(writer, "invalid version: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+		to := 0
+		if toQueryParam := request.URL.Query().Get("to"); toQueryParam != "" {
+			to, err = strconv.Atoi(toQueryParam)
+			if err != nil {
+				http.Error(writer, "invalid to query parameter: "+err.Error(), http.StatusBadRequest)
+				return
+			}
+		}
+		result, err, errCode := control.DiffDeviceTypeVersions(token, request.PathValue("id"), from, to)
+		if err != nil {
+			http.Error(writer, err.Error(), errCode)
+			return
+		}
+		writer.Header().Set("Content-Type", "application/json; charset=utf-8")
+		err = json.NewEncoder(writer).Encode(result)
+		if err != nil {
+			log.Println("ERROR: unable to encode response", err)
+		}
+		return
+	})
+}
+
+// Rollback godoc
+// @Summary      rollback device-type
+// @Description  publishes the definition of a recorded version as update of the device-type; the update is validated and recorded as new version
+// @Tags         set, device-types
+// @Produce      json
+// @Security Bearer
+// @Param        id path string true "DeviceType Id"
+// @Param        version path integer true "version to restore"
+// @Param        wait query bool false "wait for done message in kafka before responding"
+// @Param        force query bool false "allow breaking changes of a device-type with devices"
+// @Success      200 {object}  models.DeviceType
+// @Failure      400
+// @Failure      401
+// @Failure      403
+// @Failure      404
+// @Failure      409 "breaking changes of a device-type with devices without force=true"
+// @Failure      500
+// @Router       /device-types/{id}/versions/{version}/rollback [POST]
+func (this *DeviceTypeVersionEndpoints) Rollback(config config.Config, router *http.ServeMux, control Controller) {
+	router.HandleFunc("POST /device-types/{id}/versions/{version}/rollback", func(writer http.ResponseWriter, request *http.Request) {
+		control := control.WithContext(request.Context())
+		token, err := auth.GetParsedToken(request)
+		if err != nil {
+			http.Error(writer, err.Error(), http.StatusBadRequest)
+			return
+		}
+		version, err := strconv.Atoi(request.PathValue("version"))
+		if err != nil {
+			http.Error(writer, "invalid version: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+		options := model.DeviceTypeUpdateOptions{}
+		if waitQueryParam := request.URL.Query().Get(WaitQueryParamName); waitQueryParam != "" {
+			options.Wait, err = strconv.ParseBool(waitQueryParam)
+			if err != nil {
+				http.Error(writer, fmt.Sprintf("invalid %v query parameter %v", WaitQueryParamName, err.Error()), http.StatusBadRequest)
+				return
+			}
+		}
+		if forceQueryParam := request.URL.Query().Get(ForceQueryParamName); forceQueryParam != "" {
+			options.Force, err = strconv.ParseBool(forceQueryParam)
+			if err != nil {
+				http.Error(writer, fmt.Sprintf("invalid %v query parameter %v", ForceQueryParamName, err.Error()), http.StatusBadRequest)
+				return
+			}
+		}
+		result, err, errCode := control.RollbackDeviceType(token, request.PathValue("id"), version, options)
+		if err != nil {
+			http.Error(writer, err.Error(), errCode)
+			return
+		}
+		writer.Header().Set("Content-Type", "application/json; charset=utf-8")
+		err = json.NewEncoder(writer).Encode(result)
+		if err != nil {
+			log.Println("ERROR: unable to encode response", err)
+		}
+		return
+	})
+}
@@ -50,6 +50,7 @@ type FunctionsEndpoints struct{}
 // @Router       /functions/{id} [GET]
 func (this *FunctionsEndpoints) Get(config config.Config, router *http.ServeMux, control Controller) {
 	router.HandleFunc("GET /functions/{id}", func(writer http.ResponseWriter, request *http.Request) {
+		control := control.WithContext(request.Context())
 		id := request.PathValue("id")
 		token, err := auth.GetParsedToken(request)
 		if err != nil {
@@ -87,6 +88,7 @@ func (this *FunctionsEndpoints) Get(config config.Config, router *http.ServeMux,
 // @Router       /functions [POST]
 func (this *FunctionsEndpoints) Create(config config.Config, router *http.ServeMux, control Controller) {
 	router.HandleFunc("POST /functions", func(writer http.ResponseWriter, request *http.Request) {
+		control := control.WithContext(request.Context())
 		function := models.Function{}
 		err := json.NewDecoder(request.Body).Decode(&function)
 		if err != nil {
@@ -140,6 +142,7 @@ func (this *FunctionsEndpoints) Create(config config.Config, router *http.ServeM
 // @Router       /functions/{id} [PUT]
 func (this *FunctionsEndpoints) Set(config config.Config, router *http.ServeMux, control Controller) {
 	router.HandleFunc("PUT /functions/{id}", func(writer http.ResponseWriter, request *http.Request) {
+		control := control.WithContext(request.Context())
 		id := request.PathValue("id")
 		function := models.Function{}
 		err := json.NewDecoder(request.Body).Decode(&function)
@@ -193,6 +196,7 @@ func (this *FunctionsEndpoints) Set(config config.Config, router *http.ServeMux,
 // @Router       /functions/{id} [DELETE]
 func (this *FunctionsEndpoints) Delete(config config.Config, router *http.ServeMux, control Controller) {
 	router.HandleFunc("DELETE /functions/{id}", func(writer http.ResponseWriter, request *http.Request) {
+		control := control.WithContext(request.Context())
 		id := request.PathValue("id")
 		token, err := auth.GetParsedToken(request)
 		if err != nil {
@@ -0,0 +1,84 @@
+/*
+ * Copyright 2024 InfAI (CC SES)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package api
+
+import (
+	"encoding/json"
+	"github.com/SENERGY-Platform/device-manager/lib/config"
+	"log"
+	"net/http"
+)
+
+func init() {
+	endpoints = append(endpoints, &HealthEndpoints{})
+}
+
+type HealthEndpoints struct{}
+
+// Live godoc
+// @Summary      liveness
+// @Description  returns 200 while the process is able to serve requests; dependencies are not checked
+// @Tags         health
+// @Success      200
+// @Router       /health/live [GET]
+func (this *HealthEndpoints) Live(config config.Config, router *http.ServeMux, control Controller) {
+	router.HandleFunc("GET /health/live", func(writer http.ResponseWriter, request *http.Request) {
+		writer.WriteHeader(http.StatusOK)
+	})
+}
+
+// Ready godoc
+// @Summary      readiness
+// @Description  returns 200 if all required dependencies are reachable, 503 otherwise
+// @Tags         health
+// @Produce      json
+// @Success      200 {object}  model.HealthStatus
+// @Failure      503 {object}  model.HealthStatus
+// @Router       /health/ready [GET]
+func (this *HealthEndpoints) Ready(config config.Config, router *http.ServeMux, control Controller) {
+	router.HandleFunc("GET /health/ready", func(writer http.ResponseWriter, request *http.Request) {
+		result := control.CheckHealth(request.Context())
+		code := http.StatusOK
+		if !result.Ready {
+			code = http.StatusServiceUnavailable
+		}
+		writer.Header().Set("Content-Type", "application/json; charset=utf-8")
+		writer.WriteHeader(code)
+		err := json.NewEncoder(writer).Encode(result)
+		if err != nil {
+			log.Println("ERROR: unable to encode response", err)
+		}
+	})
+}
+
+// Dependencies godoc
+// @Summary      dependency status
+// @Description  checks kafka (or the configured bus), device-repository, permissions-v2, converter and the done-wait listener, with latencies
+// @Tags         health
+// @Produce      json
+// @Success      200 {object}  model.HealthStatus
+// @Router       /health/dependencies [GET]
+func (this *HealthEndpoints) Dependencies(config config.Config, router *http.ServeMux, control Controller) {
+	router.HandleFunc("GET /health/dependencies", func(writer http.ResponseWriter, request *http.Request) {
+		result := control.CheckHealth(request.Context())
+		writer.Header().Set("Content-Type", "application/json; charset=utf-8")
+		err := json.NewEncoder(writer).Encode(result)
+		if err != nil {
+			log.Println("ERROR: unable to encode response", err)
+		}
+	})
+}
@@ -50,6 +50,7 @@ type HubsEndpoints struct{}
 // @Router       /hubs/{id} [GET]
 func (this *HubsEndpoints) Get(config config.Config, router *http.ServeMux, control Controller) {
 	router.HandleFunc("GET /hubs/{id}", func(writer http.ResponseWriter, request *http.Request) {
+		control := control.WithContext(request.Context())
 		id := request.PathValue("id")
 		token, err := auth.GetParsedToken(request)
 		if err != nil {
@@ -87,6 +88,7 @@ func (this *HubsEndpoints) Get(config config.Config, router *http.ServeMux, cont
 // @Router       /hubs/{id} [HEAD]
 func (this *HubsEndpoints) Head(config config.Config, router *http.ServeMux, control Controller) {
 	router.HandleFunc("HEAD /hubs/{id}", func(writer http.ResponseWriter, request *http.Request) {
+		control := control.WithContext(request.Context())
 		id := request.PathValue("id")
 		token, err := auth.GetParsedToken(request)
 		if err != nil {
@@ -116,6 +118,7 @@ func (this *HubsEndpoints) Head(config config.Config, router *http.ServeMux, con
 // @Router       /hubs [POST]
 func (this *HubsEndpoints) Create(config config.Config, router *http.ServeMux, control Controller) {
 	router.HandleFunc("POST /hubs", func(writer http.ResponseWriter, request *http.Request) {
+		control := control.WithContext(request.Context())
 		hub := models.Hub{}
 		err := json.NewDecoder(request.Body).Decode(&hub)
 		if err != nil {
@@ -175,6 +178,7 @@ func (this *HubsEndpoints) Create(config config.Config, router *http.ServeMux, c
 // @Router       /hubs/{id} [PUT]
 func (this *HubsEndpoints) Set(config config.Config, router *http.ServeMux, control Controller) {
 	router.HandleFunc("PUT /hubs/{id}", func(writer http.ResponseWriter, request *http.Request) {
+		control := control.WithContext(request.Context())
 		id := request.PathValue("id")
 		userId := request.URL.Query().Get("user_id")
 		hub := models.Hub{}
@@ -237,6 +241,7 @@ func (this *HubsEndpoints) Set(config config.Config, router *http.ServeMux, cont
 // @Router       /hubs/{id}/name [PUT]
 func (this *HubsEndpoints) SetName(config config.Config, router *http.ServeMux, control Controller) {
 	router.HandleFunc("PUT /hubs/{id}/name", func(writer http.ResponseWriter, request *http.Request) {
+		control := control.WithContext(request.Context())
 		id := request.PathValue("id")
 		name := ""
 		err := json.NewDecoder(request.Body).Decode(&name)
@@ -296,6 +301,7 @@ func (this *HubsEndpoints) SetName(config config.Config, router *http.ServeMux,
 // @Router       /hubs/{id} [DELETE]
 func (this *HubsEndpoints) Delete(config config.Config, router *http.ServeMux, control Controller) {
 	router.HandleFunc("DELETE /hubs/{id}", func(writer http.ResponseWriter, request *http.Request) {
+		control := control.WithContext(request.Context())
 		id := request.PathValue("id")
 		token, err := auth.GetParsedToken(request)
 		if err != nil {
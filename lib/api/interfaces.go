@@ -17,6 +17,7 @@
 package api
 
 import (
+	"context"
 	"github.com/SENERGY-Platform/device-manager/lib/auth"
 	"github.com/SENERGY-Platform/device-manager/lib/model"
 	"github.com/SENERGY-Platform/models/go/models"
@@ -33,6 +34,15 @@ type Controller interface {
 	PublishDeviceTypeCreate(token auth.Token, dt models.DeviceType, options model.DeviceTypeUpdateOptions) (result models.DeviceType, err error, code int)
 	PublishDeviceTypeUpdate(token auth.Token, id string, dt models.DeviceType, options model.DeviceTypeUpdateOptions) (result models.DeviceType, err error, code int)
 	PublishDeviceTypeDelete(token auth.Token, id string, options model.DeviceTypeDeleteOptions) (err error, code int)
+	MigrateDeviceType(token auth.Token, id string, request model.DeviceTypeMigrationRequest, progress func(model.DeviceTypeMigrationProgress)) (result model.DeviceTypeMigrationProgress, err error, code int)
+	StartDeviceTypeMigration(token auth.Token, id string, request model.DeviceTypeMigrationRequest) (result model.DeviceTypeMigrationJob, err error, code int)
+	ReadDeviceTypeMigration(token auth.Token, id string, jobId string) (result model.DeviceTypeMigrationJob, err error, code int)
+	CloneDeviceType(token auth.Token, id string, request model.CloneRequest, options model.DeviceTypeUpdateOptions) (result models.DeviceType, err error, code int)
+	AnalyzeDeviceTypeUpdate(token auth.Token, id string, dt models.DeviceType) (result model.DeviceTypeUpdateAnalysis, err error, code int)
+	ListDeviceTypeVersions(token auth.Token, id string) (result []model.DeviceTypeVersion, err error, code int)
+	ReadDeviceTypeVersion(token auth.Token, id string, version int) (result model.DeviceTypeVersion, err error, code int)
+	DiffDeviceTypeVersions(token auth.Token, id string, from int, to int) (result model.DeviceTypeVersionDiff, err error, code int)
+	RollbackDeviceType(token auth.Token, id string, version int, options model.DeviceTypeUpdateOptions) (result models.DeviceType, err error, code int)
 
 	ListDevicesByQuery(token auth.Token, query url.Values) (devices []models.Device, err error, code int)
 	ReadDevice(token auth.Token, id string) (device models.Device, err error, code int)
@@ -40,6 +50,7 @@ type Controller interface {
 	PublishDeviceCreate(token auth.Token, device models.Device, options model.DeviceCreateOptions) (result models.Device, err error, code int)
 	PublishDeviceUpdate(token auth.Token, id string, device models.Device, options model.DeviceUpdateOptions) (result models.Device, err error, code int)
 	PublishDeviceDelete(token auth.Token, id string, options model.DeviceDeleteOptions) (err error, code int)
+	CloneDevice(token auth.Token, id string, request model.CloneRequest, options model.DeviceCreateOptions) (result models.Device, err error, code int)
 
 	ReadHub(token auth.Token, id string) (hub models.Hub, err error, code int)
 	PublishHubCreate(token auth.Token, hub models.Hub, options model.HubUpdateOptions) (result models.Hub, err error, code int)
@@ -84,4 +95,39 @@ type Controller interface {
 	PublishLocationDelete(token auth.Token, id string, options model.LocationDeleteOptions) (err error, code int)
 
 	ValidateDistinctDeviceTypeAttributes(token auth.Token, devicetype models.DeviceType, attributeKeys []string) error
+
+	GetReadModelStatus(token auth.Token) (result []model.ReadModelTopicStatus, err error, code int)
+
+	Replay(token auth.Token, kind string, options model.ReplayOptions, progress func(model.ReplayProgress)) (result model.ReplayProgress, err error, code int)
+
+	ReadPermissions(token auth.Token, topic string, id string) (result model.ResourcePermissions, err error, code int)
+	SetPermissions(token auth.Token, topic string, id string, permissions model.ResourcePermissions) (result model.ResourcePermissions, err error, code int)
+	SetSubjectPermissions(token auth.Token, topic string, id string, subject string, subjectId string, permissions *model.PermissionsMap) (result model.ResourcePermissions, err error, code int)
+
+	ReadPropagation(token auth.Token, topic string, id string) (result model.Propagation, err error, code int)
+	SetPropagation(token auth.Token, topic string, id string, request model.PropagationRequest) (result model.Propagation, err error, code int)
+
+	CreateTransfer(token auth.Token, topic string, id string, request model.TransferRequest) (result model.Transfer, err error, code int)
+	ListTransfers(token auth.Token) (result []model.Transfer, err error, code int)
+	AcceptTransfer(token auth.Token, id string) (result model.Transfer, err error, code int)
+	DeleteTransfer(token auth.Token, id string) (err error, code int)
+
+	CreateShare(token auth.Token, topic string, id string, request model.ShareRequest) (result model.Share, err error, code int)
+	ListShares(token auth.Token, topic string, id string) (result []model.Share, err error, code int)
+	RevokeShare(token auth.Token, topic string, id string, shareId string) (err error, code int)
+
+	CreateApiKey(token auth.Token, request model.ApiKeyCreateRequest) (result model.ApiKeyCreated, err error, code int)
+	ListApiKeys(token auth.Token) (result []model.ApiKey, err error, code int)
+	RevokeApiKey(token auth.Token, id string) (err error, code int)
+	AuthenticateApiKey(secretKey string) (token auth.Token, scopes []model.ApiKeyScope, err error, code int)
+
+	QueryAudit(token auth.Token, query model.AuditQuery) (result []model.AuditRecord, err error, code int)
+
+	ListTrash(token auth.Token, kind string) (result []model.TrashEntry, err error, code int)
+	RestoreTrash(token auth.Token, id string) (result model.TrashEntry, err error, code int)
+
+	CheckHealth(ctx context.Context) (result model.HealthStatus)
+
+	// WithContext returns a controller which traces its upstream calls and publishes as children of the span in ctx
+	WithContext(ctx context.Context) Controller
 }
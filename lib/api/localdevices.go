@@ -61,6 +61,7 @@ type LocalDevicesEndpoints struct{}
 // @Router       /local-devices/{id} [GET]
 func (this *LocalDevicesEndpoints) List(config config.Config, router *http.ServeMux, control Controller) {
 	router.HandleFunc("GET /local-devices", func(writer http.ResponseWriter, request *http.Request) {
+		control := control.WithContext(request.Context())
 		token, err := jwt.GetParsedToken(request)
 		if err != nil {
 			http.Error(writer, err.Error(), http.StatusBadRequest)
@@ -123,6 +124,7 @@ func (this *LocalDevicesEndpoints) List(config config.Config, router *http.Serve
 // @Router       /local-devices/{id} [GET]
 func (this *LocalDevicesEndpoints) Get(config config.Config, router *http.ServeMux, control Controller) {
 	router.HandleFunc("GET /local-devices/{id}", func(writer http.ResponseWriter, request *http.Request) {
+		control := control.WithContext(request.Context())
 		id := request.PathValue("id")
 		token, err := auth.GetParsedToken(request)
 		if err != nil {
@@ -164,6 +166,7 @@ func (this *LocalDevicesEndpoints) Get(config config.Config, router *http.ServeM
 // @Router       /local-devices/{id} [POST]
 func (this *LocalDevicesEndpoints) Create(config config.Config, router *http.ServeMux, control Controller) {
 	router.HandleFunc("POST /local-devices", func(writer http.ResponseWriter, request *http.Request) {
+		control := control.WithContext(request.Context())
 		device := models.Device{}
 		err := json.NewDecoder(request.Body).Decode(&device)
 		if err != nil {
@@ -223,6 +226,7 @@ func (this *LocalDevicesEndpoints) Create(config config.Config, router *http.Ser
 // @Router       /local-devices/{id} [PUT]
 func (this *LocalDevicesEndpoints) Set(config config.Config, router *http.ServeMux, control Controller) {
 	router.HandleFunc("PUT /local-devices/{id}", func(writer http.ResponseWriter, request *http.Request) {
+		control := control.WithContext(request.Context())
 		id := request.PathValue("id")
 		token, err := auth.GetParsedToken(request)
 		if err != nil {
@@ -295,6 +299,7 @@ func (this *LocalDevicesEndpoints) Set(config config.Config, router *http.ServeM
 // @Router       /local-devices/{id} [DELETE]
 func (this *LocalDevicesEndpoints) Delete(config config.Config, router *http.ServeMux, control Controller) {
 	router.HandleFunc("DELETE /local-devices/{id}", func(writer http.ResponseWriter, request *http.Request) {
+		control := control.WithContext(request.Context())
 		id := request.PathValue("id")
 		token, err := auth.GetParsedToken(request)
 		if err != nil {
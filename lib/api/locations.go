@@ -50,6 +50,7 @@ type LocationsEndpoints struct{}
 // @Router       /locations/{id} [GET]
 func (this *LocationsEndpoints) Get(config config.Config, router *http.ServeMux, control Controller) {
 	router.HandleFunc("GET /locations/{id}", func(writer http.ResponseWriter, request *http.Request) {
+		control := control.WithContext(request.Context())
 		id := request.PathValue("id")
 		token, err := auth.GetParsedToken(request)
 		if err != nil {
@@ -88,6 +89,7 @@ func (this *LocationsEndpoints) Get(config config.Config, router *http.ServeMux,
 // @Router       /locations [POST]
 func (this *LocationsEndpoints) Create(config config.Config, router *http.ServeMux, control Controller) {
 	router.HandleFunc("POST /locations", func(writer http.ResponseWriter, request *http.Request) {
+		control := control.WithContext(request.Context())
 		location := models.Location{}
 		err := json.NewDecoder(request.Body).Decode(&location)
 		if err != nil {
@@ -145,6 +147,7 @@ func (this *LocationsEndpoints) Create(config config.Config, router *http.ServeM
 // @Router       /locations/{id} [PUT]
 func (this *LocationsEndpoints) Set(config config.Config, router *http.ServeMux, control Controller) {
 	router.HandleFunc("PUT /locations/{id}", func(writer http.ResponseWriter, request *http.Request) {
+		control := control.WithContext(request.Context())
 		id := request.PathValue("id")
 		location := models.Location{}
 		err := json.NewDecoder(request.Body).Decode(&location)
@@ -198,6 +201,7 @@ func (this *LocationsEndpoints) Set(config config.Config, router *http.ServeMux,
 // @Router       /locations/{id} [DELETE]
 func (this *LocationsEndpoints) Delete(config config.Config, router *http.ServeMux, control Controller) {
 	router.HandleFunc("DELETE /locations/{id}", func(writer http.ResponseWriter, request *http.Request) {
+		control := control.WithContext(request.Context())
 		id := request.PathValue("id")
 		token, err := auth.GetParsedToken(request)
 		if err != nil {
@@ -0,0 +1,40 @@
+/*
+ * Copyright 2024 InfAI (CC SES)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package api
+
+import (
+	"github.com/SENERGY-Platform/device-manager/lib/config"
+	"github.com/SENERGY-Platform/device-manager/lib/metrics"
+	"net/http"
+)
+
+func init() {
+	endpoints = append(endpoints, &MetricsEndpoints{})
+}
+
+type MetricsEndpoints struct{}
+
+// Metrics godoc
+// @Summary      prometheus metrics
+// @Description  request, publish, done-wait, upstream call and listener metrics in the prometheus text format
+// @Tags         metrics
+// @Produce      plain
+// @Success      200
+// @Router       /metrics [GET]
+func (this *MetricsEndpoints) Metrics(config config.Config, router *http.ServeMux, control Controller) {
+	router.Handle("GET /metrics", metrics.Handler())
+}
@@ -0,0 +1,219 @@
+/*
+ * Copyright 2024 InfAI (CC SES)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package api
+
+import (
+	"encoding/json"
+	"github.com/SENERGY-Platform/device-manager/lib/auth"
+	"github.com/SENERGY-Platform/device-manager/lib/config"
+	"github.com/SENERGY-Platform/device-manager/lib/model"
+	"log"
+	"net/http"
+)
+
+func init() {
+	endpoints = append(endpoints, &PermissionsEndpoints{})
+}
+
+type PermissionsEndpoints struct{}
+
+// permissionResources maps the path segments of resources with permission management to their topics
+func permissionResources(config config.Config) map[string]string {
+	return map[string]string{
+		"devices":       config.DeviceTopic,
+		"hubs":          config.HubTopic,
+		"device-groups": config.DeviceGroupTopic,
+		"locations":     config.LocationTopic,
+		"device-types":  config.DeviceTypeTopic,
+	}
+}
+
+// Get godoc
+// @Summary      get permissions
+// @Description  get the user, group and role permissions of a resource; requires administrate rights
+// @Tags         get, permissions
+// @Produce      json
+// @Security Bearer
+// @Param        id path string true "Resource Id"
+// @Success      200 {object}  model.ResourcePermissions
+// @Failure      400
+// @Failure      401
+// @Failure      403
+// @Failure      404
+// @Failure      500
+// @Router       /devices/{id}/permissions [GET]
+// @Router       /hubs/{id}/permissions [GET]
+// @Router       /device-groups/{id}/permissions [GET]
+// @Router       /locations/{id}/permissions [GET]
+// @Router       /device-types/{id}/permissions [GET]
+func (this *PermissionsEndpoints) Get(config config.Config, router *http.ServeMux, control Controller) {
+	for path, topic := range permissionResources(config) {
+		router.HandleFunc("GET /"+path+"/{id}/permissions", func(writer http.ResponseWriter, request *http.Request) {
+			control := control.WithContext(request.Context())
+			token, err := auth.GetParsedToken(request)
+			if err != nil {
+				http.Error(writer, err.Error(), http.StatusBadRequest)
+				return
+			}
+			result, err, errCode := control.ReadPermissions(token, topic, request.PathValue("id"))
+			writePermissions(writer, result, err, errCode)
+		})
+	}
+}
+
+// Set godoc
+// @Summary      set permissions
+// @Description  replaces the permissions of a resource; requires administrate rights. at least one user must keep administrate rights, the owner of devices and hubs must keep them.
+// @Tags         update, permissions
+// @Accept       json
+// @Produce      json
+// @Security Bearer
+// @Param        id path string true "Resource Id"
+// @Param        message body model.ResourcePermissions true "permissions"
+// @Success      200 {object}  model.ResourcePermissions
+// @Failure      400
+// @Failure      401
+// @Failure      403
+// @Failure      404
+// @Failure      500
+// @Router       /devices/{id}/permissions [PUT]
+// @Router       /hubs/{id}/permissions [PUT]
+// @Router       /device-groups/{id}/permissions [PUT]
+// @Router       /locations/{id}/permissions [PUT]
+// @Router       /device-types/{id}/permissions [PUT]
+func (this *PermissionsEndpoints) Set(config config.Config, router *http.ServeMux, control Controller) {
+	for path, topic := range permissionResources(config) {
+		router.HandleFunc("PUT /"+path+"/{id}/permissions", func(writer http.ResponseWriter, request *http.Request) {
+			control := control.WithContext(request.Context())
+			token, err := auth.GetParsedToken(request)
+			if err != nil {
+				http.Error(writer, err.Error(), http.StatusBadRequest)
+				return
+			}
+			permissions := model.ResourcePermissions{}
+			err = json.NewDecoder(request.Body).Decode(&permissions)
+			if err != nil {
+				http.Error(writer, err.Error(), http.StatusBadRequest)
+				return
+			}
+			result, err, errCode := control.SetPermissions(token, topic, request.PathValue("id"), permissions)
+			writePermissions(writer, result, err, errCode)
+		})
+	}
+}
+
+// SetSubject godoc
+// @Summary      set permissions of user or group
+// @Description  sets the permissions of a single user or group and keeps all other permissions of the resource; requires administrate rights
+// @Tags         update, permissions
+// @Accept       json
+// @Produce      json
+// @Security Bearer
+// @Param        id path string true "Resource Id"
+// @Param        subject-id path string true "User or Group Id"
+// @Param        message body model.PermissionsMap true "permissions"
+// @Success      200 {object}  model.ResourcePermissions
+// @Failure      400
+// @Failure      401
+// @Failure      403
+// @Failure      404
+// @Failure      500
+// @Router       /devices/{id}/permissions/users/{subject-id} [PUT]
+// @Router       /devices/{id}/permissions/groups/{subject-id} [PUT]
+// @Router       /hubs/{id}/permissions/users/{subject-id} [PUT]
+// @Router       /hubs/{id}/permissions/groups/{subject-id} [PUT]
+// @Router       /device-groups/{id}/permissions/users/{subject-id} [PUT]
+// @Router       /device-groups/{id}/permissions/groups/{subject-id} [PUT]
+// @Router       /locations/{id}/permissions/users/{subject-id} [PUT]
+// @Router       /locations/{id}/permissions/groups/{subject-id} [PUT]
+// @Router       /device-types/{id}/permissions/users/{subject-id} [PUT]
+// @Router       /device-types/{id}/permissions/groups/{subject-id} [PUT]
+func (this *PermissionsEndpoints) SetSubject(config config.Config, router *http.ServeMux, control Controller) {
+	for path, topic := range permissionResources(config) {
+		for _, subject := range []string{model.PermissionSubjectUsers, model.PermissionSubjectGroups} {
+			router.HandleFunc("PUT /"+path+"/{id}/permissions/"+subject+"/{subjectId}", func(writer http.ResponseWriter, request *http.Request) {
+				control := control.WithContext(request.Context())
+				token, err := auth.GetParsedToken(request)
+				if err != nil {
+					http.Error(writer, err.Error(), http.StatusBadRequest)
+					return
+				}
+				permissions := model.PermissionsMap{}
+				err = json.NewDecoder(request.Body).Decode(&permissions)
+				if err != nil {
+					http.Error(writer, err.Error(), http.StatusBadRequest)
+					return
+				}
+				result, err, errCode := control.SetSubjectPermissions(token, topic, request.PathValue("id"), subject, request.PathValue("subjectId"), &permissions)
+				writePermissions(writer, result, err, errCode)
+			})
+		}
+	}
+}
+
+// RemoveSubject godoc
+// @Summary      remove permissions of user or group
+// @Description  removes all permissions of a single user or group from the resource; requires administrate rights
+// @Tags         delete, permissions
+// @Produce      json
+// @Security Bearer
+// @Param        id path string true "Resource Id"
+// @Param        subject-id path string true "User or Group Id"
+// @Success      200 {object}  model.ResourcePermissions
+// @Failure      400
+// @Failure      401
+// @Failure      403
+// @Failure      404
+// @Failure      500
+// @Router       /devices/{id}/permissions/users/{subject-id} [DELETE]
+// @Router       /devices/{id}/permissions/groups/{subject-id} [DELETE]
+// @Router       /hubs/{id}/permissions/users/{subject-id} [DELETE]
+// @Router       /hubs/{id}/permissions/groups/{subject-id} [DELETE]
+// @Router       /device-groups/{id}/permissions/users/{subject-id} [DELETE]
+// @Router       /device-groups/{id}/permissions/groups/{subject-id} [DELETE]
+// @Router       /locations/{id}/permissions/users/{subject-id} [DELETE]
+// @Router       /locations/{id}/permissions/groups/{subject-id} [DELETE]
+// @Router       /device-types/{id}/permissions/users/{subject-id} [DELETE]
+// @Router       /device-types/{id}/permissions/groups/{subject-id} [DELETE]
+func (this *PermissionsEndpoints) RemoveSubject(config config.Config, router *http.ServeMux, control Controller) {
+	for path, topic := range permissionResources(config) {
+		for _, subject := range []string{model.PermissionSubjectUsers, model.PermissionSubjectGroups} {
+			router.HandleFunc("DELETE /"+path+"/{id}/permissions/"+subject+"/{subjectId}", func(writer http.ResponseWriter, request *http.Request) {
+				control := control.WithContext(request.Context())
+				token, err := auth.GetParsedToken(request)
+				if err != nil {
+					http.Error(writer, err.Error(), http.StatusBadRequest)
+					return
+				}
+				result, err, errCode := control.SetSubjectPermissions(token, topic, request.PathValue("id"), subject, request.PathValue("subjectId"), nil)
+				writePermissions(writer, result, err, errCode)
+			})
+		}
+	}
+}
+
+func writePermissions(writer http.ResponseWriter, result model.ResourcePermissions, err error, errCode int) {
+	if err != nil {
+		http.Error(writer, err.Error(), errCode)
+		return
+	}
+	writer.Header().Set("Content-Type", "application/json; charset=utf-8")
+	err = json.NewEncoder(writer).Encode(result)
+	if err != nil {
+		log.Println("ERROR: unable to encode response", err)
+	}
+}
@@ -0,0 +1,116 @@
+/*
+ * Copyright 2024 InfAI (CC SES)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package api
+
+import (
+	"encoding/json"
+	"github.com/SENERGY-Platform/device-manager/lib/auth"
+	"github.com/SENERGY-Platform/device-manager/lib/config"
+	"github.com/SENERGY-Platform/device-manager/lib/model"
+	"log"
+	"net/http"
+)
+
+func init() {
+	endpoints = append(endpoints, &PropagationEndpoints{})
+}
+
+type PropagationEndpoints struct{}
+
+// Get godoc
+// @Summary      get propagate-sharing
+// @Description  get the propagate-sharing flag of a location or device-group and the rights it granted on member devices; requires administrate rights
+// @Tags         get, permissions
+// @Produce      json
+// @Security Bearer
+// @Param        id path string true "Location or Device-Group Id"
+// @Success      200 {object}  model.Propagation
+// @Failure      400
+// @Failure      401
+// @Failure      403
+// @Failure      404
+// @Failure      500
+// @Router       /locations/{id}/propagation [GET]
+// @Router       /device-groups/{id}/propagation [GET]
+func (this *PropagationEndpoints) Get(config config.Config, router *http.ServeMux, control Controller) {
+	for path, topic := range map[string]string{"locations": config.LocationTopic, "device-groups": config.DeviceGroupTopic} {
+		router.HandleFunc("GET /"+path+"/{id}/propagation", func(writer http.ResponseWriter, request *http.Request) {
+			control := control.WithContext(request.Context())
+			token, err := auth.GetParsedToken(request)
+			if err != nil {
+				http.Error(writer, err.Error(), http.StatusBadRequest)
+				return
+			}
+			result, err, errCode := control.ReadPropagation(token, topic, request.PathValue("id"))
+			if err != nil {
+				http.Error(writer, err.Error(), errCode)
+				return
+			}
+			writer.Header().Set("Content-Type", "application/json; charset=utf-8")
+			err = json.NewEncoder(writer).Encode(result)
+			if err != nil {
+				log.Println("ERROR: unable to encode response", err)
+			}
+		})
+	}
+}
+
+// Set godoc
+// @Summary      set propagate-sharing
+// @Description  enables or disables the propagation of the user permissions (without administrate) of a location or device-group to its member devices. rights are granted on devices the requesting user may administrate, also on devices added later; removed users and devices lose the propagated rights. requires administrate rights.
+// @Tags         update, permissions
+// @Accept       json
+// @Produce      json
+// @Security Bearer
+// @Param        id path string true "Location or Device-Group Id"
+// @Param        message body model.PropagationRequest true "flag"
+// @Success      200 {object}  model.Propagation
+// @Failure      400
+// @Failure      401
+// @Failure      403
+// @Failure      404
+// @Failure      500
+// @Router       /locations/{id}/propagation [PUT]
+// @Router       /device-groups/{id}/propagation [PUT]
+func (this *PropagationEndpoints) Set(config config.Config, router *http.ServeMux, control Controller) {
+	for path, topic := range map[string]string{"locations": config.LocationTopic, "device-groups": config.DeviceGroupTopic} {
+		router.HandleFunc("PUT /"+path+"/{id}/propagation", func(writer http.ResponseWriter, request *http.Request) {
+			control := control.WithContext(request.Context())
+			token, err := auth.GetParsedToken(request)
+			if err != nil {
+				http.Error(writer, err.Error(), http.StatusBadRequest)
+				return
+			}
+			propagationRequest := model.PropagationRequest{}
+			err = json.NewDecoder(request.Body).Decode(&propagationRequest)
+			if err != nil {
+				http.Error(writer, err.Error(), http.StatusBadRequest)
+				return
+			}
+			result, err, errCode := control.SetPropagation(token, topic, request.PathValue("id"), propagationRequest)
+			if err != nil {
+				http.Error(writer, err.Error(), errCode)
+				return
+			}
+			writer.Header().Set("Content-Type", "application/json; charset=utf-8")
+			err = json.NewEncoder(writer).Encode(result)
+			if err != nil {
+				log.Println("ERROR: unable to encode response", err)
+			}
+		})
+	}
+}
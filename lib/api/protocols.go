@@ -51,6 +51,7 @@ type ProtocolsEndpoints struct{}
 // @Router       /protocols/{id} [GET]
 func (this *ProtocolsEndpoints) Get(config config.Config, router *http.ServeMux, control Controller) {
 	router.HandleFunc("GET /protocols/{id}", func(writer http.ResponseWriter, request *http.Request) {
+		control := control.WithContext(request.Context())
 		id := request.PathValue("id")
 		token, err := auth.GetParsedToken(request)
 		if err != nil {
@@ -88,6 +89,7 @@ func (this *ProtocolsEndpoints) Get(config config.Config, router *http.ServeMux,
 // @Router       /protocols [POST]
 func (this *ProtocolsEndpoints) Create(config config.Config, router *http.ServeMux, control Controller) {
 	router.HandleFunc("POST /protocols", func(writer http.ResponseWriter, request *http.Request) {
+		control := control.WithContext(request.Context())
 		protocol := models.Protocol{}
 		err := json.NewDecoder(request.Body).Decode(&protocol)
 		if err != nil {
@@ -141,6 +143,7 @@ func (this *ProtocolsEndpoints) Create(config config.Config, router *http.ServeM
 // @Router       /protocols/{id} [PUT]
 func (this *ProtocolsEndpoints) Set(config config.Config, router *http.ServeMux, control Controller) {
 	router.HandleFunc("PUT /protocols/{id}", func(writer http.ResponseWriter, request *http.Request) {
+		control := control.WithContext(request.Context())
 		id := request.PathValue("id")
 		protocol := models.Protocol{}
 		err := json.NewDecoder(request.Body).Decode(&protocol)
@@ -194,6 +197,7 @@ func (this *ProtocolsEndpoints) Set(config config.Config, router *http.ServeMux,
 // @Router       /protocols/{id} [DELETE]
 func (this *ProtocolsEndpoints) Delete(config config.Config, router *http.ServeMux, control Controller) {
 	router.HandleFunc("DELETE /protocols/{id}", func(writer http.ResponseWriter, request *http.Request) {
+		control := control.WithContext(request.Context())
 		id := request.PathValue("id")
 		token, err := auth.GetParsedToken(request)
 		if err != nil {
@@ -0,0 +1,65 @@
+/*
+ * Copyright 2024 InfAI (CC SES)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package api
+
+import (
+	"encoding/json"
+	"github.com/SENERGY-Platform/device-manager/lib/auth"
+	"github.com/SENERGY-Platform/device-manager/lib/config"
+	"log"
+	"net/http"
+)
+
+func init() {
+	endpoints = append(endpoints, &ReadModelEndpoints{})
+}
+
+type ReadModelEndpoints struct{}
+
+// Status godoc
+// @Summary      read model status
+// @Description  freshness of the local read model per topic; reads fall back to the device-repository for topics that are not fresh
+// @Tags         read-model
+// @Produce      json
+// @Security Bearer
+// @Success      200 {array}  model.ReadModelTopicStatus
+// @Failure      400
+// @Failure      401
+// @Failure      404
+// @Failure      500
+// @Router       /read-model/status [GET]
+func (this *ReadModelEndpoints) Status(config config.Config, router *http.ServeMux, control Controller) {
+	router.HandleFunc("GET /read-model/status", func(writer http.ResponseWriter, request *http.Request) {
+		control := control.WithContext(request.Context())
+		token, err := auth.GetParsedToken(request)
+		if err != nil {
+			http.Error(writer, err.Error(), http.StatusBadRequest)
+			return
+		}
+		result, err, errCode := control.GetReadModelStatus(token)
+		if err != nil {
+			http.Error(writer, err.Error(), errCode)
+			return
+		}
+		writer.Header().Set("Content-Type", "application/json; charset=utf-8")
+		err = json.NewEncoder(writer).Encode(result)
+		if err != nil {
+			log.Println("ERROR: unable to encode response", err)
+		}
+		return
+	})
+}
@@ -0,0 +1,111 @@
+/*
+ * Copyright 2024 InfAI (CC SES)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/SENERGY-Platform/device-manager/lib/auth"
+	"github.com/SENERGY-Platform/device-manager/lib/config"
+	"github.com/SENERGY-Platform/device-manager/lib/model"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+func init() {
+	endpoints = append(endpoints, &ReplayEndpoints{})
+}
+
+type ReplayEndpoints struct{}
+
+// Replay godoc
+// @Summary      replay resources
+// @Description  reads all resources of a kind from the device-repository and republishes them as PUT commands; to repair the state of downstream consumers; only for admins.
+// @Description  the replay stops, if the client disconnects; resources are read in id order
+// @Tags         admin, replay
+// @Produce      json
+// @Security Bearer
+// @Param        kind path string true "resource kind (topic name, e.g. devices, device-types, hubs)"
+// @Param        ids query string false "filter; comma-seperated list"
+// @Param        dry-run query bool false "only count resources, nothing is published"
+// @Param        rate query number false "max published commands per second; default unlimited"
+// @Param        batch-size query integer false "page size used to read from the device-repository; default 1000"
+// @Success      200 {object}  model.ReplayProgress
+// @Failure      400
+// @Failure      401
+// @Failure      403
+// @Failure      500
+// @Router       /admin/replay/{kind} [POST]
+func (this *ReplayEndpoints) Replay(config config.Config, router *http.ServeMux, control Controller) {
+	router.HandleFunc("POST /admin/replay/{kind}", func(writer http.ResponseWriter, request *http.Request) {
+		control := control.WithContext(request.Context())
+		kind := request.PathValue("kind")
+		token, err := auth.GetParsedToken(request)
+		if err != nil {
+			http.Error(writer, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		options := model.ReplayOptions{}
+		query := request.URL.Query()
+		if query.Has("ids") {
+			options.Ids = []string{}
+			for _, id := range strings.Split(query.Get("ids"), ",") {
+				if id = strings.TrimSpace(id); id != "" {
+					options.Ids = append(options.Ids, id)
+				}
+			}
+		}
+		if dryRun := query.Get("dry-run"); dryRun != "" {
+			options.DryRun, err = strconv.ParseBool(dryRun)
+			if err != nil {
+				http.Error(writer, fmt.Sprintf("invalid dry-run query parameter %v", err.Error()), http.StatusBadRequest)
+				return
+			}
+		}
+		if rate := query.Get("rate"); rate != "" {
+			options.RatePerSecond, err = strconv.ParseFloat(rate, 64)
+			if err != nil {
+				http.Error(writer, fmt.Sprintf("invalid rate query parameter %v", err.Error()), http.StatusBadRequest)
+				return
+			}
+		}
+		if batchSize := query.Get("batch-size"); batchSize != "" {
+			options.BatchSize, err = strconv.ParseInt(batchSize, 10, 64)
+			if err != nil {
+				http.Error(writer, fmt.Sprintf("invalid batch-size query parameter %v", err.Error()), http.StatusBadRequest)
+				return
+			}
+		}
+
+		result, err, errCode := control.Replay(token, kind, options, func(progress model.ReplayProgress) {
+			log.Printf("replay %v: listed=%v published=%v failed=%v done=%v\n", progress.Kind, progress.Listed, progress.Published, len(progress.Failed), progress.Done)
+		})
+		if err != nil {
+			http.Error(writer, err.Error(), errCode)
+			return
+		}
+		writer.Header().Set("Content-Type", "application/json; charset=utf-8")
+		err = json.NewEncoder(writer).Encode(result)
+		if err != nil {
+			log.Println("ERROR: unable to encode response", err)
+		}
+		return
+	})
+}
@@ -0,0 +1,162 @@
+/*
+ * Copyright 2024 InfAI (CC SES)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package api
+
+import (
+	"encoding/json"
+	"github.com/SENERGY-Platform/device-manager/lib/auth"
+	"github.com/SENERGY-Platform/device-manager/lib/config"
+	"github.com/SENERGY-Platform/device-manager/lib/model"
+	"log"
+	"net/http"
+)
+
+func init() {
+	endpoints = append(endpoints, &ShareEndpoints{})
+}
+
+type ShareEndpoints struct{}
+
+// shareResources maps the path segments of resources which may be shared to their topics
+func shareResources(config config.Config) map[string]string {
+	return map[string]string{
+		"devices":       config.DeviceTopic,
+		"device-groups": config.DeviceGroupTopic,
+		"locations":     config.LocationTopic,
+	}
+}
+
+// Create godoc
+// @Summary      share resource
+// @Description  grants read, write and/or execute rights to a user until expires_at; expired shares are revoked in the background and the previous permissions of the user restored. requires administrate rights.
+// @Tags         create, shares
+// @Accept       json
+// @Produce      json
+// @Security Bearer
+// @Param        id path string true "Resource Id"
+// @Param        message body model.ShareRequest true "share"
+// @Success      200 {object}  model.Share
+// @Failure      400
+// @Failure      401
+// @Failure      403
+// @Failure      404
+// @Failure      500
+// @Router       /devices/{id}/shares [POST]
+// @Router       /device-groups/{id}/shares [POST]
+// @Router       /locations/{id}/shares [POST]
+func (this *ShareEndpoints) Create(config config.Config, router *http.ServeMux, control Controller) {
+	for path, topic := range shareResources(config) {
+		router.HandleFunc("POST /"+path+"/{id}/shares", func(writer http.ResponseWriter, request *http.Request) {
+			control := control.WithContext(request.Context())
+			token, err := auth.GetParsedToken(request)
+			if err != nil {
+				http.Error(writer, err.Error(), http.StatusBadRequest)
+				return
+			}
+			shareRequest := model.ShareRequest{}
+			err = json.NewDecoder(request.Body).Decode(&shareRequest)
+			if err != nil {
+				http.Error(writer, err.Error(), http.StatusBadRequest)
+				return
+			}
+			result, err, errCode := control.CreateShare(token, topic, request.PathValue("id"), shareRequest)
+			if err != nil {
+				http.Error(writer, err.Error(), errCode)
+				return
+			}
+			writer.Header().Set("Content-Type", "application/json; charset=utf-8")
+			err = json.NewEncoder(writer).Encode(result)
+			if err != nil {
+				log.Println("ERROR: unable to encode response", err)
+			}
+		})
+	}
+}
+
+// List godoc
+// @Summary      list shares
+// @Description  lists the active and revoked shares of a resource; requires administrate rights
+// @Tags         list, shares
+// @Produce      json
+// @Security Bearer
+// @Param        id path string true "Resource Id"
+// @Success      200 {array}  model.Share
+// @Failure      400
+// @Failure      401
+// @Failure      403
+// @Failure      404
+// @Failure      500
+// @Router       /devices/{id}/shares [GET]
+// @Router       /device-groups/{id}/shares [GET]
+// @Router       /locations/{id}/shares [GET]
+func (this *ShareEndpoints) List(config config.Config, router *http.ServeMux, control Controller) {
+	for path, topic := range shareResources(config) {
+		router.HandleFunc("GET /"+path+"/{id}/shares", func(writer http.ResponseWriter, request *http.Request) {
+			control := control.WithContext(request.Context())
+			token, err := auth.GetParsedToken(request)
+			if err != nil {
+				http.Error(writer, err.Error(), http.StatusBadRequest)
+				return
+			}
+			result, err, errCode := control.ListShares(token, topic, request.PathValue("id"))
+			if err != nil {
+				http.Error(writer, err.Error(), errCode)
+				return
+			}
+			writer.Header().Set("Content-Type", "application/json; charset=utf-8")
+			err = json.NewEncoder(writer).Encode(result)
+			if err != nil {
+				log.Println("ERROR: unable to encode response", err)
+			}
+		})
+	}
+}
+
+// Revoke godoc
+// @Summary      revoke share
+// @Description  revokes a share before its expiry and restores the previous permissions of the user; requires administrate rights
+// @Tags         delete, shares
+// @Security Bearer
+// @Param        id path string true "Resource Id"
+// @Param        share-id path string true "Share Id"
+// @Success      200
+// @Failure      400
+// @Failure      401
+// @Failure      403
+// @Failure      404
+// @Failure      500
+// @Router       /devices/{id}/shares/{share-id} [DELETE]
+// @Router       /device-groups/{id}/shares/{share-id} [DELETE]
+// @Router       /locations/{id}/shares/{share-id} [DELETE]
+func (this *ShareEndpoints) Revoke(config config.Config, router *http.ServeMux, control Controller) {
+	for path, topic := range shareResources(config) {
+		router.HandleFunc("DELETE /"+path+"/{id}/shares/{shareId}", func(writer http.ResponseWriter, request *http.Request) {
+			control := control.WithContext(request.Context())
+			token, err := auth.GetParsedToken(request)
+			if err != nil {
+				http.Error(writer, err.Error(), http.StatusBadRequest)
+				return
+			}
+			err, errCode := control.RevokeShare(token, topic, request.PathValue("id"), request.PathValue("shareId"))
+			if err != nil {
+				http.Error(writer, err.Error(), errCode)
+				return
+			}
+			writer.WriteHeader(http.StatusOK)
+		})
+	}
+}
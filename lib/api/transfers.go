@@ -0,0 +1,177 @@
+/*
+ * Copyright 2024 InfAI (CC SES)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package api
+
+import (
+	"encoding/json"
+	"github.com/SENERGY-Platform/device-manager/lib/auth"
+	"github.com/SENERGY-Platform/device-manager/lib/config"
+	"github.com/SENERGY-Platform/device-manager/lib/model"
+	"log"
+	"net/http"
+)
+
+func init() {
+	endpoints = append(endpoints, &TransferEndpoints{})
+}
+
+type TransferEndpoints struct{}
+
+// Create godoc
+// @Summary      create ownership transfer
+// @Description  creates a pending ownership transfer to user_id, which the recipient accepts with POST /transfers/{id}/accept; only for the owner and admins. pending transfers expire.
+// @Tags         create, transfers
+// @Accept       json
+// @Produce      json
+// @Security Bearer
+// @Param        id path string true "Device or Hub Id"
+// @Param        message body model.TransferRequest true "transfer"
+// @Success      200 {object}  model.Transfer
+// @Failure      400
+// @Failure      401
+// @Failure      403
+// @Failure      404
+// @Failure      500
+// @Router       /devices/{id}/transfer [POST]
+// @Router       /hubs/{id}/transfer [POST]
+func (this *TransferEndpoints) Create(config config.Config, router *http.ServeMux, control Controller) {
+	for path, topic := range map[string]string{"devices": config.DeviceTopic, "hubs": config.HubTopic} {
+		router.HandleFunc("POST /"+path+"/{id}/transfer", func(writer http.ResponseWriter, request *http.Request) {
+			control := control.WithContext(request.Context())
+			token, err := auth.GetParsedToken(request)
+			if err != nil {
+				http.Error(writer, err.Error(), http.StatusBadRequest)
+				return
+			}
+			transferRequest := model.TransferRequest{}
+			err = json.NewDecoder(request.Body).Decode(&transferRequest)
+			if err != nil {
+				http.Error(writer, err.Error(), http.StatusBadRequest)
+				return
+			}
+			result, err, errCode := control.CreateTransfer(token, topic, request.PathValue("id"), transferRequest)
+			if err != nil {
+				http.Error(writer, err.Error(), errCode)
+				return
+			}
+			writer.Header().Set("Content-Type", "application/json; charset=utf-8")
+			err = json.NewEncoder(writer).Encode(result)
+			if err != nil {
+				log.Println("ERROR: unable to encode response", err)
+			}
+		})
+	}
+}
+
+// List godoc
+// @Summary      list ownership transfers
+// @Description  lists the pending ownership transfers from or to the requesting user
+// @Tags         list, transfers
+// @Produce      json
+// @Security Bearer
+// @Success      200 {array}  model.Transfer
+// @Failure      400
+// @Failure      401
+// @Failure      500
+// @Router       /transfers [GET]
+func (this *TransferEndpoints) List(config config.Config, router *http.ServeMux, control Controller) {
+	router.HandleFunc("GET /transfers", func(writer http.ResponseWriter, request *http.Request) {
+		control := control.WithContext(request.Context())
+		token, err := auth.GetParsedToken(request)
+		if err != nil {
+			http.Error(writer, err.Error(), http.StatusBadRequest)
+			return
+		}
+		result, err, errCode := control.ListTransfers(token)
+		if err != nil {
+			http.Error(writer, err.Error(), errCode)
+			return
+		}
+		writer.Header().Set("Content-Type", "application/json; charset=utf-8")
+		err = json.NewEncoder(writer).Encode(result)
+		if err != nil {
+			log.Println("ERROR: unable to encode response", err)
+		}
+		return
+	})
+}
+
+// Accept godoc
+// @Summary      accept ownership transfer
+// @Description  grants the recipient administrate rights, sets the recipient as owner and optionally removes the permissions of the old owner; only for the recipient
+// @Tags         update, transfers
+// @Produce      json
+// @Security Bearer
+// @Param        id path string true "Transfer Id"
+// @Success      200 {object}  model.Transfer
+// @Failure      400
+// @Failure      401
+// @Failure      404
+// @Failure      409
+// @Failure      500
+// @Router       /transfers/{id}/accept [POST]
+func (this *TransferEndpoints) Accept(config config.Config, router *http.ServeMux, control Controller) {
+	router.HandleFunc("POST /transfers/{id}/accept", func(writer http.ResponseWriter, request *http.Request) {
+		control := control.WithContext(request.Context())
+		token, err := auth.GetParsedToken(request)
+		if err != nil {
+			http.Error(writer, err.Error(), http.StatusBadRequest)
+			return
+		}
+		result, err, errCode := control.AcceptTransfer(token, request.PathValue("id"))
+		if err != nil {
+			http.Error(writer, err.Error(), errCode)
+			return
+		}
+		writer.Header().Set("Content-Type", "application/json; charset=utf-8")
+		err = json.NewEncoder(writer).Encode(result)
+		if err != nil {
+			log.Println("ERROR: unable to encode response", err)
+		}
+		return
+	})
+}
+
+// Delete godoc
+// @Summary      cancel ownership transfer
+// @Description  cancels or declines a pending ownership transfer; for the old owner, the recipient and admins
+// @Tags         delete, transfers
+// @Security Bearer
+// @Param        id path string true "Transfer Id"
+// @Success      200
+// @Failure      400
+// @Failure      401
+// @Failure      404
+// @Failure      500
+// @Router       /transfers/{id} [DELETE]
+func (this *TransferEndpoints) Delete(config config.Config, router *http.ServeMux, control Controller) {
+	router.HandleFunc("DELETE /transfers/{id}", func(writer http.ResponseWriter, request *http.Request) {
+		control := control.WithContext(request.Context())
+		token, err := auth.GetParsedToken(request)
+		if err != nil {
+			http.Error(writer, err.Error(), http.StatusBadRequest)
+			return
+		}
+		err, errCode := control.DeleteTransfer(token, request.PathValue("id"))
+		if err != nil {
+			http.Error(writer, err.Error(), errCode)
+			return
+		}
+		writer.WriteHeader(http.StatusOK)
+		return
+	})
+}
@@ -0,0 +1,107 @@
+/*
+ * Copyright 2024 InfAI (CC SES)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package api
+
+import (
+	"encoding/json"
+	"github.com/SENERGY-Platform/device-manager/lib/auth"
+	"github.com/SENERGY-Platform/device-manager/lib/config"
+	"github.com/SENERGY-Platform/device-manager/lib/model"
+	"log"
+	"net/http"
+)
+
+func init() {
+	endpoints = append(endpoints, &TrashEndpoints{})
+}
+
+type TrashEndpoints struct{}
+
+// List godoc
+// @Summary      list trash
+// @Description  lists soft deleted resources, which the user deleted or could administrate; admins get all entries. needs soft_delete.
+// @Tags         list, trash
+// @Produce      json
+// @Security Bearer
+// @Param        kind query string false "resource kind (topic), e.g. devices"
+// @Success      200 {array}  model.TrashEntry
+// @Failure      400
+// @Failure      401
+// @Failure      404
+// @Failure      500
+// @Router       /trash [GET]
+func (this *TrashEndpoints) List(config config.Config, router *http.ServeMux, control Controller) {
+	router.HandleFunc("GET /trash", func(writer http.ResponseWriter, request *http.Request) {
+		control := control.WithContext(request.Context())
+		token, err := auth.GetParsedToken(request)
+		if err != nil {
+			http.Error(writer, err.Error(), http.StatusBadRequest)
+			return
+		}
+		result, err, errCode := control.ListTrash(token, request.URL.Query().Get("kind"))
+		if err != nil {
+			http.Error(writer, err.Error(), errCode)
+			return
+		}
+		if result == nil {
+			result = []model.TrashEntry{}
+		}
+		writer.Header().Set("Content-Type", "application/json; charset=utf-8")
+		err = json.NewEncoder(writer).Encode(result)
+		if err != nil {
+			log.Println("ERROR: unable to encode response", err)
+		}
+		return
+	})
+}
+
+// Restore godoc
+// @Summary      restore resource
+// @Description  republishes a soft deleted resource with its original id and permissions and removes it from the trash
+// @Tags         trash
+// @Produce      json
+// @Security Bearer
+// @Param        id path string true "trash entry id"
+// @Success      200 {object}  model.TrashEntry
+// @Failure      400
+// @Failure      401
+// @Failure      403
+// @Failure      404
+// @Failure      409
+// @Failure      500
+// @Router       /trash/{id}/restore [POST]
+func (this *TrashEndpoints) Restore(config config.Config, router *http.ServeMux, control Controller) {
+	router.HandleFunc("POST /trash/{id}/restore", func(writer http.ResponseWriter, request *http.Request) {
+		control := control.WithContext(request.Context())
+		token, err := auth.GetParsedToken(request)
+		if err != nil {
+			http.Error(writer, err.Error(), http.StatusBadRequest)
+			return
+		}
+		result, err, errCode := control.RestoreTrash(token, request.PathValue("id"))
+		if err != nil {
+			http.Error(writer, err.Error(), errCode)
+			return
+		}
+		writer.Header().Set("Content-Type", "application/json; charset=utf-8")
+		err = json.NewEncoder(writer).Encode(result)
+		if err != nil {
+			log.Println("ERROR: unable to encode response", err)
+		}
+		return
+	})
+}
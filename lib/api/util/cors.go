@@ -32,7 +32,7 @@ func (this *CorsMiddleware) ServeHTTP(res http.ResponseWriter, req *http.Request
 		origin = "*"
 	}
 	res.Header().Set("Access-Control-Allow-Origin", origin)
-	res.Header().Set("Access-Control-Allow-Headers", "Origin, X-Requested-With, Content-Type, Accept, authorization, Authorization")
+	res.Header().Set("Access-Control-Allow-Headers", "Origin, X-Requested-With, X-Request-Id, Content-Type, Accept, authorization, Authorization")
 	res.Header().Set("Access-Control-Allow-Credentials", "true")
 	res.Header().Set("Access-Control-Allow-Methods", "POST, GET, OPTIONS, PUT, DELETE")
 
@@ -18,19 +18,126 @@ package util
 
 import (
 	"bytes"
+	"errors"
+	"fmt"
+	"github.com/SENERGY-Platform/device-manager/lib/config"
 	"io"
+	"log"
+	"net"
 	"net/http"
+	"net/http/httputil"
+	"net/url"
 	"strings"
+	"sync"
+	"time"
 )
 
+type ForwardOptions struct {
+	Timeout          time.Duration //max time until the response headers of an upstream arrive; 0 for no timeout
+	Retries          int           //additional attempts after a failed attempt, if the request may be repeated
+	UnhealthyFor     time.Duration //time a failed upstream is only used if no healthy upstream is left
+	MaxRetryBodySize int64         //bodies of idempotent requests up to this size are buffered to be repeatable
+}
+
+var DefaultForwardOptions = ForwardOptions{
+	Timeout:          30 * time.Second,
+	Retries:          2,
+	UnhealthyFor:     10 * time.Second,
+	MaxRetryBodySize: 1024 * 1024,
+}
+
+// ForwardOptionsFromConfig reads the edit_forward_* config fields; invalid values fall back to DefaultForwardOptions.
+// edit_forward_retries 0 uses the default, negative disables retries
+func ForwardOptionsFromConfig(conf config.Config) (result ForwardOptions) {
+	result = DefaultForwardOptions
+	if conf.EditForwardTimeout != "" {
+		timeout, err := time.ParseDuration(conf.EditForwardTimeout)
+		if err != nil {
+			log.Println("WARNING: invalid edit_forward_timeout --> use default", DefaultForwardOptions.Timeout, err)
+		} else {
+			result.Timeout = timeout
+		}
+	}
+	if conf.EditForwardUnhealthyFor != "" {
+		unhealthyFor, err := time.ParseDuration(conf.EditForwardUnhealthyFor)
+		if err != nil {
+			log.Println("WARNING: invalid edit_forward_unhealthy_for --> use default", DefaultForwardOptions.UnhealthyFor, err)
+		} else {
+			result.UnhealthyFor = unhealthyFor
+		}
+	}
+	if conf.EditForwardRetries < 0 {
+		result.Retries = 0
+	} else if conf.EditForwardRetries > 0 {
+		result.Retries = int(conf.EditForwardRetries)
+	}
+	return result
+}
+
+// NewConditionalForward proxies requests matching condition to remote, a comma separated list of upstreams.
+// the first healthy upstream is used; upstreams failing with a transport error or 502, 503 or 504 are marked unhealthy for ForwardOptions.UnhealthyFor.
 func NewConditionalForward(defaultHandler http.Handler, remote string, condition func(r *http.Request) bool) *ConditionalForward {
-	return &ConditionalForward{defaultHandler: defaultHandler, condition: condition, remote: remote}
+	return NewConditionalForwardWithOptions(defaultHandler, remote, DefaultForwardOptions, condition)
+}
+
+func NewConditionalForwardWithOptions(defaultHandler http.Handler, remote string, options ForwardOptions, condition func(r *http.Request) bool) *ConditionalForward {
+	result := &ConditionalForward{
+		defaultHandler: defaultHandler,
+		condition:      condition,
+		options:        options,
+		transport: &http.Transport{
+			Proxy: http.ProxyFromEnvironment,
+			DialContext: (&net.Dialer{
+				Timeout:   10 * time.Second,
+				KeepAlive: 30 * time.Second,
+			}).DialContext,
+			ForceAttemptHTTP2:     true,
+			MaxIdleConns:          100,
+			IdleConnTimeout:       90 * time.Second,
+			TLSHandshakeTimeout:   10 * time.Second,
+			ExpectContinueTimeout: 1 * time.Second,
+			ResponseHeaderTimeout: options.Timeout,
+		},
+	}
+	for _, element := range strings.Split(remote, ",") {
+		element = strings.TrimSpace(element)
+		if element == "" {
+			continue
+		}
+		u, err := url.Parse(element)
+		if err != nil {
+			log.Println("ERROR: invalid edit_forward upstream; ignore", element, err)
+			continue
+		}
+		result.upstreams = append(result.upstreams, &upstream{url: u})
+	}
+	return result
 }
 
 type ConditionalForward struct {
 	defaultHandler http.Handler
-	remote         string
 	condition      func(r *http.Request) bool
+	options        ForwardOptions
+	transport      http.RoundTripper
+	upstreams      []*upstream
+}
+
+type upstream struct {
+	url            *url.URL
+	mux            sync.Mutex
+	unhealthyUntil time.Time
+}
+
+func (this *upstream) healthy() bool {
+	this.mux.Lock()
+	defer this.mux.Unlock()
+	return time.Now().After(this.unhealthyUntil)
+}
+
+func (this *upstream) setUnhealthy(duration time.Duration) {
+	this.mux.Lock()
+	defer this.mux.Unlock()
+	this.unhealthyUntil = time.Now().Add(duration)
 }
 
 func (this *ConditionalForward) ServeHTTP(w http.ResponseWriter, r *http.Request) {
@@ -41,43 +148,142 @@ func (this *ConditionalForward) ServeHTTP(w http.ResponseWriter, r *http.Request
 	}
 }
 
-func (this *ConditionalForward) forward(w http.ResponseWriter, r *http.Request) {
-	body, err := io.ReadAll(r.Body)
-	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
-		return
+// candidates returns the healthy upstreams in configured order, followed by the unhealthy ones
+func (this *ConditionalForward) candidates() (result []*upstream) {
+	unhealthy := []*upstream{}
+	for _, u := range this.upstreams {
+		if u.healthy() {
+			result = append(result, u)
+		} else {
+			unhealthy = append(unhealthy, u)
+		}
 	}
+	return append(result, unhealthy...)
+}
 
-	url := this.remote
-	if strings.HasPrefix(r.RequestURI, "/") {
-		url = url + r.RequestURI
-	} else {
-		url = url + "/" + r.RequestURI
-	}
+var errUpstreamUnavailable = errors.New("upstream unavailable")
 
-	proxyReq, err := http.NewRequest(r.Method, url, bytes.NewReader(body))
-	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+func (this *ConditionalForward) forward(w http.ResponseWriter, r *http.Request) {
+	if len(this.upstreams) == 0 {
+		http.Error(w, "no edit_forward upstream configured", http.StatusBadGateway)
 		return
 	}
-
-	proxyReq.Header = r.Header
-
-	resp, err := http.DefaultClient.Do(proxyReq)
+	body, repeatable, err := this.prepareBody(r)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusBadGateway)
+		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
-	defer resp.Body.Close()
-	copyHeader(w.Header(), resp.Header)
-	w.WriteHeader(resp.StatusCode)
-	io.Copy(w, resp.Body)
+	candidates := this.candidates()
+	attempts := this.options.Retries + 1
+	var lastErr error
+	for attempt := 0; attempt < attempts; attempt++ {
+		target := candidates[attempt%len(candidates)]
+		if attempt >= len(candidates) {
+			time.Sleep(time.Duration(attempt) * 100 * time.Millisecond)
+		}
+		counter := body()
+		last := attempt == attempts-1
+		lastErr = this.attempt(w, r, target, counter, !last && repeatable)
+		if lastErr == nil {
+			return
+		}
+		target.setUnhealthy(this.options.UnhealthyFor)
+		log.Printf("WARNING: edit forward to %v failed (attempt %v of %v): %v", target.url.Host, attempt+1, attempts, lastErr)
+		if !repeatable && !(isDialError(lastErr) && counter.read == 0) {
+			break
+		}
+		if r.Context().Err() != nil {
+			break
+		}
+	}
+	code := http.StatusBadGateway
+	if isTimeout(lastErr) {
+		code = http.StatusGatewayTimeout
+	}
+	http.Error(w, "edit forward failed: "+lastErr.Error(), code)
+}
+
+// attempt proxies the request to target; returns an error without writing to w if the attempt failed.
+// with retryStatus, responses with 502, 503 and 504 count as failed attempts.
+func (this *ConditionalForward) attempt(w http.ResponseWriter, r *http.Request, target *upstream, body *readCounter, retryStatus bool) (err error) {
+	proxy := &httputil.ReverseProxy{
+		Rewrite: func(request *httputil.ProxyRequest) {
+			request.SetURL(target.url)
+			request.Out.Header["X-Forwarded-For"] = request.In.Header["X-Forwarded-For"]
+			request.SetXForwarded()
+		},
+		Transport:     this.transport,
+		FlushInterval: -1,
+		ModifyResponse: func(response *http.Response) error {
+			switch response.StatusCode {
+			case http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+				target.setUnhealthy(this.options.UnhealthyFor)
+				if retryStatus {
+					return fmt.Errorf("%w: %v", errUpstreamUnavailable, response.Status)
+				}
+			}
+			return nil
+		},
+		ErrorHandler: func(writer http.ResponseWriter, request *http.Request, e error) {
+			err = e
+		},
+	}
+	in := r.WithContext(r.Context())
+	in.Body = body
+	proxy.ServeHTTP(w, in)
+	return err
 }
 
-func copyHeader(dst, src http.Header) {
-	for k, vv := range src {
-		for _, v := range vv {
-			dst.Add(k, v)
+// prepareBody returns a factory for the body of each attempt.
+// the body is buffered if the request is idempotent and small enough; otherwise it is streamed and only the first attempt receives it.
+func (this *ConditionalForward) prepareBody(r *http.Request) (factory func() *readCounter, repeatable bool, err error) {
+	if r.Body == nil || r.Body == http.NoBody {
+		return func() *readCounter { return &readCounter{reader: http.NoBody} }, isIdempotent(r.Method), nil
+	}
+	if isIdempotent(r.Method) && r.ContentLength >= 0 && r.ContentLength <= this.options.MaxRetryBodySize {
+		buf, err := io.ReadAll(r.Body)
+		if err != nil {
+			return nil, false, err
 		}
+		return func() *readCounter { return &readCounter{reader: io.NopCloser(bytes.NewReader(buf))} }, true, nil
+	}
+	stream := &readCounter{reader: r.Body}
+	return func() *readCounter { return stream }, false, nil
+}
+
+func isIdempotent(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodPut, http.MethodDelete:
+		return true
+	default:
+		return false
 	}
 }
+
+// isDialError is true if the upstream has never received the request
+func isDialError(err error) bool {
+	opErr := &net.OpError{}
+	return errors.As(err, &opErr) && opErr.Op == "dial"
+}
+
+func isTimeout(err error) bool {
+	var netErr net.Error
+	return errors.As(err, &netErr) && netErr.Timeout()
+}
+
+// readCounter counts the bytes read by the transport; closing is left to the http server,
+// so that a streamed body remains usable if the transport closes it after a failed dial
+type readCounter struct {
+	reader io.ReadCloser
+	read   int64
+}
+
+func (this *readCounter) Read(p []byte) (n int, err error) {
+	n, err = this.reader.Read(p)
+	this.read += int64(n)
+	return n, err
+}
+
+func (this *readCounter) Close() error {
+	return nil
+}
@@ -0,0 +1,198 @@
+/*
+ * Copyright 2024 InfAI (CC SES)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package apikey stores api keys hashed in a json file, which is shared by the replicas of the service (e.g. on a shared volume), and authenticates them.
+// keys have the form "<id>.<secret>"; the id is used for the lookup, only the sha256 hash of the secret is stored.
+package apikey
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"github.com/SENERGY-Platform/device-manager/lib/jsonstore"
+	"github.com/SENERGY-Platform/device-manager/lib/model"
+	"github.com/google/uuid"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Scheme is the Authorization scheme of api keys: "Authorization: ApiKey <key>"
+const Scheme = "ApiKey"
+
+const (
+	ActionRead              = "read"
+	ActionCreate            = "create"
+	ActionUpdate            = "update"
+	ActionDelete            = "delete"
+	ActionReadPermissions   = "read-permissions"   //permissions, shares and propagation settings
+	ActionUpdatePermissions = "update-permissions" //permissions, shares, propagation settings and ownership transfers
+)
+
+var Actions = []string{ActionRead, ActionCreate, ActionUpdate, ActionDelete, ActionReadPermissions, ActionUpdatePermissions}
+
+const Any = "*"
+
+var ErrInvalidKey = errors.New("invalid api key")
+var ErrNotFound = errors.New("api key not found")
+
+type Store struct {
+	keys *jsonstore.Map[storedKey] //without location for an in-memory store
+	now  func() time.Time
+}
+
+type storedKey struct {
+	model.ApiKey
+	Hash string `json:"hash"`
+}
+
+// New loads the keys of the json file at location, if it exists; an empty location keeps the keys only in memory.
+// keys created or revoked by other replicas are read from the file before each use
+func New(location string) (*Store, error) {
+	keys, err := jsonstore.NewMap[storedKey](location, func(key storedKey) string {
+		return key.Id
+	}, func(a storedKey, b storedKey) bool {
+		return a.CreatedAt.Before(b.CreatedAt)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &Store{keys: keys, now: time.Now}, nil
+}
+
+// Create stores key with a new id and creation time and returns it with its secret form
+func (this *Store) Create(key model.ApiKey) (result model.ApiKeyCreated, err error) {
+	secret := make([]byte, 32)
+	_, err = rand.Read(secret)
+	if err != nil {
+		return result, err
+	}
+	key.Id = uuid.NewString()
+	key.CreatedAt = this.now()
+	secretStr := base64.RawURLEncoding.EncodeToString(secret)
+	err = this.keys.Update(func(keys map[string]storedKey) error {
+		keys[key.Id] = storedKey{ApiKey: key, Hash: hash(secretStr)}
+		return nil
+	})
+	if err != nil {
+		return result, err
+	}
+	return model.ApiKeyCreated{ApiKey: key, Key: key.Id + "." + secretStr}, nil
+}
+
+// List returns the keys of userId, or of all users if userId is empty, ordered by creation
+func (this *Store) List(userId string) (result []model.ApiKey) {
+	result = []model.ApiKey{}
+	this.keys.View(func(keys map[string]storedKey) {
+		for _, key := range keys {
+			if userId == "" || key.UserId == userId || key.CreatedBy == userId {
+				result = append(result, key.ApiKey)
+			}
+		}
+	})
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].CreatedAt.Before(result[j].CreatedAt)
+	})
+	return result
+}
+
+// Get returns the key with the given id, including revoked and expired keys
+func (this *Store) Get(id string) (result model.ApiKey, err error) {
+	err = ErrNotFound
+	this.keys.View(func(keys map[string]storedKey) {
+		if key, ok := keys[id]; ok {
+			result, err = key.ApiKey, nil
+		}
+	})
+	return result, err
+}
+
+// Revoke marks the key as revoked; revoked keys are kept to be listed
+func (this *Store) Revoke(id string) error {
+	return this.keys.Update(func(keys map[string]storedKey) error {
+		key, ok := keys[id]
+		if !ok {
+			return ErrNotFound
+		}
+		if key.RevokedAt != nil {
+			return jsonstore.SkipWrite
+		}
+		now := this.now()
+		key.RevokedAt = &now
+		keys[id] = key
+		return nil
+	})
+}
+
+// Authenticate returns the key of the secret form "<id>.<secret>", if it is neither expired nor revoked
+func (this *Store) Authenticate(secretKey string) (model.ApiKey, error) {
+	id, secret, found := strings.Cut(strings.TrimSpace(secretKey), ".")
+	if !found {
+		return model.ApiKey{}, ErrInvalidKey
+	}
+	var key storedKey
+	var ok bool
+	this.keys.View(func(keys map[string]storedKey) {
+		key, ok = keys[id]
+	})
+	if !ok || subtle.ConstantTimeCompare([]byte(key.Hash), []byte(hash(secret))) != 1 {
+		return model.ApiKey{}, ErrInvalidKey
+	}
+	if key.RevokedAt != nil {
+		return model.ApiKey{}, errors.New("api key revoked")
+	}
+	if !key.ExpiresAt.After(this.now()) {
+		return model.ApiKey{}, errors.New("api key expired")
+	}
+	return key.ApiKey, nil
+}
+
+func hash(secret string) string {
+	sum := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(sum[:])
+}
+
+// Allowed reports if one of the scopes allows the operation
+func Allowed(scopes []model.ApiKeyScope, op Operation) bool {
+	return slices.ContainsFunc(scopes, func(scope model.ApiKeyScope) bool {
+		return (scope.Kind == Any || scope.Kind == op.Kind) && (slices.Contains(scope.Actions, Any) || slices.Contains(scope.Actions, op.Action))
+	})
+}
+
+// ValidateScopes checks that the scopes only name known kinds and actions
+func ValidateScopes(scopes []model.ApiKeyScope) error {
+	if len(scopes) == 0 {
+		return errors.New("missing scopes")
+	}
+	for _, scope := range scopes {
+		if scope.Kind != Any && !slices.Contains(Kinds, scope.Kind) {
+			return errors.New("unknown scope kind: " + scope.Kind)
+		}
+		if len(scope.Actions) == 0 {
+			return errors.New("missing scope actions for " + scope.Kind)
+		}
+		for _, action := range scope.Actions {
+			if action != Any && !slices.Contains(Actions, action) {
+				return errors.New("unknown scope action: " + action)
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,216 @@
+/*
+ * Copyright 2024 InfAI (CC SES)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package apikey
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"sync"
+)
+
+// Operation is what a request does in terms of api key scopes
+type Operation struct {
+	Kind   string
+	Action string
+}
+
+// Kinds are the resource kinds of api key scopes
+var Kinds = []string{"devices", "local-devices", "hubs", "device-groups", "locations", "device-types", "protocols", "aspects", "functions", "characteristics", "concepts", "device-classes"}
+
+// routes maps the api routes which may be used with api keys to their operations.
+// routes which are not listed (e.g. api key management, trash, transfers of the requesting user, audit and admin endpoints) are rejected.
+var routes = map[string]Operation{
+	"GET /devices":                                      {"devices", ActionRead},
+	"GET /devices/{id}":                                 {"devices", ActionRead},
+	"POST /devices":                                     {"devices", ActionCreate},
+	"POST /devices/{id}/clone":                          {"devices", ActionCreate},
+	"PUT /devices/{id}":                                 {"devices", ActionUpdate},
+	"PUT /devices/{id}/attributes":                      {"devices", ActionUpdate},
+	"PUT /devices/{id}/display_name":                    {"devices", ActionUpdate},
+	"DELETE /devices":                                   {"devices", ActionDelete},
+	"DELETE /devices/{id}":                              {"devices", ActionDelete},
+	"GET /devices/{id}/permissions":                     {"devices", ActionReadPermissions},
+	"GET /devices/{id}/shares":                          {"devices", ActionReadPermissions},
+	"PUT /devices/{id}/permissions":                     {"devices", ActionUpdatePermissions},
+	"PUT /devices/{id}/permissions/users/{subject}":     {"devices", ActionUpdatePermissions},
+	"PUT /devices/{id}/permissions/groups/{subject}":    {"devices", ActionUpdatePermissions},
+	"DELETE /devices/{id}/permissions/users/{subject}":  {"devices", ActionUpdatePermissions},
+	"DELETE /devices/{id}/permissions/groups/{subject}": {"devices", ActionUpdatePermissions},
+	"POST /devices/{id}/shares":                         {"devices", ActionUpdatePermissions},
+	"DELETE /devices/{id}/shares/{share}":               {"devices", ActionUpdatePermissions},
+	"POST /devices/{id}/transfer":                       {"devices", ActionUpdatePermissions},
+
+	"GET /local-devices":         {"local-devices", ActionRead},
+	"GET /local-devices/{id}":    {"local-devices", ActionRead},
+	"POST /local-devices":        {"local-devices", ActionCreate},
+	"PUT /local-devices/{id}":    {"local-devices", ActionUpdate},
+	"DELETE /local-devices/{id}": {"local-devices", ActionDelete},
+
+	"GET /hubs/{id}":                                 {"hubs", ActionRead},
+	"POST /hubs":                                     {"hubs", ActionCreate},
+	"PUT /hubs/{id}":                                 {"hubs", ActionUpdate},
+	"PUT /hubs/{id}/name":                            {"hubs", ActionUpdate},
+	"DELETE /hubs/{id}":                              {"hubs", ActionDelete},
+	"GET /hubs/{id}/permissions":                     {"hubs", ActionReadPermissions},
+	"GET /hubs/{id}/shares":                          {"hubs", ActionReadPermissions},
+	"PUT /hubs/{id}/permissions":                     {"hubs", ActionUpdatePermissions},
+	"PUT /hubs/{id}/permissions/users/{subject}":     {"hubs", ActionUpdatePermissions},
+	"PUT /hubs/{id}/permissions/groups/{subject}":    {"hubs", ActionUpdatePermissions},
+	"DELETE /hubs/{id}/permissions/users/{subject}":  {"hubs", ActionUpdatePermissions},
+	"DELETE /hubs/{id}/permissions/groups/{subject}": {"hubs", ActionUpdatePermissions},
+	"POST /hubs/{id}/shares":                         {"hubs", ActionUpdatePermissions},
+	"DELETE /hubs/{id}/shares/{share}":               {"hubs", ActionUpdatePermissions},
+	"POST /hubs/{id}/transfer":                       {"hubs", ActionUpdatePermissions},
+
+	"GET /device-groups/{id}":                                 {"device-groups", ActionRead},
+	"POST /device-groups":                                     {"device-groups", ActionCreate},
+	"PUT /device-groups/{id}":                                 {"device-groups", ActionUpdate},
+	"DELETE /device-groups/{id}":                              {"device-groups", ActionDelete},
+	"GET /device-groups/{id}/permissions":                     {"device-groups", ActionReadPermissions},
+	"GET /device-groups/{id}/shares":                          {"device-groups", ActionReadPermissions},
+	"GET /device-groups/{id}/propagation":                     {"device-groups", ActionReadPermissions},
+	"PUT /device-groups/{id}/permissions":                     {"device-groups", ActionUpdatePermissions},
+	"PUT /device-groups/{id}/permissions/users/{subject}":     {"device-groups", ActionUpdatePermissions},
+	"PUT /device-groups/{id}/permissions/groups/{subject}":    {"device-groups", ActionUpdatePermissions},
+	"DELETE /device-groups/{id}/permissions/users/{subject}":  {"device-groups", ActionUpdatePermissions},
+	"DELETE /device-groups/{id}/permissions/groups/{subject}": {"device-groups", ActionUpdatePermissions},
+	"POST /device-groups/{id}/shares":                         {"device-groups", ActionUpdatePermissions},
+	"DELETE /device-groups/{id}/shares/{share}":               {"device-groups", ActionUpdatePermissions},
+	"PUT /device-groups/{id}/propagation":                     {"device-groups", ActionUpdatePermissions},
+
+	"GET /locations/{id}":                                 {"locations", ActionRead},
+	"POST /locations":                                     {"locations", ActionCreate},
+	"PUT /locations/{id}":                                 {"locations", ActionUpdate},
+	"DELETE /locations/{id}":                              {"locations", ActionDelete},
+	"GET /locations/{id}/permissions":                     {"locations", ActionReadPermissions},
+	"GET /locations/{id}/shares":                          {"locations", ActionReadPermissions},
+	"GET /locations/{id}/propagation":                     {"locations", ActionReadPermissions},
+	"PUT /locations/{id}/permissions":                     {"locations", ActionUpdatePermissions},
+	"PUT /locations/{id}/permissions/users/{subject}":     {"locations", ActionUpdatePermissions},
+	"PUT /locations/{id}/permissions/groups/{subject}":    {"locations", ActionUpdatePermissions},
+	"DELETE /locations/{id}/permissions/users/{subject}":  {"locations", ActionUpdatePermissions},
+	"DELETE /locations/{id}/permissions/groups/{subject}": {"locations", ActionUpdatePermissions},
+	"POST /locations/{id}/shares":                         {"locations", ActionUpdatePermissions},
+	"DELETE /locations/{id}/shares/{share}":               {"locations", ActionUpdatePermissions},
+	"PUT /locations/{id}/propagation":                     {"locations", ActionUpdatePermissions},
+
+	"GET /device-types/{id}":                                 {"device-types", ActionRead},
+	"GET /device-types/{id}/versions":                        {"device-types", ActionRead},
+	"GET /device-types/{id}/versions/{version}":              {"device-types", ActionRead},
+	"GET /device-types/{id}/versions/{version}/diff":         {"device-types", ActionRead},
+	"POST /device-types/{id}/analysis":                       {"device-types", ActionRead},
+	"POST /device-types":                                     {"device-types", ActionCreate},
+	"POST /device-types/{id}/clone":                          {"device-types", ActionCreate},
+	"PUT /device-types/{id}":                                 {"device-types", ActionUpdate},
+	"POST /device-types/{id}/versions/{version}/rollback":    {"device-types", ActionUpdate},
+	"POST /device-types/{id}/migrate":                        {"devices", ActionUpdate},
+	"GET /device-types/{id}/migrations/{job}":                {"devices", ActionRead},
+	"DELETE /device-types/{id}":                              {"device-types", ActionDelete},
+	"GET /device-types/{id}/permissions":                     {"device-types", ActionReadPermissions},
+	"GET /device-types/{id}/shares":                          {"device-types", ActionReadPermissions},
+	"PUT /device-types/{id}/permissions":                     {"device-types", ActionUpdatePermissions},
+	"PUT /device-types/{id}/permissions/users/{subject}":     {"device-types", ActionUpdatePermissions},
+	"PUT /device-types/{id}/permissions/groups/{subject}":    {"device-types", ActionUpdatePermissions},
+	"DELETE /device-types/{id}/permissions/users/{subject}":  {"device-types", ActionUpdatePermissions},
+	"DELETE /device-types/{id}/permissions/groups/{subject}": {"device-types", ActionUpdatePermissions},
+	"POST /device-types/{id}/shares":                         {"device-types", ActionUpdatePermissions},
+	"DELETE /device-types/{id}/shares/{share}":               {"device-types", ActionUpdatePermissions},
+
+	"GET /protocols/{id}":    {"protocols", ActionRead},
+	"POST /protocols":        {"protocols", ActionCreate},
+	"PUT /protocols/{id}":    {"protocols", ActionUpdate},
+	"DELETE /protocols/{id}": {"protocols", ActionDelete},
+
+	"GET /aspects/{id}":    {"aspects", ActionRead},
+	"POST /aspects":        {"aspects", ActionCreate},
+	"PUT /aspects/{id}":    {"aspects", ActionUpdate},
+	"DELETE /aspects/{id}": {"aspects", ActionDelete},
+
+	"GET /functions/{id}":    {"functions", ActionRead},
+	"POST /functions":        {"functions", ActionCreate},
+	"PUT /functions/{id}":    {"functions", ActionUpdate},
+	"DELETE /functions/{id}": {"functions", ActionDelete},
+
+	"GET /characteristics/{id}":    {"characteristics", ActionRead},
+	"POST /characteristics":        {"characteristics", ActionCreate},
+	"PUT /characteristics/{id}":    {"characteristics", ActionUpdate},
+	"DELETE /characteristics/{id}": {"characteristics", ActionDelete},
+
+	"GET /concepts/{id}":    {"concepts", ActionRead},
+	"POST /concepts":        {"concepts", ActionCreate},
+	"PUT /concepts/{id}":    {"concepts", ActionUpdate},
+	"DELETE /concepts/{id}": {"concepts", ActionDelete},
+
+	"GET /device-classes/{id}":    {"device-classes", ActionRead},
+	"POST /device-classes":        {"device-classes", ActionCreate},
+	"PUT /device-classes/{id}":    {"device-classes", ActionUpdate},
+	"DELETE /device-classes/{id}": {"device-classes", ActionDelete},
+}
+
+// bodyOperations adds operations to routes, which depend on the request body
+var bodyOperations = map[string]func(body []byte) ([]Operation, error){
+	//migrations with delete_source delete the source device-type
+	"POST /device-types/{id}/migrate": func(body []byte) ([]Operation, error) {
+		request := struct {
+			DryRun       bool `json:"dry_run"`
+			DeleteSource bool `json:"delete_source"`
+		}{}
+		err := json.NewDecoder(bytes.NewReader(body)).Decode(&request)
+		if err != nil {
+			return nil, err
+		}
+		if request.DeleteSource && !request.DryRun {
+			return []Operation{{"device-types", ActionDelete}}, nil
+		}
+		return nil, nil
+	},
+}
+
+var routeMux = sync.OnceValue(func() *http.ServeMux {
+	mux := http.NewServeMux()
+	for pattern := range routes {
+		mux.Handle(pattern, http.NotFoundHandler())
+	}
+	return mux
+})
+
+// OperationsOf returns the operations of the api route matching request; ok is false for routes which may not be used with api keys.
+// routes whose operations depend on the body read it; the body of the request is replaced to be read again by the handler
+func OperationsOf(request *http.Request) (ops []Operation, ok bool, err error) {
+	_, pattern := routeMux().Handler(request)
+	op, ok := routes[pattern]
+	if !ok {
+		return nil, false, nil
+	}
+	ops = []Operation{op}
+	fromBody, ok := bodyOperations[pattern]
+	if !ok {
+		return ops, true, nil
+	}
+	body, err := io.ReadAll(request.Body)
+	if err != nil {
+		return nil, true, err
+	}
+	request.Body = io.NopCloser(bytes.NewReader(body))
+	more, err := fromBody(body)
+	if err != nil {
+		return nil, true, err
+	}
+	return append(ops, more...), true, nil
+}
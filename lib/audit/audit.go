@@ -0,0 +1,115 @@
+/*
+ * Copyright 2024 InfAI (CC SES)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package audit records write operations to an audit topic and/or an append-only local file.
+package audit
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/SENERGY-Platform/device-manager/lib/bus"
+	"github.com/SENERGY-Platform/device-manager/lib/config"
+	"github.com/SENERGY-Platform/device-manager/lib/model"
+	"log"
+	"time"
+)
+
+// operations of audit records
+const (
+	Create         = "create"
+	Update         = "update"
+	Delete         = "delete"
+	Permissions    = "permissions"
+	Propagation    = "propagation"
+	Transfer       = "transfer"
+	TransferAccept = "transfer-accept"
+	TransferDelete = "transfer-delete"
+	Share          = "share"
+	ShareRevoke    = "share-revoke"
+	Replay         = "replay"
+	Restore        = "restore"
+	Rollback       = "rollback"
+	Migrate        = "migrate"
+)
+
+// SystemActor is the actor of changes made by the service itself
+const SystemActor = "device-manager"
+
+const (
+	OutcomeSuccess = "success"
+	OutcomeError   = "error"
+)
+
+type Auditor struct {
+	producer bus.Producer
+	topic    string
+	store    *Store
+}
+
+// New returns nil if neither config.AuditTopic nor config.AuditFile is set; producer may be nil if no audit topic is used
+func New(conf config.Config, producer bus.Producer) (result *Auditor, err error) {
+	result = &Auditor{}
+	if conf.AuditTopic != "" && conf.AuditTopic != "-" && producer != nil {
+		result.producer = producer
+		result.topic = conf.AuditTopic
+	}
+	if conf.AuditFile != "" && conf.AuditFile != "-" {
+		result.store, err = NewStore(conf.AuditFile)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if result.producer == nil && result.store == nil {
+		return nil, nil
+	}
+	return result, nil
+}
+
+// Record completes record with the time and the request id of ctx and writes it to the configured sinks.
+// errors are logged; the audited operation is already done.
+func (this *Auditor) Record(ctx context.Context, record model.AuditRecord) {
+	record.Time = time.Now()
+	record.RequestId = RequestIdFromContext(ctx)
+	if record.Outcome == "" {
+		record.Outcome = OutcomeSuccess
+	}
+	if this.store != nil {
+		err := this.store.Append(record)
+		if err != nil {
+			log.Println("ERROR: unable to store audit record", err)
+		}
+	}
+	if this.producer != nil {
+		msg, err := json.Marshal(record)
+		if err != nil {
+			log.Println("ERROR: unable to marshal audit record", err)
+			return
+		}
+		err = this.producer.Publish(ctx, this.topic, record.Kind+"/"+record.ResourceId, msg)
+		if err != nil {
+			log.Println("ERROR: unable to publish audit record", err)
+		}
+	}
+}
+
+// Query returns the records of the local store; ok is false if no local store is configured
+func (this *Auditor) Query(query model.AuditQuery) (result []model.AuditRecord, ok bool, err error) {
+	if this.store == nil {
+		return nil, false, nil
+	}
+	result, err = this.store.Query(query)
+	return result, true, err
+}
@@ -0,0 +1,113 @@
+/*
+ * Copyright 2024 InfAI (CC SES)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package audit
+
+import (
+	"encoding/json"
+	"github.com/SENERGY-Platform/device-manager/lib/model"
+	"reflect"
+	"slices"
+)
+
+// Diff compares the json forms of before and after (nil for create and delete) and returns the changed fields.
+// objects are compared per field with dot separated paths and lists of objects with ids (e.g. services) per id with "services[<id>]" paths;
+// added or removed objects, other lists and other values are compared as a whole. the path of a created or deleted resource is empty.
+func Diff(before interface{}, after interface{}) (result []model.AuditChange) {
+	return diff("", normalize(before), normalize(after))
+}
+
+func normalize(value interface{}) (result interface{}) {
+	if value == nil {
+		return nil
+	}
+	temp, err := json.Marshal(value)
+	if err != nil {
+		return nil
+	}
+	err = json.Unmarshal(temp, &result)
+	if err != nil {
+		return nil
+	}
+	return result
+}
+
+func diff(path string, before interface{}, after interface{}) (result []model.AuditChange) {
+	beforeMap, beforeIsMap := before.(map[string]interface{})
+	afterMap, afterIsMap := after.(map[string]interface{})
+	if beforeIsMap && afterIsMap {
+		keys := []string{}
+		for key := range beforeMap {
+			keys = append(keys, key)
+		}
+		for key := range afterMap {
+			if _, ok := beforeMap[key]; !ok {
+				keys = append(keys, key)
+			}
+		}
+		slices.Sort(keys)
+		for _, key := range keys {
+			subPath := key
+			if path != "" {
+				subPath = path + "." + key
+			}
+			result = append(result, diff(subPath, beforeMap[key], afterMap[key])...)
+		}
+		return result
+	}
+	beforeList, beforeIsList := before.([]interface{})
+	afterList, afterIsList := after.([]interface{})
+	if beforeIsList && afterIsList {
+		beforeIds, beforeById, beforeOk := byId(beforeList)
+		afterIds, afterById, afterOk := byId(afterList)
+		if beforeOk && afterOk {
+			for _, id := range afterIds {
+				if _, ok := beforeById[id]; !ok {
+					beforeIds = append(beforeIds, id)
+				}
+			}
+			for _, id := range beforeIds {
+				result = append(result, diff(path+"["+id+"]", beforeById[id], afterById[id])...)
+			}
+			return result
+		}
+	}
+	if reflect.DeepEqual(before, after) {
+		return nil
+	}
+	return []model.AuditChange{{Path: path, Before: before, After: after}}
+}
+
+// byId indexes a list of objects by their unique "id" field; ok is false for other lists
+func byId(list []interface{}) (ids []string, result map[string]interface{}, ok bool) {
+	result = map[string]interface{}{}
+	for _, element := range list {
+		object, isObject := element.(map[string]interface{})
+		if !isObject {
+			return nil, nil, false
+		}
+		id, isString := object["id"].(string)
+		if !isString || id == "" {
+			return nil, nil, false
+		}
+		if _, duplicate := result[id]; duplicate {
+			return nil, nil, false
+		}
+		ids = append(ids, id)
+		result[id] = object
+	}
+	return ids, result, true
+}
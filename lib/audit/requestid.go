@@ -0,0 +1,53 @@
+/*
+ * Copyright 2024 InfAI (CC SES)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package audit
+
+import (
+	"context"
+	"github.com/google/uuid"
+	"net/http"
+)
+
+const RequestIdHeader = "X-Request-Id"
+
+type requestIdKey struct{}
+
+func ContextWithRequestId(ctx context.Context, requestId string) context.Context {
+	return context.WithValue(ctx, requestIdKey{}, requestId)
+}
+
+func RequestIdFromContext(ctx context.Context) string {
+	if ctx == nil {
+		return ""
+	}
+	result, _ := ctx.Value(requestIdKey{}).(string)
+	return result
+}
+
+// NewRequestIdMiddleware uses the X-Request-Id header of the request or generates one,
+// adds it to the request context, the request header (for forwarded requests) and the response header
+func NewRequestIdMiddleware(handler http.Handler) http.Handler {
+	return http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		requestId := request.Header.Get(RequestIdHeader)
+		if requestId == "" || len(requestId) > 128 {
+			requestId = uuid.NewString()
+			request.Header.Set(RequestIdHeader, requestId)
+		}
+		writer.Header().Set(RequestIdHeader, requestId)
+		handler.ServeHTTP(writer, request.WithContext(ContextWithRequestId(request.Context(), requestId)))
+	})
+}
@@ -0,0 +1,111 @@
+/*
+ * Copyright 2024 InfAI (CC SES)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package audit
+
+import (
+	"bufio"
+	"encoding/json"
+	"github.com/SENERGY-Platform/device-manager/lib/model"
+	"log"
+	"os"
+	"sync"
+)
+
+// Store appends records as json lines to a local file; records are never changed or removed by the service
+type Store struct {
+	location string
+	mux      sync.Mutex
+	file     *os.File
+}
+
+func NewStore(location string) (*Store, error) {
+	file, err := os.OpenFile(location, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		return nil, err
+	}
+	return &Store{location: location, file: file}, nil
+}
+
+func (this *Store) Append(record model.AuditRecord) error {
+	line, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+	this.mux.Lock()
+	defer this.mux.Unlock()
+	_, err = this.file.Write(append(line, '\n'))
+	if err != nil {
+		return err
+	}
+	return this.file.Sync()
+}
+
+// Query scans the file and returns the matching records in chronological order; limit defaults to 100
+func (this *Store) Query(query model.AuditQuery) (result []model.AuditRecord, err error) {
+	limit := query.Limit
+	if limit <= 0 {
+		limit = 100
+	}
+	file, err := os.Open(this.location)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	result = []model.AuditRecord{}
+	skipped := int64(0)
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		record := model.AuditRecord{}
+		err = json.Unmarshal(scanner.Bytes(), &record)
+		if err != nil {
+			log.Println("WARNING: skip invalid audit record", err)
+			continue
+		}
+		if !matches(record, query) {
+			continue
+		}
+		if skipped < query.Offset {
+			skipped++
+			continue
+		}
+		result = append(result, record)
+		if int64(len(result)) >= limit {
+			break
+		}
+	}
+	return result, scanner.Err()
+}
+
+func matches(record model.AuditRecord, query model.AuditQuery) bool {
+	if query.Resource != "" && record.ResourceId != query.Resource {
+		return false
+	}
+	if query.Kind != "" && record.Kind != query.Kind {
+		return false
+	}
+	if query.Actor != "" && record.Actor != query.Actor {
+		return false
+	}
+	if !query.From.IsZero() && record.Time.Before(query.From) {
+		return false
+	}
+	if !query.To.IsZero() && !record.Time.Before(query.To) {
+		return false
+	}
+	return true
+}
@@ -17,6 +17,10 @@
 package auth
 
 import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/rsa"
+	"errors"
 	"github.com/SENERGY-Platform/service-commons/pkg/jwt"
 	gojwt "github.com/golang-jwt/jwt"
 	"strings"
@@ -62,3 +66,42 @@ func CreateTokenWithRoles(issuer string, userId string, roles []string) (token T
 	token.RealmAccess = realmAccess
 	return token, nil
 }
+
+// CreateSignedTokenWithRoles creates a token signed with key (RS256 for rsa keys, ES256 for ecdsa P-256 keys), e.g. to test a Verifier
+func CreateSignedTokenWithRoles(issuer string, userId string, roles []string, key crypto.Signer, kid string) (token Token, err error) {
+	realmAccess := RealmAccess{"roles": roles}
+	claims := KeycloakClaims{
+		realmAccess,
+		gojwt.StandardClaims{
+			ExpiresAt: time.Now().Add(10 * time.Minute).Unix(),
+			IssuedAt:  time.Now().Unix(),
+			Issuer:    issuer,
+			Subject:   userId,
+		},
+	}
+	tokenString, err := sign(claims, key, kid)
+	if err != nil {
+		return token, err
+	}
+	token.Token = "Bearer " + tokenString
+	token.Sub = userId
+	token.RealmAccess = realmAccess
+	return token, nil
+}
+
+func sign(claims gojwt.Claims, key crypto.Signer, kid string) (string, error) {
+	var method gojwt.SigningMethod
+	switch key.(type) {
+	case *rsa.PrivateKey:
+		method = gojwt.SigningMethodRS256
+	case *ecdsa.PrivateKey:
+		method = gojwt.SigningMethodES256
+	default:
+		return "", errors.New("unsupported key type")
+	}
+	jwtoken := gojwt.NewWithClaims(method, claims)
+	if kid != "" {
+		jwtoken.Header["kid"] = kid
+	}
+	return jwtoken.SignedString(key)
+}
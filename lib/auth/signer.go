@@ -0,0 +1,90 @@
+/*
+ * Copyright 2024 InfAI (CC SES)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package auth
+
+import (
+	"crypto"
+	"errors"
+	"fmt"
+	gojwt "github.com/golang-jwt/jwt"
+	"time"
+)
+
+// SignedTokenTtl is the max lifetime of tokens created by a Signer
+const SignedTokenTtl = 5 * time.Minute
+
+// Signer creates tokens signed with a local private key, e.g. for requests with api keys.
+// the public key has to be trusted by this service (jwt_public_key_file or jwks_url) and by the upstream services which verify tokens.
+type Signer struct {
+	key      crypto.Signer
+	kid      string
+	issuer   string
+	audience string
+	now      func() time.Time
+}
+
+type signerClaims struct {
+	RealmAccess RealmAccess `json:"realm_access"`
+	Username    string      `json:"preferred_username,omitempty"`
+	gojwt.StandardClaims
+}
+
+// NewSigner loads the private key of the pem file at keyFile; tokens get kid as key id and the first audience as aud claim
+func NewSigner(keyFile string, kid string, issuer string, audience []string) (*Signer, error) {
+	if keyFile == "" || keyFile == "-" {
+		return nil, errors.New("missing signing key file")
+	}
+	key, err := LoadPemPrivateKey(keyFile)
+	if err != nil {
+		return nil, fmt.Errorf("invalid signing key file: %w", err)
+	}
+	result := &Signer{key: key, kid: kid, issuer: issuer, now: time.Now}
+	if len(audience) > 0 {
+		result.audience = audience[0]
+	}
+	return result, nil
+}
+
+// Sign creates a token for userId with roles, which expires after SignedTokenTtl or at expiresAt, whichever comes first
+func (this *Signer) Sign(userId string, username string, roles []string, expiresAt time.Time) (token Token, err error) {
+	now := this.now()
+	exp := now.Add(SignedTokenTtl)
+	if expiresAt.Before(exp) {
+		exp = expiresAt
+	}
+	realmAccess := RealmAccess{"roles": roles}
+	claims := signerClaims{
+		RealmAccess: realmAccess,
+		Username:    username,
+		StandardClaims: gojwt.StandardClaims{
+			Audience:  this.audience,
+			ExpiresAt: exp.Unix(),
+			IssuedAt:  now.Unix(),
+			Issuer:    this.issuer,
+			Subject:   userId,
+		},
+	}
+	tokenString, err := sign(claims, this.key, this.kid)
+	if err != nil {
+		return token, err
+	}
+	token.Token = "Bearer " + tokenString
+	token.Sub = userId
+	token.RealmAccess = realmAccess
+	token.Username = username
+	return token, nil
+}
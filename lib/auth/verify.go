@@ -0,0 +1,428 @@
+/*
+ * Copyright 2024 InfAI (CC SES)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package auth
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"github.com/SENERGY-Platform/device-manager/lib/config"
+	gojwt "github.com/golang-jwt/jwt"
+	"log"
+	"math/big"
+	"net/http"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultJwksRefreshInterval is used if config.JwksRefreshInterval is empty
+const DefaultJwksRefreshInterval = time.Hour
+
+// JwksMinRefreshInterval limits the refreshes triggered by tokens with unknown key ids
+var JwksMinRefreshInterval = 10 * time.Second
+
+var signingMethods = []string{"RS256", "RS384", "RS512", "PS256", "PS384", "PS512", "ES256", "ES384", "ES512"}
+
+// Verifier checks signatures and claims of tokens with the keys of a JWKS url (e.g. keycloak) or of a local pem file.
+// JWKS keys are refreshed periodically and when a token references an unknown key id, to follow key rotations.
+type Verifier struct {
+	jwksUrl         string
+	refreshInterval time.Duration
+	issuer          string
+	audience        []string
+	leeway          time.Duration
+	client          *http.Client
+	now             func() time.Time
+
+	fileKeys []crypto.PublicKey
+
+	mux         sync.Mutex
+	jwksKeys    map[string]crypto.PublicKey //by key id
+	fetchedAt   time.Time
+	attemptedAt time.Time
+	refreshing  bool
+}
+
+// NewVerifier returns nil if neither config.JwksUrl nor config.JwtPublicKeyFile is set.
+// keys of the JWKS url are fetched with the first token.
+func NewVerifier(conf config.Config) (result *Verifier, err error) {
+	if (conf.JwksUrl == "" || conf.JwksUrl == "-") && (conf.JwtPublicKeyFile == "" || conf.JwtPublicKeyFile == "-") {
+		return nil, nil
+	}
+	result = &Verifier{
+		issuer:          conf.JwtIssuer,
+		audience:        conf.JwtAudience,
+		refreshInterval: DefaultJwksRefreshInterval,
+		client:          &http.Client{Timeout: 10 * time.Second},
+		now:             time.Now,
+		jwksKeys:        map[string]crypto.PublicKey{},
+	}
+	if conf.JwtLeeway != "" {
+		result.leeway, err = time.ParseDuration(conf.JwtLeeway)
+		if err != nil {
+			return nil, fmt.Errorf("invalid jwt_leeway: %w", err)
+		}
+	}
+	if conf.JwksRefreshInterval != "" {
+		result.refreshInterval, err = time.ParseDuration(conf.JwksRefreshInterval)
+		if err != nil {
+			return nil, fmt.Errorf("invalid jwks_refresh_interval: %w", err)
+		}
+	}
+	if conf.JwtPublicKeyFile != "" && conf.JwtPublicKeyFile != "-" {
+		result.fileKeys, err = LoadPemKeys(conf.JwtPublicKeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("invalid jwt_public_key_file: %w", err)
+		}
+	}
+	if conf.JwksUrl != "" && conf.JwksUrl != "-" {
+		result.jwksUrl = conf.JwksUrl
+	}
+	return result, nil
+}
+
+// Verify checks the signature, expiry (exp, nbf, iat with leeway), issuer and audience of a token with or without "Bearer " prefix
+func (this *Verifier) Verify(token string) error {
+	token = strings.TrimSpace(token)
+	if len(token) > 7 && strings.ToLower(token[:7]) == "bearer " {
+		token = token[7:]
+	}
+	parser := gojwt.Parser{ValidMethods: signingMethods, SkipClaimsValidation: true}
+	unverified, _, err := parser.ParseUnverified(token, gojwt.MapClaims{})
+	if err != nil {
+		return fmt.Errorf("invalid token: %w", err)
+	}
+	kid, _ := unverified.Header["kid"].(string)
+	keys := this.getKeys(kid)
+	if len(keys) == 0 {
+		return fmt.Errorf("invalid token: unknown key id %q", kid)
+	}
+	for _, key := range keys {
+		claims := gojwt.MapClaims{}
+		_, err = parser.ParseWithClaims(token, claims, func(*gojwt.Token) (interface{}, error) { return key, nil })
+		if err == nil {
+			return this.checkClaims(claims)
+		}
+	}
+	return fmt.Errorf("invalid token: %w", err)
+}
+
+// getKeys returns the JWKS key with the id kid, or else the keys of the pem file.
+// if the JWKS does not contain kid, the keys are refreshed, unless that happened in the last JwksMinRefreshInterval.
+func (this *Verifier) getKeys(kid string) []crypto.PublicKey {
+	if this.jwksUrl != "" {
+		this.mux.Lock()
+		stale := this.now().Sub(this.fetchedAt) > this.refreshInterval
+		_, known := this.jwksKeys[kid]
+		mayRefresh := this.now().Sub(this.attemptedAt) > JwksMinRefreshInterval
+		this.mux.Unlock()
+		if (stale || !known) && mayRefresh {
+			err := this.refresh()
+			if err != nil {
+				log.Println("WARNING: unable to refresh jwks --> use known keys", err)
+			}
+		}
+		this.mux.Lock()
+		key, ok := this.jwksKeys[kid]
+		this.mux.Unlock()
+		if ok {
+			return []crypto.PublicKey{key}
+		}
+	}
+	return this.fileKeys
+}
+
+func (this *Verifier) refresh() error {
+	this.mux.Lock()
+	if this.refreshing {
+		this.mux.Unlock()
+		return nil
+	}
+	this.refreshing = true
+	this.attemptedAt = this.now()
+	this.mux.Unlock()
+	defer func() {
+		this.mux.Lock()
+		this.refreshing = false
+		this.mux.Unlock()
+	}()
+
+	keys, err := this.fetchJwks()
+	if err != nil {
+		return err
+	}
+	this.mux.Lock()
+	defer this.mux.Unlock()
+	this.jwksKeys = keys
+	this.fetchedAt = this.now()
+	return nil
+}
+
+type jwks struct {
+	Keys []jwk `json:"keys"`
+}
+
+type jwk struct {
+	Kid string `json:"kid"`
+	Kty string `json:"kty"`
+	Use string `json:"use"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	Crv string `json:"crv"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+}
+
+func (this *Verifier) fetchJwks() (result map[string]crypto.PublicKey, err error) {
+	resp, err := this.client.Get(this.jwksUrl)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected jwks response status %v", resp.StatusCode)
+	}
+	set := jwks{}
+	err = json.NewDecoder(resp.Body).Decode(&set)
+	if err != nil {
+		return nil, err
+	}
+	result = map[string]crypto.PublicKey{}
+	for _, key := range set.Keys {
+		if key.Use != "" && key.Use != "sig" {
+			continue
+		}
+		publicKey, err := key.publicKey()
+		if err != nil {
+			log.Println("WARNING: ignore jwks key", key.Kid, err)
+			continue
+		}
+		result[key.Kid] = publicKey
+	}
+	return result, nil
+}
+
+func (this jwk) publicKey() (crypto.PublicKey, error) {
+	switch this.Kty {
+	case "RSA":
+		n, err := base64.RawURLEncoding.DecodeString(this.N)
+		if err != nil {
+			return nil, err
+		}
+		e, err := base64.RawURLEncoding.DecodeString(this.E)
+		if err != nil {
+			return nil, err
+		}
+		return &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(new(big.Int).SetBytes(e).Int64())}, nil
+	case "EC":
+		var curve elliptic.Curve
+		switch this.Crv {
+		case "P-256":
+			curve = elliptic.P256()
+		case "P-384":
+			curve = elliptic.P384()
+		case "P-521":
+			curve = elliptic.P521()
+		default:
+			return nil, fmt.Errorf("unsupported curve %v", this.Crv)
+		}
+		x, err := base64.RawURLEncoding.DecodeString(this.X)
+		if err != nil {
+			return nil, err
+		}
+		y, err := base64.RawURLEncoding.DecodeString(this.Y)
+		if err != nil {
+			return nil, err
+		}
+		return &ecdsa.PublicKey{Curve: curve, X: new(big.Int).SetBytes(x), Y: new(big.Int).SetBytes(y)}, nil
+	default:
+		return nil, fmt.Errorf("unsupported key type %v", this.Kty)
+	}
+}
+
+func (this *Verifier) checkClaims(claims gojwt.MapClaims) error {
+	now := this.now()
+	exp, ok := numericClaim(claims, "exp")
+	if !ok {
+		return errors.New("invalid token: missing exp claim")
+	}
+	if now.After(exp.Add(this.leeway)) {
+		return errors.New("invalid token: expired")
+	}
+	if nbf, ok := numericClaim(claims, "nbf"); ok && now.Add(this.leeway).Before(nbf) {
+		return errors.New("invalid token: not yet valid")
+	}
+	if iat, ok := numericClaim(claims, "iat"); ok && now.Add(this.leeway).Before(iat) {
+		return errors.New("invalid token: issued in the future")
+	}
+	if this.issuer != "" {
+		iss, _ := claims["iss"].(string)
+		if iss != this.issuer {
+			return fmt.Errorf("invalid token: unexpected issuer %q", iss)
+		}
+	}
+	if len(this.audience) > 0 {
+		aud := []string{}
+		switch value := claims["aud"].(type) {
+		case string:
+			aud = append(aud, value)
+		case []interface{}:
+			for _, element := range value {
+				if str, ok := element.(string); ok {
+					aud = append(aud, str)
+				}
+			}
+		}
+		if !slices.ContainsFunc(aud, func(a string) bool { return slices.Contains(this.audience, a) }) {
+			return fmt.Errorf("invalid token: unexpected audience %v", aud)
+		}
+	}
+	return nil
+}
+
+func numericClaim(claims gojwt.MapClaims, name string) (result time.Time, ok bool) {
+	switch value := claims[name].(type) {
+	case float64:
+		return time.Unix(int64(value), 0), true
+	case json.Number:
+		i, err := value.Int64()
+		if err != nil {
+			return result, false
+		}
+		return time.Unix(i, 0), true
+	default:
+		return result, false
+	}
+}
+
+// LoadPemKeys reads all public keys of a pem file: PUBLIC KEY, RSA PUBLIC KEY and CERTIFICATE blocks,
+// and the public part of private key blocks, so that tests can sign and verify with the same file
+func LoadPemKeys(location string) (result []crypto.PublicKey, err error) {
+	data, err := os.ReadFile(location)
+	if err != nil {
+		return nil, err
+	}
+	for {
+		var block *pem.Block
+		block, data = pem.Decode(data)
+		if block == nil {
+			break
+		}
+		var key crypto.PublicKey
+		switch block.Type {
+		case "PUBLIC KEY":
+			key, err = x509.ParsePKIXPublicKey(block.Bytes)
+		case "RSA PUBLIC KEY":
+			key, err = x509.ParsePKCS1PublicKey(block.Bytes)
+		case "CERTIFICATE":
+			var cert *x509.Certificate
+			cert, err = x509.ParseCertificate(block.Bytes)
+			if err == nil {
+				key = cert.PublicKey
+			}
+		case "PRIVATE KEY", "RSA PRIVATE KEY", "EC PRIVATE KEY":
+			var private crypto.Signer
+			private, err = parsePrivateKey(block)
+			if err == nil {
+				key = private.Public()
+			}
+		default:
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("unable to parse %v block: %w", block.Type, err)
+		}
+		result = append(result, key)
+	}
+	if len(result) == 0 {
+		return nil, errors.New("no key found in " + location)
+	}
+	return result, nil
+}
+
+// LoadPemPrivateKey reads the first private key of a pem file
+func LoadPemPrivateKey(location string) (crypto.Signer, error) {
+	data, err := os.ReadFile(location)
+	if err != nil {
+		return nil, err
+	}
+	for {
+		var block *pem.Block
+		block, data = pem.Decode(data)
+		if block == nil {
+			return nil, errors.New("no private key found in " + location)
+		}
+		if strings.HasSuffix(block.Type, "PRIVATE KEY") {
+			return parsePrivateKey(block)
+		}
+	}
+}
+
+func parsePrivateKey(block *pem.Block) (crypto.Signer, error) {
+	switch block.Type {
+	case "RSA PRIVATE KEY":
+		return x509.ParsePKCS1PrivateKey(block.Bytes)
+	case "EC PRIVATE KEY":
+		return x509.ParseECPrivateKey(block.Bytes)
+	default:
+		key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+		if err != nil {
+			return nil, err
+		}
+		signer, ok := key.(crypto.Signer)
+		if !ok {
+			return nil, errors.New("unsupported private key type")
+		}
+		return signer, nil
+	}
+}
+
+// NewVerifyMiddleware rejects requests with a bearer Authorization header which is not accepted by verifier with 401.
+// requests without Authorization header are passed on; endpoints which need a token reject them themselves.
+// verifier may be nil to disable the verification.
+func NewVerifyMiddleware(handler http.Handler, verifier *Verifier) http.Handler {
+	if verifier == nil {
+		return handler
+	}
+	return http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		token := request.Header.Get("Authorization")
+		scheme, _, found := strings.Cut(token, " ")
+		if found && !strings.EqualFold(scheme, "bearer") {
+			//other schemes (e.g. api keys) are checked by their own middleware
+			handler.ServeHTTP(writer, request)
+			return
+		}
+		if token != "" {
+			err := verifier.Verify(token)
+			if err != nil {
+				http.Error(writer, err.Error(), http.StatusUnauthorized)
+				return
+			}
+		}
+		handler.ServeHTTP(writer, request)
+	})
+}
@@ -0,0 +1,91 @@
+/*
+ * Copyright 2024 InfAI (CC SES)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package bus abstracts the message bus used to publish commands and to consume user and done messages.
+// the backend is selected by config.BusBackend:
+//   - "kafka" (default)
+//   - "nats": JetStream streams and durable consumers (config.NatsJetStream), or core nats with at-most-once delivery and no history
+//   - "channel": in-process, for single binary deployments and tests
+package bus
+
+import (
+	"context"
+	"fmt"
+	"github.com/SENERGY-Platform/device-manager/lib/config"
+)
+
+type Bus interface {
+	Producer
+	Consumer
+}
+
+type Producer interface {
+	// Publish sends message to topic; the key decides the ordering of messages, if supported by the backend.
+	// the trace context of ctx is sent as message headers, if supported by the backend
+	Publish(ctx context.Context, topic string, key string, message []byte) error
+}
+
+type Consumer interface {
+	// Subscribe calls handler for each message of topic until ctx is done.
+	// subscribers with the same non-empty group share the messages; subscribers without group receive all messages.
+	// handler errors are retried; if the retries are exhausted or the subscription fails, onError is called.
+	Subscribe(ctx context.Context, topic string, group string, handler Handler, onError func(err error)) error
+}
+
+// Handler handles a message of topic; ctx carries the span of the consumed message
+type Handler func(ctx context.Context, topic string, msg []byte) error
+
+// HealthChecker is implemented by backends which can report their connection state
+type HealthChecker interface {
+	CheckHealth(ctx context.Context) error
+}
+
+const (
+	BackendKafka   = "kafka"
+	BackendNats    = "nats"
+	BackendChannel = "channel"
+)
+
+// New creates the bus selected by config.BusBackend; topics are created if the backend requires it
+func New(ctx context.Context, conf config.Config, topics ...string) (Bus, error) {
+	switch conf.BusBackend {
+	case "", BackendKafka:
+		return NewKafka(ctx, conf, topics...)
+	case BackendNats:
+		return NewNats(ctx, conf, topics...)
+	case BackendChannel:
+		return DefaultChannel, nil
+	default:
+		return nil, fmt.Errorf("unknown bus_backend %v", conf.BusBackend)
+	}
+}
+
+// CommandTopics returns all topics the publisher writes to
+func CommandTopics(conf config.Config) []string {
+	return []string{
+		conf.DeviceTypeTopic,
+		conf.DeviceGroupTopic,
+		conf.ProtocolTopic,
+		conf.DeviceTopic,
+		conf.HubTopic,
+		conf.ConceptTopic,
+		conf.CharacteristicTopic,
+		conf.AspectTopic,
+		conf.FunctionTopic,
+		conf.DeviceClassTopic,
+		conf.LocationTopic,
+	}
+}
@@ -0,0 +1,122 @@
+/*
+ * Copyright 2024 InfAI (CC SES)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package bus
+
+import (
+	"context"
+	"github.com/SENERGY-Platform/device-manager/lib/tracing"
+	"hash/fnv"
+	"slices"
+	"sync"
+	"time"
+)
+
+// ChannelBufferSize is the number of messages a subscription buffers before Publish blocks
+var ChannelBufferSize = 1000
+
+// DefaultChannel is the in-process bus used for config.BusBackend "channel"
+var DefaultChannel = NewChannel()
+
+// Channel is an in-process bus; messages are delivered in publish order to every subscriber without group
+// and to one subscriber per group. like kafka partitions, messages with the same key go to the same group member, as long as the members do not change;
+// messages without key are distributed round-robin. messages published before a subscription are not delivered.
+type Channel struct {
+	mux           sync.Mutex
+	subscriptions map[string][]*subscription //topic -> subscriptions
+	next          map[string]int             //topic+group -> index of the next group member
+}
+
+type subscription struct {
+	group    string
+	messages chan envelope
+	done     <-chan struct{}
+}
+
+type envelope struct {
+	time    time.Time
+	headers map[string]string
+	message []byte
+}
+
+func NewChannel() *Channel {
+	return &Channel{subscriptions: map[string][]*subscription{}, next: map[string]int{}}
+}
+
+func (this *Channel) Publish(ctx context.Context, topic string, key string, message []byte) error {
+	msg := envelope{time: time.Now(), headers: map[string]string{}, message: message}
+	tracing.Inject(ctx, msg.headers)
+	for _, sub := range this.receivers(topic, key) {
+		select {
+		case sub.messages <- msg:
+		case <-sub.done:
+		}
+	}
+	return nil
+}
+
+func (this *Channel) receivers(topic string, key string) (result []*subscription) {
+	this.mux.Lock()
+	defer this.mux.Unlock()
+	groups := map[string][]*subscription{}
+	for _, sub := range this.subscriptions[topic] {
+		if sub.group == "" {
+			result = append(result, sub)
+		} else {
+			groups[sub.group] = append(groups[sub.group], sub)
+		}
+	}
+	for group, members := range groups {
+		if key != "" {
+			hash := fnv.New32a()
+			hash.Write([]byte(key))
+			result = append(result, members[hash.Sum32()%uint32(len(members))])
+			continue
+		}
+		index := this.next[topic+"/"+group] % len(members)
+		this.next[topic+"/"+group] = index + 1
+		result = append(result, members[index])
+	}
+	return result
+}
+
+func (this *Channel) Subscribe(ctx context.Context, topic string, group string, handler Handler, onError func(err error)) error {
+	sub := &subscription{group: group, messages: make(chan envelope, ChannelBufferSize), done: ctx.Done()}
+	this.mux.Lock()
+	this.subscriptions[topic] = append(this.subscriptions[topic], sub)
+	this.mux.Unlock()
+	go func() {
+		defer func() {
+			this.mux.Lock()
+			defer this.mux.Unlock()
+			this.subscriptions[topic] = slices.DeleteFunc(this.subscriptions[topic], func(e *subscription) bool {
+				return e == sub
+			})
+		}()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case msg := <-sub.messages:
+				err := handleWithRetry(handler, topic, msg.message, msg.time, msg.headers)
+				if err != nil && onError != nil {
+					onError(err)
+				}
+			}
+		}
+	}()
+	return nil
+}
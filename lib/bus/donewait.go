@@ -0,0 +1,55 @@
+/*
+ * Copyright 2024 InfAI (CC SES)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package bus
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/SENERGY-Platform/service-commons/pkg/donewait"
+	"github.com/SENERGY-Platform/service-commons/pkg/signal"
+	"log"
+)
+
+// StartDoneWaitListener is the bus equivalent of donewait.StartDoneWaitListener:
+// done messages of all topics are forwarded to the signal broker (nil defaults to signal.DefaultBroker).
+// onError is called if a consumer stops; may be nil
+func StartDoneWaitListener(ctx context.Context, consumer Consumer, topics []string, broker *signal.Broker, onError func(err error)) error {
+	if broker == nil {
+		broker = signal.DefaultBroker
+	}
+	for _, topic := range topics {
+		err := consumer.Subscribe(ctx, topic, "", func(_ context.Context, topic string, msg []byte) error {
+			doneMsg := donewait.DoneMsg{}
+			err := json.Unmarshal(msg, &doneMsg)
+			if err != nil {
+				log.Printf("ERROR: unable to interpret message for done wait on topic %v: %v \nmessage = %v", topic, err, string(msg))
+				return nil
+			}
+			broker.Pub(signal.Known.UpdateDone, donewait.SerializeDoneMsg(doneMsg))
+			return nil
+		}, func(err error) {
+			log.Println("ERROR: done wait listener", topic, err)
+			if onError != nil {
+				onError(err)
+			}
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
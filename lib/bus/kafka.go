@@ -0,0 +1,262 @@
+/*
+ * Copyright 2024 InfAI (CC SES)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package bus
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/SENERGY-Platform/device-manager/lib/config"
+	"github.com/SENERGY-Platform/device-manager/lib/kafka/util"
+	"github.com/SENERGY-Platform/device-manager/lib/tracing"
+	commonskafka "github.com/SENERGY-Platform/service-commons/pkg/kafka"
+	"github.com/segmentio/kafka-go"
+	"io"
+	"log"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// WriteErrorHealthWindow is the time a failed write lets CheckHealth fail.
+// afterwards only the broker metadata is checked, so that an unready replica, which receives no writes, becomes ready once kafka recovers
+var WriteErrorHealthWindow = 30 * time.Second
+
+type Kafka struct {
+	ctx            context.Context
+	config         config.Config
+	mux            sync.Mutex
+	writers        map[string]*kafka.Writer
+	lastWriteErr   error //error of the last write; reset by the next successful write
+	lastWriteErrAt time.Time
+}
+
+func NewKafka(ctx context.Context, conf config.Config, topics ...string) (*Kafka, error) {
+	if len(topics) > 0 {
+		log.Println("ensure kafka topics")
+		err := util.InitTopic(conf.KafkaUrl, topics...)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return &Kafka{ctx: ctx, config: conf, writers: map[string]*kafka.Writer{}}, nil
+}
+
+func (this *Kafka) Publish(ctx context.Context, topic string, key string, message []byte) error {
+	headers := map[string]string{}
+	tracing.Inject(ctx, headers)
+	kafkaHeaders := []kafka.Header{}
+	for name, value := range headers {
+		kafkaHeaders = append(kafkaHeaders, kafka.Header{Key: name, Value: []byte(value)})
+	}
+	err := this.getWriter(topic).WriteMessages(
+		context.Background(),
+		kafka.Message{
+			Key:     []byte(key),
+			Value:   message,
+			Time:    time.Now(),
+			Headers: kafkaHeaders,
+		},
+	)
+	this.mux.Lock()
+	this.lastWriteErr = err
+	this.lastWriteErrAt = time.Now()
+	this.mux.Unlock()
+	return err
+}
+
+// CheckHealth requests the broker metadata and fails if the last write failed within WriteErrorHealthWindow
+func (this *Kafka) CheckHealth(ctx context.Context) error {
+	this.mux.Lock()
+	lastWriteErr := this.lastWriteErr
+	if lastWriteErr != nil && time.Since(this.lastWriteErrAt) > WriteErrorHealthWindow {
+		lastWriteErr = nil
+	}
+	this.mux.Unlock()
+	if lastWriteErr != nil {
+		return fmt.Errorf("last write failed: %w", lastWriteErr)
+	}
+	conn, err := kafka.DialContext(ctx, "tcp", this.config.KafkaUrl)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	if deadline, ok := ctx.Deadline(); ok {
+		err = conn.SetDeadline(deadline)
+		if err != nil {
+			return err
+		}
+	}
+	brokers, err := conn.Brokers()
+	if err != nil {
+		return err
+	}
+	if len(brokers) == 0 {
+		return errors.New("no kafka brokers in metadata")
+	}
+	return nil
+}
+
+func (this *Kafka) getWriter(topic string) *kafka.Writer {
+	this.mux.Lock()
+	defer this.mux.Unlock()
+	writer, ok := this.writers[topic]
+	if !ok {
+		log.Println("Produce to ", topic)
+		writer = getProducer(this.ctx, this.config.KafkaUrl, topic, this.config.LogLevel == "DEBUG")
+		this.writers[topic] = writer
+	}
+	return writer
+}
+
+func getProducer(ctx context.Context, broker string, topic string, debug bool) (writer *kafka.Writer) {
+	var logger *log.Logger
+	if debug {
+		logger = log.New(os.Stdout, "[KAFKA-PRODUCER] ", 0)
+	} else {
+		logger = log.New(io.Discard, "", 0)
+	}
+	writer = &kafka.Writer{
+		Addr:        kafka.TCP(broker),
+		Topic:       topic,
+		MaxAttempts: 10,
+		Logger:      logger,
+		BatchSize:   1,
+		Balancer:    &KeySeparationBalancer{SubBalancer: &kafka.Hash{}, Seperator: "/"},
+	}
+	go func() {
+		<-ctx.Done()
+		err := writer.Close()
+		if err != nil {
+			log.Println("ERROR: unable to close producer for", topic, err)
+		}
+	}()
+	return writer
+}
+
+func kafkaHeaders(headers []kafka.Header) map[string]string {
+	result := map[string]string{}
+	for _, header := range headers {
+		result[header.Key] = string(header.Value)
+	}
+	return result
+}
+
+type KeySeparationBalancer struct {
+	SubBalancer kafka.Balancer
+	Seperator   string
+}
+
+func (this *KeySeparationBalancer) Balance(msg kafka.Message, partitions ...int) (partition int) {
+	key := string(msg.Key)
+	if this.Seperator != "" {
+		keyParts := strings.Split(key, this.Seperator)
+		key = keyParts[0]
+	}
+	msg.Key = []byte(key)
+	return this.SubBalancer.Balance(msg, partitions...)
+}
+
+// Subscribe consumes with a consumer group and commits after handling; without group, consumption starts at the last offset
+func (this *Kafka) Subscribe(ctx context.Context, topic string, group string, handler Handler, onError func(err error)) error {
+	if group == "" {
+		return commonskafka.NewMultiConsumer(ctx, commonskafka.Config{
+			KafkaUrl:    this.config.KafkaUrl,
+			StartOffset: commonskafka.LastOffset,
+			Debug:       this.config.Debug,
+			OnError:     onError,
+		}, []string{topic}, func(delivery commonskafka.Message) error {
+			return handleWithRetry(handler, delivery.Topic, delivery.Value, delivery.Time, nil)
+		})
+	}
+	_, err := NewKafkaConsumer(ctx, this.config.KafkaUrl, group, topic, func(m kafka.Message) error {
+		return handleWithRetry(handler, m.Topic, m.Value, m.Time, kafkaHeaders(m.Headers))
+	}, func(err error, consumer *KafkaConsumer) {
+		onError(err)
+	})
+	return err
+}
+
+// NewKafkaConsumer consumes topic with a consumer group and commits each message after listener returned without error
+func NewKafkaConsumer(ctx context.Context, broker string, groupid string, topic string, listener func(msg kafka.Message) error, errorhandler func(err error, consumer *KafkaConsumer)) (consumer *KafkaConsumer, err error) {
+	consumer = &KafkaConsumer{ctx: ctx, groupId: groupid, broker: broker, topic: topic, listener: listener, errorhandler: errorhandler}
+	err = consumer.start()
+	return
+}
+
+type KafkaConsumer struct {
+	count        int
+	broker       string
+	groupId      string
+	topic        string
+	ctx          context.Context
+	listener     func(msg kafka.Message) error
+	errorhandler func(err error, consumer *KafkaConsumer)
+	mux          sync.Mutex
+}
+
+func (this *KafkaConsumer) start() error {
+	log.Println("DEBUG: consume topic: \"" + this.topic + "\"")
+
+	err := util.InitTopic(this.broker, this.topic)
+	if err != nil {
+		log.Println("ERROR: unable to create topic", err)
+		return err
+	}
+	r := kafka.NewReader(kafka.ReaderConfig{
+		CommitInterval:         0, //synchronous commits
+		Brokers:                []string{this.broker},
+		GroupID:                this.groupId,
+		Topic:                  this.topic,
+		MaxWait:                1 * time.Second,
+		Logger:                 log.New(io.Discard, "", 0),
+		ErrorLogger:            log.New(io.Discard, "", 0),
+		WatchPartitionChanges:  true,
+		PartitionWatchInterval: time.Minute,
+	})
+	go func() {
+		defer r.Close()
+		defer log.Println("close consumer for topic ", this.topic)
+		for {
+			select {
+			case <-this.ctx.Done():
+				return
+			default:
+				m, err := r.FetchMessage(this.ctx)
+				if err == io.EOF || err == context.Canceled {
+					return
+				}
+				if err != nil {
+					log.Println("ERROR: while consuming topic ", this.topic, err)
+					this.errorhandler(err, this)
+					return
+				}
+
+				err = this.listener(m)
+
+				if err != nil {
+					log.Println("ERROR: unable to handle message (no commit)", err)
+					this.errorhandler(err, this)
+				} else {
+					err = r.CommitMessages(this.ctx, m)
+				}
+			}
+		}
+	}()
+	return err
+}
@@ -0,0 +1,212 @@
+/*
+ * Copyright 2024 InfAI (CC SES)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package bus
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/SENERGY-Platform/device-manager/lib/config"
+	"github.com/SENERGY-Platform/device-manager/lib/tracing"
+	"github.com/nats-io/nats.go"
+	"github.com/nats-io/nats.go/jetstream"
+	"log"
+	"strings"
+	"time"
+)
+
+// KeyHeader carries the message key, because nats has no native message keys
+const KeyHeader = "Key"
+
+// Nats uses topics as subjects and groups as queue groups (core nats) or durable consumers (JetStream).
+// with config.NatsJetStream, every topic is captured by a stream of the same name, so that messages published
+// while a group is not consuming are delivered later. core nats delivers at most once: messages published
+// while no member of a group is subscribed are lost.
+type Nats struct {
+	conn *nats.Conn
+	js   jetstream.JetStream //nil for core nats
+}
+
+func NewNats(ctx context.Context, conf config.Config, topics ...string) (*Nats, error) {
+	conn, err := nats.Connect(conf.NatsUrl, nats.Name(conf.GroupId), nats.MaxReconnects(-1), nats.ErrorHandler(func(conn *nats.Conn, sub *nats.Subscription, err error) {
+		log.Println("ERROR: nats", err)
+	}))
+	if err != nil {
+		return nil, err
+	}
+	go func() {
+		<-ctx.Done()
+		err := conn.Drain()
+		if err != nil {
+			log.Println("ERROR: unable to drain nats connection", err)
+		}
+	}()
+	result := &Nats{conn: conn}
+	if !conf.NatsJetStream {
+		log.Println("WARNING: core nats delivers at most once; messages published while a consumer is down are lost")
+		return result, nil
+	}
+	result.js, err = jetstream.New(conn)
+	if err != nil {
+		return nil, err
+	}
+	for _, topic := range topics {
+		err = result.ensureStream(ctx, topic)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return result, nil
+}
+
+func (this *Nats) Publish(ctx context.Context, topic string, key string, message []byte) error {
+	msg := nats.NewMsg(topic)
+	headers := map[string]string{}
+	tracing.Inject(ctx, headers)
+	for name, value := range headers {
+		msg.Header.Set(name, value)
+	}
+	msg.Header.Set(KeyHeader, key)
+	msg.Data = message
+	if this.js != nil {
+		//waits for the stream to acknowledge the message
+		_, err := this.js.PublishMsg(ctx, msg)
+		return err
+	}
+	err := this.conn.PublishMsg(msg)
+	if err != nil {
+		return err
+	}
+	return this.conn.Flush()
+}
+
+// NatsName replaces characters which are not allowed in stream and consumer names
+func NatsName(name string) string {
+	return strings.NewReplacer(".", "_", "*", "_", ">", "_", " ", "_", "/", "_", "\\", "_").Replace(name)
+}
+
+// ensureStream creates a stream capturing topic; existing streams are kept unchanged, because other services may have configured them
+func (this *Nats) ensureStream(ctx context.Context, topic string) error {
+	_, err := this.js.CreateStream(ctx, jetstream.StreamConfig{Name: NatsName(topic), Subjects: []string{topic}})
+	if errors.Is(err, jetstream.ErrStreamNameAlreadyInUse) {
+		return nil
+	}
+	return err
+}
+
+func natsHeaders(header nats.Header) map[string]string {
+	result := map[string]string{}
+	for name := range header {
+		result[name] = header.Get(name)
+	}
+	return result
+}
+
+func (this *Nats) CheckHealth(ctx context.Context) error {
+	if status := this.conn.Status(); status != nats.CONNECTED {
+		return fmt.Errorf("nats connection %v", status.String())
+	}
+	return this.conn.FlushWithContext(ctx)
+}
+
+func (this *Nats) Subscribe(ctx context.Context, topic string, group string, handler Handler, onError func(err error)) error {
+	if this.js != nil {
+		return this.subscribeJetStream(ctx, topic, group, handler, onError)
+	}
+	cb := func(msg *nats.Msg) {
+		err := handleWithRetry(handler, msg.Subject, msg.Data, time.Time{}, natsHeaders(msg.Header))
+		if err != nil && onError != nil {
+			onError(err)
+		}
+	}
+	var sub *nats.Subscription
+	var err error
+	if group == "" {
+		sub, err = this.conn.Subscribe(topic, cb)
+	} else {
+		sub, err = this.conn.QueueSubscribe(topic, group, cb)
+	}
+	if err != nil {
+		return err
+	}
+	go func() {
+		<-ctx.Done()
+		err := sub.Unsubscribe()
+		if err != nil && err != nats.ErrConnectionClosed && err != nats.ErrConnectionDraining {
+			log.Println("ERROR: unable to unsubscribe from", topic, err)
+		}
+	}()
+	return nil
+}
+
+// subscribeJetStream consumes with a durable consumer named after the group, which keeps its position while no member is running;
+// without group, an ephemeral consumer receives the messages published after the subscription.
+// messages are acknowledged after handling; the ack wait covers the handler retries.
+func (this *Nats) subscribeJetStream(ctx context.Context, topic string, group string, handler Handler, onError func(err error)) error {
+	err := this.ensureStream(ctx, topic)
+	if err != nil {
+		return err
+	}
+	consumerConfig := jetstream.ConsumerConfig{
+		FilterSubject: topic,
+		AckPolicy:     jetstream.AckExplicitPolicy,
+		AckWait:       HandlerRetryTimeout + time.Minute,
+	}
+	if group == "" {
+		consumerConfig.DeliverPolicy = jetstream.DeliverNewPolicy
+		consumerConfig.InactiveThreshold = time.Minute
+	} else {
+		consumerConfig.Durable = NatsName(group)
+		consumerConfig.DeliverPolicy = jetstream.DeliverAllPolicy
+	}
+	consumer, err := this.js.CreateOrUpdateConsumer(ctx, NatsName(topic), consumerConfig)
+	if err != nil {
+		return err
+	}
+	consumeCtx, err := consumer.Consume(func(msg jetstream.Msg) {
+		messageTime := time.Time{}
+		if metadata, err := msg.Metadata(); err == nil {
+			messageTime = metadata.Timestamp
+		}
+		err := handleWithRetry(handler, msg.Subject(), msg.Data(), messageTime, natsHeaders(msg.Headers()))
+		if err != nil {
+			//like an uncommitted kafka message: reported, but not redelivered endlessly
+			if termErr := msg.Term(); termErr != nil {
+				log.Println("ERROR: unable to terminate nats message", topic, termErr)
+			}
+			if onError != nil {
+				onError(err)
+			}
+			return
+		}
+		if ackErr := msg.Ack(); ackErr != nil {
+			log.Println("ERROR: unable to ack nats message", topic, ackErr)
+		}
+	}, jetstream.ConsumeErrHandler(func(consumeCtx jetstream.ConsumeContext, err error) {
+		if !errors.Is(err, jetstream.ErrNoHeartbeat) {
+			log.Println("ERROR: nats consumer", topic, err)
+		}
+	}))
+	if err != nil {
+		return err
+	}
+	go func() {
+		<-ctx.Done()
+		consumeCtx.Stop()
+	}()
+	return nil
+}
@@ -0,0 +1,67 @@
+/*
+ * Copyright 2024 InfAI (CC SES)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package bus
+
+import (
+	"context"
+	"errors"
+	"github.com/SENERGY-Platform/device-manager/lib/metrics"
+	"github.com/SENERGY-Platform/device-manager/lib/tracing"
+	"go.opentelemetry.io/otel/trace"
+	"log"
+	"time"
+)
+
+// HandlerRetryTimeout is the time a failing handler is retried before the message is given up
+var HandlerRetryTimeout = 10 * time.Minute
+
+// handleWithRetry calls handler with the context of the consume span until it succeeds or HandlerRetryTimeout is exceeded;
+// messageTime is the publish time of the message, zero if unknown; headers may carry a trace context and may be nil
+func handleWithRetry(handler Handler, topic string, msg []byte, messageTime time.Time, headers map[string]string) (err error) {
+	metrics.ObserveListenerLag(topic, messageTime)
+	ctx, span := tracing.Start(tracing.Extract(context.Background(), headers), "consume "+topic, trace.WithSpanKind(trace.SpanKindConsumer))
+	defer func() { tracing.End(span, err) }()
+	attempt := 0
+	return retry(func() error {
+		if attempt > 0 {
+			metrics.ListenerRetries.WithLabelValues(topic).Inc()
+		}
+		attempt++
+		return handler(ctx, topic, msg)
+	}, func(n int64) time.Duration {
+		return time.Duration(n) * time.Second
+	}, HandlerRetryTimeout)
+}
+
+func retry(f func() error, waitProvider func(n int64) time.Duration, timeout time.Duration) (err error) {
+	err = errors.New("")
+	start := time.Now()
+	for i := int64(1); err != nil && time.Since(start) < timeout; i++ {
+		err = f()
+		if err != nil {
+			log.Println("ERROR: bus listener error:", err)
+			wait := waitProvider(i)
+			if time.Since(start)+wait < timeout {
+				log.Println("ERROR: retry after:", wait.String())
+				time.Sleep(wait)
+			} else {
+				return err
+			}
+		}
+	}
+	return err
+}
@@ -0,0 +1,224 @@
+/*
+ * Copyright 2024 InfAI (CC SES)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cache
+
+import (
+	"container/list"
+	"context"
+	"encoding/json"
+	"github.com/SENERGY-Platform/device-manager/lib/bus"
+	"github.com/SENERGY-Platform/device-manager/lib/metrics"
+	"github.com/SENERGY-Platform/service-commons/pkg/signal"
+	"log"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Cache holds rarely changing resources (e.g. aspects, functions, device-types) read from the device-repository.
+// entries are removed after ttl, by least recent use if more than maxSize entries exist,
+// and by Invalidate when a command for the resource is consumed or the device-repository reports it as done.
+type Cache struct {
+	ttl     time.Duration
+	maxSize int
+	now     func() time.Time
+	mux     sync.Mutex
+	entries map[key]*list.Element
+	lru     *list.List //front is the most recently used entry
+	reads   map[key]*read
+}
+
+// read tracks the invalidations of a key while values are read from the device-repository
+type read struct {
+	count int
+	gen   uint64
+}
+
+type key struct {
+	kind string
+	id   string
+}
+
+type entry struct {
+	key     key
+	value   []byte //json, to prevent callers from modifying the stored value
+	expires time.Time
+}
+
+// New creates a cache; a maxSize of 0 or less disables the size limit
+func New(ttl time.Duration, maxSize int) *Cache {
+	return &Cache{
+		ttl:     ttl,
+		maxSize: maxSize,
+		now:     time.Now,
+		entries: map[key]*list.Element{},
+		lru:     list.New(),
+		reads:   map[key]*read{},
+	}
+}
+
+// Subscribe invalidates entries on commands consumed from topics, without consumer group,
+// and on done messages received by donewait.StartDoneWaitListener (default signal broker).
+// the second invalidation removes values read between the command and its handling by the device-repository.
+func (this *Cache) Subscribe(ctx context.Context, consumer bus.Consumer, topics []string) error {
+	for _, topic := range topics {
+		err := consumer.Subscribe(ctx, topic, "", func(_ context.Context, topic string, msg []byte) error {
+			cmd := struct {
+				Id string `json:"id"`
+			}{}
+			err := json.Unmarshal(msg, &cmd)
+			if err != nil {
+				log.Println("WARNING: unable to interpret command for cache invalidation; ignore", topic, err)
+				return nil
+			}
+			this.Invalidate(topic, cmd.Id)
+			return nil
+		}, func(err error) {
+			log.Println("ERROR: cache invalidation consumer stopped --> clear cache and disable it", err)
+			this.disable()
+		})
+		if err != nil {
+			return err
+		}
+	}
+	id := signal.Sub("", signal.Known.UpdateDone, func(value string, _ *sync.WaitGroup) {
+		//"command|kind|id|handler", see donewait.SerializeDoneMsg
+		parts := strings.SplitN(value, "|", 4)
+		if len(parts) == 4 {
+			this.Invalidate(parts[1], parts[2])
+		}
+	})
+	go func() {
+		<-ctx.Done()
+		signal.Unsub(id)
+	}()
+	return nil
+}
+
+// Invalidate removes the entry; kind is the topic of the resource.
+// a value read before the invalidation and stored after it is discarded.
+func (this *Cache) Invalidate(kind string, id string) {
+	this.mux.Lock()
+	defer this.mux.Unlock()
+	k := key{kind: kind, id: id}
+	if r, ok := this.reads[k]; ok {
+		r.gen++
+	}
+	if element, ok := this.entries[k]; ok {
+		this.lru.Remove(element)
+		delete(this.entries, k)
+	}
+}
+
+// Len returns the number of entries, including expired ones which have not yet been removed
+func (this *Cache) Len() int {
+	this.mux.Lock()
+	defer this.mux.Unlock()
+	return len(this.entries)
+}
+
+func (this *Cache) disable() {
+	this.mux.Lock()
+	defer this.mux.Unlock()
+	this.ttl = 0
+	this.entries = map[key]*list.Element{}
+	this.lru.Init()
+}
+
+// startRead returns the generation a value of k has to be stored with; each call must be followed by a call to set
+func (this *Cache) startRead(k key) uint64 {
+	this.mux.Lock()
+	defer this.mux.Unlock()
+	r, ok := this.reads[k]
+	if !ok {
+		r = &read{}
+		this.reads[k] = r
+	}
+	r.count++
+	return r.gen
+}
+
+func (this *Cache) get(k key) (value []byte, ok bool) {
+	this.mux.Lock()
+	defer this.mux.Unlock()
+	element, ok := this.entries[k]
+	if !ok {
+		return nil, false
+	}
+	e := element.Value.(*entry)
+	if !e.expires.After(this.now()) {
+		this.lru.Remove(element)
+		delete(this.entries, k)
+		return nil, false
+	}
+	this.lru.MoveToFront(element)
+	return e.value, true
+}
+
+// set ends a read started by startRead and stores value, if it is not nil and k has not been invalidated since the read started
+func (this *Cache) set(k key, value []byte, gen uint64) {
+	this.mux.Lock()
+	defer this.mux.Unlock()
+	r := this.reads[k]
+	r.count--
+	if r.count == 0 {
+		delete(this.reads, k)
+	}
+	if value == nil || this.ttl <= 0 || r.gen != gen {
+		return
+	}
+	e := &entry{key: k, value: value, expires: this.now().Add(this.ttl)}
+	if element, ok := this.entries[k]; ok {
+		element.Value = e
+		this.lru.MoveToFront(element)
+		return
+	}
+	this.entries[k] = this.lru.PushFront(e)
+	for this.maxSize > 0 && this.lru.Len() > this.maxSize {
+		oldest := this.lru.Back()
+		this.lru.Remove(oldest)
+		delete(this.entries, oldest.Value.(*entry).key)
+	}
+}
+
+// Use returns the cached resource or calls get and caches its result, if it returned no error.
+// kind is the topic of the resource and the label of the cache metrics.
+func Use[T any](cache *Cache, kind string, id string, get func() (T, error, int)) (result T, err error, code int) {
+	k := key{kind: kind, id: id}
+	if value, ok := cache.get(k); ok {
+		err = json.Unmarshal(value, &result)
+		if err == nil {
+			metrics.CacheRequests.WithLabelValues(kind, "hit").Inc()
+			return result, nil, http.StatusOK
+		}
+		log.Println("WARNING: unable to read resource from cache", kind, id, err)
+	}
+	metrics.CacheRequests.WithLabelValues(kind, "miss").Inc()
+	gen := cache.startRead(k)
+	result, err, code = get()
+	if err != nil {
+		cache.set(k, nil, gen)
+		return result, err, code
+	}
+	value, marshalErr := json.Marshal(result)
+	if marshalErr != nil {
+		log.Println("WARNING: unable to cache resource", kind, id, marshalErr)
+	}
+	cache.set(k, value, gen)
+	return result, err, code
+}
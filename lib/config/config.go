@@ -34,7 +34,10 @@ type Config struct {
 	ServerPort string `json:"server_port"`
 	Debug      bool   `json:"debug"`
 
-	EditForward string `json:"edit_forward"`
+	EditForward             string `json:"edit_forward"`               //comma separated list of upstreams to forward POST, PUT and DELETE requests to; empty or "-" to handle them locally
+	EditForwardTimeout      string `json:"edit_forward_timeout"`       //max time until an upstream sends the response headers
+	EditForwardRetries      int64  `json:"edit_forward_retries"`       //additional attempts for forwarded requests which may be repeated (idempotent or never received); 0 uses the default, negative disables retries
+	EditForwardUnhealthyFor string `json:"edit_forward_unhealthy_for"` //time a failed upstream is skipped while other upstreams are available
 
 	PermissionsV2Url    string `json:"permissions_v2_url"`
 	DeviceTopic         string `json:"device_topic"`
@@ -54,11 +57,71 @@ type Config struct {
 	GroupId             string `json:"group_id"`
 	HttpClientTimeout   string `json:"http_client_timeout"`
 
+	UpstreamTimeouts            map[string]string `json:"upstream_timeouts"`          //timeouts of upstream calls by "<path-prefix>" or "<METHOD> <path-prefix>"; others use http_client_timeout
+	UpstreamRetries             int64             `json:"upstream_retries"`           //additional attempts of upstream GET requests after connection errors, 502, 503 or 504; 0 uses the default, negative disables retries
+	UpstreamRetryBackoff        string            `json:"upstream_retry_backoff"`     //wait before the first retry, doubled for each further retry
+	UpstreamBreakerThreshold    int64             `json:"upstream_breaker_threshold"` //consecutive failures of an upstream host until requests fail fast with 503; 0 uses the default, negative disables the circuit breaker
+	UpstreamBreakerOpenFor      string            `json:"upstream_breaker_open_for"`  //time requests fail fast before a probe request is sent
+	UpstreamMaxIdleConns        int64             `json:"upstream_max_idle_conns"`
+	UpstreamMaxIdleConnsPerHost int64             `json:"upstream_max_idle_conns_per_host"`
+	UpstreamMaxConnsPerHost     int64             `json:"upstream_max_conns_per_host"` //0 for no limit
+	UpstreamIdleConnTimeout     string            `json:"upstream_idle_conn_timeout"`
+
 	DisableValidation bool     `json:"disable_validation"`
 	ConverterUrl      string   `json:"converter_url"` //to validate concept conversions, may be empty to disable concept conversion validation
 	HandleDoneWait    bool     `json:"handle_done_wait"`
 	DoneTopics        []string `json:"done_topics"`
 	DoneHandler       []string `json:"done_handler"`
+
+	ReadModel             bool   `json:"read_model"`               //consume command topics into a local read model which answers Read* calls while it is fresh
+	ReadModelMaxStaleness string `json:"read_model_max_staleness"` //reads fall back to the device-repository if the consumer is behind the partition high-water marks or if they have not been checked for this duration
+
+	ResourceCacheTtl  string `json:"resource_cache_ttl"`  //max age of cached device-types, protocols, aspects, functions, concepts, characteristics and device-classes; empty or "-" to disable
+	ResourceCacheSize int64  `json:"resource_cache_size"` //max number of cached resources; 0 for no limit
+
+	ReadYourWritesTtl string `json:"read_your_writes_ttl"` //max time a user reads own writes from a local overlay while waiting for the done messages; empty or "-" to disable
+
+	ApiKeyFile           string `json:"api_key_file"`             //json file of the hashed api keys, shared by all replicas; empty or "-" disables api keys
+	ApiKeyMaxTtl         string `json:"api_key_max_ttl"`          //default and max lifetime of api keys
+	ApiKeySigningKeyFile string `json:"api_key_signing_key_file"` //pem file of the private key which signs the tokens of api key requests; required for api keys. its public key must be trusted here (jwt_public_key_file) and by the upstream services
+	ApiKeySigningKeyId   string `json:"api_key_signing_key_id"`   //kid header of the tokens of api key requests
+
+	TransferFile string `json:"transfer_file"` //json file of the pending ownership transfers, shared by all replicas (e.g. on a shared volume); empty disables transfers
+	TransferTtl  string `json:"transfer_ttl"`  //time until pending ownership transfers expire
+
+	ShareFile         string `json:"share_file"`          //json file of the time-limited shares, shared by all replicas (e.g. on a shared volume); empty disables shares
+	ShareReapInterval string `json:"share_reap_interval"` //interval to revoke expired shares
+
+	PropagationFile string `json:"propagation_file"` //json file of the propagate-sharing flags of locations and device-groups and their grants, shared by all replicas (e.g. on a shared volume); empty disables propagation
+
+	AuditTopic string `json:"audit_topic"` //topic for audit records of all write operations; empty or "-" to disable
+	AuditFile  string `json:"audit_file"`  //append-only json lines file for audit records, queryable with GET /audit; empty or "-" to disable
+
+	SoftDelete     bool   `json:"soft_delete"`     //snapshots resources and their permissions before deletion, restorable with POST /trash/{id}/restore
+	TrashFile      string `json:"trash_file"`      //json file of the soft deleted resources, shared by all replicas (e.g. on a shared volume); required for soft_delete
+	TrashRetention string `json:"trash_retention"` //time until soft deleted resources are purged from the trash
+
+	DeviceTypeVersionFile  string `json:"device_type_version_file"`  //json file of the device-type versions, shared by all replicas (e.g. on a shared volume); empty disables versions
+	DeviceTypeVersionLimit int64  `json:"device_type_version_limit"` //max number of kept versions per device-type; 0 for no limit
+
+	MigrationJobFile string `json:"migration_job_file"` //json file of the device-type migration jobs, shared by all replicas (e.g. on a shared volume); empty disables migrations, except dry runs
+
+	PolicyFile           string `json:"policy_file"`            //json file mapping realm roles to allowed create, update and delete actions per resource kind (see lib/policy); empty or "-" allows all
+	PolicyReloadInterval string `json:"policy_reload_interval"` //interval to check the policy file for changes
+
+	JwksUrl             string   `json:"jwks_url"`              //verify token signatures with the keys of this JWKS url (e.g. keycloak .../protocol/openid-connect/certs); empty or "-" to rely on the api gateway
+	JwtPublicKeyFile    string   `json:"jwt_public_key_file"`   //verify token signatures with the keys of this pem file; may be combined with jwks_url
+	JwtIssuer           string   `json:"jwt_issuer"`            //expected iss claim of verified tokens; empty to skip the check
+	JwtAudience         []string `json:"jwt_audience"`          //one of these must be in the aud claim of verified tokens; empty to skip the check
+	JwtLeeway           string   `json:"jwt_leeway"`            //tolerated clock skew for exp, nbf and iat
+	JwksRefreshInterval string   `json:"jwks_refresh_interval"` //keys are also refreshed when a token references an unknown key id
+
+	BusBackend    string `json:"bus_backend"`    //kafka | nats | channel; empty defaults to kafka
+	NatsUrl       string `json:"nats_url"`       //used if bus_backend is nats
+	NatsJetStream bool   `json:"nats_jetstream"` //persist topics in JetStream streams and consume with durable consumers; false uses core nats, which loses messages published while a consumer is down
+
+	OtlpEndpoint     string  `json:"otlp_endpoint"`      //url of an OTLP/http trace collector (e.g. http://otel-collector:4318); empty or "-" disables tracing
+	TraceSampleRatio float64 `json:"trace_sample_ratio"` //ratio of sampled root spans; 0 samples all
 }
 
 // loads config from json in location and used environment variables (e.g KafkaUrl --> KAFKA_URL)
@@ -0,0 +1,149 @@
+/*
+ * Copyright 2024 InfAI (CC SES)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package controller
+
+import (
+	"errors"
+	"github.com/SENERGY-Platform/device-manager/lib/apikey"
+	"github.com/SENERGY-Platform/device-manager/lib/auth"
+	"github.com/SENERGY-Platform/device-manager/lib/model"
+	"log"
+	"net/http"
+	"slices"
+	"time"
+)
+
+// ApiKeyIssuer is the issuer of tokens created for api key requests
+const ApiKeyIssuer = "device-manager-api-key"
+
+const DefaultApiKeyMaxTtl = 365 * 24 * time.Hour
+
+var errApiKeysDisabled = errors.New("api keys are disabled")
+
+func (this *Controller) CreateApiKey(token auth.Token, request model.ApiKeyCreateRequest) (result model.ApiKeyCreated, err error, code int) {
+	if this.apiKeys == nil {
+		return result, errApiKeysDisabled, http.StatusNotFound
+	}
+	if request.Name == "" {
+		return result, errors.New("missing name"), http.StatusBadRequest
+	}
+	err = apikey.ValidateScopes(request.Scopes)
+	if err != nil {
+		return result, err, http.StatusBadRequest
+	}
+	key := model.ApiKey{
+		Name:      request.Name,
+		UserId:    request.UserId,
+		Roles:     request.Roles,
+		Scopes:    request.Scopes,
+		CreatedBy: token.GetUserId(),
+	}
+	if key.UserId == "" {
+		key.UserId = token.GetUserId()
+	}
+	if key.UserId != token.GetUserId() && !token.IsAdmin() {
+		return result, errors.New("only admins may create api keys for other users or service accounts"), http.StatusForbidden
+	}
+	if key.Roles == nil && key.UserId == token.GetUserId() {
+		key.Roles = slices.DeleteFunc(slices.Clone(token.GetRoles()), isAdminRole)
+	}
+	for _, role := range key.Roles {
+		if isAdminRole(role) {
+			return result, errors.New("api keys may not have the admin role"), http.StatusForbidden
+		}
+		if !token.HasRole(role) {
+			return result, errors.New("api keys may only have roles of the requesting user: " + role), http.StatusForbidden
+		}
+	}
+	maxTtl := DefaultApiKeyMaxTtl
+	if this.config.ApiKeyMaxTtl != "" {
+		maxTtl, err = time.ParseDuration(this.config.ApiKeyMaxTtl)
+		if err != nil {
+			log.Println("WARNING: invalid api_key_max_ttl --> use default", DefaultApiKeyMaxTtl, err)
+			maxTtl = DefaultApiKeyMaxTtl
+		}
+	}
+	key.ExpiresAt = time.Now().Add(maxTtl)
+	if request.ExpiresAt != nil {
+		if !request.ExpiresAt.After(time.Now()) {
+			return result, errors.New("expires_at must be in the future"), http.StatusBadRequest
+		}
+		if request.ExpiresAt.After(key.ExpiresAt) {
+			return result, errors.New("expires_at exceeds the max lifetime of api keys " + maxTtl.String()), http.StatusBadRequest
+		}
+		key.ExpiresAt = *request.ExpiresAt
+	}
+	result, err = this.apiKeys.Create(key)
+	if err != nil {
+		return result, err, http.StatusInternalServerError
+	}
+	return result, nil, http.StatusOK
+}
+
+// ListApiKeys returns the keys created by or for the requesting user; admins get all keys
+func (this *Controller) ListApiKeys(token auth.Token) (result []model.ApiKey, err error, code int) {
+	if this.apiKeys == nil {
+		return result, errApiKeysDisabled, http.StatusNotFound
+	}
+	if token.IsAdmin() {
+		return this.apiKeys.List(""), nil, http.StatusOK
+	}
+	return this.apiKeys.List(token.GetUserId()), nil, http.StatusOK
+}
+
+func (this *Controller) RevokeApiKey(token auth.Token, id string) (err error, code int) {
+	if this.apiKeys == nil {
+		return errApiKeysDisabled, http.StatusNotFound
+	}
+	key, err := this.apiKeys.Get(id)
+	if errors.Is(err, apikey.ErrNotFound) {
+		return err, http.StatusNotFound
+	}
+	if err != nil {
+		return err, http.StatusInternalServerError
+	}
+	if !token.IsAdmin() && !slices.Contains([]string{key.UserId, key.CreatedBy}, token.GetUserId()) {
+		return apikey.ErrNotFound, http.StatusNotFound
+	}
+	err = this.apiKeys.Revoke(id)
+	if err != nil {
+		return err, http.StatusInternalServerError
+	}
+	return nil, http.StatusOK
+}
+
+// AuthenticateApiKey exchanges a valid api key for a signed token with the user id and roles of the key, for the checks of this service and of the upstream services
+func (this *Controller) AuthenticateApiKey(secretKey string) (token auth.Token, scopes []model.ApiKeyScope, err error, code int) {
+	if this.apiKeys == nil || this.apiKeySigner == nil {
+		return token, nil, errApiKeysDisabled, http.StatusUnauthorized
+	}
+	key, err := this.apiKeys.Authenticate(secretKey)
+	if err != nil {
+		return token, nil, err, http.StatusUnauthorized
+	}
+	//api key tokens never have the admin role
+	roles := slices.DeleteFunc(slices.Clone(key.Roles), isAdminRole)
+	token, err = this.apiKeySigner.Sign(key.UserId, "api-key:"+key.Name, roles, key.ExpiresAt)
+	if err != nil {
+		return token, nil, err, http.StatusInternalServerError
+	}
+	return token, key.Scopes, nil, http.StatusOK
+}
+
+func isAdminRole(role string) bool {
+	return role == "admin"
+}
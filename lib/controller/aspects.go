@@ -20,6 +20,7 @@ import (
 	"errors"
 	"github.com/SENERGY-Platform/device-manager/lib/auth"
 	"github.com/SENERGY-Platform/device-manager/lib/model"
+	"github.com/SENERGY-Platform/device-manager/lib/policy"
 	"github.com/SENERGY-Platform/models/go/models"
 	"github.com/SENERGY-Platform/service-commons/pkg/donewait"
 	"net/http"
@@ -30,6 +31,9 @@ func (this *Controller) ReadAspect(token auth.Token, id string) (aspect models.A
 }
 
 func (this *Controller) PublishAspectCreate(token auth.Token, aspect models.Aspect, options model.AspectUpdateOptions) (models.Aspect, error, int) {
+	if err, code := this.checkPolicy(token, this.config.AspectTopic, policy.Create); err != nil {
+		return aspect, err, code
+	}
 	if !token.IsAdmin() {
 		return aspect, errors.New("access denied"), http.StatusForbidden
 	}
@@ -45,7 +49,7 @@ func (this *Controller) PublishAspectCreate(token auth.Token, aspect models.Aspe
 		Command:      "PUT",
 	})
 
-	err = this.publisher.PublishAspect(aspect, token.GetUserId())
+	err = this.publisherFor(token).PublishAspect(aspect, token.GetUserId())
 	if err != nil {
 		return aspect, err, http.StatusInternalServerError
 	}
@@ -59,6 +63,9 @@ func (this *Controller) PublishAspectCreate(token auth.Token, aspect models.Aspe
 }
 
 func (this *Controller) PublishAspectUpdate(token auth.Token, id string, aspect models.Aspect, options model.AspectUpdateOptions) (models.Aspect, error, int) {
+	if err, code := this.checkPolicy(token, this.config.AspectTopic, policy.Update); err != nil {
+		return aspect, err, code
+	}
 	if !token.IsAdmin() {
 		return aspect, errors.New("access denied"), http.StatusForbidden
 	}
@@ -81,7 +88,7 @@ func (this *Controller) PublishAspectUpdate(token auth.Token, id string, aspect
 		Command:      "PUT",
 	})
 
-	err = this.publisher.PublishAspect(aspect, token.GetUserId())
+	err = this.publisherFor(token).PublishAspect(aspect, token.GetUserId())
 	if err != nil {
 		return aspect, err, http.StatusInternalServerError
 	}
@@ -95,6 +102,9 @@ func (this *Controller) PublishAspectUpdate(token auth.Token, id string, aspect
 }
 
 func (this *Controller) PublishAspectDelete(token auth.Token, id string, options model.AspectDeleteOptions) (error, int) {
+	if err, code := this.checkPolicy(token, this.config.AspectTopic, policy.Delete); err != nil {
+		return err, code
+	}
 	if !token.IsAdmin() {
 		return errors.New("access denied"), http.StatusForbidden
 	}
@@ -103,14 +113,20 @@ func (this *Controller) PublishAspectDelete(token auth.Token, id string, options
 		return err, code
 	}
 
+	undoTrash, err, code := this.moveToTrash(token, this.config.AspectTopic, id)
+	if err != nil {
+		return err, code
+	}
+
 	wait := this.optionalWait(options.Wait, donewait.DoneMsg{
 		ResourceKind: this.config.AspectTopic,
 		ResourceId:   id,
 		Command:      "DELETE",
 	})
 
-	err = this.publisher.PublishAspectDelete(id, token.GetUserId())
+	err = this.publisherFor(token).PublishAspectDelete(id, token.GetUserId())
 	if err != nil {
+		undoTrash()
 		return err, http.StatusInternalServerError
 	}
 
@@ -0,0 +1,54 @@
+/*
+ * Copyright 2024 InfAI (CC SES)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package controller
+
+import (
+	"context"
+	"errors"
+	"github.com/SENERGY-Platform/device-manager/lib/audit"
+	"github.com/SENERGY-Platform/device-manager/lib/auth"
+	"github.com/SENERGY-Platform/device-manager/lib/model"
+	"net/http"
+)
+
+var errAuditStoreDisabled = errors.New("audit store is disabled")
+
+// QueryAudit returns the records of the local audit file; admins only
+func (this *Controller) QueryAudit(token auth.Token, query model.AuditQuery) (result []model.AuditRecord, err error, code int) {
+	if !token.IsAdmin() {
+		return result, errors.New("only admins may read the audit log"), http.StatusForbidden
+	}
+	if this.auditor == nil {
+		return result, errAuditStoreDisabled, http.StatusNotFound
+	}
+	result, ok, err := this.auditor.Query(query)
+	if !ok {
+		return result, errAuditStoreDisabled, http.StatusNotFound
+	}
+	if err != nil {
+		return result, err, http.StatusInternalServerError
+	}
+	return result, nil, http.StatusOK
+}
+
+// auditSystem records changes made by the service itself, e.g. the revocation of expired shares
+func (this *Controller) auditSystem(kind string, id string, operation string, before interface{}, after interface{}, err error, code int) {
+	if this.auditor == nil {
+		return
+	}
+	this.auditor.Record(context.Background(), auditRecord(audit.SystemActor, nil, kind, id, operation, before, after, err, code))
+}
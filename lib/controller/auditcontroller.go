@@ -0,0 +1,524 @@
+/*
+ * Copyright 2024 InfAI (CC SES)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package controller
+
+import (
+	"context"
+	"github.com/SENERGY-Platform/device-manager/lib/api"
+	"github.com/SENERGY-Platform/device-manager/lib/audit"
+	"github.com/SENERGY-Platform/device-manager/lib/auth"
+	"github.com/SENERGY-Platform/device-manager/lib/config"
+	"github.com/SENERGY-Platform/device-manager/lib/model"
+	"github.com/SENERGY-Platform/models/go/models"
+)
+
+// auditController records all write operations of the wrapped controller;
+// the state before an update or delete is read with the token of the actor
+type auditController struct {
+	api.Controller
+	auditor *audit.Auditor
+	config  config.Config
+	ctx     context.Context
+}
+
+func newAuditController(controller api.Controller, auditor *audit.Auditor, conf config.Config, ctx context.Context) *auditController {
+	return &auditController{Controller: controller, auditor: auditor, config: conf, ctx: ctx}
+}
+
+func (this *auditController) record(token auth.Token, kind string, id string, operation string, before interface{}, after interface{}, err error, code int) {
+	this.auditor.Record(this.ctx, auditRecord(token.GetUserId(), token.GetRoles(), kind, id, operation, before, after, err, code))
+}
+
+func auditRecord(actor string, roles []string, kind string, id string, operation string, before interface{}, after interface{}, err error, code int) model.AuditRecord {
+	result := model.AuditRecord{
+		Actor:      actor,
+		Roles:      roles,
+		Kind:       kind,
+		ResourceId: id,
+		Operation:  operation,
+		Diff:       audit.Diff(before, after),
+		Outcome:    audit.OutcomeSuccess,
+		Code:       code,
+	}
+	if err != nil {
+		result.Outcome = audit.OutcomeError
+		result.Error = err.Error()
+	}
+	return result
+}
+
+// orNil returns nil for failed reads, so that the diff of a failed read is not reported as a change of all fields
+func orNil[T any](value T, err error, _ int) interface{} {
+	if err != nil {
+		return nil
+	}
+	return value
+}
+
+func (this *auditController) Replay(token auth.Token, kind string, options model.ReplayOptions, progress func(model.ReplayProgress)) (result model.ReplayProgress, err error, code int) {
+	result, err, code = this.Controller.Replay(token, kind, options, progress)
+	this.record(token, kind, "", audit.Replay, nil, options, err, code)
+	return result, err, code
+}
+
+func (this *auditController) PublishDeviceGroupCreate(token auth.Token, element models.DeviceGroup, options model.DeviceGroupUpdateOptions) (result models.DeviceGroup, err error, code int) {
+	result, err, code = this.Controller.PublishDeviceGroupCreate(token, element, options)
+	if err != nil {
+		this.record(token, this.config.DeviceGroupTopic, element.Id, audit.Create, nil, element, err, code)
+	} else {
+		this.record(token, this.config.DeviceGroupTopic, result.Id, audit.Create, nil, result, err, code)
+	}
+	return result, err, code
+}
+
+func (this *auditController) PublishDeviceGroupUpdate(token auth.Token, id string, element models.DeviceGroup, options model.DeviceGroupUpdateOptions) (result models.DeviceGroup, err error, code int) {
+	before := orNil(this.Controller.ReadDeviceGroup(token, id))
+	result, err, code = this.Controller.PublishDeviceGroupUpdate(token, id, element, options)
+	if err != nil {
+		this.record(token, this.config.DeviceGroupTopic, id, audit.Update, before, element, err, code)
+	} else {
+		this.record(token, this.config.DeviceGroupTopic, id, audit.Update, before, result, err, code)
+	}
+	return result, err, code
+}
+
+func (this *auditController) PublishDeviceGroupDelete(token auth.Token, id string, options model.DeviceGroupDeleteOptions) (err error, code int) {
+	before := orNil(this.Controller.ReadDeviceGroup(token, id))
+	err, code = this.Controller.PublishDeviceGroupDelete(token, id, options)
+	this.record(token, this.config.DeviceGroupTopic, id, audit.Delete, before, nil, err, code)
+	return err, code
+}
+
+func (this *auditController) PublishDeviceTypeCreate(token auth.Token, element models.DeviceType, options model.DeviceTypeUpdateOptions) (result models.DeviceType, err error, code int) {
+	result, err, code = this.Controller.PublishDeviceTypeCreate(token, element, options)
+	if err != nil {
+		this.record(token, this.config.DeviceTypeTopic, element.Id, audit.Create, nil, element, err, code)
+	} else {
+		this.record(token, this.config.DeviceTypeTopic, result.Id, audit.Create, nil, result, err, code)
+	}
+	return result, err, code
+}
+
+func (this *auditController) PublishDeviceTypeUpdate(token auth.Token, id string, element models.DeviceType, options model.DeviceTypeUpdateOptions) (result models.DeviceType, err error, code int) {
+	before := orNil(this.Controller.ReadDeviceType(token, id))
+	result, err, code = this.Controller.PublishDeviceTypeUpdate(token, id, element, options)
+	if err != nil {
+		this.record(token, this.config.DeviceTypeTopic, id, audit.Update, before, element, err, code)
+	} else {
+		this.record(token, this.config.DeviceTypeTopic, id, audit.Update, before, result, err, code)
+	}
+	return result, err, code
+}
+
+func (this *auditController) PublishDeviceTypeDelete(token auth.Token, id string, options model.DeviceTypeDeleteOptions) (err error, code int) {
+	before := orNil(this.Controller.ReadDeviceType(token, id))
+	err, code = this.Controller.PublishDeviceTypeDelete(token, id, options)
+	this.record(token, this.config.DeviceTypeTopic, id, audit.Delete, before, nil, err, code)
+	return err, code
+}
+
+func (this *auditController) PublishDeviceCreate(token auth.Token, element models.Device, options model.DeviceCreateOptions) (result models.Device, err error, code int) {
+	result, err, code = this.Controller.PublishDeviceCreate(token, element, options)
+	if err != nil {
+		this.record(token, this.config.DeviceTopic, element.Id, audit.Create, nil, element, err, code)
+	} else {
+		this.record(token, this.config.DeviceTopic, result.Id, audit.Create, nil, result, err, code)
+	}
+	return result, err, code
+}
+
+func (this *auditController) PublishDeviceUpdate(token auth.Token, id string, element models.Device, options model.DeviceUpdateOptions) (result models.Device, err error, code int) {
+	before := orNil(this.Controller.ReadDevice(token, id))
+	result, err, code = this.Controller.PublishDeviceUpdate(token, id, element, options)
+	if err != nil {
+		this.record(token, this.config.DeviceTopic, id, audit.Update, before, element, err, code)
+	} else {
+		this.record(token, this.config.DeviceTopic, id, audit.Update, before, result, err, code)
+	}
+	return result, err, code
+}
+
+func (this *auditController) PublishDeviceDelete(token auth.Token, id string, options model.DeviceDeleteOptions) (err error, code int) {
+	before := orNil(this.Controller.ReadDevice(token, id))
+	err, code = this.Controller.PublishDeviceDelete(token, id, options)
+	this.record(token, this.config.DeviceTopic, id, audit.Delete, before, nil, err, code)
+	return err, code
+}
+
+func (this *auditController) PublishHubCreate(token auth.Token, element models.Hub, options model.HubUpdateOptions) (result models.Hub, err error, code int) {
+	result, err, code = this.Controller.PublishHubCreate(token, element, options)
+	if err != nil {
+		this.record(token, this.config.HubTopic, element.Id, audit.Create, nil, element, err, code)
+	} else {
+		this.record(token, this.config.HubTopic, result.Id, audit.Create, nil, result, err, code)
+	}
+	return result, err, code
+}
+
+func (this *auditController) PublishHubUpdate(token auth.Token, id string, element models.Hub, options model.HubUpdateOptions) (result models.Hub, err error, code int) {
+	before := orNil(this.Controller.ReadHub(token, id))
+	result, err, code = this.Controller.PublishHubUpdate(token, id, element, options)
+	if err != nil {
+		this.record(token, this.config.HubTopic, id, audit.Update, before, element, err, code)
+	} else {
+		this.record(token, this.config.HubTopic, id, audit.Update, before, result, err, code)
+	}
+	return result, err, code
+}
+
+func (this *auditController) PublishHubDelete(token auth.Token, id string, options model.HubDeleteOptions) (err error, code int) {
+	before := orNil(this.Controller.ReadHub(token, id))
+	err, code = this.Controller.PublishHubDelete(token, id, options)
+	this.record(token, this.config.HubTopic, id, audit.Delete, before, nil, err, code)
+	return err, code
+}
+
+func (this *auditController) PublishProtocolCreate(token auth.Token, element models.Protocol, options model.ProtocolUpdateOptions) (result models.Protocol, err error, code int) {
+	result, err, code = this.Controller.PublishProtocolCreate(token, element, options)
+	if err != nil {
+		this.record(token, this.config.ProtocolTopic, element.Id, audit.Create, nil, element, err, code)
+	} else {
+		this.record(token, this.config.ProtocolTopic, result.Id, audit.Create, nil, result, err, code)
+	}
+	return result, err, code
+}
+
+func (this *auditController) PublishProtocolUpdate(token auth.Token, id string, element models.Protocol, options model.ProtocolUpdateOptions) (result models.Protocol, err error, code int) {
+	before := orNil(this.Controller.ReadProtocol(token, id))
+	result, err, code = this.Controller.PublishProtocolUpdate(token, id, element, options)
+	if err != nil {
+		this.record(token, this.config.ProtocolTopic, id, audit.Update, before, element, err, code)
+	} else {
+		this.record(token, this.config.ProtocolTopic, id, audit.Update, before, result, err, code)
+	}
+	return result, err, code
+}
+
+func (this *auditController) PublishProtocolDelete(token auth.Token, id string, options model.ProtocolDeleteOptions) (err error, code int) {
+	before := orNil(this.Controller.ReadProtocol(token, id))
+	err, code = this.Controller.PublishProtocolDelete(token, id, options)
+	this.record(token, this.config.ProtocolTopic, id, audit.Delete, before, nil, err, code)
+	return err, code
+}
+
+func (this *auditController) PublishConceptCreate(token auth.Token, element models.Concept, options model.ConceptUpdateOptions) (result models.Concept, err error, code int) {
+	result, err, code = this.Controller.PublishConceptCreate(token, element, options)
+	if err != nil {
+		this.record(token, this.config.ConceptTopic, element.Id, audit.Create, nil, element, err, code)
+	} else {
+		this.record(token, this.config.ConceptTopic, result.Id, audit.Create, nil, result, err, code)
+	}
+	return result, err, code
+}
+
+func (this *auditController) PublishConceptUpdate(token auth.Token, id string, element models.Concept, options model.ConceptUpdateOptions) (result models.Concept, err error, code int) {
+	before := orNil(this.Controller.ReadConcept(token, id))
+	result, err, code = this.Controller.PublishConceptUpdate(token, id, element, options)
+	if err != nil {
+		this.record(token, this.config.ConceptTopic, id, audit.Update, before, element, err, code)
+	} else {
+		this.record(token, this.config.ConceptTopic, id, audit.Update, before, result, err, code)
+	}
+	return result, err, code
+}
+
+func (this *auditController) PublishConceptDelete(token auth.Token, id string, options model.ConceptDeleteOptions) (err error, code int) {
+	before := orNil(this.Controller.ReadConcept(token, id))
+	err, code = this.Controller.PublishConceptDelete(token, id, options)
+	this.record(token, this.config.ConceptTopic, id, audit.Delete, before, nil, err, code)
+	return err, code
+}
+
+func (this *auditController) PublishCharacteristicCreate(token auth.Token, element models.Characteristic, options model.CharacteristicUpdateOptions) (result models.Characteristic, err error, code int) {
+	result, err, code = this.Controller.PublishCharacteristicCreate(token, element, options)
+	if err != nil {
+		this.record(token, this.config.CharacteristicTopic, element.Id, audit.Create, nil, element, err, code)
+	} else {
+		this.record(token, this.config.CharacteristicTopic, result.Id, audit.Create, nil, result, err, code)
+	}
+	return result, err, code
+}
+
+func (this *auditController) PublishCharacteristicUpdate(token auth.Token, id string, element models.Characteristic, options model.CharacteristicUpdateOptions) (result models.Characteristic, err error, code int) {
+	before := orNil(this.Controller.ReadCharacteristic(token, id))
+	result, err, code = this.Controller.PublishCharacteristicUpdate(token, id, element, options)
+	if err != nil {
+		this.record(token, this.config.CharacteristicTopic, id, audit.Update, before, element, err, code)
+	} else {
+		this.record(token, this.config.CharacteristicTopic, id, audit.Update, before, result, err, code)
+	}
+	return result, err, code
+}
+
+func (this *auditController) PublishCharacteristicDelete(token auth.Token, id string, options model.CharacteristicDeleteOptions) (err error, code int) {
+	before := orNil(this.Controller.ReadCharacteristic(token, id))
+	err, code = this.Controller.PublishCharacteristicDelete(token, id, options)
+	this.record(token, this.config.CharacteristicTopic, id, audit.Delete, before, nil, err, code)
+	return err, code
+}
+
+func (this *auditController) PublishAspectCreate(token auth.Token, element models.Aspect, options model.AspectUpdateOptions) (result models.Aspect, err error, code int) {
+	result, err, code = this.Controller.PublishAspectCreate(token, element, options)
+	if err != nil {
+		this.record(token, this.config.AspectTopic, element.Id, audit.Create, nil, element, err, code)
+	} else {
+		this.record(token, this.config.AspectTopic, result.Id, audit.Create, nil, result, err, code)
+	}
+	return result, err, code
+}
+
+func (this *auditController) PublishAspectUpdate(token auth.Token, id string, element models.Aspect, options model.AspectUpdateOptions) (result models.Aspect, err error, code int) {
+	before := orNil(this.Controller.ReadAspect(token, id))
+	result, err, code = this.Controller.PublishAspectUpdate(token, id, element, options)
+	if err != nil {
+		this.record(token, this.config.AspectTopic, id, audit.Update, before, element, err, code)
+	} else {
+		this.record(token, this.config.AspectTopic, id, audit.Update, before, result, err, code)
+	}
+	return result, err, code
+}
+
+func (this *auditController) PublishAspectDelete(token auth.Token, id string, options model.AspectDeleteOptions) (err error, code int) {
+	before := orNil(this.Controller.ReadAspect(token, id))
+	err, code = this.Controller.PublishAspectDelete(token, id, options)
+	this.record(token, this.config.AspectTopic, id, audit.Delete, before, nil, err, code)
+	return err, code
+}
+
+func (this *auditController) PublishFunctionCreate(token auth.Token, element models.Function, options model.FunctionUpdateOptions) (result models.Function, err error, code int) {
+	result, err, code = this.Controller.PublishFunctionCreate(token, element, options)
+	if err != nil {
+		this.record(token, this.config.FunctionTopic, element.Id, audit.Create, nil, element, err, code)
+	} else {
+		this.record(token, this.config.FunctionTopic, result.Id, audit.Create, nil, result, err, code)
+	}
+	return result, err, code
+}
+
+func (this *auditController) PublishFunctionUpdate(token auth.Token, id string, element models.Function, options model.FunctionUpdateOptions) (result models.Function, err error, code int) {
+	before := orNil(this.Controller.ReadFunction(token, id))
+	result, err, code = this.Controller.PublishFunctionUpdate(token, id, element, options)
+	if err != nil {
+		this.record(token, this.config.FunctionTopic, id, audit.Update, before, element, err, code)
+	} else {
+		this.record(token, this.config.FunctionTopic, id, audit.Update, before, result, err, code)
+	}
+	return result, err, code
+}
+
+func (this *auditController) PublishFunctionDelete(token auth.Token, id string, options model.FunctionDeleteOptions) (err error, code int) {
+	before := orNil(this.Controller.ReadFunction(token, id))
+	err, code = this.Controller.PublishFunctionDelete(token, id, options)
+	this.record(token, this.config.FunctionTopic, id, audit.Delete, before, nil, err, code)
+	return err, code
+}
+
+func (this *auditController) PublishDeviceClassCreate(token auth.Token, element models.DeviceClass, options model.DeviceClassUpdateOptions) (result models.DeviceClass, err error, code int) {
+	result, err, code = this.Controller.PublishDeviceClassCreate(token, element, options)
+	if err != nil {
+		this.record(token, this.config.DeviceClassTopic, element.Id, audit.Create, nil, element, err, code)
+	} else {
+		this.record(token, this.config.DeviceClassTopic, result.Id, audit.Create, nil, result, err, code)
+	}
+	return result, err, code
+}
+
+func (this *auditController) PublishDeviceClassUpdate(token auth.Token, id string, element models.DeviceClass, options model.DeviceClassUpdateOptions) (result models.DeviceClass, err error, code int) {
+	before := orNil(this.Controller.ReadDeviceClass(token, id))
+	result, err, code = this.Controller.PublishDeviceClassUpdate(token, id, element, options)
+	if err != nil {
+		this.record(token, this.config.DeviceClassTopic, id, audit.Update, before, element, err, code)
+	} else {
+		this.record(token, this.config.DeviceClassTopic, id, audit.Update, before, result, err, code)
+	}
+	return result, err, code
+}
+
+func (this *auditController) PublishDeviceClassDelete(token auth.Token, id string, options model.DeviceClassDeleteOptions) (err error, code int) {
+	before := orNil(this.Controller.ReadDeviceClass(token, id))
+	err, code = this.Controller.PublishDeviceClassDelete(token, id, options)
+	this.record(token, this.config.DeviceClassTopic, id, audit.Delete, before, nil, err, code)
+	return err, code
+}
+
+func (this *auditController) PublishLocationCreate(token auth.Token, element models.Location, options model.LocationUpdateOptions) (result models.Location, err error, code int) {
+	result, err, code = this.Controller.PublishLocationCreate(token, element, options)
+	if err != nil {
+		this.record(token, this.config.LocationTopic, element.Id, audit.Create, nil, element, err, code)
+	} else {
+		this.record(token, this.config.LocationTopic, result.Id, audit.Create, nil, result, err, code)
+	}
+	return result, err, code
+}
+
+func (this *auditController) PublishLocationUpdate(token auth.Token, id string, element models.Location, options model.LocationUpdateOptions) (result models.Location, err error, code int) {
+	before := orNil(this.Controller.ReadLocation(token, id))
+	result, err, code = this.Controller.PublishLocationUpdate(token, id, element, options)
+	if err != nil {
+		this.record(token, this.config.LocationTopic, id, audit.Update, before, element, err, code)
+	} else {
+		this.record(token, this.config.LocationTopic, id, audit.Update, before, result, err, code)
+	}
+	return result, err, code
+}
+
+func (this *auditController) PublishLocationDelete(token auth.Token, id string, options model.LocationDeleteOptions) (err error, code int) {
+	before := orNil(this.Controller.ReadLocation(token, id))
+	err, code = this.Controller.PublishLocationDelete(token, id, options)
+	this.record(token, this.config.LocationTopic, id, audit.Delete, before, nil, err, code)
+	return err, code
+}
+
+func (this *auditController) SetPermissions(token auth.Token, topic string, id string, permissions model.ResourcePermissions) (result model.ResourcePermissions, err error, code int) {
+	before := orNil(this.Controller.ReadPermissions(token, topic, id))
+	result, err, code = this.Controller.SetPermissions(token, topic, id, permissions)
+	if err != nil {
+		this.record(token, topic, id, audit.Permissions, before, permissions, err, code)
+	} else {
+		this.record(token, topic, id, audit.Permissions, before, result, err, code)
+	}
+	return result, err, code
+}
+
+func (this *auditController) SetSubjectPermissions(token auth.Token, topic string, id string, subject string, subjectId string, permissions *model.PermissionsMap) (result model.ResourcePermissions, err error, code int) {
+	before := orNil(this.Controller.ReadPermissions(token, topic, id))
+	result, err, code = this.Controller.SetSubjectPermissions(token, topic, id, subject, subjectId, permissions)
+	if err != nil {
+		this.record(token, topic, id, audit.Permissions, before, nil, err, code)
+	} else {
+		this.record(token, topic, id, audit.Permissions, before, result, err, code)
+	}
+	return result, err, code
+}
+
+func (this *auditController) SetPropagation(token auth.Token, topic string, id string, request model.PropagationRequest) (result model.Propagation, err error, code int) {
+	before := orNil(this.Controller.ReadPropagation(token, topic, id))
+	result, err, code = this.Controller.SetPropagation(token, topic, id, request)
+	if err != nil {
+		this.record(token, topic, id, audit.Propagation, before, request, err, code)
+	} else {
+		this.record(token, topic, id, audit.Propagation, before, result, err, code)
+	}
+	return result, err, code
+}
+
+func (this *auditController) CreateTransfer(token auth.Token, topic string, id string, request model.TransferRequest) (result model.Transfer, err error, code int) {
+	result, err, code = this.Controller.CreateTransfer(token, topic, id, request)
+	if err != nil {
+		this.record(token, topic, id, audit.Transfer, nil, request, err, code)
+	} else {
+		this.record(token, topic, id, audit.Transfer, nil, result, err, code)
+	}
+	return result, err, code
+}
+
+func (this *auditController) AcceptTransfer(token auth.Token, id string) (result model.Transfer, err error, code int) {
+	result, err, code = this.Controller.AcceptTransfer(token, id)
+	if err != nil {
+		this.record(token, "transfers", id, audit.TransferAccept, nil, nil, err, code)
+	} else {
+		this.record(token, result.Kind, result.ResourceId, audit.TransferAccept, nil, result, err, code)
+	}
+	return result, err, code
+}
+
+func (this *auditController) DeleteTransfer(token auth.Token, id string) (err error, code int) {
+	err, code = this.Controller.DeleteTransfer(token, id)
+	this.record(token, "transfers", id, audit.TransferDelete, nil, nil, err, code)
+	return err, code
+}
+
+func (this *auditController) CreateShare(token auth.Token, topic string, id string, request model.ShareRequest) (result model.Share, err error, code int) {
+	result, err, code = this.Controller.CreateShare(token, topic, id, request)
+	if err != nil {
+		this.record(token, topic, id, audit.Share, nil, request, err, code)
+	} else {
+		this.record(token, topic, id, audit.Share, nil, result, err, code)
+	}
+	return result, err, code
+}
+
+func (this *auditController) RevokeShare(token auth.Token, topic string, id string, shareId string) (err error, code int) {
+	err, code = this.Controller.RevokeShare(token, topic, id, shareId)
+	this.record(token, topic, id, audit.ShareRevoke, nil, map[string]string{"share_id": shareId}, err, code)
+	return err, code
+}
+
+func (this *auditController) CreateApiKey(token auth.Token, request model.ApiKeyCreateRequest) (result model.ApiKeyCreated, err error, code int) {
+	result, err, code = this.Controller.CreateApiKey(token, request)
+	if err != nil {
+		this.record(token, "api-keys", "", audit.Create, nil, request, err, code)
+	} else {
+		this.record(token, "api-keys", result.Id, audit.Create, nil, result.ApiKey, err, code) //never record the secret
+	}
+	return result, err, code
+}
+
+func (this *auditController) RevokeApiKey(token auth.Token, id string) (err error, code int) {
+	err, code = this.Controller.RevokeApiKey(token, id)
+	this.record(token, "api-keys", id, audit.Delete, nil, nil, err, code)
+	return err, code
+}
+
+func (this *auditController) RestoreTrash(token auth.Token, id string) (result model.TrashEntry, err error, code int) {
+	result, err, code = this.Controller.RestoreTrash(token, id)
+	if err != nil {
+		this.record(token, "trash", id, audit.Restore, nil, nil, err, code)
+	} else {
+		this.record(token, result.Kind, result.ResourceId, audit.Restore, nil, result.Resource, err, code)
+	}
+	return result, err, code
+}
+
+func (this *auditController) RollbackDeviceType(token auth.Token, id string, version int, options model.DeviceTypeUpdateOptions) (result models.DeviceType, err error, code int) {
+	before := orNil(this.Controller.ReadDeviceType(token, id))
+	result, err, code = this.Controller.RollbackDeviceType(token, id, version, options)
+	if err != nil {
+		this.record(token, this.config.DeviceTypeTopic, id, audit.Rollback, before, nil, err, code)
+	} else {
+		this.record(token, this.config.DeviceTypeTopic, id, audit.Rollback, before, result, err, code)
+	}
+	return result, err, code
+}
+
+func (this *auditController) MigrateDeviceType(token auth.Token, id string, request model.DeviceTypeMigrationRequest, progress func(model.DeviceTypeMigrationProgress)) (result model.DeviceTypeMigrationProgress, err error, code int) {
+	result, err, code = this.Controller.MigrateDeviceType(token, id, request, progress)
+	if !request.DryRun {
+		this.record(token, this.config.DeviceTypeTopic, id, audit.Migrate, nil, result, err, code)
+	}
+	return result, err, code
+}
+
+func (this *auditController) CloneDeviceType(token auth.Token, id string, request model.CloneRequest, options model.DeviceTypeUpdateOptions) (result models.DeviceType, err error, code int) {
+	result, err, code = this.Controller.CloneDeviceType(token, id, request, options)
+	if err != nil {
+		this.record(token, this.config.DeviceTypeTopic, result.Id, audit.Create, nil, nil, err, code)
+	} else {
+		this.record(token, this.config.DeviceTypeTopic, result.Id, audit.Create, nil, result, err, code)
+	}
+	return result, err, code
+}
+
+func (this *auditController) CloneDevice(token auth.Token, id string, request model.CloneRequest, options model.DeviceCreateOptions) (result models.Device, err error, code int) {
+	result, err, code = this.Controller.CloneDevice(token, id, request, options)
+	if err != nil {
+		this.record(token, this.config.DeviceTopic, result.Id, audit.Create, nil, nil, err, code)
+	} else {
+		this.record(token, this.config.DeviceTopic, result.Id, audit.Create, nil, result, err, code)
+	}
+	return result, err, code
+}
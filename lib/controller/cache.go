@@ -0,0 +1,132 @@
+/*
+ * Copyright 2024 InfAI (CC SES)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package controller
+
+import (
+	"context"
+	"fmt"
+	"github.com/SENERGY-Platform/device-manager/lib/auth"
+	"github.com/SENERGY-Platform/device-manager/lib/bus"
+	"github.com/SENERGY-Platform/device-manager/lib/cache"
+	"github.com/SENERGY-Platform/device-manager/lib/config"
+	"github.com/SENERGY-Platform/device-manager/lib/controller/com"
+	"github.com/SENERGY-Platform/models/go/models"
+	"strings"
+	"time"
+)
+
+// newCache returns nil if config.ResourceCacheTtl is empty or "-".
+// without consumer (edit forward mode) entries are only removed by ttl.
+func newCache(ctx context.Context, conf config.Config, consumer bus.Consumer) (*cache.Cache, error) {
+	if conf.ResourceCacheTtl == "" || conf.ResourceCacheTtl == "-" {
+		return nil, nil
+	}
+	ttl, err := time.ParseDuration(conf.ResourceCacheTtl)
+	if err != nil {
+		return nil, fmt.Errorf("invalid resource_cache_ttl: %w", err)
+	}
+	result := cache.New(ttl, int(conf.ResourceCacheSize))
+	if consumer == nil {
+		return result, nil
+	}
+	err = result.Subscribe(ctx, consumer, []string{
+		conf.DeviceTypeTopic,
+		conf.ProtocolTopic,
+		conf.AspectTopic,
+		conf.FunctionTopic,
+		conf.ConceptTopic,
+		conf.CharacteristicTopic,
+		conf.DeviceClassTopic,
+	})
+	return result, err
+}
+
+// cacheCom answers Get* calls of rarely changing resources from the cache
+type cacheCom struct {
+	Com
+	cache  *cache.Cache
+	config config.Config
+}
+
+func newCacheCom(c Com, resourceCache *cache.Cache, conf config.Config) Com {
+	return &cacheCom{Com: c, cache: resourceCache, config: conf}
+}
+
+func (this *cacheCom) withContext(ctx context.Context) Com {
+	return newCacheCom(comWithContext(this.Com, ctx), this.cache, this.config)
+}
+
+func useCache[T any](resourceCache *cache.Cache, kind string, id string, get func() (T, error, int)) (T, error, int) {
+	if strings.Contains(id, com.Seperator) {
+		//id modifiers are only interpreted by the device-repository
+		return get()
+	}
+	return cache.Use(resourceCache, kind, id, get)
+}
+
+// GetDeviceType checks the read permission of the user on cache hits; on misses the device-repository checks it
+func (this *cacheCom) GetDeviceType(token auth.Token, id string) (models.DeviceType, error, int) {
+	loaded := false
+	dt, err, code := useCache(this.cache, this.config.DeviceTypeTopic, id, func() (models.DeviceType, error, int) {
+		loaded = true
+		return this.Com.GetDeviceType(token, id)
+	})
+	if err != nil || loaded {
+		return dt, err, code
+	}
+	err, code = this.Com.PermissionCheckForDeviceType(token, id, "r")
+	if err != nil {
+		return models.DeviceType{}, err, code
+	}
+	return dt, nil, code
+}
+
+func (this *cacheCom) GetProtocol(token auth.Token, id string) (models.Protocol, error, int) {
+	return useCache(this.cache, this.config.ProtocolTopic, id, func() (models.Protocol, error, int) {
+		return this.Com.GetProtocol(token, id)
+	})
+}
+
+func (this *cacheCom) GetAspect(token auth.Token, id string) (models.Aspect, error, int) {
+	return useCache(this.cache, this.config.AspectTopic, id, func() (models.Aspect, error, int) {
+		return this.Com.GetAspect(token, id)
+	})
+}
+
+func (this *cacheCom) GetFunction(token auth.Token, id string) (models.Function, error, int) {
+	return useCache(this.cache, this.config.FunctionTopic, id, func() (models.Function, error, int) {
+		return this.Com.GetFunction(token, id)
+	})
+}
+
+func (this *cacheCom) GetConcept(token auth.Token, id string) (models.Concept, error, int) {
+	return useCache(this.cache, this.config.ConceptTopic, id, func() (models.Concept, error, int) {
+		return this.Com.GetConcept(token, id)
+	})
+}
+
+func (this *cacheCom) GetCharacteristic(token auth.Token, id string) (models.Characteristic, error, int) {
+	return useCache(this.cache, this.config.CharacteristicTopic, id, func() (models.Characteristic, error, int) {
+		return this.Com.GetCharacteristic(token, id)
+	})
+}
+
+func (this *cacheCom) GetDeviceClass(token auth.Token, id string) (models.DeviceClass, error, int) {
+	return useCache(this.cache, this.config.DeviceClassTopic, id, func() (models.DeviceClass, error, int) {
+		return this.Com.GetDeviceClass(token, id)
+	})
+}
@@ -20,6 +20,7 @@ import (
 	"errors"
 	"github.com/SENERGY-Platform/device-manager/lib/auth"
 	"github.com/SENERGY-Platform/device-manager/lib/model"
+	"github.com/SENERGY-Platform/device-manager/lib/policy"
 	"github.com/SENERGY-Platform/models/go/models"
 	"github.com/SENERGY-Platform/service-commons/pkg/donewait"
 	"net/http"
@@ -27,6 +28,9 @@ import (
 )
 
 func (this *Controller) PublishCharacteristicCreate(token auth.Token, characteristic models.Characteristic, options model.CharacteristicUpdateOptions) (models.Characteristic, error, int) {
+	if err, code := this.checkPolicy(token, this.config.CharacteristicTopic, policy.Create); err != nil {
+		return characteristic, err, code
+	}
 	if characteristic.Id != "" {
 		return characteristic, errors.New("expect empty id"), http.StatusBadRequest
 	}
@@ -43,7 +47,7 @@ func (this *Controller) PublishCharacteristicCreate(token auth.Token, characteri
 		Command:      "PUT",
 	})
 
-	err = this.publisher.PublishCharacteristic(characteristic, token.GetUserId())
+	err = this.publisherFor(token).PublishCharacteristic(characteristic, token.GetUserId())
 	if err != nil {
 		return characteristic, err, http.StatusInternalServerError
 	}
@@ -57,6 +61,9 @@ func (this *Controller) PublishCharacteristicCreate(token auth.Token, characteri
 }
 
 func (this *Controller) PublishCharacteristicUpdate(token auth.Token, characteristicId string, characteristic models.Characteristic, options model.CharacteristicUpdateOptions) (models.Characteristic, error, int) {
+	if err, code := this.checkPolicy(token, this.config.CharacteristicTopic, policy.Update); err != nil {
+		return characteristic, err, code
+	}
 	if characteristic.Id != characteristicId {
 		return characteristic, errors.New("characteristic id in body unequal to characteristic id in request endpoint"), http.StatusBadRequest
 	}
@@ -82,7 +89,7 @@ func (this *Controller) PublishCharacteristicUpdate(token auth.Token, characteri
 		Command:      "PUT",
 	})
 
-	err = this.publisher.PublishCharacteristic(characteristic, token.GetUserId())
+	err = this.publisherFor(token).PublishCharacteristic(characteristic, token.GetUserId())
 	if err != nil {
 		debug.PrintStack()
 		return characteristic, err, http.StatusInternalServerError
@@ -97,6 +104,9 @@ func (this *Controller) PublishCharacteristicUpdate(token auth.Token, characteri
 }
 
 func (this *Controller) PublishCharacteristicDelete(token auth.Token, id string, options model.CharacteristicDeleteOptions) (error, int) {
+	if err, code := this.checkPolicy(token, this.config.CharacteristicTopic, policy.Delete); err != nil {
+		return err, code
+	}
 	err, code := this.com.PermissionCheckForCharacteristic(token, id, "a")
 	if err != nil {
 		return err, code
@@ -106,14 +116,20 @@ func (this *Controller) PublishCharacteristicDelete(token auth.Token, id string,
 		return err, code
 	}
 
+	undoTrash, err, code := this.moveToTrash(token, this.config.CharacteristicTopic, id)
+	if err != nil {
+		return err, code
+	}
+
 	wait := this.optionalWait(options.Wait, donewait.DoneMsg{
 		ResourceKind: this.config.CharacteristicTopic,
 		ResourceId:   id,
 		Command:      "DELETE",
 	})
 
-	err = this.publisher.PublishCharacteristicDelete(id, token.GetUserId())
+	err = this.publisherFor(token).PublishCharacteristicDelete(id, token.GetUserId())
 	if err != nil {
+		undoTrash()
 		return err, http.StatusInternalServerError
 	}
 
@@ -0,0 +1,121 @@
+/*
+ * Copyright 2024 InfAI (CC SES)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package controller
+
+import (
+	"encoding/json"
+	"github.com/SENERGY-Platform/device-manager/lib/auth"
+	"github.com/SENERGY-Platform/device-manager/lib/model"
+	"github.com/SENERGY-Platform/models/go/models"
+	"github.com/google/uuid"
+	"net/http"
+)
+
+// CloneSourceAttributeKey is the attribute, which references the source of a cloned device or device-type
+const CloneSourceAttributeKey = "device-manager/clone-source"
+const CloneSourceAttributeOrigin = "device-manager"
+
+// CloneDeviceType publishes a copy of the device-type id with new ids for the device-type, its services, contents and content variables
+func (this *Controller) CloneDeviceType(token auth.Token, id string, request model.CloneRequest, options model.DeviceTypeUpdateOptions) (result models.DeviceType, err error, code int) {
+	source, err, code := this.com.GetDeviceType(token, id)
+	if err != nil {
+		return result, err, code
+	}
+	result, err = deepCopy(source)
+	if err != nil {
+		return result, err, http.StatusInternalServerError
+	}
+	result.Id = ""
+	for i, service := range result.Services {
+		service.Id = ""
+		for j, content := range service.Inputs {
+			service.Inputs[j] = clearContentIds(content)
+		}
+		for j, content := range service.Outputs {
+			service.Outputs[j] = clearContentIds(content)
+		}
+		result.Services[i] = service
+	}
+	if request.Name != "" {
+		result.Name = request.Name
+	}
+	result.Attributes = cloneAttributes(result.Attributes, request.Attributes, id)
+	return this.PublishDeviceTypeCreate(token, result, options)
+}
+
+// CloneDevice publishes a copy of the device id, owned by the requesting user
+func (this *Controller) CloneDevice(token auth.Token, id string, request model.CloneRequest, options model.DeviceCreateOptions) (result models.Device, err error, code int) {
+	source, err, code := this.com.GetDevice(token, id)
+	if err != nil {
+		return result, err, code
+	}
+	result, err = deepCopy(source)
+	if err != nil {
+		return result, err, http.StatusInternalServerError
+	}
+	result.Id = ""
+	result.OwnerId = ""
+	result.LocalId = request.LocalId
+	if result.LocalId == "" {
+		result.LocalId = uuid.NewString()
+	}
+	if request.Name != "" {
+		result.Name = request.Name
+	}
+	result.Attributes = cloneAttributes(result.Attributes, request.Attributes, id)
+	return this.PublishDeviceCreate(token, result, options)
+}
+
+// clearContentIds removes the ids of the content and its variables, to be regenerated on create
+func clearContentIds(content models.Content) models.Content {
+	content.Id = ""
+	content.ContentVariable = clearContentVariableIds(content.ContentVariable)
+	return content
+}
+
+func clearContentVariableIds(variable models.ContentVariable) models.ContentVariable {
+	variable.Id = ""
+	for i, sub := range variable.SubContentVariables {
+		variable.SubContentVariables[i] = clearContentVariableIds(sub)
+	}
+	return variable
+}
+
+// cloneAttributes replaces attributes with the keys of overrides and sets the CloneSourceAttributeKey to sourceId
+func cloneAttributes(attributes []models.Attribute, overrides []models.Attribute, sourceId string) (result []models.Attribute) {
+	overrides = append(overrides, models.Attribute{Key: CloneSourceAttributeKey, Value: sourceId, Origin: CloneSourceAttributeOrigin})
+	replaced := map[string]bool{}
+	for _, attr := range overrides {
+		replaced[attr.Key] = true
+	}
+	for _, attr := range attributes {
+		if !replaced[attr.Key] {
+			result = append(result, attr)
+		}
+	}
+	return append(result, overrides...)
+}
+
+// deepCopy copies element with all its slices and maps
+func deepCopy[T any](element T) (result T, err error) {
+	temp, err := json.Marshal(element)
+	if err != nil {
+		return result, err
+	}
+	err = json.Unmarshal(temp, &result)
+	return result, err
+}
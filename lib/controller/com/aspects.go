@@ -23,7 +23,7 @@ import (
 )
 
 func (this *Com) GetAspect(token auth.Token, id string) (aspect models.Aspect, err error, code int) {
-	err, code = getResourceFromService(token, this.config.DeviceRepoUrl+"/aspects", id, &aspect)
+	err, code = getResourceFromService(this.context(), this.client, token, this.config.DeviceRepoUrl+"/aspects", id, &aspect)
 	return
 }
 
@@ -31,7 +31,7 @@ func (this *Com) ValidateAspect(token auth.Token, aspect models.Aspect) (err err
 	if err = PreventIdModifier(aspect.Id); err != nil {
 		return err, http.StatusBadRequest
 	}
-	return validateResources(token, this.config, []string{
+	return validateResources(this.context(), this.client, token, this.config, []string{
 		this.config.DeviceRepoUrl + "/aspects?dry-run=true",
 	}, aspect)
 }
@@ -40,7 +40,7 @@ func (this *Com) ValidateAspectDelete(token auth.Token, id string) (err error, c
 	if err = PreventIdModifier(id); err != nil {
 		return err, http.StatusBadRequest
 	}
-	return validateResourceDelete(token, this.config, []string{
+	return validateResourceDelete(this.context(), this.client, token, this.config, []string{
 		this.config.DeviceRepoUrl + "/aspects",
 	}, id)
 }
@@ -28,11 +28,11 @@ func (this *Com) ValidateCharacteristic(token auth.Token, characteristic models.
 	}
 	list := []string{}
 	list = append(list, this.config.DeviceRepoUrl+"/characteristics?dry-run=true")
-	return validateResources(token, this.config, list, characteristic)
+	return validateResources(this.context(), this.client, token, this.config, list, characteristic)
 }
 
 func (this *Com) GetCharacteristic(token auth.Token, id string) (concept models.Characteristic, err error, code int) {
-	err, code = getResourceFromService(token, this.config.DeviceRepoUrl+"/characteristics", id, &concept)
+	err, code = getResourceFromService(this.context(), this.client, token, this.config.DeviceRepoUrl+"/characteristics", id, &concept)
 	return
 }
 
@@ -40,7 +40,7 @@ func (this *Com) ValidateCharacteristicDelete(token auth.Token, id string) (err
 	if err = PreventIdModifier(id); err != nil {
 		return err, http.StatusBadRequest
 	}
-	return validateResourceDelete(token, this.config, []string{
+	return validateResourceDelete(this.context(), this.client, token, this.config, []string{
 		this.config.DeviceRepoUrl + "/characteristics",
 	}, id)
 }
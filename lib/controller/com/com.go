@@ -17,21 +17,44 @@
 package com
 
 import (
+	"context"
 	"github.com/SENERGY-Platform/device-manager/lib/config"
-	devicerepo "github.com/SENERGY-Platform/device-repository/lib/client"
-	"github.com/SENERGY-Platform/permissions-v2/pkg/client"
+	"github.com/SENERGY-Platform/device-manager/lib/tracing"
+	"github.com/SENERGY-Platform/device-manager/lib/upstream"
+	"net/http"
 )
 
 type Com struct {
 	config  config.Config
-	perm    client.Client
-	devices devicerepo.Interface
+	perm    *permissionsClient
+	devices *repositoryClient
+	client  *http.Client
+	ctx     context.Context //set by WithContext; nil for Com of New
 }
 
+// New creates a Com, whose calls to the device-repository, permissions-v2 and the converter all use one upstream client with retries and circuit breaker.
+// the requests carry the trace context of WithContext
 func New(config config.Config) *Com {
+	httpClient := &http.Client{Transport: tracing.NewHttpTransport(upstream.NewTransport(upstream.OptionsFromConfig(config)))}
 	return &Com{
 		config:  config,
-		perm:    client.New(config.PermissionsV2Url),
-		devices: devicerepo.NewClient(config.DeviceRepoUrl, nil),
+		perm:    &permissionsClient{baseUrl: config.PermissionsV2Url, client: httpClient},
+		devices: &repositoryClient{baseUrl: config.DeviceRepoUrl, client: httpClient},
+		client:  httpClient,
 	}
 }
+
+// WithContext returns a copy of the Com, whose requests to other services use ctx
+func (this *Com) WithContext(ctx context.Context) *Com {
+	result := *this
+	result.ctx = ctx
+	return &result
+}
+
+// context returns the context of requests to other services
+func (this *Com) context() context.Context {
+	if this.ctx == nil {
+		return context.Background()
+	}
+	return this.ctx
+}
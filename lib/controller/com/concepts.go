@@ -23,7 +23,7 @@ import (
 )
 
 func (this *Com) GetConcept(token auth.Token, id string) (concept models.Concept, err error, code int) {
-	err, code = getResourceFromService(token, this.config.DeviceRepoUrl+"/concepts", id, &concept)
+	err, code = getResourceFromService(this.context(), this.client, token, this.config.DeviceRepoUrl+"/concepts", id, &concept)
 	return
 }
 
@@ -31,12 +31,12 @@ func (this *Com) ValidateConcept(token auth.Token, concept models.Concept) (err
 	if err = PreventIdModifier(concept.Id); err != nil {
 		return err, http.StatusBadRequest
 	}
-	err, code = validateResources(token, this.config, []string{this.config.DeviceRepoUrl + "/concepts?dry-run=true"}, concept)
+	err, code = validateResources(this.context(), this.client, token, this.config, []string{this.config.DeviceRepoUrl + "/concepts?dry-run=true"}, concept)
 	if err != nil {
 		return err, code
 	}
 	if this.config.ConverterUrl != "" && this.config.ConverterUrl != "-" {
-		err, code = validateResource(token, this.config, "POST", this.config.ConverterUrl+"/validate/extended-conversions", map[string]interface{}{
+		err, code = validateResource(this.context(), this.client, token, this.config, "POST", this.config.ConverterUrl+"/validate/extended-conversions", map[string]interface{}{
 			"nodes":      concept.CharacteristicIds,
 			"extensions": concept.Conversions,
 		})
@@ -48,7 +48,7 @@ func (this *Com) ValidateConceptDelete(token auth.Token, id string) (err error,
 	if err = PreventIdModifier(id); err != nil {
 		return err, http.StatusBadRequest
 	}
-	return validateResourceDelete(token, this.config, []string{
+	return validateResourceDelete(this.context(), this.client, token, this.config, []string{
 		this.config.DeviceRepoUrl + "/concepts",
 	}, id)
 }
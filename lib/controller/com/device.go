@@ -28,13 +28,13 @@ import (
 )
 
 func (this *Com) ListDevicesByQuery(token auth.Token, query url.Values) (devices []models.Device, err error, code int) {
-	req, err := http.NewRequest("GET", this.config.DeviceRepoUrl+"/devices?"+query.Encode(), nil)
+	req, err := http.NewRequestWithContext(this.context(), "GET", this.config.DeviceRepoUrl+"/devices?"+query.Encode(), nil)
 	if err != nil {
 		debug.PrintStack()
 		return devices, err, http.StatusInternalServerError
 	}
 	req.Header.Set("Authorization", token.Token)
-	resp, err := http.DefaultClient.Do(req)
+	resp, err := this.client.Do(req)
 	if err != nil {
 		debug.PrintStack()
 		return devices, err, http.StatusInternalServerError
@@ -54,12 +54,12 @@ func (this *Com) ListDevicesByQuery(token auth.Token, query url.Values) (devices
 }
 
 func (this *Com) GetDevice(token auth.Token, id string) (device models.Device, err error, code int) {
-	err, code = getResourceFromService(token, this.config.DeviceRepoUrl+"/devices", id, &device)
+	err, code = getResourceFromService(this.context(), this.client, token, this.config.DeviceRepoUrl+"/devices", id, &device)
 	return
 }
 
 func (this *Com) GetDeviceByLocalId(token auth.Token, ownerId string, localid string) (device models.Device, err error, code int) {
-	err, code = getResourceFromServiceWithQueryParam(token, this.config.DeviceRepoUrl+"/devices", localid, url.Values{"as": {"local_id"}, "owner_id": {ownerId}}, &device)
+	err, code = getResourceFromServiceWithQueryParam(this.context(), this.client, token, this.config.DeviceRepoUrl+"/devices", localid, url.Values{"as": {"local_id"}, "owner_id": {ownerId}}, &device)
 	return
 }
 
@@ -67,7 +67,7 @@ func (this *Com) ValidateDevice(token auth.Token, device models.Device) (err err
 	if err = PreventIdModifier(device.Id); err != nil {
 		return err, http.StatusBadRequest
 	}
-	return validateResources(token, this.config, []string{
+	return validateResources(this.context(), this.client, token, this.config, []string{
 		this.config.DeviceRepoUrl + "/devices?dry-run=true",
 	}, device)
 }
@@ -23,7 +23,7 @@ import (
 )
 
 func (this *Com) GetDeviceClass(token auth.Token, id string) (deviceClass models.DeviceClass, err error, code int) {
-	err, code = getResourceFromService(token, this.config.DeviceRepoUrl+"/device-classes", id, &deviceClass)
+	err, code = getResourceFromService(this.context(), this.client, token, this.config.DeviceRepoUrl+"/device-classes", id, &deviceClass)
 	return
 }
 
@@ -31,7 +31,7 @@ func (this *Com) ValidateDeviceClass(token auth.Token, deviceClass models.Device
 	if err = PreventIdModifier(deviceClass.Id); err != nil {
 		return err, http.StatusBadRequest
 	}
-	return validateResources(token, this.config, []string{
+	return validateResources(this.context(), this.client, token, this.config, []string{
 		this.config.DeviceRepoUrl + "/device-classes?dry-run=true",
 	}, deviceClass)
 }
@@ -40,7 +40,7 @@ func (this *Com) ValidateDeviceClassDelete(token auth.Token, id string) (err err
 	if err = PreventIdModifier(id); err != nil {
 		return err, http.StatusBadRequest
 	}
-	return validateResourceDelete(token, this.config, []string{
+	return validateResourceDelete(this.context(), this.client, token, this.config, []string{
 		this.config.DeviceRepoUrl + "/device-classes",
 	}, id)
 }
@@ -26,7 +26,7 @@ func (this *Com) GetTechnicalDeviceGroup(token auth.Token, id string) (dt models
 	if this.config.DeviceRepoUrl == "" || this.config.DeviceRepoUrl == "-" {
 		return models.DeviceGroup{}, nil, http.StatusOK
 	}
-	err, code = getResourceFromService(token, this.config.DeviceRepoUrl+"/device-groups", id, &dt)
+	err, code = getResourceFromService(this.context(), this.client, token, this.config.DeviceRepoUrl+"/device-groups", id, &dt)
 	return
 }
 
@@ -38,14 +38,14 @@ func (this *Com) ValidateDeviceGroup(token auth.Token, dg models.DeviceGroup) (e
 	if this.config.DeviceRepoUrl != "" && this.config.DeviceRepoUrl != "-" {
 		list = append(list, this.config.DeviceRepoUrl+"/device-groups?dry-run=true")
 	}
-	return validateResources(token, this.config, list, dg)
+	return validateResources(this.context(), this.client, token, this.config, list, dg)
 }
 
 func (this *Com) ValidateDeviceGroupDelete(token auth.Token, id string) (err error, code int) {
 	if err = PreventIdModifier(id); err != nil {
 		return err, http.StatusBadRequest
 	}
-	return validateResourceDelete(token, this.config, []string{
+	return validateResourceDelete(this.context(), this.client, token, this.config, []string{
 		this.config.DeviceRepoUrl + "/device-groups",
 	}, id)
 }
@@ -23,7 +23,7 @@ import (
 )
 
 func (this *Com) GetDeviceType(token auth.Token, id string) (dt models.DeviceType, err error, code int) {
-	err, code = getResourceFromService(token, this.config.DeviceRepoUrl+"/device-types", id, &dt)
+	err, code = getResourceFromService(this.context(), this.client, token, this.config.DeviceRepoUrl+"/device-types", id, &dt)
 	return
 }
 
@@ -31,5 +31,5 @@ func (this *Com) ValidateDeviceType(token auth.Token, dt models.DeviceType) (err
 	if err = PreventIdModifier(dt.Id); err != nil {
 		return err, http.StatusBadRequest
 	}
-	return validateResources(token, this.config, []string{this.config.DeviceRepoUrl + "/device-types?dry-run=true"}, dt)
+	return validateResources(this.context(), this.client, token, this.config, []string{this.config.DeviceRepoUrl + "/device-types?dry-run=true"}, dt)
 }
@@ -23,7 +23,7 @@ import (
 )
 
 func (this *Com) GetFunction(token auth.Token, id string) (function models.Function, err error, code int) {
-	err, code = getResourceFromService(token, this.config.DeviceRepoUrl+"/functions", id, &function)
+	err, code = getResourceFromService(this.context(), this.client, token, this.config.DeviceRepoUrl+"/functions", id, &function)
 	return
 }
 
@@ -31,7 +31,7 @@ func (this *Com) ValidateFunction(token auth.Token, function models.Function) (e
 	if err = PreventIdModifier(function.Id); err != nil {
 		return err, http.StatusBadRequest
 	}
-	return validateResources(token, this.config, []string{
+	return validateResources(this.context(), this.client, token, this.config, []string{
 		this.config.DeviceRepoUrl + "/functions?dry-run=true",
 	}, function)
 }
@@ -40,7 +40,7 @@ func (this *Com) ValidateFunctionDelete(token auth.Token, id string) (err error,
 	if err = PreventIdModifier(id); err != nil {
 		return err, http.StatusBadRequest
 	}
-	return validateResourceDelete(token, this.config, []string{
+	return validateResourceDelete(this.context(), this.client, token, this.config, []string{
 		this.config.DeviceRepoUrl + "/functions",
 	}, id)
 }
@@ -25,7 +25,7 @@ import (
 
 // expects previous permission check and use own admin jwt to access hub
 func (this *Com) GetHub(token auth.Token, id string) (hub models.Hub, err error, code int) {
-	err, code = getResourceFromService(token, this.config.DeviceRepoUrl+"/hubs", id, &hub)
+	err, code = getResourceFromService(this.context(), this.client, token, this.config.DeviceRepoUrl+"/hubs", id, &hub)
 	if this.config.Debug {
 		log.Printf("DEBUG: read hub from device-repo: %v %v %#v", code, err, hub)
 	}
@@ -36,7 +36,7 @@ func (this *Com) ValidateHub(token auth.Token, hub models.Hub) (err error, code
 	if err = PreventIdModifier(hub.Id); err != nil {
 		return err, http.StatusBadRequest
 	}
-	return validateResources(token, this.config, []string{
+	return validateResources(this.context(), this.client, token, this.config, []string{
 		this.config.DeviceRepoUrl + "/hubs?dry-run=true",
 	}, hub)
 }
@@ -0,0 +1,58 @@
+/*
+ * Copyright 2024 InfAI (CC SES)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package com
+
+import (
+	devicerepo "github.com/SENERGY-Platform/device-repository/lib/client"
+	"github.com/SENERGY-Platform/models/go/models"
+)
+
+func (this *Com) ListHubs(token string, options devicerepo.HubListOptions) (result []models.Hub, err error, code int) {
+	return this.devices.ListHubs(this.context(), token, options)
+}
+
+func (this *Com) ListDeviceGroups(token string, options devicerepo.DeviceGroupListOptions) (result []models.DeviceGroup, err error, code int) {
+	return this.devices.ListDeviceGroups(this.context(), token, options)
+}
+
+func (this *Com) ListLocations(token string, options devicerepo.LocationListOptions) (result []models.Location, err error, code int) {
+	return this.devices.ListLocations(this.context(), token, options)
+}
+
+func (this *Com) ListProtocols(token string, limit int64, offset int64, sort string) (result []models.Protocol, err error, code int) {
+	return this.devices.ListProtocols(this.context(), token, limit, offset, sort)
+}
+
+func (this *Com) ListAspects(options devicerepo.AspectListOptions) (result []models.Aspect, err error, code int) {
+	return this.devices.ListAspects(this.context(), options)
+}
+
+func (this *Com) ListFunctions(options devicerepo.FunctionListOptions) (result []models.Function, err error, code int) {
+	return this.devices.ListFunctions(this.context(), options)
+}
+
+func (this *Com) ListConcepts(options devicerepo.ConceptListOptions) (result []models.Concept, err error, code int) {
+	return this.devices.ListConcepts(this.context(), options)
+}
+
+func (this *Com) ListCharacteristics(options devicerepo.CharacteristicListOptions) (result []models.Characteristic, err error, code int) {
+	return this.devices.ListCharacteristics(this.context(), options)
+}
+
+func (this *Com) ListDeviceClasses(options devicerepo.DeviceClassListOptions) (result []models.DeviceClass, err error, code int) {
+	return this.devices.ListDeviceClasses(this.context(), options)
+}
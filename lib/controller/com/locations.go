@@ -23,7 +23,7 @@ import (
 )
 
 func (this *Com) GetLocation(token auth.Token, id string) (Location models.Location, err error, code int) {
-	err, code = getResourceFromService(token, this.config.DeviceRepoUrl+"/locations", id, &Location)
+	err, code = getResourceFromService(this.context(), this.client, token, this.config.DeviceRepoUrl+"/locations", id, &Location)
 	return
 }
 
@@ -31,7 +31,7 @@ func (this *Com) ValidateLocation(token auth.Token, location models.Location) (e
 	if err = PreventIdModifier(location.Id); err != nil {
 		return err, http.StatusBadRequest
 	}
-	return validateResources(token, this.config, []string{
+	return validateResources(this.context(), this.client, token, this.config, []string{
 		this.config.DeviceRepoUrl + "/locations?dry-run=true",
 	}, location)
 }
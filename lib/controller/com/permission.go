@@ -25,7 +25,7 @@ import (
 )
 
 func (this *Com) GetResourceRights(token auth.Token, kind string, id string) (result model.Resource, err error, code int) {
-	return this.perm.GetResource(token.Jwt(), kind, id)
+	return this.perm.GetResource(this.context(), token.Jwt(), kind, id)
 }
 
 func (this *Com) PermissionCheckForDeviceList(token auth.Token, ids []string, rights string) (result map[string]bool, err error, code int) {
@@ -35,7 +35,7 @@ func (this *Com) PermissionCheckForDeviceList(token auth.Token, ids []string, ri
 	if err != nil {
 		return result, err, http.StatusInternalServerError
 	}
-	return this.perm.CheckMultiplePermissions(token.Jwt(), this.config.DeviceTopic, ids, permissions...)
+	return this.perm.CheckMultiplePermissions(this.context(), token.Jwt(), this.config.DeviceTopic, ids, permissions...)
 }
 
 func (this *Com) PermissionCheckForDevice(token auth.Token, id string, permission string) (err error, code int) {
@@ -92,7 +92,7 @@ func (this *Com) PermissionCheck(token auth.Token, id string, permission string,
 	if err != nil {
 		return err, http.StatusInternalServerError
 	}
-	accessMap, err, code := this.perm.CheckMultiplePermissions(token.Jwt(), resource, []string{id}, permissions...)
+	accessMap, err, code := this.perm.CheckMultiplePermissions(this.context(), token.Jwt(), resource, []string{id}, permissions...)
 	if err != nil {
 		return err, code
 	}
@@ -111,7 +111,7 @@ func (this *Com) DevicesOfTypeExist(token auth.Token, deviceTypeId string) (resu
 		return false, errors.New("only for admins allowed"), http.StatusForbidden
 	}
 	deviceTypeId = removeIdModifier(deviceTypeId)
-	devices, err, code := this.devices.ListDevices(token.Jwt(), devicerepo.DeviceListOptions{
+	devices, err, code := this.devices.ListDevices(this.context(), token.Jwt(), devicerepo.DeviceListOptions{
 		DeviceTypeIds: []string{deviceTypeId},
 		Limit:         1,
 		Offset:        0,
@@ -123,7 +123,7 @@ func (this *Com) DevicesOfTypeExist(token auth.Token, deviceTypeId string) (resu
 }
 
 func (this *Com) DeviceLocalIdToId(token auth.Token, localId string) (id string, err error, code int) {
-	device, err, code := this.devices.ReadDeviceByLocalId(token.GetUserId(), localId, token.Jwt(), devicerepo.READ)
+	device, err, code := this.devices.ReadDeviceByLocalId(this.context(), token.GetUserId(), localId, token.Jwt(), devicerepo.READ)
 	if err != nil {
 		return "", err, code
 	}
@@ -23,14 +23,13 @@ import (
 )
 
 func (this *Com) SetPermission(token string, topicId string, id string, permissions model.ResourcePermissions) (result model.ResourcePermissions, err error, code int) {
-	return this.perm.SetPermission(token, topicId, id, permissions)
+	return this.perm.SetPermission(this.context(), token, topicId, id, permissions)
 }
 
 func (this *Com) ListDeviceTypes(token string, options devicerepo.DeviceTypeListOptions) (result []models.DeviceType, err error, code int) {
-	result, _, err, code = this.devices.ListDeviceTypesV3(token, options)
-	return
+	return this.devices.ListDeviceTypes(this.context(), token, options)
 }
 
 func (this *Com) ListDevices(token string, options devicerepo.DeviceListOptions) (result []models.Device, err error, code int) {
-	return this.devices.ListDevices(token, options)
+	return this.devices.ListDevices(this.context(), token, options)
 }
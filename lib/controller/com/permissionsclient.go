@@ -0,0 +1,78 @@
+/*
+ * Copyright 2024 InfAI (CC SES)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package com
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"github.com/SENERGY-Platform/permissions-v2/pkg/client"
+	"github.com/SENERGY-Platform/permissions-v2/pkg/model"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// permissionsClient sends the requests of the permissions-v2 client library, which are used by Com, with its own http client,
+// because the library always uses http.DefaultClient and requests without context.
+type permissionsClient struct {
+	baseUrl string
+	client  *http.Client
+}
+
+func (this *permissionsClient) GetResource(ctx context.Context, token string, topicId string, id string) (result model.Resource, err error, code int) {
+	return permissionsRequest[model.Resource](ctx, this, token, http.MethodGet, "/manage/"+url.PathEscape(topicId)+"/"+url.PathEscape(id), url.Values{}, nil)
+}
+
+func (this *permissionsClient) SetPermission(ctx context.Context, token string, topicId string, id string, permissions model.ResourcePermissions) (result model.ResourcePermissions, err error, code int) {
+	body, err := json.Marshal(permissions)
+	if err != nil {
+		return result, err, http.StatusInternalServerError
+	}
+	return permissionsRequest[model.ResourcePermissions](ctx, this, token, http.MethodPut, "/manage/"+url.PathEscape(topicId)+"/"+url.PathEscape(id), url.Values{}, body)
+}
+
+func (this *permissionsClient) CheckMultiplePermissions(ctx context.Context, token string, topicId string, ids []string, permissions ...model.Permission) (result map[string]bool, err error, code int) {
+	query := url.Values{}
+	query.Set("permissions", model.PermissionList(permissions).Encode())
+	query.Set("ids", strings.Join(ids, ","))
+	return permissionsRequest[map[string]bool](ctx, this, token, http.MethodGet, "/check/"+url.PathEscape(topicId), query, nil)
+}
+
+func (this *permissionsClient) ListAccessibleResourceIds(ctx context.Context, token string, topicId string, options model.ListOptions, permissions ...model.Permission) (result []string, err error, code int) {
+	query := url.Values{}
+	query.Set("permissions", model.PermissionList(permissions).Encode())
+	if options.Limit > 0 {
+		query.Set("limit", strconv.FormatInt(options.Limit, 10))
+	}
+	if options.Offset > 0 {
+		query.Set("offset", strconv.FormatInt(options.Offset, 10))
+	}
+	return permissionsRequest[[]string](ctx, this, token, http.MethodGet, "/accessible/"+url.PathEscape(topicId), query, nil)
+}
+
+// permissionsRequest sends a request with the client version, which permissions-v2 expects from its client library
+func permissionsRequest[T any](ctx context.Context, this *permissionsClient, token string, method string, path string, query url.Values, body []byte) (result T, err error, code int) {
+	query.Set("version", client.ClientVersion)
+	req, err := http.NewRequestWithContext(ctx, method, this.baseUrl+path+"?"+query.Encode(), bytes.NewReader(body))
+	if err != nil {
+		return result, err, http.StatusInternalServerError
+	}
+	req.Header.Set("Authorization", token)
+	return doJson[T](this.client, req)
+}
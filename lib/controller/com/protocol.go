@@ -23,7 +23,7 @@ import (
 )
 
 func (this *Com) GetProtocol(token auth.Token, id string) (protocol models.Protocol, err error, code int) {
-	err, code = getResourceFromService(token, this.config.DeviceRepoUrl+"/protocols", id, &protocol)
+	err, code = getResourceFromService(this.context(), this.client, token, this.config.DeviceRepoUrl+"/protocols", id, &protocol)
 	return
 }
 
@@ -31,7 +31,7 @@ func (this *Com) ValidateProtocol(token auth.Token, protocol models.Protocol) (e
 	if err = PreventIdModifier(protocol.Id); err != nil {
 		return err, http.StatusBadRequest
 	}
-	return validateResources(token, this.config, []string{
+	return validateResources(this.context(), this.client, token, this.config, []string{
 		this.config.DeviceRepoUrl + "/protocols?dry-run=true",
 	}, protocol)
 }
@@ -0,0 +1,220 @@
+/*
+ * Copyright 2024 InfAI (CC SES)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package com
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	devicerepo "github.com/SENERGY-Platform/device-repository/lib/client"
+	"github.com/SENERGY-Platform/models/go/models"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// repositoryClient sends the list and read requests of the device-repository client library with its own http client,
+// because the library always uses http.DefaultClient and requests without context.
+// the query parameters mirror github.com/SENERGY-Platform/device-repository/lib/client.
+type repositoryClient struct {
+	baseUrl string
+	client  *http.Client
+}
+
+func (this *repositoryClient) ListDevices(ctx context.Context, token string, options devicerepo.DeviceListOptions) (result []models.Device, err error, code int) {
+	query := listQuery(options.Ids, options.Search, options.SortBy, options.Limit, options.Offset)
+	setPermission(query, options.Permission)
+	setList(query, "local_ids", options.LocalIds)
+	setString(query, "owner", options.Owner)
+	setList(query, "device-type-ids", options.DeviceTypeIds)
+	if options.ConnectionState != nil {
+		query.Set("connection-state", *options.ConnectionState)
+	}
+	setList(query, "attr-keys", options.AttributeKeys)
+	setList(query, "attr-values", options.AttributeValues)
+	return get[[]models.Device](ctx, this.client, token, this.baseUrl+"/devices", query)
+}
+
+func (this *repositoryClient) ReadDeviceByLocalId(ctx context.Context, ownerId string, localId string, token string, permission models.PermissionFlag) (result models.Device, err error, code int) {
+	query := url.Values{}
+	setPermission(query, permission)
+	query.Set("as", "local_id")
+	query.Set("owner_id", ownerId)
+	return get[models.Device](ctx, this.client, token, this.baseUrl+"/devices/"+url.PathEscape(localId), query)
+}
+
+func (this *repositoryClient) ListHubs(ctx context.Context, token string, options devicerepo.HubListOptions) (result []models.Hub, err error, code int) {
+	query := listQuery(options.Ids, options.Search, options.SortBy, options.Limit, options.Offset)
+	setPermission(query, options.Permission)
+	if options.ConnectionState != nil {
+		query.Set("connection-state", *options.ConnectionState)
+	}
+	setString(query, "local-device-id", options.LocalDeviceId)
+	setString(query, "owner", options.OwnerId)
+	return get[[]models.Hub](ctx, this.client, token, this.baseUrl+"/hubs", query)
+}
+
+func (this *repositoryClient) ListDeviceTypes(ctx context.Context, token string, options devicerepo.DeviceTypeListOptions) (result []models.DeviceType, err error, code int) {
+	query := listQuery(options.Ids, options.Search, options.SortBy, options.Limit, options.Offset)
+	setList(query, "protocol-ids", options.ProtocolIds)
+	setList(query, "attr-keys", options.AttributeKeys)
+	setList(query, "attr-values", options.AttributeValues)
+	if options.IncludeModified {
+		query.Set("include-modified", "true")
+	}
+	if options.IgnoreUnmodified {
+		query.Set("ignore-unmodified", "true")
+	}
+	if len(options.Criteria) > 0 {
+		criteria, err := json.Marshal(options.Criteria)
+		if err != nil {
+			return result, err, http.StatusBadRequest
+		}
+		query.Set("criteria", string(criteria))
+	}
+	return get[[]models.DeviceType](ctx, this.client, token, this.baseUrl+"/v3/device-types", query)
+}
+
+func (this *repositoryClient) ListDeviceGroups(ctx context.Context, token string, options devicerepo.DeviceGroupListOptions) (result []models.DeviceGroup, err error, code int) {
+	query := listQuery(options.Ids, options.Search, options.SortBy, options.Limit, options.Offset)
+	setPermission(query, options.Permission)
+	if options.IgnoreGenerated {
+		query.Set("ignore_generated", "true")
+	}
+	if options.FilterGenericDuplicateCriteria {
+		query.Set("filter_generic_duplicate_criteria", "true")
+	}
+	if options.Criteria != nil {
+		criteria, err := json.Marshal(options.Criteria)
+		if err != nil {
+			return result, err, http.StatusInternalServerError
+		}
+		query.Set("criteria", string(criteria))
+	}
+	return get[[]models.DeviceGroup](ctx, this.client, token, this.baseUrl+"/device-groups", query)
+}
+
+func (this *repositoryClient) ListLocations(ctx context.Context, token string, options devicerepo.LocationListOptions) (result []models.Location, err error, code int) {
+	query := listQuery(options.Ids, options.Search, options.SortBy, options.Limit, options.Offset)
+	setPermission(query, options.Permission)
+	return get[[]models.Location](ctx, this.client, token, this.baseUrl+"/locations", query)
+}
+
+func (this *repositoryClient) ListProtocols(ctx context.Context, token string, limit int64, offset int64, sort string) (result []models.Protocol, err error, code int) {
+	query := url.Values{}
+	query.Set("limit", strconv.FormatInt(limit, 10))
+	query.Set("offset", strconv.FormatInt(offset, 10))
+	query.Set("sort", sort)
+	return get[[]models.Protocol](ctx, this.client, token, this.baseUrl+"/protocols", query)
+}
+
+func (this *repositoryClient) ListAspects(ctx context.Context, options devicerepo.AspectListOptions) (result []models.Aspect, err error, code int) {
+	query := listQuery(options.Ids, options.Search, options.SortBy, options.Limit, options.Offset)
+	return get[[]models.Aspect](ctx, this.client, "", this.baseUrl+"/v2/aspects", query)
+}
+
+func (this *repositoryClient) ListFunctions(ctx context.Context, options devicerepo.FunctionListOptions) (result []models.Function, err error, code int) {
+	query := listQuery(options.Ids, options.Search, options.SortBy, options.Limit, options.Offset)
+	setString(query, "rdf_type", options.RdfType)
+	return get[[]models.Function](ctx, this.client, "", this.baseUrl+"/functions", query)
+}
+
+func (this *repositoryClient) ListConcepts(ctx context.Context, options devicerepo.ConceptListOptions) (result []models.Concept, err error, code int) {
+	query := listQuery(options.Ids, options.Search, options.SortBy, options.Limit, options.Offset)
+	return get[[]models.Concept](ctx, this.client, "", this.baseUrl+"/v2/concepts", query)
+}
+
+func (this *repositoryClient) ListCharacteristics(ctx context.Context, options devicerepo.CharacteristicListOptions) (result []models.Characteristic, err error, code int) {
+	query := listQuery(options.Ids, options.Search, options.SortBy, options.Limit, options.Offset)
+	return get[[]models.Characteristic](ctx, this.client, "", this.baseUrl+"/v2/characteristics", query)
+}
+
+func (this *repositoryClient) ListDeviceClasses(ctx context.Context, options devicerepo.DeviceClassListOptions) (result []models.DeviceClass, err error, code int) {
+	query := listQuery(options.Ids, options.Search, options.SortBy, options.Limit, options.Offset)
+	return get[[]models.DeviceClass](ctx, this.client, "", this.baseUrl+"/v2/device-classes", query)
+}
+
+// listQuery sets the query parameters shared by all list endpoints; empty values are omitted
+func listQuery(ids []string, search string, sortBy string, limit int64, offset int64) url.Values {
+	query := url.Values{}
+	setString(query, "search", search)
+	if ids != nil {
+		query.Set("ids", strings.Join(ids, ","))
+	}
+	setString(query, "sort", sortBy)
+	if limit != 0 {
+		query.Set("limit", strconv.FormatInt(limit, 10))
+	}
+	if offset != 0 {
+		query.Set("offset", strconv.FormatInt(offset, 10))
+	}
+	return query
+}
+
+func setPermission(query url.Values, permission models.PermissionFlag) {
+	if permission != models.UnsetPermissionFlag {
+		query.Set("p", string(permission))
+	}
+}
+
+func setString(query url.Values, key string, value string) {
+	if value != "" {
+		query.Set(key, value)
+	}
+}
+
+func setList(query url.Values, key string, values []string) {
+	if values != nil {
+		query.Set(key, strings.Join(values, ","))
+	}
+}
+
+// get sends a GET request with the token (if not empty) and decodes the json response;
+// responses with a status code above 299 are returned as error with their status code
+func get[T any](ctx context.Context, client *http.Client, token string, endpoint string, query url.Values) (result T, err error, code int) {
+	if len(query) > 0 {
+		endpoint = endpoint + "?" + query.Encode()
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return result, err, http.StatusInternalServerError
+	}
+	if token != "" {
+		req.Header.Set("Authorization", token)
+	}
+	return doJson[T](client, req)
+}
+
+func doJson[T any](client *http.Client, req *http.Request) (result T, err error, code int) {
+	resp, err := client.Do(req)
+	if err != nil {
+		return result, err, http.StatusInternalServerError
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode > 299 {
+		temp, _ := io.ReadAll(resp.Body)
+		return result, fmt.Errorf("unexpected statuscode %v: %v", resp.StatusCode, string(temp)), resp.StatusCode
+	}
+	err = json.NewDecoder(resp.Body).Decode(&result)
+	if err != nil {
+		_, _ = io.ReadAll(resp.Body)
+		return result, err, http.StatusInternalServerError
+	}
+	return result, nil, http.StatusOK
+}
@@ -2,6 +2,7 @@ package com
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"errors"
 	"github.com/SENERGY-Platform/device-manager/lib/auth"
@@ -13,17 +14,16 @@ import (
 	"runtime/debug"
 	"slices"
 	"strings"
-	"time"
 )
 
-func getResourceFromService(token auth.Token, endpoint string, id string, result interface{}) (err error, code int) {
-	req, err := http.NewRequest("GET", endpoint+"/"+url.PathEscape(id), nil)
+func getResourceFromService(ctx context.Context, httpClient *http.Client, token auth.Token, endpoint string, id string, result interface{}) (err error, code int) {
+	req, err := http.NewRequestWithContext(ctx, "GET", endpoint+"/"+url.PathEscape(id), nil)
 	if err != nil {
 		debug.PrintStack()
 		return err, http.StatusInternalServerError
 	}
 	req.Header.Set("Authorization", token.Token)
-	resp, err := http.DefaultClient.Do(req)
+	resp, err := httpClient.Do(req)
 	if err != nil {
 		debug.PrintStack()
 		return err, http.StatusInternalServerError
@@ -45,14 +45,14 @@ func getResourceFromService(token auth.Token, endpoint string, id string, result
 	return nil, http.StatusOK
 }
 
-func getResourceFromServiceWithQueryParam(token auth.Token, endpoint string, id string, query url.Values, result interface{}) (err error, code int) {
-	req, err := http.NewRequest("GET", endpoint+"/"+url.PathEscape(id)+"?"+query.Encode(), nil)
+func getResourceFromServiceWithQueryParam(ctx context.Context, httpClient *http.Client, token auth.Token, endpoint string, id string, query url.Values, result interface{}) (err error, code int) {
+	req, err := http.NewRequestWithContext(ctx, "GET", endpoint+"/"+url.PathEscape(id)+"?"+query.Encode(), nil)
 	if err != nil {
 		debug.PrintStack()
 		return err, http.StatusInternalServerError
 	}
 	req.Header.Set("Authorization", token.Token)
-	resp, err := http.DefaultClient.Do(req)
+	resp, err := httpClient.Do(req)
 	if err != nil {
 		debug.PrintStack()
 		return err, http.StatusInternalServerError
@@ -74,12 +74,12 @@ func getResourceFromServiceWithQueryParam(token auth.Token, endpoint string, id
 	return nil, http.StatusOK
 }
 
-func validateResources(token auth.Token, config config.Config, endpoints []string, resource interface{}) (err error, code int) {
+func validateResources(ctx context.Context, httpClient *http.Client, token auth.Token, config config.Config, endpoints []string, resource interface{}) (err error, code int) {
 	if config.DisableValidation {
 		return nil, http.StatusOK
 	}
 	for _, endpoint := range endpoints {
-		err, code = validateResource(token, config, "PUT", endpoint, resource)
+		err, code = validateResource(ctx, httpClient, token, config, "PUT", endpoint, resource)
 		if err != nil {
 			return err, code
 		}
@@ -87,7 +87,7 @@ func validateResources(token auth.Token, config config.Config, endpoints []strin
 	return nil, http.StatusOK
 }
 
-func validateResource(token auth.Token, config config.Config, method string, endpoint string, resource interface{}) (err error, code int) {
+func validateResource(ctx context.Context, httpClient *http.Client, token auth.Token, config config.Config, method string, endpoint string, resource interface{}) (err error, code int) {
 	if config.DisableValidation {
 		return nil, http.StatusOK
 	}
@@ -97,16 +97,13 @@ func validateResource(token auth.Token, config config.Config, method string, end
 		debug.PrintStack()
 		return err, http.StatusInternalServerError
 	}
-	req, err := http.NewRequest(method, endpoint, b)
+	req, err := http.NewRequestWithContext(ctx, method, endpoint, b)
 	if err != nil {
 		debug.PrintStack()
 		return err, http.StatusInternalServerError
 	}
 	req.Header.Set("Authorization", token.Token)
-	client := http.Client{
-		Timeout: 10 * time.Second,
-	}
-	resp, err := client.Do(req)
+	resp, err := httpClient.Do(req)
 	if err != nil {
 		debug.PrintStack()
 		return err, http.StatusInternalServerError
@@ -123,18 +120,18 @@ func validateResource(token auth.Token, config config.Config, method string, end
 	return nil, http.StatusOK
 }
 
-func validateResourceDelete(token auth.Token, config config.Config, endpoints []string, id string) (err error, code int) {
+func validateResourceDelete(ctx context.Context, httpClient *http.Client, token auth.Token, config config.Config, endpoints []string, id string) (err error, code int) {
 	if config.DisableValidation {
 		return nil, http.StatusOK
 	}
 	for _, endpoint := range endpoints {
-		req, err := http.NewRequest("DELETE", endpoint+"/"+url.PathEscape(id)+"?dry-run=true", nil)
+		req, err := http.NewRequestWithContext(ctx, "DELETE", endpoint+"/"+url.PathEscape(id)+"?dry-run=true", nil)
 		if err != nil {
 			debug.PrintStack()
 			return err, http.StatusInternalServerError
 		}
 		req.Header.Set("Authorization", token.Token)
-		resp, err := http.DefaultClient.Do(req)
+		resp, err := httpClient.Do(req)
 		if err != nil {
 			debug.PrintStack()
 			return err, http.StatusInternalServerError
@@ -152,7 +149,8 @@ func validateResourceDelete(token auth.Token, config config.Config, endpoints []
 	return nil, http.StatusOK
 }
 
-func containsOtherAdmin(m map[string]client.PermissionsMap, notThisKey string) bool {
+// ContainsOtherAdmin reports if a user other than notThisKey has administrate rights; use "" for any user
+func ContainsOtherAdmin(m map[string]client.PermissionsMap, notThisKey string) bool {
 	for k, v := range m {
 		if k != notThisKey && v.Administrate {
 			return true
@@ -166,7 +164,7 @@ var ResourcesEffectedByUserDelete_BATCH_SIZE int64 = 1000
 func (this *Com) ResourcesEffectedByUserDelete(token auth.Token, resource string) (deleteResourceIds []string, deleteUserFromResource []client.Resource, err error) {
 	userid := token.GetUserId()
 	err = this.iterateResource(token, resource, ResourcesEffectedByUserDelete_BATCH_SIZE, client.Administrate, func(element client.Resource) {
-		if containsOtherAdmin(element.UserPermissions, userid) {
+		if ContainsOtherAdmin(element.UserPermissions, userid) {
 			deleteUserFromResource = append(deleteUserFromResource, element)
 		} else {
 			deleteResourceIds = append(deleteResourceIds, element.Id)
@@ -218,13 +216,13 @@ func (this *Com) iterateResource(token auth.Token, resource string, batchsize in
 			Offset: offset,
 		}
 		offset += batchsize
-		ids, err, _ := this.perm.ListAccessibleResourceIds(token.Jwt(), resource, options, rights)
+		ids, err, _ := this.perm.ListAccessibleResourceIds(this.context(), token.Jwt(), resource, options, rights)
 		if err != nil {
 			return err
 		}
 		lastCount = int64(len(ids))
 		for _, id := range ids {
-			element, err, _ := this.perm.GetResource(client.InternalAdminToken, resource, id)
+			element, err, _ := this.perm.GetResource(this.context(), client.InternalAdminToken, resource, id)
 			if err != nil {
 				return err
 			}
@@ -20,6 +20,7 @@ import (
 	"errors"
 	"github.com/SENERGY-Platform/device-manager/lib/auth"
 	"github.com/SENERGY-Platform/device-manager/lib/model"
+	"github.com/SENERGY-Platform/device-manager/lib/policy"
 	"github.com/SENERGY-Platform/models/go/models"
 	"github.com/SENERGY-Platform/service-commons/pkg/donewait"
 	"net/http"
@@ -31,6 +32,9 @@ func (this *Controller) ReadConcept(token auth.Token, id string) (concept models
 }
 
 func (this *Controller) PublishConceptCreate(token auth.Token, concept models.Concept, options model.ConceptUpdateOptions) (models.Concept, error, int) {
+	if err, code := this.checkPolicy(token, this.config.ConceptTopic, policy.Create); err != nil {
+		return concept, err, code
+	}
 	if concept.Id != "" {
 		return concept, errors.New("expect empty id"), http.StatusBadRequest
 	}
@@ -46,7 +50,7 @@ func (this *Controller) PublishConceptCreate(token auth.Token, concept models.Co
 		Command:      "PUT",
 	})
 
-	err = this.publisher.PublishConcept(concept, token.GetUserId())
+	err = this.publisherFor(token).PublishConcept(concept, token.GetUserId())
 	if err != nil {
 		return concept, err, http.StatusInternalServerError
 	}
@@ -60,6 +64,9 @@ func (this *Controller) PublishConceptCreate(token auth.Token, concept models.Co
 }
 
 func (this *Controller) PublishConceptUpdate(token auth.Token, id string, concept models.Concept, options model.ConceptUpdateOptions) (models.Concept, error, int) {
+	if err, code := this.checkPolicy(token, this.config.ConceptTopic, policy.Update); err != nil {
+		return concept, err, code
+	}
 	if concept.Id != id {
 		return concept, errors.New("concept id in body unequal to concept id in request endpoint"), http.StatusBadRequest
 	}
@@ -85,7 +92,7 @@ func (this *Controller) PublishConceptUpdate(token auth.Token, id string, concep
 		Command:      "PUT",
 	})
 
-	err = this.publisher.PublishConcept(concept, token.GetUserId())
+	err = this.publisherFor(token).PublishConcept(concept, token.GetUserId())
 	if err != nil {
 		debug.PrintStack()
 		return concept, err, http.StatusInternalServerError
@@ -100,6 +107,9 @@ func (this *Controller) PublishConceptUpdate(token auth.Token, id string, concep
 }
 
 func (this *Controller) PublishConceptDelete(token auth.Token, id string, options model.ConceptDeleteOptions) (error, int) {
+	if err, code := this.checkPolicy(token, this.config.ConceptTopic, policy.Delete); err != nil {
+		return err, code
+	}
 	err, code := this.com.PermissionCheckForConcept(token, id, "a")
 	if err != nil {
 		return err, code
@@ -109,14 +119,20 @@ func (this *Controller) PublishConceptDelete(token auth.Token, id string, option
 		return err, code
 	}
 
+	undoTrash, err, code := this.moveToTrash(token, this.config.ConceptTopic, id)
+	if err != nil {
+		return err, code
+	}
+
 	wait := this.optionalWait(options.Wait, donewait.DoneMsg{
 		ResourceKind: this.config.ConceptTopic,
 		ResourceId:   id,
 		Command:      "DELETE",
 	})
 
-	err = this.publisher.PublishConceptDelete(id, token.GetUserId())
+	err = this.publisherFor(token).PublishConceptDelete(id, token.GetUserId())
 	if err != nil {
+		undoTrash()
 		return err, http.StatusInternalServerError
 	}
 
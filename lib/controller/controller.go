@@ -18,25 +18,57 @@ package controller
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"github.com/SENERGY-Platform/device-manager/lib/api"
+	"github.com/SENERGY-Platform/device-manager/lib/apikey"
+	"github.com/SENERGY-Platform/device-manager/lib/audit"
 	"github.com/SENERGY-Platform/device-manager/lib/auth"
+	"github.com/SENERGY-Platform/device-manager/lib/bus"
 	"github.com/SENERGY-Platform/device-manager/lib/config"
 	"github.com/SENERGY-Platform/device-manager/lib/controller/com"
 	"github.com/SENERGY-Platform/device-manager/lib/kafka/listener"
 	"github.com/SENERGY-Platform/device-manager/lib/kafka/publisher"
+	"github.com/SENERGY-Platform/device-manager/lib/metrics"
+	"github.com/SENERGY-Platform/device-manager/lib/migration"
+	"github.com/SENERGY-Platform/device-manager/lib/overlay"
+	"github.com/SENERGY-Platform/device-manager/lib/policy"
+	"github.com/SENERGY-Platform/device-manager/lib/propagation"
+	"github.com/SENERGY-Platform/device-manager/lib/readmodel"
+	"github.com/SENERGY-Platform/device-manager/lib/share"
+	"github.com/SENERGY-Platform/device-manager/lib/transfer"
+	"github.com/SENERGY-Platform/device-manager/lib/trash"
+	"github.com/SENERGY-Platform/device-manager/lib/versions"
 	"github.com/SENERGY-Platform/device-repository/lib/client"
 	"github.com/SENERGY-Platform/models/go/models"
 	permv2 "github.com/SENERGY-Platform/permissions-v2/pkg/client"
 	"github.com/SENERGY-Platform/permissions-v2/pkg/model"
 	"github.com/SENERGY-Platform/service-commons/pkg/donewait"
-	"github.com/SENERGY-Platform/service-commons/pkg/kafka"
 	"net/url"
 	"time"
 )
 
 type Controller struct {
-	publisher Publisher
-	com       Com
-	config    config.Config
+	publisher    Publisher
+	com          Com
+	config       config.Config
+	readmodel    *readmodel.ReadModel
+	overlay      *overlay.Overlay
+	policy       *policy.Store
+	apiKeys      *apikey.Store
+	apiKeySigner *auth.Signer
+	transfers    *transfer.Store
+	shares       *share.Store
+	propagation  *propagation.Store
+	auditor      *audit.Auditor
+	trash        *trash.Store
+	versions     *versions.Store
+	migrations   *migration.Store
+
+	ctx              context.Context //set by WithContext; nil for the base controller
+	lifetime         context.Context //cancelled when the controller of New stops; nil for controllers of NewWithPublisher and NewWithDependencies
+	healthChecks     []healthCheck
+	doneWaitListener *listenerState
 }
 
 func New(basectx context.Context, conf config.Config) (ctrl *Controller, err error) {
@@ -52,39 +84,127 @@ func New(basectx context.Context, conf config.Config) (ctrl *Controller, err err
 	}
 
 	var publ Publisher
+	var b bus.Bus
 	if conf.EditForward == "" || conf.EditForward == "-" {
-		publ, err = publisher.New(conf, ctx)
+		topics := bus.CommandTopics(conf)
+		if conf.AuditTopic != "" && conf.AuditTopic != "-" {
+			topics = append(topics, conf.AuditTopic)
+		}
+		b, err = bus.New(ctx, conf, topics...)
 		if err != nil {
 			return &Controller{}, err
 		}
+		publ = publisher.NewWithBus(conf, b)
 	} else {
 		publ = publisher.Void{}
 	}
 
-	ctrl = &Controller{com: com.New(conf), publisher: publ, config: conf}
+	ctrl = &Controller{com: newObservedCom(com.New(conf)), publisher: publ, config: conf, lifetime: ctx, doneWaitListener: &listenerState{}}
+	if conf.ApiKeyFile != "" && conf.ApiKeyFile != "-" {
+		ctrl.apiKeys, err = apikey.New(conf.ApiKeyFile)
+		if err != nil {
+			return ctrl, err
+		}
+		issuer := conf.JwtIssuer
+		if issuer == "" {
+			issuer = ApiKeyIssuer
+		}
+		ctrl.apiKeySigner, err = auth.NewSigner(conf.ApiKeySigningKeyFile, conf.ApiKeySigningKeyId, issuer, conf.JwtAudience)
+		if err != nil {
+			return ctrl, fmt.Errorf("api keys need api_key_signing_key_file: %w", err)
+		}
+	}
+	ctrl.transfers, err = newTransfers(conf)
+	if err != nil {
+		return ctrl, err
+	}
+	ctrl.shares, err = newShares(conf)
+	if err != nil {
+		return ctrl, err
+	}
+	ctrl.propagation, err = newPropagation(conf)
+	if err != nil {
+		return ctrl, err
+	}
+	ctrl.auditor, err = audit.New(conf, b)
+	if err != nil {
+		return ctrl, err
+	}
+	ctrl.trash, err = newTrash(conf)
+	if err != nil {
+		return ctrl, err
+	}
+	ctrl.startTrashPurge(ctx)
+	ctrl.versions, err = newVersions(conf)
+	if err != nil {
+		return ctrl, err
+	}
+	ctrl.migrations, err = newMigrations(conf)
+	if err != nil {
+		return ctrl, err
+	}
+	if b != nil {
+		err = ctrl.SubscribeDeviceTypeVersions(ctx, b)
+		if err != nil {
+			return ctrl, err
+		}
+	}
+	if ctrl.shares != nil {
+		err = ctrl.startShareReaper(ctx)
+		if err != nil {
+			return ctrl, err
+		}
+	}
+	ctrl.policy, err = newPolicy(ctx, conf)
+	if err != nil {
+		return ctrl, err
+	}
+	resourceCache, err := newCache(ctx, conf, b)
+	if err != nil {
+		return ctrl, err
+	}
+	if resourceCache != nil {
+		ctrl.com = newCacheCom(ctrl.com, resourceCache, conf)
+	}
+	if conf.ReadModel {
+		if conf.BusBackend != "" && conf.BusBackend != bus.BackendKafka {
+			return ctrl, errors.New("read_model needs the kafka bus_backend to replay the command topics")
+		}
+		ctrl.readmodel = readmodel.New(conf)
+		err = ctrl.readmodel.Start(ctx)
+		if err != nil {
+			return ctrl, err
+		}
+		ctrl.com = newReadModelCom(ctrl.com, ctrl.readmodel, conf)
+	}
 	if conf.EditForward == "" || conf.EditForward == "-" {
-		err = listener.Start(ctx, conf, ctrl)
+		ctrl.overlay, err = newOverlay(ctx, conf)
+		if err != nil {
+			return ctrl, err
+		}
+		if ctrl.overlay != nil {
+			ctrl.com = newOverlayCom(ctrl.com, ctrl.overlay, conf)
+		}
+	}
+	if conf.EditForward == "" || conf.EditForward == "-" {
+		err = listener.Start(ctx, conf, b, ctrl)
 		if err != nil {
 			return ctrl, err
 		}
 	}
 
 	if conf.HandleDoneWait {
-		err = donewait.StartDoneWaitListener(ctx, kafka.Config{
-			KafkaUrl:    conf.KafkaUrl,
-			StartOffset: kafka.LastOffset,
-			Debug:       conf.Debug,
-		}, conf.DoneTopics, nil)
+		err = bus.StartDoneWaitListener(ctx, b, conf.DoneTopics, nil, ctrl.doneWaitListener.setError)
 		if err != nil {
 			return ctrl, err
 		}
 	}
+	ctrl.addDefaultHealthChecks(conf, b)
 	return ctrl, err
 }
 
-func getWaitContext() (ctx context.Context) {
-	ctx, _ = context.WithTimeout(context.Background(), time.Minute)
-	return
+func getWaitContext() (ctx context.Context, cancel context.CancelFunc) {
+	return context.WithTimeout(context.Background(), time.Minute)
 }
 
 func (this *Controller) optionalWait(wait bool, msg donewait.DoneMsg) func() error {
@@ -99,13 +219,129 @@ func (this *Controller) optionalWait(wait bool, msg donewait.DoneMsg) func() err
 				Handler:      handler,
 			})
 		}
-		f = donewait.AsyncWaitMultiple(getWaitContext(), list, nil)
+		ctx, cancel := getWaitContext()
+		waits := []func() error{}
+		for _, element := range list {
+			waits = append(waits, donewait.AsyncWait(ctx, element, nil))
+		}
+		f = func() error {
+			defer cancel()
+			errList := []error{}
+			for i, wait := range waits {
+				err := wait()
+				observeDoneWait(list[i].Handler, err)
+				if err != nil {
+					errList = append(errList, err)
+				}
+			}
+			return errors.Join(errList...)
+		}
 	}
 	return f
 }
 
+func observeDoneWait(handler string, err error) {
+	outcome := "success"
+	if errors.Is(err, context.DeadlineExceeded) {
+		outcome = "timeout"
+	} else if err != nil {
+		outcome = "error"
+	}
+	metrics.DoneWait.WithLabelValues(handler, outcome).Inc()
+}
+
+// WithContext returns a copy of the controller which traces Com calls and publishes as children of the span in ctx.
+// if auditing is enabled, the write operations of the copy are recorded with the request id of ctx
+func (this *Controller) WithContext(ctx context.Context) api.Controller {
+	result := this.withContext(ctx)
+	if this.auditor != nil {
+		return newAuditController(result, this.auditor, this.config, ctx)
+	}
+	return result
+}
+
+// withContext is WithContext without auditing, for operations not triggered by api requests
+func (this *Controller) withContext(ctx context.Context) *Controller {
+	result := *this
+	result.ctx = ctx
+	result.com = comWithContext(this.com, ctx)
+	if publ, ok := this.publisher.(*publisher.Publisher); ok {
+		result.publisher = publ.WithContext(ctx)
+	}
+	return &result
+}
+
+// context returns the context of WithContext or context.Background()
+func (this *Controller) context() context.Context {
+	if this.ctx == nil {
+		return context.Background()
+	}
+	return this.ctx
+}
+
 func NewWithPublisher(conf config.Config, publisher Publisher) (*Controller, error) {
-	return &Controller{com: com.New(conf), publisher: publisher}, nil
+	transfers, err := newTransfers(conf)
+	if err != nil {
+		return nil, err
+	}
+	shares, err := newShares(conf)
+	if err != nil {
+		return nil, err
+	}
+	propagations, err := newPropagation(conf)
+	if err != nil {
+		return nil, err
+	}
+	auditor, err := audit.New(conf, nil)
+	if err != nil {
+		return nil, err
+	}
+	trashStore, err := newTrash(conf)
+	if err != nil {
+		return nil, err
+	}
+	versionStore, err := newVersions(conf)
+	if err != nil {
+		return nil, err
+	}
+	migrations, err := newMigrations(conf)
+	if err != nil {
+		return nil, err
+	}
+	return &Controller{com: newObservedCom(com.New(conf)), publisher: publisher, config: conf, transfers: transfers, shares: shares, propagation: propagations, auditor: auditor, trash: trashStore, versions: versionStore, migrations: migrations}, nil
+}
+
+// NewWithDependencies creates a controller without kafka listeners, done-wait or read model
+func NewWithDependencies(conf config.Config, publisher Publisher, com Com) (*Controller, error) {
+	transfers, err := newTransfers(conf)
+	if err != nil {
+		return nil, err
+	}
+	shares, err := newShares(conf)
+	if err != nil {
+		return nil, err
+	}
+	propagations, err := newPropagation(conf)
+	if err != nil {
+		return nil, err
+	}
+	auditor, err := audit.New(conf, nil)
+	if err != nil {
+		return nil, err
+	}
+	trashStore, err := newTrash(conf)
+	if err != nil {
+		return nil, err
+	}
+	versionStore, err := newVersions(conf)
+	if err != nil {
+		return nil, err
+	}
+	migrations, err := newMigrations(conf)
+	if err != nil {
+		return nil, err
+	}
+	return &Controller{com: newObservedCom(com), publisher: publisher, config: conf, transfers: transfers, shares: shares, propagation: propagations, auditor: auditor, trash: trashStore, versions: versionStore, migrations: migrations}, nil
 }
 
 type Publisher interface {
@@ -204,6 +440,15 @@ type Com interface {
 
 	ListDeviceTypes(token string, options client.DeviceTypeListOptions) (result []models.DeviceType, err error, code int)
 	ListDevices(token string, options client.DeviceListOptions) (result []models.Device, err error, code int)
+	ListHubs(token string, options client.HubListOptions) (result []models.Hub, err error, code int)
+	ListDeviceGroups(token string, options client.DeviceGroupListOptions) (result []models.DeviceGroup, err error, code int)
+	ListLocations(token string, options client.LocationListOptions) (result []models.Location, err error, code int)
+	ListProtocols(token string, limit int64, offset int64, sort string) (result []models.Protocol, err error, code int)
+	ListAspects(options client.AspectListOptions) (result []models.Aspect, err error, code int)
+	ListFunctions(options client.FunctionListOptions) (result []models.Function, err error, code int)
+	ListConcepts(options client.ConceptListOptions) (result []models.Concept, err error, code int)
+	ListCharacteristics(options client.CharacteristicListOptions) (result []models.Characteristic, err error, code int)
+	ListDeviceClasses(options client.DeviceClassListOptions) (result []models.DeviceClass, err error, code int)
 }
 
 func (this *Controller) GetCom() Com {
@@ -22,6 +22,7 @@ import (
 	"github.com/SENERGY-Platform/device-manager/lib/auth"
 	"github.com/SENERGY-Platform/device-manager/lib/controller/com"
 	"github.com/SENERGY-Platform/device-manager/lib/model"
+	"github.com/SENERGY-Platform/device-manager/lib/policy"
 	"github.com/SENERGY-Platform/models/go/models"
 	"github.com/SENERGY-Platform/service-commons/pkg/donewait"
 	"log"
@@ -48,6 +49,9 @@ func (this *Controller) ReadDevice(token auth.Token, id string) (device models.D
 }
 
 func (this *Controller) PublishDeviceCreate(token auth.Token, device models.Device, options model.DeviceCreateOptions) (models.Device, error, int) {
+	if err, code := this.checkPolicy(token, this.config.DeviceTopic, policy.Create); err != nil {
+		return device, err, code
+	}
 	device.GenerateId()
 	if device.OwnerId != "" && device.OwnerId != token.GetUserId() {
 		return device, errors.New("new devices must be initialised with the requesting user as owner-id"), http.StatusBadRequest
@@ -65,7 +69,7 @@ func (this *Controller) PublishDeviceCreate(token auth.Token, device models.Devi
 		Command:      "PUT",
 	})
 
-	err = this.publisher.PublishDevice(device, token.GetUserId())
+	err = this.publisherFor(token).PublishDevice(device, token.GetUserId())
 	if err != nil {
 		return device, err, http.StatusInternalServerError
 	}
@@ -80,6 +84,9 @@ func (this *Controller) PublishDeviceCreate(token auth.Token, device models.Devi
 
 // admins may create new devices but only without setting options.UpdateOnlySameOriginAttributes
 func (this *Controller) PublishDeviceUpdate(token auth.Token, id string, device models.Device, options model.DeviceUpdateOptions) (_ models.Device, err error, code int) {
+	if err, code := this.checkPolicy(token, this.config.DeviceTopic, policy.Update); err != nil {
+		return device, err, code
+	}
 	if device.Id != id {
 		return device, errors.New("id in body unequal to id in request endpoint"), http.StatusBadRequest
 	}
@@ -151,7 +158,7 @@ func (this *Controller) PublishDeviceUpdate(token auth.Token, id string, device
 		Command:      "PUT",
 	})
 
-	err = this.publisher.PublishDevice(device, device.OwnerId)
+	err = this.publisherFor(token).PublishDevice(device, device.OwnerId)
 	if err != nil {
 		return device, err, http.StatusInternalServerError
 	}
@@ -165,6 +172,9 @@ func (this *Controller) PublishDeviceUpdate(token auth.Token, id string, device
 }
 
 func (this *Controller) PublishDeviceDelete(token auth.Token, id string, options model.DeviceDeleteOptions) (error, int) {
+	if err, code := this.checkPolicy(token, this.config.DeviceTopic, policy.Delete); err != nil {
+		return err, code
+	}
 	if err := com.PreventIdModifier(id); err != nil {
 		return err, http.StatusBadRequest
 	}
@@ -173,14 +183,20 @@ func (this *Controller) PublishDeviceDelete(token auth.Token, id string, options
 		return err, code
 	}
 
+	undoTrash, err, code := this.moveToTrash(token, this.config.DeviceTopic, id)
+	if err != nil {
+		return err, code
+	}
+
 	wait := this.optionalWait(options.Wait, donewait.DoneMsg{
 		ResourceKind: this.config.DeviceTopic,
 		ResourceId:   id,
 		Command:      "DELETE",
 	})
 
-	err = this.publisher.PublishDeviceDelete(id, token.GetUserId())
+	err = this.publisherFor(token).PublishDeviceDelete(id, token.GetUserId())
 	if err != nil {
+		undoTrash()
 		return err, http.StatusInternalServerError
 	}
 
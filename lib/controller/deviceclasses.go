@@ -20,6 +20,7 @@ import (
 	"errors"
 	"github.com/SENERGY-Platform/device-manager/lib/auth"
 	"github.com/SENERGY-Platform/device-manager/lib/model"
+	"github.com/SENERGY-Platform/device-manager/lib/policy"
 	"github.com/SENERGY-Platform/models/go/models"
 	"github.com/SENERGY-Platform/service-commons/pkg/donewait"
 	"net/http"
@@ -30,6 +31,9 @@ func (this *Controller) ReadDeviceClass(token auth.Token, id string) (deviceClas
 }
 
 func (this *Controller) PublishDeviceClassCreate(token auth.Token, deviceClass models.DeviceClass, options model.DeviceClassUpdateOptions) (models.DeviceClass, error, int) {
+	if err, code := this.checkPolicy(token, this.config.DeviceClassTopic, policy.Create); err != nil {
+		return deviceClass, err, code
+	}
 	if !token.IsAdmin() {
 		return deviceClass, errors.New("access denied"), http.StatusForbidden
 	}
@@ -45,7 +49,7 @@ func (this *Controller) PublishDeviceClassCreate(token auth.Token, deviceClass m
 		Command:      "PUT",
 	})
 
-	err = this.publisher.PublishDeviceClass(deviceClass, token.GetUserId())
+	err = this.publisherFor(token).PublishDeviceClass(deviceClass, token.GetUserId())
 	if err != nil {
 		return deviceClass, err, http.StatusInternalServerError
 	}
@@ -59,6 +63,9 @@ func (this *Controller) PublishDeviceClassCreate(token auth.Token, deviceClass m
 }
 
 func (this *Controller) PublishDeviceClassUpdate(token auth.Token, id string, deviceClass models.DeviceClass, options model.DeviceClassUpdateOptions) (models.DeviceClass, error, int) {
+	if err, code := this.checkPolicy(token, this.config.DeviceClassTopic, policy.Update); err != nil {
+		return deviceClass, err, code
+	}
 	if !token.IsAdmin() {
 		return deviceClass, errors.New("access denied"), http.StatusForbidden
 	}
@@ -81,7 +88,7 @@ func (this *Controller) PublishDeviceClassUpdate(token auth.Token, id string, de
 		Command:      "PUT",
 	})
 
-	err = this.publisher.PublishDeviceClass(deviceClass, token.GetUserId())
+	err = this.publisherFor(token).PublishDeviceClass(deviceClass, token.GetUserId())
 	if err != nil {
 		return deviceClass, err, http.StatusInternalServerError
 	}
@@ -95,6 +102,9 @@ func (this *Controller) PublishDeviceClassUpdate(token auth.Token, id string, de
 }
 
 func (this *Controller) PublishDeviceClassDelete(token auth.Token, id string, options model.DeviceClassDeleteOptions) (error, int) {
+	if err, code := this.checkPolicy(token, this.config.DeviceClassTopic, policy.Delete); err != nil {
+		return err, code
+	}
 	if !token.IsAdmin() {
 		return errors.New("access denied"), http.StatusForbidden
 	}
@@ -103,14 +113,20 @@ func (this *Controller) PublishDeviceClassDelete(token auth.Token, id string, op
 		return err, code
 	}
 
+	undoTrash, err, code := this.moveToTrash(token, this.config.DeviceClassTopic, id)
+	if err != nil {
+		return err, code
+	}
+
 	wait := this.optionalWait(options.Wait, donewait.DoneMsg{
 		ResourceKind: this.config.DeviceClassTopic,
 		ResourceId:   id,
 		Command:      "DELETE",
 	})
 
-	err = this.publisher.PublishDeviceClassDelete(id, token.GetUserId())
+	err = this.publisherFor(token).PublishDeviceClassDelete(id, token.GetUserId())
 	if err != nil {
+		undoTrash()
 		return err, http.StatusInternalServerError
 	}
 
@@ -21,6 +21,7 @@ import (
 	"github.com/SENERGY-Platform/device-manager/lib/auth"
 	"github.com/SENERGY-Platform/device-manager/lib/controller/com"
 	"github.com/SENERGY-Platform/device-manager/lib/model"
+	"github.com/SENERGY-Platform/device-manager/lib/policy"
 	"github.com/SENERGY-Platform/models/go/models"
 	"github.com/SENERGY-Platform/service-commons/pkg/donewait"
 	"log"
@@ -33,6 +34,9 @@ func (this *Controller) ReadDeviceGroup(token auth.Token, id string) (dt models.
 }
 
 func (this *Controller) PublishDeviceGroupCreate(token auth.Token, dg models.DeviceGroup, options model.DeviceGroupUpdateOptions) (result models.DeviceGroup, err error, code int) {
+	if err, code := this.checkPolicy(token, this.config.DeviceGroupTopic, policy.Create); err != nil {
+		return dg, err, code
+	}
 	if dg.Id != "" {
 		return result, errors.New("expect empty device-group id"), http.StatusBadRequest
 	}
@@ -54,7 +58,7 @@ func (this *Controller) PublishDeviceGroupCreate(token auth.Token, dg models.Dev
 		Command:      "PUT",
 	})
 
-	err = this.publisher.PublishDeviceGroup(dg, token.GetUserId())
+	err = this.publisherFor(token).PublishDeviceGroup(dg, token.GetUserId())
 	if err != nil {
 		return dg, err, http.StatusInternalServerError
 	}
@@ -68,6 +72,9 @@ func (this *Controller) PublishDeviceGroupCreate(token auth.Token, dg models.Dev
 }
 
 func (this *Controller) PublishDeviceGroupUpdate(token auth.Token, id string, dg models.DeviceGroup, options model.DeviceGroupUpdateOptions) (result models.DeviceGroup, err error, code int) {
+	if err, code := this.checkPolicy(token, this.config.DeviceGroupTopic, policy.Update); err != nil {
+		return dg, err, code
+	}
 	if dg.Id != id {
 		return dg, errors.New("id in body unequal to id in request endpoint"), http.StatusBadRequest
 	}
@@ -110,11 +117,12 @@ func (this *Controller) PublishDeviceGroupUpdate(token auth.Token, id string, dg
 		Command:      "PUT",
 	})
 
-	err = this.publisher.PublishDeviceGroup(dg, token.GetUserId())
+	err = this.publisherFor(token).PublishDeviceGroup(dg, token.GetUserId())
 	if err != nil {
 		debug.PrintStack()
 		return dg, err, http.StatusInternalServerError
 	}
+	this.propagateSharing(&token, this.config.DeviceGroupTopic, id, append([]string{}, dg.DeviceIds...))
 
 	err = wait()
 	if err != nil {
@@ -125,6 +133,9 @@ func (this *Controller) PublishDeviceGroupUpdate(token auth.Token, id string, dg
 }
 
 func (this *Controller) PublishDeviceGroupDelete(token auth.Token, id string, options model.DeviceGroupDeleteOptions) (error, int) {
+	if err, code := this.checkPolicy(token, this.config.DeviceGroupTopic, policy.Delete); err != nil {
+		return err, code
+	}
 	if err := com.PreventIdModifier(id); err != nil {
 		return err, http.StatusBadRequest
 	}
@@ -138,16 +149,23 @@ func (this *Controller) PublishDeviceGroupDelete(token auth.Token, id string, op
 		return err, code
 	}
 
+	undoTrash, err, code := this.moveToTrash(token, this.config.DeviceGroupTopic, id)
+	if err != nil {
+		return err, code
+	}
+
 	wait := this.optionalWait(options.Wait, donewait.DoneMsg{
 		ResourceKind: this.config.DeviceGroupTopic,
 		ResourceId:   id,
 		Command:      "DELETE",
 	})
 
-	err = this.publisher.PublishDeviceGroupDelete(id, token.GetUserId())
+	err = this.publisherFor(token).PublishDeviceGroupDelete(id, token.GetUserId())
 	if err != nil {
+		undoTrash()
 		return err, http.StatusInternalServerError
 	}
+	this.stopPropagation(this.config.DeviceGroupTopic, id)
 
 	err = wait()
 	if err != nil {
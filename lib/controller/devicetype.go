@@ -21,8 +21,10 @@ import (
 	"github.com/SENERGY-Platform/device-manager/lib/auth"
 	"github.com/SENERGY-Platform/device-manager/lib/controller/com"
 	"github.com/SENERGY-Platform/device-manager/lib/model"
+	"github.com/SENERGY-Platform/device-manager/lib/policy"
 	"github.com/SENERGY-Platform/device-repository/lib/client"
 	"github.com/SENERGY-Platform/models/go/models"
+	permv2 "github.com/SENERGY-Platform/permissions-v2/pkg/client"
 	"github.com/SENERGY-Platform/service-commons/pkg/donewait"
 	"net/http"
 	"runtime/debug"
@@ -39,6 +41,9 @@ func (this *Controller) ReadDeviceType(token auth.Token, id string) (dt models.D
 }
 
 func (this *Controller) PublishDeviceTypeCreate(token auth.Token, dt models.DeviceType, options model.DeviceTypeUpdateOptions) (models.DeviceType, error, int) {
+	if err, code := this.checkPolicy(token, this.config.DeviceTypeTopic, policy.Create); err != nil {
+		return dt, err, code
+	}
 	if dt.Id != "" {
 		return dt, errors.New("expect empty id"), http.StatusBadRequest
 	}
@@ -54,7 +59,7 @@ func (this *Controller) PublishDeviceTypeCreate(token auth.Token, dt models.Devi
 		Command:      "PUT",
 	})
 
-	err = this.publisher.PublishDeviceType(dt, token.GetUserId())
+	err = this.publisherFor(token).PublishDeviceType(dt, token.GetUserId())
 	if err != nil {
 		return dt, err, http.StatusInternalServerError
 	}
@@ -68,6 +73,9 @@ func (this *Controller) PublishDeviceTypeCreate(token auth.Token, dt models.Devi
 }
 
 func (this *Controller) PublishDeviceTypeUpdate(token auth.Token, id string, dt models.DeviceType, options model.DeviceTypeUpdateOptions) (models.DeviceType, error, int) {
+	if err, code := this.checkPolicy(token, this.config.DeviceTypeTopic, policy.Update); err != nil {
+		return dt, err, code
+	}
 	if dt.Id != id {
 		return dt, errors.New("id in body unequal to id in request endpoint"), http.StatusBadRequest
 	}
@@ -87,13 +95,21 @@ func (this *Controller) PublishDeviceTypeUpdate(token auth.Token, id string, dt
 		return dt, err, code
 	}
 
+	analysis, err, code := this.analyzeDeviceTypeUpdate(token, dt)
+	if err != nil {
+		return dt, err, code
+	}
+	if analysis.ForceRequired && !options.Force {
+		return dt, breakingChangesError(analysis), http.StatusConflict
+	}
+
 	wait := this.optionalWait(options.Wait, donewait.DoneMsg{
 		ResourceKind: this.config.DeviceTypeTopic,
 		ResourceId:   dt.Id,
 		Command:      "PUT",
 	})
 
-	err = this.publisher.PublishDeviceType(dt, token.GetUserId())
+	err = this.publisherFor(token).PublishDeviceType(dt, token.GetUserId())
 	if err != nil {
 		debug.PrintStack()
 		return dt, err, http.StatusInternalServerError
@@ -108,17 +124,30 @@ func (this *Controller) PublishDeviceTypeUpdate(token auth.Token, id string, dt
 }
 
 func (this *Controller) PublishDeviceTypeDelete(token auth.Token, id string, options model.DeviceTypeDeleteOptions) (error, int) {
+	if err, code := this.checkPolicy(token, this.config.DeviceTypeTopic, policy.Delete); err != nil {
+		return err, code
+	}
 	if err := com.PreventIdModifier(id); err != nil {
 		return err, http.StatusBadRequest
 	}
-	exists, err, code := this.com.DevicesOfTypeExist(token, id)
+	err, code := this.com.PermissionCheckForDeviceType(token, id, "a")
+	if err != nil {
+		return err, code
+	}
+	//DevicesOfTypeExist is admin only; the rights of token on the device-type are checked above
+	adminToken, err := auth.Parse(permv2.InternalAdminToken)
+	if err != nil {
+		return err, http.StatusInternalServerError
+	}
+	exists, err, code := this.com.DevicesOfTypeExist(adminToken, id)
 	if err != nil {
 		return err, code
 	}
 	if exists {
 		return errors.New("expect no dependent devices"), http.StatusBadRequest
 	}
-	err, code = this.com.PermissionCheckForDeviceType(token, id, "a")
+
+	undoTrash, err, code := this.moveToTrash(token, this.config.DeviceTypeTopic, id)
 	if err != nil {
 		return err, code
 	}
@@ -129,8 +158,9 @@ func (this *Controller) PublishDeviceTypeDelete(token auth.Token, id string, opt
 		Command:      "DELETE",
 	})
 
-	err = this.publisher.PublishDeviceTypeDelete(id, token.GetUserId())
+	err = this.publisherFor(token).PublishDeviceTypeDelete(id, token.GetUserId())
 	if err != nil {
+		undoTrash()
 		return err, http.StatusInternalServerError
 	}
 
@@ -0,0 +1,175 @@
+/*
+ * Copyright 2024 InfAI (CC SES)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package controller
+
+import (
+	"errors"
+	"github.com/SENERGY-Platform/device-manager/lib/auth"
+	"github.com/SENERGY-Platform/device-manager/lib/model"
+	"github.com/SENERGY-Platform/models/go/models"
+	"github.com/SENERGY-Platform/permissions-v2/pkg/client"
+	"net/http"
+	"strings"
+)
+
+// AnalyzeDeviceTypeUpdate lists the changes of dt compared with the stored device-type and whether the update needs force
+func (this *Controller) AnalyzeDeviceTypeUpdate(token auth.Token, id string, dt models.DeviceType) (result model.DeviceTypeUpdateAnalysis, err error, code int) {
+	if dt.Id != id {
+		return result, errors.New("id in body unequal to id in request endpoint"), http.StatusBadRequest
+	}
+	if !token.IsAdmin() {
+		err, code = this.com.PermissionCheckForDeviceType(token, id, "r")
+		if err != nil {
+			return result, err, code
+		}
+	}
+	dt.GenerateId()
+	return this.analyzeDeviceTypeUpdate(token, dt)
+}
+
+func (this *Controller) analyzeDeviceTypeUpdate(token auth.Token, dt models.DeviceType) (result model.DeviceTypeUpdateAnalysis, err error, code int) {
+	result.Changes = []model.DeviceTypeChange{}
+	stored, err, code := this.com.GetDeviceType(token, dt.Id)
+	if code == http.StatusNotFound {
+		return result, nil, http.StatusOK
+	}
+	if err != nil {
+		return result, err, code
+	}
+	this.recordDeviceTypeBaseline(stored)
+	result.Changes = deviceTypeChanges(stored, dt)
+	for _, change := range result.Changes {
+		result.Breaking = result.Breaking || change.Breaking
+	}
+	if result.Breaking {
+		//DevicesOfTypeExist is admin only; the rights of token on the device-type are checked by the callers
+		adminToken, err := auth.Parse(client.InternalAdminToken)
+		if err != nil {
+			return result, err, http.StatusInternalServerError
+		}
+		result.DevicesExist, err, code = this.com.DevicesOfTypeExist(adminToken, dt.Id)
+		if err != nil {
+			return result, err, code
+		}
+	}
+	result.ForceRequired = result.Breaking && result.DevicesExist
+	return result, nil, http.StatusOK
+}
+
+func breakingChangesError(analysis model.DeviceTypeUpdateAnalysis) error {
+	list := []string{}
+	for _, change := range analysis.Changes {
+		if change.Breaking {
+			list = append(list, change.Type+" "+change.Path)
+		}
+	}
+	return errors.New("breaking changes of a device-type with devices need force=true: " + strings.Join(list, ", "))
+}
+
+// deviceTypeChanges compares services, contents and content variables by id.
+// removed or changed elements break devices and processes using them; added elements and new bindings do not.
+func deviceTypeChanges(before models.DeviceType, after models.DeviceType) (result []model.DeviceTypeChange) {
+	afterServices := map[string]models.Service{}
+	for _, service := range after.Services {
+		afterServices[service.Id] = service
+	}
+	beforeServices := map[string]bool{}
+	for _, service := range before.Services {
+		beforeServices[service.Id] = true
+		path := "services[" + service.Id + "]"
+		updated, ok := afterServices[service.Id]
+		if !ok {
+			result = append(result, model.DeviceTypeChange{Type: model.DeviceTypeChangeServiceRemoved, Path: path, Before: service.LocalId, Breaking: true})
+			continue
+		}
+		result = appendChange(result, model.DeviceTypeChangeLocalId, path+".local_id", service.LocalId, updated.LocalId, true)
+		result = appendChange(result, model.DeviceTypeChangeInteraction, path+".interaction", string(service.Interaction), string(updated.Interaction), true)
+		result = appendChange(result, model.DeviceTypeChangeProtocol, path+".protocol_id", service.ProtocolId, updated.ProtocolId, true)
+		result = append(result, contentChanges(path+".inputs", service.Inputs, updated.Inputs)...)
+		result = append(result, contentChanges(path+".outputs", service.Outputs, updated.Outputs)...)
+	}
+	for _, service := range after.Services {
+		if !beforeServices[service.Id] {
+			result = append(result, model.DeviceTypeChange{Type: model.DeviceTypeChangeServiceAdded, Path: "services[" + service.Id + "]", After: service.LocalId})
+		}
+	}
+	return result
+}
+
+func contentChanges(path string, before []models.Content, after []models.Content) (result []model.DeviceTypeChange) {
+	afterContents := map[string]models.Content{}
+	for _, content := range after {
+		afterContents[content.Id] = content
+	}
+	beforeContents := map[string]bool{}
+	for _, content := range before {
+		beforeContents[content.Id] = true
+		contentPath := path + "[" + content.Id + "]"
+		updated, ok := afterContents[content.Id]
+		if !ok {
+			result = append(result, model.DeviceTypeChange{Type: model.DeviceTypeChangeContentRemoved, Path: contentPath, Before: content.ContentVariable.Name, Breaking: true})
+			continue
+		}
+		result = appendChange(result, model.DeviceTypeChangeSerialization, contentPath+".serialization", string(content.Serialization), string(updated.Serialization), true)
+		result = appendChange(result, model.DeviceTypeChangeProtocolSegment, contentPath+".protocol_segment_id", content.ProtocolSegmentId, updated.ProtocolSegmentId, true)
+		result = append(result, variableChanges(contentPath+".content_variable", content.ContentVariable, updated.ContentVariable)...)
+	}
+	for _, content := range after {
+		if !beforeContents[content.Id] {
+			result = append(result, model.DeviceTypeChange{Type: model.DeviceTypeChangeContentAdded, Path: path + "[" + content.Id + "]", After: content.ContentVariable.Name})
+		}
+	}
+	return result
+}
+
+func variableChanges(path string, before models.ContentVariable, after models.ContentVariable) (result []model.DeviceTypeChange) {
+	result = appendChange(result, model.DeviceTypeChangeVariableName, path+".name", before.Name, after.Name, true)
+	result = appendChange(result, model.DeviceTypeChangeVariableType, path+".type", string(before.Type), string(after.Type), true)
+	//new bindings add capabilities; changed or removed bindings break users of the old binding
+	result = appendChange(result, model.DeviceTypeChangeCharacteristicBinding, path+".characteristic_id", before.CharacteristicId, after.CharacteristicId, before.CharacteristicId != "")
+	result = appendChange(result, model.DeviceTypeChangeFunctionBinding, path+".function_id", before.FunctionId, after.FunctionId, before.FunctionId != "")
+	result = appendChange(result, model.DeviceTypeChangeAspectBinding, path+".aspect_id", before.AspectId, after.AspectId, before.AspectId != "")
+
+	afterVariables := map[string]models.ContentVariable{}
+	for _, variable := range after.SubContentVariables {
+		afterVariables[variable.Id] = variable
+	}
+	beforeVariables := map[string]bool{}
+	for _, variable := range before.SubContentVariables {
+		beforeVariables[variable.Id] = true
+		variablePath := path + ".sub_content_variables[" + variable.Id + "]"
+		updated, ok := afterVariables[variable.Id]
+		if !ok {
+			result = append(result, model.DeviceTypeChange{Type: model.DeviceTypeChangeVariableRemoved, Path: variablePath, Before: variable.Name, Breaking: true})
+			continue
+		}
+		result = append(result, variableChanges(variablePath, variable, updated)...)
+	}
+	for _, variable := range after.SubContentVariables {
+		if !beforeVariables[variable.Id] {
+			result = append(result, model.DeviceTypeChange{Type: model.DeviceTypeChangeVariableAdded, Path: path + ".sub_content_variables[" + variable.Id + "]", After: variable.Name})
+		}
+	}
+	return result
+}
+
+func appendChange(list []model.DeviceTypeChange, changeType string, path string, before string, after string, breaking bool) []model.DeviceTypeChange {
+	if before == after {
+		return list
+	}
+	return append(list, model.DeviceTypeChange{Type: changeType, Path: path, Before: before, After: after, Breaking: breaking})
+}
@@ -20,6 +20,7 @@ import (
 	"errors"
 	"github.com/SENERGY-Platform/device-manager/lib/auth"
 	"github.com/SENERGY-Platform/device-manager/lib/model"
+	"github.com/SENERGY-Platform/device-manager/lib/policy"
 	"github.com/SENERGY-Platform/models/go/models"
 	"github.com/SENERGY-Platform/service-commons/pkg/donewait"
 	"net/http"
@@ -30,6 +31,9 @@ func (this *Controller) ReadFunction(token auth.Token, id string) (function mode
 }
 
 func (this *Controller) PublishFunctionCreate(token auth.Token, function models.Function, options model.FunctionUpdateOptions) (models.Function, error, int) {
+	if err, code := this.checkPolicy(token, this.config.FunctionTopic, policy.Create); err != nil {
+		return function, err, code
+	}
 	if !token.IsAdmin() {
 		return function, errors.New("access denied"), http.StatusForbidden
 	}
@@ -45,7 +49,7 @@ func (this *Controller) PublishFunctionCreate(token auth.Token, function models.
 		Command:      "PUT",
 	})
 
-	err = this.publisher.PublishFunction(function, token.GetUserId())
+	err = this.publisherFor(token).PublishFunction(function, token.GetUserId())
 	if err != nil {
 		return function, err, http.StatusInternalServerError
 	}
@@ -59,6 +63,9 @@ func (this *Controller) PublishFunctionCreate(token auth.Token, function models.
 }
 
 func (this *Controller) PublishFunctionUpdate(token auth.Token, id string, function models.Function, options model.FunctionUpdateOptions) (models.Function, error, int) {
+	if err, code := this.checkPolicy(token, this.config.FunctionTopic, policy.Update); err != nil {
+		return function, err, code
+	}
 	if !token.IsAdmin() {
 		return function, errors.New("access denied"), http.StatusForbidden
 	}
@@ -81,7 +88,7 @@ func (this *Controller) PublishFunctionUpdate(token auth.Token, id string, funct
 		Command:      "PUT",
 	})
 
-	err = this.publisher.PublishFunction(function, token.GetUserId())
+	err = this.publisherFor(token).PublishFunction(function, token.GetUserId())
 	if err != nil {
 		return function, err, http.StatusInternalServerError
 	}
@@ -95,6 +102,9 @@ func (this *Controller) PublishFunctionUpdate(token auth.Token, id string, funct
 }
 
 func (this *Controller) PublishFunctionDelete(token auth.Token, id string, options model.FunctionDeleteOptions) (error, int) {
+	if err, code := this.checkPolicy(token, this.config.FunctionTopic, policy.Delete); err != nil {
+		return err, code
+	}
 	if !token.IsAdmin() {
 		return errors.New("access denied"), http.StatusForbidden
 	}
@@ -103,14 +113,20 @@ func (this *Controller) PublishFunctionDelete(token auth.Token, id string, optio
 		return err, code
 	}
 
+	undoTrash, err, code := this.moveToTrash(token, this.config.FunctionTopic, id)
+	if err != nil {
+		return err, code
+	}
+
 	wait := this.optionalWait(options.Wait, donewait.DoneMsg{
 		ResourceKind: this.config.FunctionTopic,
 		ResourceId:   id,
 		Command:      "DELETE",
 	})
 
-	err = this.publisher.PublishFunctionDelete(id, token.GetUserId())
+	err = this.publisherFor(token).PublishFunctionDelete(id, token.GetUserId())
 	if err != nil {
+		undoTrash()
 		return err, http.StatusInternalServerError
 	}
 
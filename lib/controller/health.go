@@ -0,0 +1,137 @@
+/*
+ * Copyright 2024 InfAI (CC SES)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package controller
+
+import (
+	"context"
+	"fmt"
+	"github.com/SENERGY-Platform/device-manager/lib/bus"
+	"github.com/SENERGY-Platform/device-manager/lib/config"
+	"github.com/SENERGY-Platform/device-manager/lib/model"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// HealthCheckTimeout limits the time of each dependency check
+var HealthCheckTimeout = 5 * time.Second
+
+type healthCheck struct {
+	name     string
+	required bool
+	check    func(ctx context.Context) error
+}
+
+// listenerState records the error of a stopped consumer
+type listenerState struct {
+	mux sync.Mutex
+	err error
+}
+
+func (this *listenerState) setError(err error) {
+	this.mux.Lock()
+	defer this.mux.Unlock()
+	this.err = err
+}
+
+func (this *listenerState) check(ctx context.Context) error {
+	this.mux.Lock()
+	defer this.mux.Unlock()
+	if this.err != nil {
+		return fmt.Errorf("listener stopped: %w", this.err)
+	}
+	return nil
+}
+
+// CheckHealth runs all dependency checks concurrently
+func (this *Controller) CheckHealth(ctx context.Context) (result model.HealthStatus) {
+	result = model.HealthStatus{Ready: true, Dependencies: make([]model.DependencyStatus, len(this.healthChecks))}
+	ctx, cancel := context.WithTimeout(ctx, HealthCheckTimeout)
+	defer cancel()
+	wg := sync.WaitGroup{}
+	for i, check := range this.healthChecks {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			start := time.Now()
+			err := check.check(ctx)
+			status := model.DependencyStatus{
+				Name:      check.name,
+				Required:  check.required,
+				Ok:        err == nil,
+				LatencyMs: time.Since(start).Milliseconds(),
+			}
+			if err != nil {
+				status.Error = err.Error()
+			}
+			result.Dependencies[i] = status
+		}()
+	}
+	wg.Wait()
+	for _, status := range result.Dependencies {
+		if status.Required && !status.Ok {
+			result.Ready = false
+		}
+	}
+	return result
+}
+
+func (this *Controller) addHealthCheck(name string, required bool, check func(ctx context.Context) error) {
+	this.healthChecks = append(this.healthChecks, healthCheck{name: name, required: required, check: check})
+}
+
+// addDefaultHealthChecks registers the checks of the dependencies configured in conf; b may be nil
+func (this *Controller) addDefaultHealthChecks(conf config.Config, b bus.Bus) {
+	if checker, ok := b.(bus.HealthChecker); ok {
+		name := conf.BusBackend
+		if name == "" {
+			name = bus.BackendKafka
+		}
+		this.addHealthCheck(name, true, checker.CheckHealth)
+	}
+	if conf.DeviceRepoUrl != "" {
+		this.addHealthCheck("device-repository", true, httpHealthCheck(conf.DeviceRepoUrl))
+	}
+	if conf.PermissionsV2Url != "" {
+		this.addHealthCheck("permissions-v2", true, httpHealthCheck(conf.PermissionsV2Url))
+	}
+	if conf.ConverterUrl != "" {
+		this.addHealthCheck("converter", false, httpHealthCheck(conf.ConverterUrl))
+	}
+	if conf.HandleDoneWait {
+		this.addHealthCheck("done-wait", true, this.doneWaitListener.check)
+	}
+}
+
+// httpHealthCheck expects any response below 500; services without a health endpoint answer with 404
+func httpHealthCheck(url string) func(ctx context.Context) error {
+	return func(ctx context.Context) error {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+		if err != nil {
+			return err
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			return err
+		}
+		resp.Body.Close()
+		if resp.StatusCode >= 500 {
+			return fmt.Errorf("unexpected status code %v", resp.StatusCode)
+		}
+		return nil
+	}
+}
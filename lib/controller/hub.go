@@ -22,6 +22,7 @@ import (
 	"github.com/SENERGY-Platform/device-manager/lib/auth"
 	"github.com/SENERGY-Platform/device-manager/lib/controller/com"
 	"github.com/SENERGY-Platform/device-manager/lib/model"
+	"github.com/SENERGY-Platform/device-manager/lib/policy"
 	"github.com/SENERGY-Platform/device-repository/lib/client"
 	"github.com/SENERGY-Platform/models/go/models"
 	"github.com/SENERGY-Platform/service-commons/pkg/donewait"
@@ -36,6 +37,9 @@ func (this *Controller) ReadHub(token auth.Token, id string) (hub models.Hub, er
 }
 
 func (this *Controller) PublishHubCreate(token auth.Token, hubEdit models.Hub, options model.HubUpdateOptions) (models.Hub, error, int) {
+	if err, code := this.checkPolicy(token, this.config.HubTopic, policy.Create); err != nil {
+		return hubEdit, err, code
+	}
 	hub, err, code := this.completeHub(token, hubEdit)
 	if err != nil {
 		return hub, err, code
@@ -56,7 +60,7 @@ func (this *Controller) PublishHubCreate(token auth.Token, hubEdit models.Hub, o
 		Command:      "PUT",
 	})
 
-	err = this.publisher.PublishHub(hub, token.GetUserId())
+	err = this.publisherFor(token).PublishHub(hub, token.GetUserId())
 	if err != nil {
 		return hub, err, http.StatusInternalServerError
 	}
@@ -70,6 +74,9 @@ func (this *Controller) PublishHubCreate(token auth.Token, hubEdit models.Hub, o
 }
 
 func (this *Controller) PublishHubUpdate(token auth.Token, id string, hubEdit models.Hub, options model.HubUpdateOptions) (models.Hub, error, int) {
+	if err, code := this.checkPolicy(token, this.config.HubTopic, policy.Update); err != nil {
+		return hubEdit, err, code
+	}
 	if hubEdit.Id != id {
 		return models.Hub{}, errors.New("hub id in body unequal to hub id in request endpoint"), http.StatusBadRequest
 	}
@@ -142,7 +149,7 @@ func (this *Controller) PublishHubUpdate(token auth.Token, id string, hubEdit mo
 		Command:      "PUT",
 	})
 
-	err = this.publisher.PublishHub(hub, hub.OwnerId)
+	err = this.publisherFor(token).PublishHub(hub, hub.OwnerId)
 	if err != nil {
 		return hub, err, http.StatusInternalServerError
 	}
@@ -156,6 +163,9 @@ func (this *Controller) PublishHubUpdate(token auth.Token, id string, hubEdit mo
 }
 
 func (this *Controller) PublishHubDelete(token auth.Token, id string, options model.HubDeleteOptions) (error, int) {
+	if err, code := this.checkPolicy(token, this.config.HubTopic, policy.Delete); err != nil {
+		return err, code
+	}
 	if err := com.PreventIdModifier(id); err != nil {
 		return err, http.StatusBadRequest
 	}
@@ -164,14 +174,20 @@ func (this *Controller) PublishHubDelete(token auth.Token, id string, options mo
 		return err, code
 	}
 
+	undoTrash, err, code := this.moveToTrash(token, this.config.HubTopic, id)
+	if err != nil {
+		return err, code
+	}
+
 	wait := this.optionalWait(options.Wait, donewait.DoneMsg{
 		ResourceKind: this.config.HubTopic,
 		ResourceId:   id,
 		Command:      "DELETE",
 	})
 
-	err = this.publisher.PublishHubDelete(id, token.GetUserId())
+	err = this.publisherFor(token).PublishHubDelete(id, token.GetUserId())
 	if err != nil {
+		undoTrash()
 		return err, http.StatusInternalServerError
 	}
 
@@ -21,6 +21,7 @@ import (
 	"github.com/SENERGY-Platform/device-manager/lib/auth"
 	"github.com/SENERGY-Platform/device-manager/lib/controller/com"
 	"github.com/SENERGY-Platform/device-manager/lib/model"
+	"github.com/SENERGY-Platform/device-manager/lib/policy"
 	"github.com/SENERGY-Platform/models/go/models"
 	"github.com/SENERGY-Platform/service-commons/pkg/donewait"
 	"net/http"
@@ -31,6 +32,9 @@ func (this *Controller) ReadLocation(token auth.Token, id string) (location mode
 }
 
 func (this *Controller) PublishLocationCreate(token auth.Token, location models.Location, options model.LocationUpdateOptions) (result models.Location, err error, code int) {
+	if err, code := this.checkPolicy(token, this.config.LocationTopic, policy.Create); err != nil {
+		return location, err, code
+	}
 	if location.Id != "" {
 		return result, errors.New("expect empty location id"), http.StatusBadRequest
 	}
@@ -51,7 +55,7 @@ func (this *Controller) PublishLocationCreate(token auth.Token, location models.
 		Command:      "PUT",
 	})
 
-	err = this.publisher.PublishLocation(location, token.GetUserId())
+	err = this.publisherFor(token).PublishLocation(location, token.GetUserId())
 	if err != nil {
 		return location, err, http.StatusInternalServerError
 	}
@@ -65,6 +69,9 @@ func (this *Controller) PublishLocationCreate(token auth.Token, location models.
 }
 
 func (this *Controller) PublishLocationUpdate(token auth.Token, id string, location models.Location, options model.LocationUpdateOptions) (models.Location, error, int) {
+	if err, code := this.checkPolicy(token, this.config.LocationTopic, policy.Update); err != nil {
+		return location, err, code
+	}
 	if location.Id != id {
 		return location, errors.New("id in body unequal to id in request endpoint"), http.StatusBadRequest
 	}
@@ -90,10 +97,11 @@ func (this *Controller) PublishLocationUpdate(token auth.Token, id string, locat
 		Command:      "PUT",
 	})
 
-	err = this.publisher.PublishLocation(location, token.GetUserId())
+	err = this.publisherFor(token).PublishLocation(location, token.GetUserId())
 	if err != nil {
 		return location, err, http.StatusInternalServerError
 	}
+	this.propagateSharing(&token, this.config.LocationTopic, id, append([]string{}, location.DeviceIds...))
 
 	err = wait()
 	if err != nil {
@@ -104,6 +112,9 @@ func (this *Controller) PublishLocationUpdate(token auth.Token, id string, locat
 }
 
 func (this *Controller) PublishLocationDelete(token auth.Token, id string, options model.LocationDeleteOptions) (error, int) {
+	if err, code := this.checkPolicy(token, this.config.LocationTopic, policy.Delete); err != nil {
+		return err, code
+	}
 	if err := com.PreventIdModifier(id); err != nil {
 		return err, http.StatusBadRequest
 	}
@@ -112,16 +123,23 @@ func (this *Controller) PublishLocationDelete(token auth.Token, id string, optio
 		return err, code
 	}
 
+	undoTrash, err, code := this.moveToTrash(token, this.config.LocationTopic, id)
+	if err != nil {
+		return err, code
+	}
+
 	wait := this.optionalWait(options.Wait, donewait.DoneMsg{
 		ResourceKind: this.config.LocationTopic,
 		ResourceId:   id,
 		Command:      "DELETE",
 	})
 
-	err = this.publisher.PublishLocationDelete(id, token.GetUserId())
+	err = this.publisherFor(token).PublishLocationDelete(id, token.GetUserId())
 	if err != nil {
+		undoTrash()
 		return err, http.StatusInternalServerError
 	}
+	this.stopPropagation(this.config.LocationTopic, id)
 
 	err = wait()
 	if err != nil {
@@ -0,0 +1,311 @@
+/*
+ * Copyright 2024 InfAI (CC SES)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package controller
+
+import (
+	"context"
+	"errors"
+	"github.com/SENERGY-Platform/device-manager/lib/audit"
+	"github.com/SENERGY-Platform/device-manager/lib/auth"
+	"github.com/SENERGY-Platform/device-manager/lib/config"
+	"github.com/SENERGY-Platform/device-manager/lib/migration"
+	"github.com/SENERGY-Platform/device-manager/lib/model"
+	"github.com/SENERGY-Platform/device-manager/lib/policy"
+	"github.com/SENERGY-Platform/device-repository/lib/client"
+	"github.com/SENERGY-Platform/models/go/models"
+	permv2 "github.com/SENERGY-Platform/permissions-v2/pkg/client"
+	"log"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+)
+
+var errMigrationsDisabled = errors.New("device-type migrations are disabled, migration_job_file is not set")
+
+// newMigrations returns nil if config.MigrationJobFile is empty; jobs must be shared by all replicas
+func newMigrations(conf config.Config) (*migration.Store, error) {
+	if conf.MigrationJobFile == "" || conf.MigrationJobFile == "-" {
+		return nil, nil
+	}
+	return migration.New(conf.MigrationJobFile)
+}
+
+// MigrationDeleteTimeout is the max time to wait for the device-repository to apply the migrated devices, before the source device-type is deleted
+var MigrationDeleteTimeout = time.Minute
+
+var migrationDeletePollInterval = time.Second
+
+var errMigrationAborted = errors.New("device-type migration aborted")
+
+// MigrateDeviceType sets the device-type of all devices of device-type id, which the user may write, to request.TargetDeviceTypeId.
+// the target must have a service for each service local id of the source; devices which the device-repository rejects with the target are not
+// published and reported as failed, and the source is only deleted if no device failed. progress may be nil and is called after every batch and once after the migration.
+// the migration stops between batches, if the context of WithContext is cancelled.
+func (this *Controller) MigrateDeviceType(token auth.Token, id string, request model.DeviceTypeMigrationRequest, progress func(model.DeviceTypeMigrationProgress)) (result model.DeviceTypeMigrationProgress, err error, code int) {
+	result = model.DeviceTypeMigrationProgress{SourceDeviceTypeId: id, TargetDeviceTypeId: request.TargetDeviceTypeId, DryRun: request.DryRun, Failed: []string{}}
+	target, err, code := this.checkMigration(token, id, request)
+	if err != nil {
+		return result, err, code
+	}
+	devices, err, code := this.listMigrationDevices(token, id, request)
+	if err != nil {
+		return result, err, code
+	}
+	return this.migrateDeviceType(this.context(), token, id, target, devices, request, progress)
+}
+
+// migrateDeviceType runs a migration checked by checkMigration for the devices of listMigrationDevices.
+// the migration may outlast the token of the request, so the rights of the user are not checked again: devices are
+// validated with the internal admin token and published and deleted in the name of the user
+func (this *Controller) migrateDeviceType(ctx context.Context, token auth.Token, id string, target models.DeviceType, devices []models.Device, request model.DeviceTypeMigrationRequest, progress func(model.DeviceTypeMigrationProgress)) (result model.DeviceTypeMigrationProgress, err error, code int) {
+	result = model.DeviceTypeMigrationProgress{SourceDeviceTypeId: id, TargetDeviceTypeId: request.TargetDeviceTypeId, DryRun: request.DryRun, Failed: []string{}, Listed: int64(len(devices))}
+	if progress == nil {
+		progress = func(model.DeviceTypeMigrationProgress) {}
+	}
+	if request.DryRun {
+		result.Done = true
+		progress(result)
+		return result, nil, http.StatusOK
+	}
+	adminToken, err := auth.Parse(permv2.InternalAdminToken)
+	if err != nil {
+		return result, err, http.StatusInternalServerError
+	}
+
+	publisher := this.publisherFor(token)
+	for batch := range slices.Chunk(devices, int(migrationBatchSize(request))) {
+		if ctx.Err() != nil {
+			return result, errMigrationAborted, http.StatusServiceUnavailable
+		}
+		for _, device := range batch {
+			device.DeviceTypeId = target.Id
+			owner := device.OwnerId
+			if owner == "" {
+				owner = token.GetUserId()
+			}
+			err, _ = this.com.ValidateDevice(adminToken, device)
+			if err != nil {
+				log.Println("WARNING: device is invalid with the target device-type", device.Id, target.Id, err)
+				result.Failed = append(result.Failed, device.Id)
+				continue
+			}
+			err = publisher.PublishDevice(device, owner)
+			if err != nil {
+				log.Println("ERROR: unable to migrate device", device.Id, err)
+				result.Failed = append(result.Failed, device.Id)
+			} else {
+				result.Migrated++
+			}
+		}
+		progress(result)
+	}
+
+	if request.DeleteSource && len(result.Failed) > 0 {
+		result.SourceDeleteError = "not deleted, because devices failed to migrate"
+	} else if request.DeleteSource {
+		err, code = this.deleteMigrationSource(ctx, adminToken, token, id)
+		if errors.Is(err, errMigrationAborted) {
+			return result, err, code
+		}
+		if err != nil {
+			result.SourceDeleteError = err.Error()
+		} else {
+			result.SourceDeleted = true
+		}
+	}
+	result.Done = true
+	progress(result)
+	return result, nil, http.StatusOK
+}
+
+// listMigrationDevices lists the devices of device-type id, which the user may write.
+// devices are listed before the first update, because migrated devices leave the filter and would shift the pages.
+// pages are ordered by the unique id, so that no device is skipped or listed twice
+func (this *Controller) listMigrationDevices(token auth.Token, id string, request model.DeviceTypeMigrationRequest) (devices []models.Device, err error, code int) {
+	batchSize := migrationBatchSize(request)
+	devices = []models.Device{}
+	var offset int64 = 0
+	for {
+		list, err, code := this.com.ListDevices(token.Jwt(), client.DeviceListOptions{DeviceTypeIds: []string{id}, Limit: batchSize, Offset: offset, SortBy: "id.asc", Permission: models.Write})
+		if err != nil {
+			return devices, err, code
+		}
+		devices = append(devices, list...)
+		offset += batchSize
+		if int64(len(list)) < batchSize {
+			return devices, nil, http.StatusOK
+		}
+	}
+}
+
+func migrationBatchSize(request model.DeviceTypeMigrationRequest) int64 {
+	if request.BatchSize <= 0 {
+		return ReplayDefaultBatchSize
+	}
+	return request.BatchSize
+}
+
+// StartDeviceTypeMigration checks the migration, lists the devices and migrates them in the background; the progress is readable with ReadDeviceTypeMigration.
+// the job stops between batches and is marked as aborted, if the context of WithContext is cancelled or the controller stops.
+// the migration is recorded in the audit log, once it is done.
+func (this *Controller) StartDeviceTypeMigration(token auth.Token, id string, request model.DeviceTypeMigrationRequest) (result model.DeviceTypeMigrationJob, err error, code int) {
+	if this.migrations == nil {
+		return result, errMigrationsDisabled, http.StatusNotFound
+	}
+	target, err, code := this.checkMigration(token, id, request)
+	if err != nil {
+		return result, err, code
+	}
+	devices, err, code := this.listMigrationDevices(token, id, request)
+	if err != nil {
+		return result, err, code
+	}
+	result, err = this.migrations.Create(model.DeviceTypeMigrationJob{
+		Request:   request,
+		Progress:  model.DeviceTypeMigrationProgress{SourceDeviceTypeId: id, TargetDeviceTypeId: request.TargetDeviceTypeId, DryRun: request.DryRun, Failed: []string{}, Listed: int64(len(devices))},
+		CreatedBy: token.GetUserId(),
+	})
+	if err != nil {
+		return result, err, http.StatusInternalServerError
+	}
+	ctx, cancel := context.WithCancel(this.context())
+	if this.lifetime != nil {
+		context.AfterFunc(this.lifetime, cancel)
+	}
+	go func() {
+		defer cancel()
+		this.runDeviceTypeMigration(ctx, token, id, target, devices, result.Id, request)
+	}()
+	return result, nil, http.StatusAccepted
+}
+
+func (this *Controller) runDeviceTypeMigration(ctx context.Context, token auth.Token, id string, target models.DeviceType, devices []models.Device, jobId string, request model.DeviceTypeMigrationRequest) {
+	result, err, code := this.migrateDeviceType(ctx, token, id, target, devices, request, func(progress model.DeviceTypeMigrationProgress) {
+		updateErr := this.migrations.Update(jobId, func(job *model.DeviceTypeMigrationJob) {
+			job.Progress = progress
+		})
+		if updateErr != nil {
+			log.Println("WARNING: unable to store migration progress", jobId, updateErr)
+		}
+	})
+	updateErr := this.migrations.Update(jobId, func(job *model.DeviceTypeMigrationJob) {
+		job.Progress = result
+		job.Progress.Done = true
+		if err != nil {
+			job.Error = err.Error()
+		}
+	})
+	if updateErr != nil {
+		log.Println("ERROR: unable to store migration result", jobId, updateErr)
+	}
+	if this.auditor != nil && !request.DryRun {
+		this.auditor.Record(context.WithoutCancel(ctx), auditRecord(token.GetUserId(), token.GetRoles(), this.config.DeviceTypeTopic, id, audit.Migrate, nil, result, err, code))
+	}
+}
+
+// ReadDeviceTypeMigration returns the migration job of device-type id; only the user who started it and admins may read it
+func (this *Controller) ReadDeviceTypeMigration(token auth.Token, id string, jobId string) (result model.DeviceTypeMigrationJob, err error, code int) {
+	if this.migrations == nil {
+		return result, errMigrationsDisabled, http.StatusNotFound
+	}
+	result, err = this.migrations.Get(jobId)
+	if errors.Is(err, migration.ErrNotFound) || (err == nil && (result.Progress.SourceDeviceTypeId != id || (result.CreatedBy != token.GetUserId() && !token.IsAdmin()))) {
+		return model.DeviceTypeMigrationJob{}, migration.ErrNotFound, http.StatusNotFound
+	}
+	if err != nil {
+		return result, err, http.StatusInternalServerError
+	}
+	return result, nil, http.StatusOK
+}
+
+// checkMigration returns the target device-type, if the devices of device-type id may be migrated to it.
+// all rights are checked before the migration starts, because a job may outlast the token of the request
+func (this *Controller) checkMigration(token auth.Token, id string, request model.DeviceTypeMigrationRequest) (target models.DeviceType, err error, code int) {
+	if request.TargetDeviceTypeId == "" || request.TargetDeviceTypeId == id {
+		return target, errors.New("expect target_device_type_id unequal to the source device-type"), http.StatusBadRequest
+	}
+	if err, code := this.checkPolicy(token, this.config.DeviceTopic, policy.Update); err != nil {
+		return target, err, code
+	}
+	source, err, code := this.com.GetDeviceType(token, id)
+	if err != nil {
+		return target, err, code
+	}
+	target, err, code = this.com.GetDeviceType(token, request.TargetDeviceTypeId)
+	if err != nil {
+		return target, err, code
+	}
+	missing := missingServiceLocalIds(source, target)
+	if len(missing) > 0 {
+		return target, errors.New("target device-type is missing services with the local ids: " + strings.Join(missing, ", ")), http.StatusBadRequest
+	}
+	if request.DeleteSource && !request.DryRun {
+		if err, code := this.checkPolicy(token, this.config.DeviceTypeTopic, policy.Delete); err != nil {
+			return target, err, code
+		}
+		if !token.IsAdmin() {
+			err, code = this.com.PermissionCheckForDeviceType(token, id, "a")
+			if err != nil {
+				return target, err, code
+			}
+		}
+	}
+	return target, nil, http.StatusOK
+}
+
+// deleteMigrationSource waits until the device-repository has no devices of the device-type and deletes it in the name of the user of token.
+// the rights of token on the device-type are checked by checkMigration, because the token may have expired since
+func (this *Controller) deleteMigrationSource(ctx context.Context, adminToken auth.Token, token auth.Token, id string) (err error, code int) {
+	deadline := time.Now().Add(MigrationDeleteTimeout)
+	for {
+		exist, err, code := this.com.DevicesOfTypeExist(adminToken, id)
+		if err != nil {
+			return err, code
+		}
+		if !exist {
+			break
+		}
+		if time.Now().After(deadline) {
+			return errors.New("devices of the source device-type still exist"), http.StatusConflict
+		}
+		select {
+		case <-ctx.Done():
+			return errMigrationAborted, http.StatusServiceUnavailable
+		case <-time.After(migrationDeletePollInterval):
+		}
+	}
+	undoTrash, err, code := this.moveToTrash(token, this.config.DeviceTypeTopic, id)
+	if err != nil {
+		return err, code
+	}
+	err = this.publisherFor(token).PublishDeviceTypeDelete(id, token.GetUserId())
+	if err != nil {
+		undoTrash()
+		return err, http.StatusInternalServerError
+	}
+	return nil, http.StatusOK
+}
+
+func missingServiceLocalIds(source models.DeviceType, target models.DeviceType) (missing []string) {
+	for _, service := range source.Services {
+		if !slices.ContainsFunc(target.Services, func(s models.Service) bool { return s.LocalId == service.LocalId }) {
+			missing = append(missing, service.LocalId)
+		}
+	}
+	return missing
+}
@@ -0,0 +1,418 @@
+/*
+ * Copyright 2024 InfAI (CC SES)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package controller
+
+import (
+	"context"
+	"github.com/SENERGY-Platform/device-manager/lib/auth"
+	"github.com/SENERGY-Platform/device-manager/lib/controller/com"
+	"github.com/SENERGY-Platform/device-manager/lib/metrics"
+	"github.com/SENERGY-Platform/device-manager/lib/tracing"
+	"github.com/SENERGY-Platform/device-repository/lib/client"
+	"github.com/SENERGY-Platform/models/go/models"
+	permv2 "github.com/SENERGY-Platform/permissions-v2/pkg/client"
+	"github.com/SENERGY-Platform/permissions-v2/pkg/model"
+	"net/url"
+	"time"
+)
+
+// observedCom records the latency and outcome of every Com call in metrics.ComDuration
+// and, if a context is set with withContext, a span as child of the span in the context.
+// requests of the com.Com to other services are children of the span of their call
+type observedCom struct {
+	Com
+	ctx context.Context
+}
+
+func newObservedCom(com Com) *observedCom {
+	return &observedCom{Com: com}
+}
+
+// contextCom is implemented by the Com decorators of this package to pass the context of Controller.WithContext down to observedCom
+type contextCom interface {
+	withContext(ctx context.Context) Com
+}
+
+func comWithContext(com Com, ctx context.Context) Com {
+	if c, ok := com.(contextCom); ok {
+		return c.withContext(ctx)
+	}
+	return com
+}
+
+func (this *observedCom) withContext(ctx context.Context) Com {
+	return &observedCom{Com: this.Com, ctx: ctx}
+}
+
+// start returns the Com for the call of method, whose requests are children of the span of the call,
+// and a function which ends the observation
+func (this *observedCom) start(method string) (inner Com, end func(err error)) {
+	start := time.Now()
+	if this.ctx == nil {
+		return this.Com, func(err error) {
+			metrics.ObserveCom(method, start, err)
+		}
+	}
+	ctx, span := tracing.Start(this.ctx, "Com."+method)
+	inner = this.Com
+	if c, ok := inner.(*com.Com); ok {
+		inner = c.WithContext(ctx)
+	}
+	return inner, func(err error) {
+		tracing.End(span, err)
+		metrics.ObserveCom(method, start, err)
+	}
+}
+
+func observe[T any](this *observedCom, method string, call func(com Com) (T, error, int)) (result T, err error, code int) {
+	inner, end := this.start(method)
+	result, err, code = call(inner)
+	end(err)
+	return result, err, code
+}
+
+func observeCheck(this *observedCom, method string, call func(com Com) (error, int)) (err error, code int) {
+	inner, end := this.start(method)
+	err, code = call(inner)
+	end(err)
+	return err, code
+}
+
+func (this *observedCom) ResourcesEffectedByUserDelete(token auth.Token, resource string) (deleteResourceIds []string, deleteUserFromResource []permv2.Resource, err error) {
+	inner, end := this.start("ResourcesEffectedByUserDelete")
+	deleteResourceIds, deleteUserFromResource, err = inner.ResourcesEffectedByUserDelete(token, resource)
+	end(err)
+	return deleteResourceIds, deleteUserFromResource, err
+}
+
+func (this *observedCom) GetResourceRights(token auth.Token, kind string, id string) (result model.Resource, err error, code int) {
+	return observe(this, "GetResourceRights", func(com Com) (model.Resource, error, int) {
+		return com.GetResourceRights(token, kind, id)
+	})
+}
+
+func (this *observedCom) SetPermission(token string, topicId string, id string, permissions model.ResourcePermissions) (result model.ResourcePermissions, err error, code int) {
+	return observe(this, "SetPermission", func(com Com) (model.ResourcePermissions, error, int) {
+		return com.SetPermission(token, topicId, id, permissions)
+	})
+}
+
+func (this *observedCom) GetTechnicalDeviceGroup(token auth.Token, id string) (result models.DeviceGroup, err error, code int) {
+	return observe(this, "GetTechnicalDeviceGroup", func(com Com) (models.DeviceGroup, error, int) {
+		return com.GetTechnicalDeviceGroup(token, id)
+	})
+}
+
+func (this *observedCom) ValidateDeviceGroup(token auth.Token, dt models.DeviceGroup) (err error, code int) {
+	return observeCheck(this, "ValidateDeviceGroup", func(com Com) (error, int) {
+		return com.ValidateDeviceGroup(token, dt)
+	})
+}
+
+func (this *observedCom) ValidateDeviceGroupDelete(token auth.Token, id string) (err error, code int) {
+	return observeCheck(this, "ValidateDeviceGroupDelete", func(com Com) (error, int) {
+		return com.ValidateDeviceGroupDelete(token, id)
+	})
+}
+
+func (this *observedCom) PermissionCheckForDeviceGroup(token auth.Token, id string, permission string) (err error, code int) {
+	return observeCheck(this, "PermissionCheckForDeviceGroup", func(com Com) (error, int) {
+		return com.PermissionCheckForDeviceGroup(token, id, permission)
+	})
+}
+
+func (this *observedCom) GetDeviceType(token auth.Token, id string) (result models.DeviceType, err error, code int) {
+	return observe(this, "GetDeviceType", func(com Com) (models.DeviceType, error, int) {
+		return com.GetDeviceType(token, id)
+	})
+}
+
+func (this *observedCom) ValidateDeviceType(token auth.Token, dt models.DeviceType) (err error, code int) {
+	return observeCheck(this, "ValidateDeviceType", func(com Com) (error, int) {
+		return com.ValidateDeviceType(token, dt)
+	})
+}
+
+func (this *observedCom) PermissionCheckForDeviceType(token auth.Token, id string, permission string) (err error, code int) {
+	return observeCheck(this, "PermissionCheckForDeviceType", func(com Com) (error, int) {
+		return com.PermissionCheckForDeviceType(token, id, permission)
+	})
+}
+
+func (this *observedCom) GetProtocol(token auth.Token, id string) (result models.Protocol, err error, code int) {
+	return observe(this, "GetProtocol", func(com Com) (models.Protocol, error, int) {
+		return com.GetProtocol(token, id)
+	})
+}
+
+func (this *observedCom) ValidateProtocol(token auth.Token, protocol models.Protocol) (err error, code int) {
+	return observeCheck(this, "ValidateProtocol", func(com Com) (error, int) {
+		return com.ValidateProtocol(token, protocol)
+	})
+}
+
+func (this *observedCom) ListDevicesByQuery(token auth.Token, query url.Values) (result []models.Device, err error, code int) {
+	return observe(this, "ListDevicesByQuery", func(com Com) ([]models.Device, error, int) {
+		return com.ListDevicesByQuery(token, query)
+	})
+}
+
+func (this *observedCom) GetDevice(token auth.Token, id string) (result models.Device, err error, code int) {
+	return observe(this, "GetDevice", func(com Com) (models.Device, error, int) {
+		return com.GetDevice(token, id)
+	})
+}
+
+func (this *observedCom) GetDeviceByLocalId(token auth.Token, ownerId string, localid string) (result models.Device, err error, code int) {
+	return observe(this, "GetDeviceByLocalId", func(com Com) (models.Device, error, int) {
+		return com.GetDeviceByLocalId(token, ownerId, localid)
+	})
+}
+
+func (this *observedCom) ValidateDevice(token auth.Token, device models.Device) (err error, code int) {
+	return observeCheck(this, "ValidateDevice", func(com Com) (error, int) {
+		return com.ValidateDevice(token, device)
+	})
+}
+
+func (this *observedCom) PermissionCheckForDevice(token auth.Token, id string, permission string) (err error, code int) {
+	return observeCheck(this, "PermissionCheckForDevice", func(com Com) (error, int) {
+		return com.PermissionCheckForDevice(token, id, permission)
+	})
+}
+
+func (this *observedCom) PermissionCheckForDeviceList(token auth.Token, ids []string, rights string) (result map[string]bool, err error, code int) {
+	return observe(this, "PermissionCheckForDeviceList", func(com Com) (map[string]bool, error, int) {
+		return com.PermissionCheckForDeviceList(token, ids, rights)
+	})
+}
+
+func (this *observedCom) GetHub(token auth.Token, id string) (result models.Hub, err error, code int) {
+	return observe(this, "GetHub", func(com Com) (models.Hub, error, int) {
+		return com.GetHub(token, id)
+	})
+}
+
+func (this *observedCom) ValidateHub(token auth.Token, hub models.Hub) (err error, code int) {
+	return observeCheck(this, "ValidateHub", func(com Com) (error, int) {
+		return com.ValidateHub(token, hub)
+	})
+}
+
+func (this *observedCom) PermissionCheckForHub(token auth.Token, id string, permission string) (err error, code int) {
+	return observeCheck(this, "PermissionCheckForHub", func(com Com) (error, int) {
+		return com.PermissionCheckForHub(token, id, permission)
+	})
+}
+
+func (this *observedCom) GetConcept(token auth.Token, id string) (result models.Concept, err error, code int) {
+	return observe(this, "GetConcept", func(com Com) (models.Concept, error, int) {
+		return com.GetConcept(token, id)
+	})
+}
+
+func (this *observedCom) ValidateConcept(token auth.Token, concept models.Concept) (err error, code int) {
+	return observeCheck(this, "ValidateConcept", func(com Com) (error, int) {
+		return com.ValidateConcept(token, concept)
+	})
+}
+
+func (this *observedCom) PermissionCheckForConcept(token auth.Token, id string, permission string) (err error, code int) {
+	return observeCheck(this, "PermissionCheckForConcept", func(com Com) (error, int) {
+		return com.PermissionCheckForConcept(token, id, permission)
+	})
+}
+
+func (this *observedCom) ValidateCharacteristic(token auth.Token, concept models.Characteristic) (err error, code int) {
+	return observeCheck(this, "ValidateCharacteristic", func(com Com) (error, int) {
+		return com.ValidateCharacteristic(token, concept)
+	})
+}
+
+func (this *observedCom) PermissionCheckForCharacteristic(token auth.Token, id string, permission string) (err error, code int) {
+	return observeCheck(this, "PermissionCheckForCharacteristic", func(com Com) (error, int) {
+		return com.PermissionCheckForCharacteristic(token, id, permission)
+	})
+}
+
+func (this *observedCom) GetCharacteristic(token auth.Token, id string) (result models.Characteristic, err error, code int) {
+	return observe(this, "GetCharacteristic", func(com Com) (models.Characteristic, error, int) {
+		return com.GetCharacteristic(token, id)
+	})
+}
+
+func (this *observedCom) DevicesOfTypeExist(token auth.Token, deviceTypeId string) (result bool, err error, code int) {
+	return observe(this, "DevicesOfTypeExist", func(com Com) (bool, error, int) {
+		return com.DevicesOfTypeExist(token, deviceTypeId)
+	})
+}
+
+func (this *observedCom) DeviceLocalIdToId(token auth.Token, localId string) (result string, err error, code int) {
+	return observe(this, "DeviceLocalIdToId", func(com Com) (string, error, int) {
+		return com.DeviceLocalIdToId(token, localId)
+	})
+}
+
+func (this *observedCom) GetAspect(token auth.Token, id string) (result models.Aspect, err error, code int) {
+	return observe(this, "GetAspect", func(com Com) (models.Aspect, error, int) {
+		return com.GetAspect(token, id)
+	})
+}
+
+func (this *observedCom) ValidateAspect(token auth.Token, aspect models.Aspect) (err error, code int) {
+	return observeCheck(this, "ValidateAspect", func(com Com) (error, int) {
+		return com.ValidateAspect(token, aspect)
+	})
+}
+
+func (this *observedCom) GetFunction(token auth.Token, id string) (result models.Function, err error, code int) {
+	return observe(this, "GetFunction", func(com Com) (models.Function, error, int) {
+		return com.GetFunction(token, id)
+	})
+}
+
+func (this *observedCom) ValidateFunction(token auth.Token, function models.Function) (err error, code int) {
+	return observeCheck(this, "ValidateFunction", func(com Com) (error, int) {
+		return com.ValidateFunction(token, function)
+	})
+}
+
+func (this *observedCom) GetDeviceClass(token auth.Token, id string) (result models.DeviceClass, err error, code int) {
+	return observe(this, "GetDeviceClass", func(com Com) (models.DeviceClass, error, int) {
+		return com.GetDeviceClass(token, id)
+	})
+}
+
+func (this *observedCom) ValidateDeviceClass(token auth.Token, deviceClass models.DeviceClass) (err error, code int) {
+	return observeCheck(this, "ValidateDeviceClass", func(com Com) (error, int) {
+		return com.ValidateDeviceClass(token, deviceClass)
+	})
+}
+
+func (this *observedCom) GetLocation(token auth.Token, id string) (result models.Location, err error, code int) {
+	return observe(this, "GetLocation", func(com Com) (models.Location, error, int) {
+		return com.GetLocation(token, id)
+	})
+}
+
+func (this *observedCom) ValidateLocation(token auth.Token, Location models.Location) (err error, code int) {
+	return observeCheck(this, "ValidateLocation", func(com Com) (error, int) {
+		return com.ValidateLocation(token, Location)
+	})
+}
+
+func (this *observedCom) PermissionCheckForLocation(token auth.Token, id string, permission string) (err error, code int) {
+	return observeCheck(this, "PermissionCheckForLocation", func(com Com) (error, int) {
+		return com.PermissionCheckForLocation(token, id, permission)
+	})
+}
+
+func (this *observedCom) ValidateAspectDelete(token auth.Token, id string) (err error, code int) {
+	return observeCheck(this, "ValidateAspectDelete", func(com Com) (error, int) {
+		return com.ValidateAspectDelete(token, id)
+	})
+}
+
+func (this *observedCom) ValidateCharacteristicDelete(token auth.Token, id string) (err error, code int) {
+	return observeCheck(this, "ValidateCharacteristicDelete", func(com Com) (error, int) {
+		return com.ValidateCharacteristicDelete(token, id)
+	})
+}
+
+func (this *observedCom) ValidateConceptDelete(token auth.Token, id string) (err error, code int) {
+	return observeCheck(this, "ValidateConceptDelete", func(com Com) (error, int) {
+		return com.ValidateConceptDelete(token, id)
+	})
+}
+
+func (this *observedCom) ValidateDeviceClassDelete(token auth.Token, id string) (err error, code int) {
+	return observeCheck(this, "ValidateDeviceClassDelete", func(com Com) (error, int) {
+		return com.ValidateDeviceClassDelete(token, id)
+	})
+}
+
+func (this *observedCom) ValidateFunctionDelete(token auth.Token, id string) (err error, code int) {
+	return observeCheck(this, "ValidateFunctionDelete", func(com Com) (error, int) {
+		return com.ValidateFunctionDelete(token, id)
+	})
+}
+
+func (this *observedCom) ListDeviceTypes(token string, options client.DeviceTypeListOptions) (result []models.DeviceType, err error, code int) {
+	return observe(this, "ListDeviceTypes", func(com Com) ([]models.DeviceType, error, int) {
+		return com.ListDeviceTypes(token, options)
+	})
+}
+
+func (this *observedCom) ListDevices(token string, options client.DeviceListOptions) (result []models.Device, err error, code int) {
+	return observe(this, "ListDevices", func(com Com) ([]models.Device, error, int) {
+		return com.ListDevices(token, options)
+	})
+}
+
+func (this *observedCom) ListHubs(token string, options client.HubListOptions) (result []models.Hub, err error, code int) {
+	return observe(this, "ListHubs", func(com Com) ([]models.Hub, error, int) {
+		return com.ListHubs(token, options)
+	})
+}
+
+func (this *observedCom) ListDeviceGroups(token string, options client.DeviceGroupListOptions) (result []models.DeviceGroup, err error, code int) {
+	return observe(this, "ListDeviceGroups", func(com Com) ([]models.DeviceGroup, error, int) {
+		return com.ListDeviceGroups(token, options)
+	})
+}
+
+func (this *observedCom) ListLocations(token string, options client.LocationListOptions) (result []models.Location, err error, code int) {
+	return observe(this, "ListLocations", func(com Com) ([]models.Location, error, int) {
+		return com.ListLocations(token, options)
+	})
+}
+
+func (this *observedCom) ListProtocols(token string, limit int64, offset int64, sort string) (result []models.Protocol, err error, code int) {
+	return observe(this, "ListProtocols", func(com Com) ([]models.Protocol, error, int) {
+		return com.ListProtocols(token, limit, offset, sort)
+	})
+}
+
+func (this *observedCom) ListAspects(options client.AspectListOptions) (result []models.Aspect, err error, code int) {
+	return observe(this, "ListAspects", func(com Com) ([]models.Aspect, error, int) {
+		return com.ListAspects(options)
+	})
+}
+
+func (this *observedCom) ListFunctions(options client.FunctionListOptions) (result []models.Function, err error, code int) {
+	return observe(this, "ListFunctions", func(com Com) ([]models.Function, error, int) {
+		return com.ListFunctions(options)
+	})
+}
+
+func (this *observedCom) ListConcepts(options client.ConceptListOptions) (result []models.Concept, err error, code int) {
+	return observe(this, "ListConcepts", func(com Com) ([]models.Concept, error, int) {
+		return com.ListConcepts(options)
+	})
+}
+
+func (this *observedCom) ListCharacteristics(options client.CharacteristicListOptions) (result []models.Characteristic, err error, code int) {
+	return observe(this, "ListCharacteristics", func(com Com) ([]models.Characteristic, error, int) {
+		return com.ListCharacteristics(options)
+	})
+}
+
+func (this *observedCom) ListDeviceClasses(options client.DeviceClassListOptions) (result []models.DeviceClass, err error, code int) {
+	return observe(this, "ListDeviceClasses", func(com Com) ([]models.DeviceClass, error, int) {
+		return com.ListDeviceClasses(options)
+	})
+}
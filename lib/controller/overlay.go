@@ -0,0 +1,331 @@
+/*
+ * Copyright 2024 InfAI (CC SES)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package controller
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/SENERGY-Platform/device-manager/lib/auth"
+	"github.com/SENERGY-Platform/device-manager/lib/config"
+	"github.com/SENERGY-Platform/device-manager/lib/controller/com"
+	"github.com/SENERGY-Platform/device-manager/lib/overlay"
+	"github.com/SENERGY-Platform/models/go/models"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// newOverlay returns nil if config.ReadYourWritesTtl is empty or "-"
+func newOverlay(ctx context.Context, conf config.Config) (*overlay.Overlay, error) {
+	if conf.ReadYourWritesTtl == "" || conf.ReadYourWritesTtl == "-" {
+		return nil, nil
+	}
+	ttl, err := time.ParseDuration(conf.ReadYourWritesTtl)
+	if err != nil {
+		return nil, fmt.Errorf("invalid read_your_writes_ttl: %w", err)
+	}
+	if !conf.HandleDoneWait {
+		//no done messages are received --> entries are only removed by ttl
+		return overlay.New(ttl, nil), nil
+	}
+	result := overlay.New(ttl, conf.DoneHandler)
+	result.Subscribe(ctx)
+	return result, nil
+}
+
+// publisherFor returns a publisher which remembers published resources in the read-your-writes overlay for the requesting user
+func (this *Controller) publisherFor(token auth.Token) Publisher {
+	if this.overlay == nil {
+		return this.publisher
+	}
+	return &overlayPublisher{Publisher: this.publisher, overlay: this.overlay, config: this.config, userId: token.GetUserId()}
+}
+
+// overlayCom answers Get* calls from the read-your-writes overlay, if the requesting user has recently written the resource
+type overlayCom struct {
+	Com
+	overlay *overlay.Overlay
+	config  config.Config
+}
+
+func newOverlayCom(c Com, o *overlay.Overlay, conf config.Config) Com {
+	return &overlayCom{Com: c, overlay: o, config: conf}
+}
+
+func (this *overlayCom) withContext(ctx context.Context) Com {
+	return newOverlayCom(comWithContext(this.Com, ctx), this.overlay, this.config)
+}
+
+func getFromOverlay[T any](o *overlay.Overlay, token auth.Token, kind string, id string) (result T, found bool, err error, code int) {
+	if strings.Contains(id, com.Seperator) {
+		//id modifiers are only interpreted by the device-repository
+		return result, false, nil, http.StatusOK
+	}
+	result, found, deleted := overlay.Get[T](o, token.GetUserId(), kind, id)
+	if deleted {
+		return result, true, errors.New("not found"), http.StatusNotFound
+	}
+	return result, found, nil, http.StatusOK
+}
+
+func (this *overlayCom) GetDevice(token auth.Token, id string) (models.Device, error, int) {
+	device, found, err, code := getFromOverlay[models.Device](this.overlay, token, this.config.DeviceTopic, id)
+	if !found {
+		return this.Com.GetDevice(token, id)
+	}
+	return device, err, code
+}
+
+func (this *overlayCom) GetHub(token auth.Token, id string) (models.Hub, error, int) {
+	hub, found, err, code := getFromOverlay[models.Hub](this.overlay, token, this.config.HubTopic, id)
+	if !found {
+		return this.Com.GetHub(token, id)
+	}
+	return hub, err, code
+}
+
+func (this *overlayCom) GetTechnicalDeviceGroup(token auth.Token, id string) (models.DeviceGroup, error, int) {
+	dg, found, err, code := getFromOverlay[models.DeviceGroup](this.overlay, token, this.config.DeviceGroupTopic, id)
+	if !found {
+		return this.Com.GetTechnicalDeviceGroup(token, id)
+	}
+	return dg, err, code
+}
+
+func (this *overlayCom) GetLocation(token auth.Token, id string) (models.Location, error, int) {
+	location, found, err, code := getFromOverlay[models.Location](this.overlay, token, this.config.LocationTopic, id)
+	if !found {
+		return this.Com.GetLocation(token, id)
+	}
+	return location, err, code
+}
+
+func (this *overlayCom) GetDeviceType(token auth.Token, id string) (models.DeviceType, error, int) {
+	dt, found, err, code := getFromOverlay[models.DeviceType](this.overlay, token, this.config.DeviceTypeTopic, id)
+	if !found {
+		return this.Com.GetDeviceType(token, id)
+	}
+	return dt, err, code
+}
+
+func (this *overlayCom) GetProtocol(token auth.Token, id string) (models.Protocol, error, int) {
+	protocol, found, err, code := getFromOverlay[models.Protocol](this.overlay, token, this.config.ProtocolTopic, id)
+	if !found {
+		return this.Com.GetProtocol(token, id)
+	}
+	return protocol, err, code
+}
+
+func (this *overlayCom) GetAspect(token auth.Token, id string) (models.Aspect, error, int) {
+	aspect, found, err, code := getFromOverlay[models.Aspect](this.overlay, token, this.config.AspectTopic, id)
+	if !found {
+		return this.Com.GetAspect(token, id)
+	}
+	return aspect, err, code
+}
+
+func (this *overlayCom) GetFunction(token auth.Token, id string) (models.Function, error, int) {
+	function, found, err, code := getFromOverlay[models.Function](this.overlay, token, this.config.FunctionTopic, id)
+	if !found {
+		return this.Com.GetFunction(token, id)
+	}
+	return function, err, code
+}
+
+func (this *overlayCom) GetConcept(token auth.Token, id string) (models.Concept, error, int) {
+	concept, found, err, code := getFromOverlay[models.Concept](this.overlay, token, this.config.ConceptTopic, id)
+	if !found {
+		return this.Com.GetConcept(token, id)
+	}
+	return concept, err, code
+}
+
+func (this *overlayCom) GetCharacteristic(token auth.Token, id string) (models.Characteristic, error, int) {
+	characteristic, found, err, code := getFromOverlay[models.Characteristic](this.overlay, token, this.config.CharacteristicTopic, id)
+	if !found {
+		return this.Com.GetCharacteristic(token, id)
+	}
+	return characteristic, err, code
+}
+
+func (this *overlayCom) GetDeviceClass(token auth.Token, id string) (models.DeviceClass, error, int) {
+	deviceClass, found, err, code := getFromOverlay[models.DeviceClass](this.overlay, token, this.config.DeviceClassTopic, id)
+	if !found {
+		return this.Com.GetDeviceClass(token, id)
+	}
+	return deviceClass, err, code
+}
+
+// overlayPublisher remembers resources before they are published, so that a fast done message can not be missed,
+// and restores the previous entries if the publish fails
+type overlayPublisher struct {
+	Publisher
+	overlay *overlay.Overlay
+	config  config.Config
+	userId  string
+}
+
+func (this *overlayPublisher) put(kind string, id string, value interface{}, publish func() error) error {
+	undo := this.overlay.Put(this.userId, kind, id, value)
+	err := publish()
+	if err != nil {
+		undo()
+	}
+	return err
+}
+
+func (this *overlayPublisher) delete(kind string, id string, publish func() error) error {
+	undo := this.overlay.Delete(this.userId, kind, id)
+	err := publish()
+	if err != nil {
+		undo()
+	}
+	return err
+}
+
+func (this *overlayPublisher) PublishDevice(device models.Device, userID string) error {
+	return this.put(this.config.DeviceTopic, device.Id, device, func() error {
+		return this.Publisher.PublishDevice(device, userID)
+	})
+}
+
+func (this *overlayPublisher) PublishDeviceDelete(id string, userID string) error {
+	return this.delete(this.config.DeviceTopic, id, func() error {
+		return this.Publisher.PublishDeviceDelete(id, userID)
+	})
+}
+
+func (this *overlayPublisher) PublishDeviceType(dt models.DeviceType, userID string) error {
+	return this.put(this.config.DeviceTypeTopic, dt.Id, dt, func() error {
+		return this.Publisher.PublishDeviceType(dt, userID)
+	})
+}
+
+func (this *overlayPublisher) PublishDeviceTypeDelete(id string, userID string) error {
+	return this.delete(this.config.DeviceTypeTopic, id, func() error {
+		return this.Publisher.PublishDeviceTypeDelete(id, userID)
+	})
+}
+
+func (this *overlayPublisher) PublishDeviceGroup(dg models.DeviceGroup, userID string) error {
+	return this.put(this.config.DeviceGroupTopic, dg.Id, dg, func() error {
+		return this.Publisher.PublishDeviceGroup(dg, userID)
+	})
+}
+
+func (this *overlayPublisher) PublishDeviceGroupDelete(id string, userID string) error {
+	return this.delete(this.config.DeviceGroupTopic, id, func() error {
+		return this.Publisher.PublishDeviceGroupDelete(id, userID)
+	})
+}
+
+func (this *overlayPublisher) PublishProtocol(protocol models.Protocol, userID string) error {
+	return this.put(this.config.ProtocolTopic, protocol.Id, protocol, func() error {
+		return this.Publisher.PublishProtocol(protocol, userID)
+	})
+}
+
+func (this *overlayPublisher) PublishProtocolDelete(id string, userID string) error {
+	return this.delete(this.config.ProtocolTopic, id, func() error {
+		return this.Publisher.PublishProtocolDelete(id, userID)
+	})
+}
+
+func (this *overlayPublisher) PublishHub(hub models.Hub, userID string) error {
+	return this.put(this.config.HubTopic, hub.Id, hub, func() error {
+		return this.Publisher.PublishHub(hub, userID)
+	})
+}
+
+func (this *overlayPublisher) PublishHubDelete(id string, userID string) error {
+	return this.delete(this.config.HubTopic, id, func() error {
+		return this.Publisher.PublishHubDelete(id, userID)
+	})
+}
+
+func (this *overlayPublisher) PublishConcept(concept models.Concept, userID string) error {
+	return this.put(this.config.ConceptTopic, concept.Id, concept, func() error {
+		return this.Publisher.PublishConcept(concept, userID)
+	})
+}
+
+func (this *overlayPublisher) PublishConceptDelete(id string, userID string) error {
+	return this.delete(this.config.ConceptTopic, id, func() error {
+		return this.Publisher.PublishConceptDelete(id, userID)
+	})
+}
+
+func (this *overlayPublisher) PublishCharacteristic(characteristic models.Characteristic, userID string) error {
+	return this.put(this.config.CharacteristicTopic, characteristic.Id, characteristic, func() error {
+		return this.Publisher.PublishCharacteristic(characteristic, userID)
+	})
+}
+
+func (this *overlayPublisher) PublishCharacteristicDelete(id string, userID string) error {
+	return this.delete(this.config.CharacteristicTopic, id, func() error {
+		return this.Publisher.PublishCharacteristicDelete(id, userID)
+	})
+}
+
+func (this *overlayPublisher) PublishAspect(aspect models.Aspect, userID string) error {
+	return this.put(this.config.AspectTopic, aspect.Id, aspect, func() error {
+		return this.Publisher.PublishAspect(aspect, userID)
+	})
+}
+
+func (this *overlayPublisher) PublishAspectDelete(id string, userID string) error {
+	return this.delete(this.config.AspectTopic, id, func() error {
+		return this.Publisher.PublishAspectDelete(id, userID)
+	})
+}
+
+func (this *overlayPublisher) PublishFunction(function models.Function, userID string) error {
+	return this.put(this.config.FunctionTopic, function.Id, function, func() error {
+		return this.Publisher.PublishFunction(function, userID)
+	})
+}
+
+func (this *overlayPublisher) PublishFunctionDelete(id string, userID string) error {
+	return this.delete(this.config.FunctionTopic, id, func() error {
+		return this.Publisher.PublishFunctionDelete(id, userID)
+	})
+}
+
+func (this *overlayPublisher) PublishDeviceClass(deviceClass models.DeviceClass, userID string) error {
+	return this.put(this.config.DeviceClassTopic, deviceClass.Id, deviceClass, func() error {
+		return this.Publisher.PublishDeviceClass(deviceClass, userID)
+	})
+}
+
+func (this *overlayPublisher) PublishDeviceClassDelete(id string, userID string) error {
+	return this.delete(this.config.DeviceClassTopic, id, func() error {
+		return this.Publisher.PublishDeviceClassDelete(id, userID)
+	})
+}
+
+func (this *overlayPublisher) PublishLocation(location models.Location, userID string) error {
+	return this.put(this.config.LocationTopic, location.Id, location, func() error {
+		return this.Publisher.PublishLocation(location, userID)
+	})
+}
+
+func (this *overlayPublisher) PublishLocationDelete(id string, userID string) error {
+	return this.delete(this.config.LocationTopic, id, func() error {
+		return this.Publisher.PublishLocationDelete(id, userID)
+	})
+}
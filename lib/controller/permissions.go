@@ -0,0 +1,139 @@
+/*
+ * Copyright 2024 InfAI (CC SES)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package controller
+
+import (
+	"errors"
+	"github.com/SENERGY-Platform/device-manager/lib/auth"
+	"github.com/SENERGY-Platform/device-manager/lib/controller/com"
+	"github.com/SENERGY-Platform/device-manager/lib/model"
+	"maps"
+	"net/http"
+)
+
+// ReadPermissions returns the permissions of a device, hub, device-group, location or device-type; topic identifies the kind (e.g. config.DeviceTopic)
+func (this *Controller) ReadPermissions(token auth.Token, topic string, id string) (result model.ResourcePermissions, err error, code int) {
+	err, code = this.checkAdministrate(token, topic, id)
+	if err != nil {
+		return result, err, code
+	}
+	resource, err, code := this.com.GetResourceRights(token, topic, id)
+	if err != nil {
+		return result, err, code
+	}
+	return resource.ResourcePermissions, nil, http.StatusOK
+}
+
+// SetPermissions replaces the permissions of a resource
+func (this *Controller) SetPermissions(token auth.Token, topic string, id string, permissions model.ResourcePermissions) (result model.ResourcePermissions, err error, code int) {
+	err, code = this.checkAdministrate(token, topic, id)
+	if err != nil {
+		return result, err, code
+	}
+	return this.setPermissions(token, topic, id, permissions)
+}
+
+// SetSubjectPermissions sets the permissions of a single user or group (subject = model.PermissionSubjectUsers | model.PermissionSubjectGroups)
+// and keeps the permissions of all other subjects; nil permissions remove the subject
+func (this *Controller) SetSubjectPermissions(token auth.Token, topic string, id string, subject string, subjectId string, permissions *model.PermissionsMap) (result model.ResourcePermissions, err error, code int) {
+	if subjectId == "" {
+		return result, errors.New("missing subject id"), http.StatusBadRequest
+	}
+	err, code = this.checkAdministrate(token, topic, id)
+	if err != nil {
+		return result, err, code
+	}
+	resource, err, code := this.com.GetResourceRights(token, topic, id)
+	if err != nil {
+		return result, err, code
+	}
+	result = resource.ResourcePermissions
+	var subjects *map[string]model.PermissionsMap
+	switch subject {
+	case model.PermissionSubjectUsers:
+		subjects = &result.UserPermissions
+	case model.PermissionSubjectGroups:
+		subjects = &result.GroupPermissions
+	default:
+		return result, errors.New("unknown permission subject " + subject), http.StatusBadRequest
+	}
+	//the maps may be shared with the com layer
+	*subjects = maps.Clone(*subjects)
+	if *subjects == nil {
+		*subjects = map[string]model.PermissionsMap{}
+	}
+	if permissions == nil {
+		delete(*subjects, subjectId)
+	} else {
+		(*subjects)[subjectId] = *permissions
+	}
+	return this.setPermissions(token, topic, id, result)
+}
+
+func (this *Controller) setPermissions(token auth.Token, topic string, id string, permissions model.ResourcePermissions) (result model.ResourcePermissions, err error, code int) {
+	if !com.ContainsOtherAdmin(permissions.UserPermissions, "") {
+		return result, errors.New("at least one user must keep administrate rights"), http.StatusBadRequest
+	}
+	owner := ""
+	if topic == this.config.DeviceTopic || topic == this.config.HubTopic {
+		owner, err, code = this.owner(token, topic, id)
+		if err != nil {
+			return result, err, code
+		}
+	}
+	if owner != "" && !permissions.UserPermissions[owner].Administrate {
+		return result, errors.New("the owner " + owner + " must keep administrate rights; change the owner first"), http.StatusBadRequest
+	}
+	result, err, code = this.com.SetPermission(token.Jwt(), topic, id, withEmptyMaps(permissions))
+	if err != nil {
+		return result, err, code
+	}
+	this.propagateSharing(&token, topic, id, nil)
+	return result, nil, http.StatusOK
+}
+
+// withEmptyMaps replaces nil permission maps, which permissions-v2 does not accept
+func withEmptyMaps(permissions model.ResourcePermissions) model.ResourcePermissions {
+	if permissions.UserPermissions == nil {
+		permissions.UserPermissions = map[string]model.PermissionsMap{}
+	}
+	if permissions.GroupPermissions == nil {
+		permissions.GroupPermissions = map[string]model.PermissionsMap{}
+	}
+	if permissions.RolePermissions == nil {
+		permissions.RolePermissions = map[string]model.PermissionsMap{}
+	}
+	return permissions
+}
+
+// checkAdministrate checks the administrate right of token on a resource with permission management
+func (this *Controller) checkAdministrate(token auth.Token, topic string, id string) (err error, code int) {
+	switch topic {
+	case this.config.DeviceTopic:
+		return this.com.PermissionCheckForDevice(token, id, "a")
+	case this.config.HubTopic:
+		return this.com.PermissionCheckForHub(token, id, "a")
+	case this.config.DeviceGroupTopic:
+		return this.com.PermissionCheckForDeviceGroup(token, id, "a")
+	case this.config.LocationTopic:
+		return this.com.PermissionCheckForLocation(token, id, "a")
+	case this.config.DeviceTypeTopic:
+		return this.com.PermissionCheckForDeviceType(token, id, "a")
+	default:
+		return errors.New("no permission management for " + topic), http.StatusBadRequest
+	}
+}
@@ -0,0 +1,60 @@
+/*
+ * Copyright 2024 InfAI (CC SES)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package controller
+
+import (
+	"context"
+	"fmt"
+	"github.com/SENERGY-Platform/device-manager/lib/auth"
+	"github.com/SENERGY-Platform/device-manager/lib/config"
+	"github.com/SENERGY-Platform/device-manager/lib/policy"
+	"net/http"
+	"time"
+)
+
+// newPolicy returns nil if config.PolicyFile is empty or "-"
+func newPolicy(ctx context.Context, conf config.Config) (*policy.Store, error) {
+	if conf.PolicyFile == "" || conf.PolicyFile == "-" {
+		return nil, nil
+	}
+	interval := 10 * time.Second
+	if conf.PolicyReloadInterval != "" {
+		var err error
+		interval, err = time.ParseDuration(conf.PolicyReloadInterval)
+		if err != nil {
+			return nil, fmt.Errorf("invalid policy_reload_interval: %w", err)
+		}
+	}
+	result, err := policy.NewStore(conf.PolicyFile)
+	if err != nil {
+		return nil, err
+	}
+	result.Watch(ctx, interval)
+	return result, nil
+}
+
+// checkPolicy returns 403 if the realm roles of token may not do action on kind; kind is the topic of the resource
+func (this *Controller) checkPolicy(token auth.Token, kind string, action string) (error, int) {
+	if this.policy == nil {
+		return nil, http.StatusOK
+	}
+	err := this.policy.Get().Check(token.GetRoles(), kind, action)
+	if err != nil {
+		return err, http.StatusForbidden
+	}
+	return nil, http.StatusOK
+}
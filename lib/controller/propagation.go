@@ -0,0 +1,258 @@
+/*
+ * Copyright 2024 InfAI (CC SES)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package controller
+
+import (
+	"errors"
+	"fmt"
+	"github.com/SENERGY-Platform/device-manager/lib/auth"
+	"github.com/SENERGY-Platform/device-manager/lib/config"
+	"github.com/SENERGY-Platform/device-manager/lib/controller/com"
+	"github.com/SENERGY-Platform/device-manager/lib/model"
+	"github.com/SENERGY-Platform/device-manager/lib/propagation"
+	"github.com/SENERGY-Platform/models/go/models"
+	"github.com/SENERGY-Platform/permissions-v2/pkg/client"
+	"log"
+	"maps"
+	"net/http"
+	"slices"
+	"strings"
+)
+
+var errPropagationDisabled = errors.New("propagation is disabled, propagation_file is not set")
+
+// newPropagation returns nil if config.PropagationFile is empty; grants must be revocable by any replica
+func newPropagation(conf config.Config) (*propagation.Store, error) {
+	if conf.PropagationFile == "" || conf.PropagationFile == "-" {
+		return nil, nil
+	}
+	return propagation.New(conf.PropagationFile)
+}
+
+func (this *Controller) ReadPropagation(token auth.Token, topic string, id string) (result model.Propagation, err error, code int) {
+	if this.propagation == nil {
+		return result, errPropagationDisabled, http.StatusNotFound
+	}
+	if topic != this.config.LocationTopic && topic != this.config.DeviceGroupTopic {
+		return result, errors.New("no propagation for " + topic), http.StatusBadRequest
+	}
+	err, code = this.checkAdministrate(token, topic, id)
+	if err != nil {
+		return result, err, code
+	}
+	result, ok := this.propagation.Get(topic, id)
+	if !ok {
+		result = model.Propagation{Kind: topic, Id: id, Grants: map[string]map[string]model.PropagatedGrant{}}
+	}
+	return result, nil, http.StatusOK
+}
+
+// SetPropagation enables or disables the propagation of the user permissions of a location or device-group to its member devices.
+// rights are only granted on devices the requesting user may administrate; disabling revokes all propagated rights.
+func (this *Controller) SetPropagation(token auth.Token, topic string, id string, request model.PropagationRequest) (result model.Propagation, err error, code int) {
+	if this.propagation == nil {
+		return result, errPropagationDisabled, http.StatusNotFound
+	}
+	if topic != this.config.LocationTopic && topic != this.config.DeviceGroupTopic {
+		return result, errors.New("no propagation for " + topic), http.StatusBadRequest
+	}
+	err, code = this.checkAdministrate(token, topic, id)
+	if err != nil {
+		return result, err, code
+	}
+	err = this.propagation.Update(topic, id, func(propagation *model.Propagation) error {
+		propagation.Enabled = request.Enabled
+		return this.syncPropagation(&token, propagation, nil)
+	})
+	if err != nil {
+		return result, err, http.StatusInternalServerError
+	}
+	return this.ReadPropagation(token, topic, id)
+}
+
+// propagateSharing applies changed permissions or members of a location or device-group with enabled propagation to the member devices.
+// members nil reads the current members. token is the user who changed the container; nil only revokes.
+// errors are logged, because the change of the container is already done.
+func (this *Controller) propagateSharing(token *auth.Token, topic string, id string, members []string) {
+	if this.propagation == nil || (topic != this.config.LocationTopic && topic != this.config.DeviceGroupTopic) {
+		return
+	}
+	if _, ok := this.propagation.Get(topic, id); !ok {
+		return
+	}
+	err := this.propagation.Update(topic, id, func(propagation *model.Propagation) error {
+		return this.syncPropagation(token, propagation, members)
+	})
+	if err != nil {
+		log.Println("ERROR: unable to propagate sharing of", topic, id, err)
+	}
+}
+
+// stopPropagation revokes all propagated rights of a deleted location or device-group
+func (this *Controller) stopPropagation(topic string, id string) {
+	if this.propagation == nil {
+		return
+	}
+	if _, ok := this.propagation.Get(topic, id); !ok {
+		return
+	}
+	err := this.propagation.Update(topic, id, func(propagation *model.Propagation) error {
+		propagation.Enabled = false
+		return this.syncPropagation(nil, propagation, nil)
+	})
+	if err != nil {
+		log.Println("ERROR: unable to revoke propagated sharing of", topic, id, err)
+	}
+}
+
+func (this *Controller) syncPropagation(token *auth.Token, propagation *model.Propagation, members []string) error {
+	adminToken, err := auth.Parse(client.InternalAdminToken)
+	if err != nil {
+		return err
+	}
+	desired := map[string]map[string]model.PermissionsMap{} //device id -> user id -> rights
+	if propagation.Enabled {
+		if members == nil {
+			members, err = this.members(adminToken, propagation.Kind, propagation.Id)
+			if err != nil {
+				return err
+			}
+		}
+		rights, err, code := this.com.GetResourceRights(adminToken, propagation.Kind, propagation.Id)
+		if err != nil && code != http.StatusNotFound {
+			return err
+		}
+		for _, deviceId := range members {
+			deviceId = strings.SplitN(deviceId, com.Seperator, 2)[0]
+			desired[deviceId] = map[string]model.PermissionsMap{}
+			for userId, permissions := range rights.UserPermissions {
+				//administrate rights are not propagated
+				permissions.Administrate = false
+				if permissions.Read || permissions.Write || permissions.Execute {
+					desired[deviceId][userId] = permissions
+				}
+			}
+		}
+	}
+	deviceIds := slices.Collect(maps.Keys(desired))
+	for deviceId := range propagation.Grants {
+		if _, ok := desired[deviceId]; !ok {
+			deviceIds = append(deviceIds, deviceId)
+		}
+	}
+	slices.Sort(deviceIds)
+	errs := []error{}
+	for _, deviceId := range deviceIds {
+		err = this.syncDevicePropagation(token, adminToken, propagation, deviceId, desired[deviceId])
+		if err != nil {
+			errs = append(errs, fmt.Errorf("device %v: %w", deviceId, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// syncDevicePropagation revokes grants which are no longer desired, by removing the rights added by the grant,
+// so that rights granted otherwise in the meantime are kept, and grants desired rights.
+// the grants of the device are only recorded if permissions-v2 accepted them
+func (this *Controller) syncDevicePropagation(token *auth.Token, adminToken auth.Token, propagation *model.Propagation, deviceId string, desired map[string]model.PermissionsMap) error {
+	rights, err, code := this.com.GetResourceRights(adminToken, this.config.DeviceTopic, deviceId)
+	if code == http.StatusNotFound {
+		delete(propagation.Grants, deviceId)
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	permissions := withEmptyMaps(rights.ResourcePermissions)
+	permissions.UserPermissions = maps.Clone(permissions.UserPermissions)
+	grants := maps.Clone(propagation.Grants[deviceId])
+	if grants == nil {
+		grants = map[string]model.PropagatedGrant{}
+	}
+	changed := false
+	for userId, grant := range grants {
+		want, ok := desired[userId]
+		if ok && (want == grant.Granted || token == nil) {
+			continue
+		}
+		if current, ok := permissions.UserPermissions[userId]; ok {
+			remaining := withoutRights(current, grant.Added)
+			if remaining == (model.PermissionsMap{}) {
+				delete(permissions.UserPermissions, userId)
+			} else {
+				permissions.UserPermissions[userId] = remaining
+			}
+		}
+		delete(grants, userId)
+		changed = true
+	}
+	allowed := false
+	if token != nil {
+		err, _ = this.checkAdministrate(*token, this.config.DeviceTopic, deviceId)
+		allowed = err == nil
+	}
+	for userId, want := range desired {
+		if _, ok := grants[userId]; ok || !allowed {
+			continue
+		}
+		current := permissions.UserPermissions[userId]
+		added := model.PermissionsMap{
+			Read:    want.Read && !current.Read,
+			Write:   want.Write && !current.Write,
+			Execute: want.Execute && !current.Execute,
+		}
+		granted := current
+		granted.Read = current.Read || want.Read
+		granted.Write = current.Write || want.Write
+		granted.Execute = current.Execute || want.Execute
+		permissions.UserPermissions[userId] = granted
+		grants[userId] = model.PropagatedGrant{Granted: want, Added: added}
+		changed = true
+	}
+	if !changed {
+		return nil
+	}
+	_, err, _ = this.com.SetPermission(client.InternalAdminToken, this.config.DeviceTopic, deviceId, permissions)
+	if err != nil {
+		return err
+	}
+	if len(grants) == 0 {
+		delete(propagation.Grants, deviceId)
+	} else {
+		propagation.Grants[deviceId] = grants
+	}
+	return nil
+}
+
+// members returns the device ids of a location or device-group; none if it does not exist
+func (this *Controller) members(token auth.Token, topic string, id string) (result []string, err error) {
+	var code int
+	switch topic {
+	case this.config.LocationTopic:
+		var location models.Location
+		location, err, code = this.com.GetLocation(token, id)
+		result = location.DeviceIds
+	case this.config.DeviceGroupTopic:
+		var group models.DeviceGroup
+		group, err, code = this.com.GetTechnicalDeviceGroup(token, id)
+		result = group.DeviceIds
+	}
+	if code == http.StatusNotFound {
+		return []string{}, nil
+	}
+	return result, err
+}
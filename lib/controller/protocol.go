@@ -21,6 +21,7 @@ import (
 	"github.com/SENERGY-Platform/device-manager/lib/auth"
 	"github.com/SENERGY-Platform/device-manager/lib/controller/com"
 	"github.com/SENERGY-Platform/device-manager/lib/model"
+	"github.com/SENERGY-Platform/device-manager/lib/policy"
 	"github.com/SENERGY-Platform/models/go/models"
 	"github.com/SENERGY-Platform/service-commons/pkg/donewait"
 	"net/http"
@@ -31,6 +32,9 @@ func (this *Controller) ReadProtocol(token auth.Token, id string) (protocol mode
 }
 
 func (this *Controller) PublishProtocolCreate(token auth.Token, protocol models.Protocol, options model.ProtocolUpdateOptions) (models.Protocol, error, int) {
+	if err, code := this.checkPolicy(token, this.config.ProtocolTopic, policy.Create); err != nil {
+		return protocol, err, code
+	}
 	if !token.IsAdmin() {
 		return protocol, errors.New("access denied"), http.StatusForbidden
 	}
@@ -46,7 +50,7 @@ func (this *Controller) PublishProtocolCreate(token auth.Token, protocol models.
 		Command:      "PUT",
 	})
 
-	err = this.publisher.PublishProtocol(protocol, token.GetUserId())
+	err = this.publisherFor(token).PublishProtocol(protocol, token.GetUserId())
 	if err != nil {
 		return protocol, err, http.StatusInternalServerError
 	}
@@ -60,6 +64,9 @@ func (this *Controller) PublishProtocolCreate(token auth.Token, protocol models.
 }
 
 func (this *Controller) PublishProtocolUpdate(token auth.Token, id string, protocol models.Protocol, options model.ProtocolUpdateOptions) (models.Protocol, error, int) {
+	if err, code := this.checkPolicy(token, this.config.ProtocolTopic, policy.Update); err != nil {
+		return protocol, err, code
+	}
 	if !token.IsAdmin() {
 		return protocol, errors.New("access denied"), http.StatusForbidden
 	}
@@ -82,7 +89,7 @@ func (this *Controller) PublishProtocolUpdate(token auth.Token, id string, proto
 		Command:      "PUT",
 	})
 
-	err = this.publisher.PublishProtocol(protocol, token.GetUserId())
+	err = this.publisherFor(token).PublishProtocol(protocol, token.GetUserId())
 	if err != nil {
 		return protocol, err, http.StatusInternalServerError
 	}
@@ -96,6 +103,9 @@ func (this *Controller) PublishProtocolUpdate(token auth.Token, id string, proto
 }
 
 func (this *Controller) PublishProtocolDelete(token auth.Token, id string, options model.ProtocolDeleteOptions) (error, int) {
+	if err, code := this.checkPolicy(token, this.config.ProtocolTopic, policy.Delete); err != nil {
+		return err, code
+	}
 	if !token.IsAdmin() {
 		return errors.New("access denied"), http.StatusForbidden
 	}
@@ -103,14 +113,20 @@ func (this *Controller) PublishProtocolDelete(token auth.Token, id string, optio
 		return err, http.StatusBadRequest
 	}
 
+	undoTrash, err, code := this.moveToTrash(token, this.config.ProtocolTopic, id)
+	if err != nil {
+		return err, code
+	}
+
 	wait := this.optionalWait(options.Wait, donewait.DoneMsg{
 		ResourceKind: this.config.ProtocolTopic,
 		ResourceId:   id,
 		Command:      "DELETE",
 	})
 
-	err := this.publisher.PublishProtocolDelete(id, token.GetUserId())
+	err = this.publisherFor(token).PublishProtocolDelete(id, token.GetUserId())
 	if err != nil {
+		undoTrash()
 		return err, http.StatusInternalServerError
 	}
 
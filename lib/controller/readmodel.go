@@ -0,0 +1,170 @@
+/*
+ * Copyright 2024 InfAI (CC SES)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package controller
+
+import (
+	"context"
+	"errors"
+	"github.com/SENERGY-Platform/device-manager/lib/auth"
+	"github.com/SENERGY-Platform/device-manager/lib/config"
+	"github.com/SENERGY-Platform/device-manager/lib/controller/com"
+	"github.com/SENERGY-Platform/device-manager/lib/model"
+	"github.com/SENERGY-Platform/device-manager/lib/readmodel"
+	"github.com/SENERGY-Platform/models/go/models"
+	"net/http"
+	"strings"
+)
+
+func (this *Controller) GetReadModelStatus(token auth.Token) (result []model.ReadModelTopicStatus, err error, code int) {
+	if this.readmodel == nil {
+		return result, errors.New("read model is disabled"), http.StatusNotFound
+	}
+	return this.readmodel.Status(), nil, http.StatusOK
+}
+
+// readModelCom answers Get* calls from the local read model while it is fresh
+// and falls back to the wrapped Com (device-repository) otherwise.
+// permissions are still checked by permissions-v2 where the device-repository would check them.
+type readModelCom struct {
+	Com
+	readmodel *readmodel.ReadModel
+	config    config.Config
+}
+
+func newReadModelCom(c Com, readModel *readmodel.ReadModel, conf config.Config) Com {
+	return &readModelCom{Com: c, readmodel: readModel, config: conf}
+}
+
+func (this *readModelCom) withContext(ctx context.Context) Com {
+	return newReadModelCom(comWithContext(this.Com, ctx), this.readmodel, this.config)
+}
+
+func getFromReadModel[T any](readModel *readmodel.ReadModel, topic string, id string) (result T, ok bool) {
+	if strings.Contains(id, com.Seperator) {
+		//id modifiers are only interpreted by the device-repository
+		return result, false
+	}
+	return readmodel.Get[T](readModel, topic, id)
+}
+
+func (this *readModelCom) GetDevice(token auth.Token, id string) (models.Device, error, int) {
+	device, ok := getFromReadModel[models.Device](this.readmodel, this.config.DeviceTopic, id)
+	if !ok {
+		return this.Com.GetDevice(token, id)
+	}
+	err, code := this.Com.PermissionCheckForDevice(token, id, "r")
+	if err != nil {
+		return models.Device{}, err, code
+	}
+	return device, nil, http.StatusOK
+}
+
+func (this *readModelCom) GetHub(token auth.Token, id string) (models.Hub, error, int) {
+	hub, ok := getFromReadModel[models.Hub](this.readmodel, this.config.HubTopic, id)
+	if !ok {
+		return this.Com.GetHub(token, id)
+	}
+	err, code := this.Com.PermissionCheckForHub(token, id, "r")
+	if err != nil {
+		return models.Hub{}, err, code
+	}
+	return hub, nil, http.StatusOK
+}
+
+func (this *readModelCom) GetTechnicalDeviceGroup(token auth.Token, id string) (models.DeviceGroup, error, int) {
+	dg, ok := getFromReadModel[models.DeviceGroup](this.readmodel, this.config.DeviceGroupTopic, id)
+	if !ok {
+		return this.Com.GetTechnicalDeviceGroup(token, id)
+	}
+	err, code := this.Com.PermissionCheckForDeviceGroup(token, id, "r")
+	if err != nil {
+		return models.DeviceGroup{}, err, code
+	}
+	return dg, nil, http.StatusOK
+}
+
+func (this *readModelCom) GetLocation(token auth.Token, id string) (models.Location, error, int) {
+	location, ok := getFromReadModel[models.Location](this.readmodel, this.config.LocationTopic, id)
+	if !ok {
+		return this.Com.GetLocation(token, id)
+	}
+	err, code := this.Com.PermissionCheckForLocation(token, id, "r")
+	if err != nil {
+		return models.Location{}, err, code
+	}
+	return location, nil, http.StatusOK
+}
+
+func (this *readModelCom) GetDeviceType(token auth.Token, id string) (models.DeviceType, error, int) {
+	dt, ok := getFromReadModel[models.DeviceType](this.readmodel, this.config.DeviceTypeTopic, id)
+	if !ok {
+		return this.Com.GetDeviceType(token, id)
+	}
+	err, code := this.Com.PermissionCheckForDeviceType(token, id, "r")
+	if err != nil {
+		return models.DeviceType{}, err, code
+	}
+	return dt, nil, http.StatusOK
+}
+
+func (this *readModelCom) GetProtocol(token auth.Token, id string) (models.Protocol, error, int) {
+	protocol, ok := getFromReadModel[models.Protocol](this.readmodel, this.config.ProtocolTopic, id)
+	if !ok {
+		return this.Com.GetProtocol(token, id)
+	}
+	return protocol, nil, http.StatusOK
+}
+
+func (this *readModelCom) GetAspect(token auth.Token, id string) (models.Aspect, error, int) {
+	aspect, ok := getFromReadModel[models.Aspect](this.readmodel, this.config.AspectTopic, id)
+	if !ok {
+		return this.Com.GetAspect(token, id)
+	}
+	return aspect, nil, http.StatusOK
+}
+
+func (this *readModelCom) GetFunction(token auth.Token, id string) (models.Function, error, int) {
+	function, ok := getFromReadModel[models.Function](this.readmodel, this.config.FunctionTopic, id)
+	if !ok {
+		return this.Com.GetFunction(token, id)
+	}
+	return function, nil, http.StatusOK
+}
+
+func (this *readModelCom) GetConcept(token auth.Token, id string) (models.Concept, error, int) {
+	concept, ok := getFromReadModel[models.Concept](this.readmodel, this.config.ConceptTopic, id)
+	if !ok {
+		return this.Com.GetConcept(token, id)
+	}
+	return concept, nil, http.StatusOK
+}
+
+func (this *readModelCom) GetCharacteristic(token auth.Token, id string) (models.Characteristic, error, int) {
+	characteristic, ok := getFromReadModel[models.Characteristic](this.readmodel, this.config.CharacteristicTopic, id)
+	if !ok {
+		return this.Com.GetCharacteristic(token, id)
+	}
+	return characteristic, nil, http.StatusOK
+}
+
+func (this *readModelCom) GetDeviceClass(token auth.Token, id string) (models.DeviceClass, error, int) {
+	deviceClass, ok := getFromReadModel[models.DeviceClass](this.readmodel, this.config.DeviceClassTopic, id)
+	if !ok {
+		return this.Com.GetDeviceClass(token, id)
+	}
+	return deviceClass, nil, http.StatusOK
+}
@@ -0,0 +1,266 @@
+/*
+ * Copyright 2024 InfAI (CC SES)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package controller
+
+import (
+	"errors"
+	"fmt"
+	"github.com/SENERGY-Platform/device-manager/lib/auth"
+	"github.com/SENERGY-Platform/device-manager/lib/model"
+	"github.com/SENERGY-Platform/device-repository/lib/client"
+	"log"
+	"net/http"
+	"slices"
+	"time"
+)
+
+var ReplayDefaultBatchSize int64 = 1000
+
+type replayElement struct {
+	id      string
+	publish func() error
+}
+
+// lists one page of resources; if ids != nil, the lister may ignore limit/offset and return all matching resources at once (idsHandled)
+// or ignore ids and list all resources (!idsHandled)
+type replayLister func(ids []string, limit int64, offset int64) (elements []replayElement, idsHandled bool, err error, code int)
+
+// Replay reads all resources of the given kind (identified by its topic, e.g. config.DeviceTopic)
+// from the device-repository and republishes them as PUT commands.
+// progress may be nil and is called after every batch and once after the replay is finished.
+// the replay stops with the partial result, if the context of WithContext is canceled (e.g. by a disconnected client).
+func (this *Controller) Replay(token auth.Token, kind string, options model.ReplayOptions, progress func(model.ReplayProgress)) (result model.ReplayProgress, err error, code int) {
+	result = model.ReplayProgress{Kind: kind, DryRun: options.DryRun, Failed: []string{}}
+	if !token.IsAdmin() {
+		return result, errors.New("only admins may replay resources"), http.StatusForbidden
+	}
+	if progress == nil {
+		progress = func(model.ReplayProgress) {}
+	}
+	lister, err := this.getReplayLister(token, kind)
+	if err != nil {
+		return result, err, http.StatusBadRequest
+	}
+	batchSize := options.BatchSize
+	if batchSize <= 0 {
+		batchSize = ReplayDefaultBatchSize
+	}
+
+	ctx := this.context()
+	var limiter <-chan time.Time
+	if options.RatePerSecond > 0 && !options.DryRun {
+		ticker := time.NewTicker(time.Duration(float64(time.Second) / options.RatePerSecond))
+		defer ticker.Stop()
+		limiter = ticker.C
+	}
+
+	//listers may ignore limit/offset if ids are set --> ids are split into chunks of batchSize
+	idChunks := [][]string{nil}
+	if options.Ids != nil {
+		idChunks = slices.Collect(slices.Chunk(options.Ids, int(batchSize)))
+	}
+	for _, ids := range idChunks {
+		idsHandled := false
+		var offset int64 = 0
+		for {
+			var elements []replayElement
+			if ctx.Err() != nil {
+				return result, fmt.Errorf("replay canceled: %w", ctx.Err()), http.StatusRequestTimeout
+			}
+			elements, idsHandled, err, code = lister(ids, batchSize, offset)
+			if err != nil {
+				return result, err, code
+			}
+			offset += batchSize
+			for _, element := range elements {
+				if options.Ids != nil && !slices.Contains(options.Ids, element.id) {
+					continue
+				}
+				result.Listed++
+				if options.DryRun {
+					continue
+				}
+				if limiter != nil {
+					select {
+					case <-limiter:
+					case <-ctx.Done():
+						return result, fmt.Errorf("replay canceled: %w", ctx.Err()), http.StatusRequestTimeout
+					}
+				} else if ctx.Err() != nil {
+					return result, fmt.Errorf("replay canceled: %w", ctx.Err()), http.StatusRequestTimeout
+				}
+				err = element.publish()
+				if err != nil {
+					log.Println("ERROR: unable to replay", kind, element.id, err)
+					result.Failed = append(result.Failed, element.id)
+				} else {
+					result.Published++
+				}
+			}
+			progress(result)
+			if idsHandled || int64(len(elements)) < batchSize {
+				break
+			}
+		}
+		if ids != nil && !idsHandled {
+			//the lister ignores ids and has listed all resources, which are already filtered by options.Ids
+			break
+		}
+	}
+	result.Done = true
+	progress(result)
+	return result, nil, http.StatusOK
+}
+
+func (this *Controller) getReplayLister(token auth.Token, kind string) (replayLister, error) {
+	userId := token.GetUserId()
+	jwt := token.Jwt()
+	switch kind {
+	case this.config.DeviceTopic:
+		return func(ids []string, limit int64, offset int64) (elements []replayElement, idsHandled bool, err error, code int) {
+			list, err, code := this.com.ListDevices(jwt, client.DeviceListOptions{Ids: ids, Limit: limit, Offset: offset, SortBy: "id.asc"})
+			for _, e := range list {
+				element := e
+				owner := element.OwnerId
+				if owner == "" {
+					owner = userId
+				}
+				elements = append(elements, replayElement{id: element.Id, publish: func() error {
+					return this.publisher.PublishDevice(element, owner)
+				}})
+			}
+			return elements, ids != nil, err, code
+		}, nil
+	case this.config.HubTopic:
+		return func(ids []string, limit int64, offset int64) (elements []replayElement, idsHandled bool, err error, code int) {
+			list, err, code := this.com.ListHubs(jwt, client.HubListOptions{Ids: ids, Limit: limit, Offset: offset, SortBy: "id.asc"})
+			for _, e := range list {
+				element := e
+				owner := element.OwnerId
+				if owner == "" {
+					owner = userId
+				}
+				elements = append(elements, replayElement{id: element.Id, publish: func() error {
+					return this.publisher.PublishHub(element, owner)
+				}})
+			}
+			return elements, ids != nil, err, code
+		}, nil
+	case this.config.DeviceTypeTopic:
+		return func(ids []string, limit int64, offset int64) (elements []replayElement, idsHandled bool, err error, code int) {
+			list, err, code := this.com.ListDeviceTypes(jwt, client.DeviceTypeListOptions{Ids: ids, Limit: limit, Offset: offset, SortBy: "id.asc"})
+			for _, e := range list {
+				element := e
+				elements = append(elements, replayElement{id: element.Id, publish: func() error {
+					return this.publisher.PublishDeviceType(element, userId)
+				}})
+			}
+			return elements, ids != nil, err, code
+		}, nil
+	case this.config.DeviceGroupTopic:
+		return func(ids []string, limit int64, offset int64) (elements []replayElement, idsHandled bool, err error, code int) {
+			list, err, code := this.com.ListDeviceGroups(jwt, client.DeviceGroupListOptions{Ids: ids, Limit: limit, Offset: offset, SortBy: "id.asc"})
+			for _, e := range list {
+				element := e
+				elements = append(elements, replayElement{id: element.Id, publish: func() error {
+					return this.publisher.PublishDeviceGroup(element, userId)
+				}})
+			}
+			return elements, ids != nil, err, code
+		}, nil
+	case this.config.LocationTopic:
+		return func(ids []string, limit int64, offset int64) (elements []replayElement, idsHandled bool, err error, code int) {
+			list, err, code := this.com.ListLocations(jwt, client.LocationListOptions{Ids: ids, Limit: limit, Offset: offset, SortBy: "id.asc"})
+			for _, e := range list {
+				element := e
+				elements = append(elements, replayElement{id: element.Id, publish: func() error {
+					return this.publisher.PublishLocation(element, userId)
+				}})
+			}
+			return elements, ids != nil, err, code
+		}, nil
+	case this.config.ProtocolTopic:
+		return func(ids []string, limit int64, offset int64) (elements []replayElement, idsHandled bool, err error, code int) {
+			//protocols can not be filtered by id in the device-repository --> local filter in Replay()
+			list, err, code := this.com.ListProtocols(jwt, limit, offset, "id.asc")
+			for _, e := range list {
+				element := e
+				elements = append(elements, replayElement{id: element.Id, publish: func() error {
+					return this.publisher.PublishProtocol(element, userId)
+				}})
+			}
+			return elements, false, err, code
+		}, nil
+	case this.config.AspectTopic:
+		return func(ids []string, limit int64, offset int64) (elements []replayElement, idsHandled bool, err error, code int) {
+			list, err, code := this.com.ListAspects(client.AspectListOptions{Ids: ids, Limit: limit, Offset: offset, SortBy: "id.asc"})
+			for _, e := range list {
+				element := e
+				elements = append(elements, replayElement{id: element.Id, publish: func() error {
+					return this.publisher.PublishAspect(element, userId)
+				}})
+			}
+			return elements, ids != nil, err, code
+		}, nil
+	case this.config.FunctionTopic:
+		return func(ids []string, limit int64, offset int64) (elements []replayElement, idsHandled bool, err error, code int) {
+			list, err, code := this.com.ListFunctions(client.FunctionListOptions{Ids: ids, Limit: limit, Offset: offset, SortBy: "id.asc"})
+			for _, e := range list {
+				element := e
+				elements = append(elements, replayElement{id: element.Id, publish: func() error {
+					return this.publisher.PublishFunction(element, userId)
+				}})
+			}
+			return elements, ids != nil, err, code
+		}, nil
+	case this.config.ConceptTopic:
+		return func(ids []string, limit int64, offset int64) (elements []replayElement, idsHandled bool, err error, code int) {
+			list, err, code := this.com.ListConcepts(client.ConceptListOptions{Ids: ids, Limit: limit, Offset: offset, SortBy: "id.asc"})
+			for _, e := range list {
+				element := e
+				elements = append(elements, replayElement{id: element.Id, publish: func() error {
+					return this.publisher.PublishConcept(element, userId)
+				}})
+			}
+			return elements, ids != nil, err, code
+		}, nil
+	case this.config.CharacteristicTopic:
+		return func(ids []string, limit int64, offset int64) (elements []replayElement, idsHandled bool, err error, code int) {
+			list, err, code := this.com.ListCharacteristics(client.CharacteristicListOptions{Ids: ids, Limit: limit, Offset: offset, SortBy: "id.asc"})
+			for _, e := range list {
+				element := e
+				elements = append(elements, replayElement{id: element.Id, publish: func() error {
+					return this.publisher.PublishCharacteristic(element, userId)
+				}})
+			}
+			return elements, ids != nil, err, code
+		}, nil
+	case this.config.DeviceClassTopic:
+		return func(ids []string, limit int64, offset int64) (elements []replayElement, idsHandled bool, err error, code int) {
+			list, err, code := this.com.ListDeviceClasses(client.DeviceClassListOptions{Ids: ids, Limit: limit, Offset: offset, SortBy: "id.asc"})
+			for _, e := range list {
+				element := e
+				elements = append(elements, replayElement{id: element.Id, publish: func() error {
+					return this.publisher.PublishDeviceClass(element, userId)
+				}})
+			}
+			return elements, ids != nil, err, code
+		}, nil
+	default:
+		return nil, errors.New("unknown resource kind " + kind)
+	}
+}
@@ -0,0 +1,246 @@
+/*
+ * Copyright 2024 InfAI (CC SES)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package controller
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/SENERGY-Platform/device-manager/lib/audit"
+	"github.com/SENERGY-Platform/device-manager/lib/auth"
+	"github.com/SENERGY-Platform/device-manager/lib/config"
+	"github.com/SENERGY-Platform/device-manager/lib/model"
+	"github.com/SENERGY-Platform/device-manager/lib/share"
+	"github.com/SENERGY-Platform/permissions-v2/pkg/client"
+	"log"
+	"maps"
+	"net/http"
+	"time"
+)
+
+var errSharesDisabled = errors.New("shares are disabled, share_file is not set")
+
+// newShares returns nil if config.ShareFile is empty; shares must outlive restarts and be revoked by any replica
+func newShares(conf config.Config) (*share.Store, error) {
+	if conf.ShareFile == "" || conf.ShareFile == "-" {
+		return nil, nil
+	}
+	return share.New(conf.ShareFile)
+}
+
+// CreateShare grants request.Rights on a device, device-group or location to request.UserId until request.ExpiresAt.
+// the rights are added to the existing permissions of the user; only the added rights are removed on revocation.
+// a new share for the same user and resource replaces the active one.
+func (this *Controller) CreateShare(token auth.Token, topic string, id string, request model.ShareRequest) (result model.Share, err error, code int) {
+	if this.shares == nil {
+		return result, errSharesDisabled, http.StatusNotFound
+	}
+	if topic != this.config.DeviceTopic && topic != this.config.DeviceGroupTopic && topic != this.config.LocationTopic {
+		return result, errors.New("no shares for " + topic), http.StatusBadRequest
+	}
+	if request.UserId == "" {
+		return result, errors.New("missing user_id"), http.StatusBadRequest
+	}
+	if request.Rights.Administrate {
+		return result, errors.New("administrate rights may not be shared"), http.StatusBadRequest
+	}
+	if !request.Rights.Read && !request.Rights.Write && !request.Rights.Execute {
+		return result, errors.New("missing rights"), http.StatusBadRequest
+	}
+	if !request.ExpiresAt.After(time.Now()) {
+		return result, errors.New("expires_at must be in the future"), http.StatusBadRequest
+	}
+	err, code = this.checkAdministrate(token, topic, id)
+	if err != nil {
+		return result, err, code
+	}
+	rights, err, code := this.com.GetResourceRights(token, topic, id)
+	if err != nil {
+		return result, err, code
+	}
+	//rights of the user without the share to replace
+	base := rights.UserPermissions[request.UserId]
+	active, replaces := this.shares.Active(topic, id, request.UserId)
+	if replaces {
+		base = withoutRights(base, active.Added)
+	}
+	added := model.PermissionsMap{
+		Read:    request.Rights.Read && !base.Read,
+		Write:   request.Rights.Write && !base.Write,
+		Execute: request.Rights.Execute && !base.Execute,
+	}
+	granted := base
+	granted.Read = base.Read || request.Rights.Read
+	granted.Write = base.Write || request.Rights.Write
+	granted.Execute = base.Execute || request.Rights.Execute
+	permissions := withEmptyMaps(rights.ResourcePermissions)
+	permissions.UserPermissions = maps.Clone(permissions.UserPermissions)
+	permissions.UserPermissions[request.UserId] = granted
+	//the share is recorded before the grant, so that no granted share is left without record and revocation
+	result, err = this.shares.Add(model.Share{
+		Kind:       topic,
+		ResourceId: id,
+		UserId:     request.UserId,
+		Rights:     request.Rights,
+		Added:      added,
+		CreatedBy:  token.GetUserId(),
+		ExpiresAt:  request.ExpiresAt,
+	})
+	if err != nil {
+		return result, err, http.StatusInternalServerError
+	}
+	_, err, code = this.com.SetPermission(token.Jwt(), topic, id, permissions)
+	if err != nil {
+		removeErr := this.shares.Remove(result.Id)
+		if removeErr != nil {
+			log.Println("ERROR: unable to remove record of failed share --> revoked by the reaper on expiry", result.Id, removeErr)
+		}
+		return model.Share{}, err, code
+	}
+	this.propagateSharing(&token, topic, id, nil)
+	if replaces {
+		_, err = this.shares.MarkRevoked(active.Id, model.ShareRevokedByReplacement)
+		if err != nil {
+			log.Println("ERROR: unable to record replaced share", active.Id, err)
+		}
+	}
+	return result, nil, http.StatusOK
+}
+
+// ListShares returns the active and revoked shares of a resource
+func (this *Controller) ListShares(token auth.Token, topic string, id string) (result []model.Share, err error, code int) {
+	if this.shares == nil {
+		return result, errSharesDisabled, http.StatusNotFound
+	}
+	err, code = this.checkAdministrate(token, topic, id)
+	if err != nil {
+		return result, err, code
+	}
+	return this.shares.List(topic, id), nil, http.StatusOK
+}
+
+// RevokeShare revokes an active share before its expiry
+func (this *Controller) RevokeShare(token auth.Token, topic string, id string, shareId string) (err error, code int) {
+	if this.shares == nil {
+		return errSharesDisabled, http.StatusNotFound
+	}
+	err, code = this.checkAdministrate(token, topic, id)
+	if err != nil {
+		return err, code
+	}
+	s, err := this.shares.Get(shareId)
+	if err == nil && (s.Kind != topic || s.ResourceId != id) {
+		err = share.ErrNotFound
+	}
+	if errors.Is(err, share.ErrNotFound) {
+		return err, http.StatusNotFound
+	}
+	if err != nil {
+		return err, http.StatusInternalServerError
+	}
+	if s.RevokedAt != nil {
+		return nil, http.StatusOK
+	}
+	return this.revokeShare(s, token.GetUserId())
+}
+
+// RevokeExpiredShares revokes all expired shares and returns the revoked shares
+func (this *Controller) RevokeExpiredShares() (revoked []model.Share, err error) {
+	errs := []error{}
+	for _, s := range this.shares.Expired() {
+		var code int
+		err, code = this.revokeShare(s, model.ShareRevokedByReaper)
+		this.auditSystem(s.Kind, s.ResourceId, audit.ShareRevoke, nil, map[string]string{"share_id": s.Id}, err, code)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("share %v: %w", s.Id, err))
+			continue
+		}
+		log.Println("revoked expired share", s.Id, s.Kind, s.ResourceId, s.UserId)
+		revoked = append(revoked, s)
+	}
+	return revoked, errors.Join(errs...)
+}
+
+// revokeShare removes the rights added by the share from the current permissions of the user, so that rights granted
+// otherwise in the meantime are kept; shares of deleted resources are only recorded as revoked
+func (this *Controller) revokeShare(s model.Share, by string) (err error, code int) {
+	adminToken, err := auth.Parse(client.InternalAdminToken)
+	if err != nil {
+		return err, http.StatusInternalServerError
+	}
+	rights, err, code := this.com.GetResourceRights(adminToken, s.Kind, s.ResourceId)
+	if err != nil && code != http.StatusNotFound {
+		return err, code
+	}
+	current, ok := rights.UserPermissions[s.UserId]
+	remaining := withoutRights(current, s.Added)
+	if err == nil && ok && remaining != current {
+		permissions := withEmptyMaps(rights.ResourcePermissions)
+		permissions.UserPermissions = maps.Clone(permissions.UserPermissions)
+		if remaining == (model.PermissionsMap{}) {
+			delete(permissions.UserPermissions, s.UserId)
+		} else {
+			permissions.UserPermissions[s.UserId] = remaining
+		}
+		_, err, code = this.com.SetPermission(client.InternalAdminToken, s.Kind, s.ResourceId, permissions)
+		if err != nil {
+			return err, code
+		}
+		this.propagateSharing(nil, s.Kind, s.ResourceId, nil)
+	}
+	_, err = this.shares.MarkRevoked(s.Id, by)
+	if err != nil {
+		return err, http.StatusInternalServerError
+	}
+	return nil, http.StatusOK
+}
+
+// withoutRights returns permissions without the rights set in removed
+func withoutRights(permissions model.PermissionsMap, removed model.PermissionsMap) model.PermissionsMap {
+	permissions.Read = permissions.Read && !removed.Read
+	permissions.Write = permissions.Write && !removed.Write
+	permissions.Execute = permissions.Execute && !removed.Execute
+	permissions.Administrate = permissions.Administrate && !removed.Administrate
+	return permissions
+}
+
+func (this *Controller) startShareReaper(ctx context.Context) error {
+	interval := time.Minute
+	if this.config.ShareReapInterval != "" {
+		var err error
+		interval, err = time.ParseDuration(this.config.ShareReapInterval)
+		if err != nil {
+			return fmt.Errorf("invalid share_reap_interval: %w", err)
+		}
+	}
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				_, err := this.RevokeExpiredShares()
+				if err != nil {
+					log.Println("ERROR: unable to revoke expired shares", err)
+				}
+			}
+		}
+	}()
+	return nil
+}
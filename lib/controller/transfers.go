@@ -0,0 +1,223 @@
+/*
+ * Copyright 2024 InfAI (CC SES)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package controller
+
+import (
+	"errors"
+	"github.com/SENERGY-Platform/device-manager/lib/auth"
+	"github.com/SENERGY-Platform/device-manager/lib/config"
+	"github.com/SENERGY-Platform/device-manager/lib/model"
+	"github.com/SENERGY-Platform/device-manager/lib/policy"
+	"github.com/SENERGY-Platform/device-manager/lib/transfer"
+	"github.com/SENERGY-Platform/models/go/models"
+	"github.com/SENERGY-Platform/permissions-v2/pkg/client"
+	"log"
+	"maps"
+	"net/http"
+	"time"
+)
+
+const DefaultTransferTtl = 7 * 24 * time.Hour
+
+var errTransfersDisabled = errors.New("ownership transfers are disabled, transfer_file is not set")
+
+// newTransfers returns nil if config.TransferFile is empty; pending transfers must be shared by all replicas
+func newTransfers(conf config.Config) (*transfer.Store, error) {
+	if conf.TransferFile == "" || conf.TransferFile == "-" {
+		return nil, nil
+	}
+	return transfer.New(conf.TransferFile)
+}
+
+// CreateTransfer creates a pending ownership transfer of a device or hub (topic = config.DeviceTopic | config.HubTopic) to request.UserId.
+// only the owner and admins may transfer; a new transfer replaces a pending transfer of the same resource.
+func (this *Controller) CreateTransfer(token auth.Token, topic string, id string, request model.TransferRequest) (result model.Transfer, err error, code int) {
+	if this.transfers == nil {
+		return result, errTransfersDisabled, http.StatusNotFound
+	}
+	if topic != this.config.DeviceTopic && topic != this.config.HubTopic {
+		return result, errors.New("no ownership transfer for " + topic), http.StatusBadRequest
+	}
+	if request.UserId == "" {
+		return result, errors.New("missing user_id"), http.StatusBadRequest
+	}
+	if err, code := this.checkPolicy(token, topic, policy.Update); err != nil {
+		return result, err, code
+	}
+	err, code = this.checkAdministrate(token, topic, id)
+	if err != nil {
+		return result, err, code
+	}
+	owner, err, code := this.owner(token, topic, id)
+	if err != nil {
+		return result, err, code
+	}
+	if owner != token.GetUserId() && !token.IsAdmin() {
+		return result, errors.New("only the owner may transfer the ownership"), http.StatusForbidden
+	}
+	if owner == request.UserId {
+		return result, errors.New("user is already the owner"), http.StatusBadRequest
+	}
+	ttl := DefaultTransferTtl
+	if this.config.TransferTtl != "" {
+		ttl, err = time.ParseDuration(this.config.TransferTtl)
+		if err != nil {
+			log.Println("WARNING: invalid transfer_ttl --> use default", DefaultTransferTtl, err)
+			ttl = DefaultTransferTtl
+		}
+	}
+	result, err = this.transfers.Create(model.Transfer{
+		Kind:           topic,
+		ResourceId:     id,
+		From:           owner,
+		To:             request.UserId,
+		RemoveOldOwner: request.RemoveOldOwner,
+		CreatedBy:      token.GetUserId(),
+		ExpiresAt:      time.Now().Add(ttl),
+	})
+	if err != nil {
+		return result, err, http.StatusInternalServerError
+	}
+	return result, nil, http.StatusOK
+}
+
+// ListTransfers returns the pending transfers from or to the requesting user
+func (this *Controller) ListTransfers(token auth.Token) (result []model.Transfer, err error, code int) {
+	if this.transfers == nil {
+		return result, errTransfersDisabled, http.StatusNotFound
+	}
+	return this.transfers.List(token.GetUserId()), nil, http.StatusOK
+}
+
+// AcceptTransfer grants the recipient administrate rights, sets the recipient as owner
+// and optionally removes the permissions of the old owner. the permissions are restored if the owner update fails.
+func (this *Controller) AcceptTransfer(token auth.Token, id string) (result model.Transfer, err error, code int) {
+	if this.transfers == nil {
+		return result, errTransfersDisabled, http.StatusNotFound
+	}
+	result, err = this.transfers.Get(id)
+	if err == nil && result.To != token.GetUserId() {
+		err = transfer.ErrNotFound
+	}
+	if err == nil {
+		result, err = this.transfers.Take(id)
+	}
+	if errors.Is(err, transfer.ErrNotFound) {
+		return result, err, http.StatusNotFound
+	}
+	if err != nil {
+		return result, err, http.StatusInternalServerError
+	}
+	err, code = this.executeTransfer(token, result)
+	if err != nil && code != http.StatusConflict && code != http.StatusNotFound {
+		restoreErr := this.transfers.Restore(result)
+		if restoreErr != nil {
+			log.Println("ERROR: unable to restore transfer", result.Id, restoreErr)
+		}
+	}
+	return result, err, code
+}
+
+// DeleteTransfer cancels or declines a pending transfer; allowed for the old owner, the recipient, the creator and admins
+func (this *Controller) DeleteTransfer(token auth.Token, id string) (err error, code int) {
+	if this.transfers == nil {
+		return errTransfersDisabled, http.StatusNotFound
+	}
+	pending, err := this.transfers.Get(id)
+	if err == nil && !token.IsAdmin() && pending.From != token.GetUserId() && pending.To != token.GetUserId() && pending.CreatedBy != token.GetUserId() {
+		err = transfer.ErrNotFound
+	}
+	if err == nil {
+		err = this.transfers.Delete(id)
+	}
+	if errors.Is(err, transfer.ErrNotFound) {
+		return err, http.StatusNotFound
+	}
+	if err != nil {
+		return err, http.StatusInternalServerError
+	}
+	return nil, http.StatusOK
+}
+
+func (this *Controller) executeTransfer(token auth.Token, t model.Transfer) (err error, code int) {
+	adminToken, err := auth.Parse(client.InternalAdminToken)
+	if err != nil {
+		return err, http.StatusInternalServerError
+	}
+	owner, err, code := this.owner(adminToken, t.Kind, t.ResourceId)
+	if err != nil {
+		return err, code
+	}
+	if owner != t.From {
+		return errors.New("the owner changed since the creation of the transfer"), http.StatusConflict
+	}
+	rights, err, code := this.com.GetResourceRights(adminToken, t.Kind, t.ResourceId)
+	if err != nil {
+		return err, code
+	}
+	original := withEmptyMaps(rights.ResourcePermissions)
+	updated := original
+	updated.UserPermissions = maps.Clone(original.UserPermissions)
+	if t.RemoveOldOwner {
+		delete(updated.UserPermissions, t.From)
+	}
+	updated.UserPermissions[t.To] = model.PermissionsMap{Read: true, Write: true, Execute: true, Administrate: true}
+	_, err, code = this.com.SetPermission(client.InternalAdminToken, t.Kind, t.ResourceId, updated)
+	if err != nil {
+		return err, code
+	}
+	switch t.Kind {
+	case this.config.DeviceTopic:
+		var device models.Device
+		device, err, code = this.com.GetDevice(adminToken, t.ResourceId)
+		if err == nil {
+			device.OwnerId = t.To
+			err = this.publisherFor(token).PublishDevice(device, t.To)
+			code = http.StatusInternalServerError
+		}
+	case this.config.HubTopic:
+		var hub models.Hub
+		hub, err, code = this.com.GetHub(adminToken, t.ResourceId)
+		if err == nil {
+			hub.OwnerId = t.To
+			err = this.publisherFor(token).PublishHub(hub, t.To)
+			code = http.StatusInternalServerError
+		}
+	}
+	if err != nil {
+		_, rollbackErr, _ := this.com.SetPermission(client.InternalAdminToken, t.Kind, t.ResourceId, original)
+		if rollbackErr != nil {
+			log.Println("ERROR: unable to restore permissions after failed transfer", t.Id, rollbackErr)
+		}
+		return err, code
+	}
+	return nil, http.StatusOK
+}
+
+// owner returns the OwnerId of a device or hub
+func (this *Controller) owner(token auth.Token, topic string, id string) (owner string, err error, code int) {
+	switch topic {
+	case this.config.DeviceTopic:
+		device, err, code := this.com.GetDevice(token, id)
+		return device.OwnerId, err, code
+	case this.config.HubTopic:
+		hub, err, code := this.com.GetHub(token, id)
+		return hub.OwnerId, err, code
+	default:
+		return "", errors.New("no owner for " + topic), http.StatusBadRequest
+	}
+}
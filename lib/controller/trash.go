@@ -0,0 +1,316 @@
+/*
+ * Copyright 2024 InfAI (CC SES)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package controller
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"github.com/SENERGY-Platform/device-manager/lib/auth"
+	"github.com/SENERGY-Platform/device-manager/lib/config"
+	"github.com/SENERGY-Platform/device-manager/lib/model"
+	"github.com/SENERGY-Platform/device-manager/lib/policy"
+	"github.com/SENERGY-Platform/device-manager/lib/trash"
+	"github.com/SENERGY-Platform/permissions-v2/pkg/client"
+	"github.com/SENERGY-Platform/service-commons/pkg/donewait"
+	"log"
+	"net/http"
+	"time"
+)
+
+const DefaultTrashRetention = 30 * 24 * time.Hour
+
+const trashPurgeInterval = time.Hour
+
+var errSoftDeleteDisabled = errors.New("soft delete is disabled")
+
+// RestoreTimeout is the max time to wait for the creation of a restored resource without done-wait, before its permissions are set
+var RestoreTimeout = time.Minute
+
+var restorePollInterval = time.Second
+
+// newTrash returns nil if config.SoftDelete is false
+func newTrash(conf config.Config) (*trash.Store, error) {
+	if !conf.SoftDelete {
+		return nil, nil
+	}
+	retention := DefaultTrashRetention
+	if conf.TrashRetention != "" {
+		var err error
+		retention, err = time.ParseDuration(conf.TrashRetention)
+		if err != nil {
+			log.Println("WARNING: invalid trash_retention --> use default", DefaultTrashRetention, err)
+			retention = DefaultTrashRetention
+		}
+	}
+	return trash.New(conf.TrashFile, retention)
+}
+
+// ListTrash returns the soft deleted resources of kind (all kinds if empty), which the user deleted or could administrate; admins get all entries
+func (this *Controller) ListTrash(token auth.Token, kind string) (result []model.TrashEntry, err error, code int) {
+	if this.trash == nil {
+		return result, errSoftDeleteDisabled, http.StatusNotFound
+	}
+	return this.trash.List(func(entry model.TrashEntry) bool {
+		return (kind == "" || entry.Kind == kind) && trashAccess(token, entry)
+	}), nil, http.StatusOK
+}
+
+// RestoreTrash republishes the resource of the trash entry with its original id and permissions
+func (this *Controller) RestoreTrash(token auth.Token, id string) (result model.TrashEntry, err error, code int) {
+	if this.trash == nil {
+		return result, errSoftDeleteDisabled, http.StatusNotFound
+	}
+	result, err = this.trash.Get(id)
+	if errors.Is(err, trash.ErrNotFound) || (err == nil && !trashAccess(token, result)) {
+		return result, trash.ErrNotFound, http.StatusNotFound
+	}
+	if err != nil {
+		return result, err, http.StatusInternalServerError
+	}
+	if err, code := this.checkPolicy(token, result.Kind, policy.Create); err != nil {
+		return result, err, code
+	}
+	result, err = this.trash.Take(id)
+	if errors.Is(err, trash.ErrNotFound) {
+		return result, err, http.StatusNotFound
+	}
+	if err != nil {
+		return result, err, http.StatusInternalServerError
+	}
+	err, code = this.restore(token, result)
+	if err != nil {
+		putErr := this.trash.Put(result)
+		if putErr != nil {
+			log.Println("ERROR: unable to put trash entry back after failed restore", result.Id, putErr)
+		}
+		return result, err, code
+	}
+	return result, nil, http.StatusOK
+}
+
+func (this *Controller) restore(token auth.Token, entry model.TrashEntry) (err error, code int) {
+	adminToken, err := auth.Parse(client.InternalAdminToken)
+	if err != nil {
+		return err, http.StatusInternalServerError
+	}
+	_, err, code = this.readResource(adminToken, entry.Kind, entry.ResourceId)
+	if err == nil {
+		return errors.New("a resource with the id of the trash entry exists"), http.StatusConflict
+	}
+	if code != http.StatusNotFound {
+		return err, code
+	}
+	owner := struct {
+		OwnerId string `json:"owner_id"`
+	}{}
+	err = json.Unmarshal(entry.Resource, &owner)
+	if err != nil {
+		return err, http.StatusInternalServerError
+	}
+	if owner.OwnerId == "" {
+		owner.OwnerId = entry.DeletedBy
+	}
+	wait := this.optionalWait(true, donewait.DoneMsg{
+		ResourceKind: entry.Kind,
+		ResourceId:   entry.ResourceId,
+		Command:      "PUT",
+	})
+	publisher := this.publisherFor(token)
+	switch entry.Kind {
+	case this.config.DeviceTopic:
+		err = publishSnapshot(entry.Resource, owner.OwnerId, publisher.PublishDevice)
+	case this.config.HubTopic:
+		err = publishSnapshot(entry.Resource, owner.OwnerId, publisher.PublishHub)
+	case this.config.DeviceTypeTopic:
+		err = publishSnapshot(entry.Resource, owner.OwnerId, publisher.PublishDeviceType)
+	case this.config.DeviceGroupTopic:
+		err = publishSnapshot(entry.Resource, owner.OwnerId, publisher.PublishDeviceGroup)
+	case this.config.ProtocolTopic:
+		err = publishSnapshot(entry.Resource, owner.OwnerId, publisher.PublishProtocol)
+	case this.config.ConceptTopic:
+		err = publishSnapshot(entry.Resource, owner.OwnerId, publisher.PublishConcept)
+	case this.config.CharacteristicTopic:
+		err = publishSnapshot(entry.Resource, owner.OwnerId, publisher.PublishCharacteristic)
+	case this.config.AspectTopic:
+		err = publishSnapshot(entry.Resource, owner.OwnerId, publisher.PublishAspect)
+	case this.config.FunctionTopic:
+		err = publishSnapshot(entry.Resource, owner.OwnerId, publisher.PublishFunction)
+	case this.config.DeviceClassTopic:
+		err = publishSnapshot(entry.Resource, owner.OwnerId, publisher.PublishDeviceClass)
+	case this.config.LocationTopic:
+		err = publishSnapshot(entry.Resource, owner.OwnerId, publisher.PublishLocation)
+	default:
+		return errors.New("unknown trash entry kind " + entry.Kind), http.StatusInternalServerError
+	}
+	if err != nil {
+		return err, http.StatusInternalServerError
+	}
+	err = wait()
+	if err != nil {
+		return err, http.StatusInternalServerError
+	}
+	//the permissions are set after the creation, so that the permissions of the created resource are replaced
+	if entry.Permissions != nil {
+		if !this.config.HandleDoneWait {
+			err, code = this.waitForRestored(adminToken, entry)
+			if err != nil {
+				return err, code
+			}
+		}
+		_, err, code = this.com.SetPermission(client.InternalAdminToken, entry.Kind, entry.ResourceId, withEmptyMaps(*entry.Permissions))
+		if err != nil {
+			log.Println("ERROR: unable to restore permissions of", entry.Kind, entry.ResourceId, err)
+			return err, code
+		}
+	}
+	return nil, http.StatusOK
+}
+
+// waitForRestored polls until the republished resource exists in the device-repository and in permissions-v2;
+// without done-wait, this is the only way to know that the creation does not overwrite the restored permissions
+func (this *Controller) waitForRestored(token auth.Token, entry model.TrashEntry) (err error, code int) {
+	deadline := time.Now().Add(RestoreTimeout)
+	for {
+		_, err, code = this.readResource(token, entry.Kind, entry.ResourceId)
+		if err == nil {
+			_, err, code = this.com.GetResourceRights(token, entry.Kind, entry.ResourceId)
+		}
+		if err == nil {
+			return nil, http.StatusOK
+		}
+		if code != http.StatusNotFound {
+			return err, code
+		}
+		if time.Now().After(deadline) {
+			return errors.New("restored resource was not created in time, its permissions are not restored"), http.StatusGatewayTimeout
+		}
+		time.Sleep(restorePollInterval)
+	}
+}
+
+func publishSnapshot[T any](snapshot json.RawMessage, owner string, publish func(T, string) error) error {
+	var resource T
+	err := json.Unmarshal(snapshot, &resource)
+	if err != nil {
+		return err
+	}
+	return publish(resource, owner)
+}
+
+// moveToTrash stores a snapshot of the resource and its permissions, if soft delete is enabled.
+// the returned undo removes the snapshot again and is called if the deletion could not be published.
+func (this *Controller) moveToTrash(token auth.Token, topic string, id string) (undo func(), err error, code int) {
+	undo = func() {}
+	if this.trash == nil {
+		return undo, nil, http.StatusOK
+	}
+	adminToken, err := auth.Parse(client.InternalAdminToken)
+	if err != nil {
+		return undo, err, http.StatusInternalServerError
+	}
+	resource, err, code := this.readResource(adminToken, topic, id)
+	if err != nil {
+		return undo, err, code
+	}
+	entry := model.TrashEntry{Kind: topic, ResourceId: id, DeletedBy: token.GetUserId()}
+	entry.Resource, err = json.Marshal(resource)
+	if err != nil {
+		return undo, err, http.StatusInternalServerError
+	}
+	rights, err, code := this.com.GetResourceRights(adminToken, topic, id)
+	if err != nil && code != http.StatusNotFound {
+		return undo, err, code
+	}
+	if err == nil {
+		entry.Permissions = &rights.ResourcePermissions
+	}
+	entry, err = this.trash.Add(entry)
+	if err != nil {
+		return undo, err, http.StatusInternalServerError
+	}
+	return func() {
+		err := this.trash.Delete(entry.Id)
+		if err != nil {
+			log.Println("ERROR: unable to remove trash entry of failed delete", entry.Id, err)
+		}
+	}, nil, http.StatusOK
+}
+
+func (this *Controller) readResource(token auth.Token, topic string, id string) (result interface{}, err error, code int) {
+	switch topic {
+	case this.config.DeviceTopic:
+		return anyResult(this.com.GetDevice(token, id))
+	case this.config.HubTopic:
+		return anyResult(this.com.GetHub(token, id))
+	case this.config.DeviceTypeTopic:
+		return anyResult(this.com.GetDeviceType(token, id))
+	case this.config.DeviceGroupTopic:
+		return anyResult(this.com.GetTechnicalDeviceGroup(token, id))
+	case this.config.ProtocolTopic:
+		return anyResult(this.com.GetProtocol(token, id))
+	case this.config.ConceptTopic:
+		return anyResult(this.com.GetConcept(token, id))
+	case this.config.CharacteristicTopic:
+		return anyResult(this.com.GetCharacteristic(token, id))
+	case this.config.AspectTopic:
+		return anyResult(this.com.GetAspect(token, id))
+	case this.config.FunctionTopic:
+		return anyResult(this.com.GetFunction(token, id))
+	case this.config.DeviceClassTopic:
+		return anyResult(this.com.GetDeviceClass(token, id))
+	case this.config.LocationTopic:
+		return anyResult(this.com.GetLocation(token, id))
+	default:
+		return nil, errors.New("unknown resource kind " + topic), http.StatusBadRequest
+	}
+}
+
+func anyResult[T any](value T, err error, code int) (interface{}, error, int) {
+	return value, err, code
+}
+
+func trashAccess(token auth.Token, entry model.TrashEntry) bool {
+	if token.IsAdmin() || entry.DeletedBy == token.GetUserId() {
+		return true
+	}
+	return entry.Permissions != nil && entry.Permissions.UserPermissions[token.GetUserId()].Administrate
+}
+
+func (this *Controller) startTrashPurge(ctx context.Context) {
+	if this.trash == nil {
+		return
+	}
+	go func() {
+		ticker := time.NewTicker(trashPurgeInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				purged, err := this.trash.Purge()
+				if err != nil {
+					log.Println("ERROR: unable to purge trash", err)
+				}
+				if purged > 0 {
+					log.Println("purged expired trash entries", purged)
+				}
+			}
+		}
+	}()
+}
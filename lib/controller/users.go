@@ -17,11 +17,17 @@
 package controller
 
 import (
+	"context"
 	"github.com/SENERGY-Platform/device-manager/lib/auth"
 	"github.com/SENERGY-Platform/permissions-v2/pkg/client"
 )
 
-func (this *Controller) DeleteUser(userId string) error {
+// DeleteUser handles the delete command of a user; Com calls and publishes are traced as children of the span in ctx
+func (this *Controller) DeleteUser(ctx context.Context, userId string) error {
+	return this.withContext(ctx).deleteUser(userId)
+}
+
+func (this *Controller) deleteUser(userId string) error {
 	token, err := auth.CreateToken("device-manager", userId)
 	if err != nil {
 		return err
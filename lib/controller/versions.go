@@ -0,0 +1,161 @@
+/*
+ * Copyright 2024 InfAI (CC SES)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package controller
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"github.com/SENERGY-Platform/device-manager/lib/audit"
+	"github.com/SENERGY-Platform/device-manager/lib/auth"
+	"github.com/SENERGY-Platform/device-manager/lib/bus"
+	"github.com/SENERGY-Platform/device-manager/lib/config"
+	"github.com/SENERGY-Platform/device-manager/lib/model"
+	"github.com/SENERGY-Platform/device-manager/lib/versions"
+	"github.com/SENERGY-Platform/models/go/models"
+	"log"
+	"net/http"
+)
+
+var errVersionsDisabled = errors.New("device-type versions are disabled, device_type_version_file is not set")
+
+// newVersions returns nil if config.DeviceTypeVersionFile is empty; versions are numbered by all replicas in the same file
+func newVersions(conf config.Config) (*versions.Store, error) {
+	if conf.DeviceTypeVersionFile == "" || conf.DeviceTypeVersionFile == "-" {
+		return nil, nil
+	}
+	return versions.New(conf.DeviceTypeVersionFile, int(conf.DeviceTypeVersionLimit))
+}
+
+// ListDeviceTypeVersions returns the kept versions of the device-type, oldest first
+func (this *Controller) ListDeviceTypeVersions(token auth.Token, id string) (result []model.DeviceTypeVersion, err error, code int) {
+	if this.versions == nil {
+		return result, errVersionsDisabled, http.StatusNotFound
+	}
+	err, code = this.checkDeviceTypeVersionAccess(token, id)
+	if err != nil {
+		return result, err, code
+	}
+	return this.versions.List(id), nil, http.StatusOK
+}
+
+func (this *Controller) ReadDeviceTypeVersion(token auth.Token, id string, version int) (result model.DeviceTypeVersion, err error, code int) {
+	if this.versions == nil {
+		return result, errVersionsDisabled, http.StatusNotFound
+	}
+	err, code = this.checkDeviceTypeVersionAccess(token, id)
+	if err != nil {
+		return result, err, code
+	}
+	result, err = this.versions.Get(id, version)
+	if errors.Is(err, versions.ErrNotFound) {
+		return result, err, http.StatusNotFound
+	}
+	if err != nil {
+		return result, err, http.StatusInternalServerError
+	}
+	return result, nil, http.StatusOK
+}
+
+// DiffDeviceTypeVersions compares the versions from and to of the device-type; to = 0 compares with the latest version
+func (this *Controller) DiffDeviceTypeVersions(token auth.Token, id string, from int, to int) (result model.DeviceTypeVersionDiff, err error, code int) {
+	before, err, code := this.ReadDeviceTypeVersion(token, id, from)
+	if err != nil {
+		return result, err, code
+	}
+	var after model.DeviceTypeVersion
+	if to == 0 {
+		after, err = this.versions.Latest(id)
+		switch {
+		case errors.Is(err, versions.ErrNotFound):
+			code = http.StatusNotFound
+		case err != nil:
+			code = http.StatusInternalServerError
+		}
+	} else {
+		after, err, code = this.ReadDeviceTypeVersion(token, id, to)
+	}
+	if err != nil {
+		return result, err, code
+	}
+	result = model.DeviceTypeVersionDiff{
+		DeviceTypeId: id,
+		From:         before.Version,
+		To:           after.Version,
+		Changes:      audit.Diff(before.DeviceType, after.DeviceType),
+	}
+	if result.Changes == nil {
+		result.Changes = []model.AuditChange{}
+	}
+	return result, nil, http.StatusOK
+}
+
+// RollbackDeviceType publishes the definition of an older version as update, which is recorded as new version when it is consumed
+func (this *Controller) RollbackDeviceType(token auth.Token, id string, version int, options model.DeviceTypeUpdateOptions) (result models.DeviceType, err error, code int) {
+	v, err, code := this.ReadDeviceTypeVersion(token, id, version)
+	if err != nil {
+		return result, err, code
+	}
+	return this.PublishDeviceTypeUpdate(token, id, v.DeviceType, options)
+}
+
+func (this *Controller) checkDeviceTypeVersionAccess(token auth.Token, id string) (error, int) {
+	if token.IsAdmin() {
+		return nil, http.StatusOK
+	}
+	return this.com.PermissionCheckForDeviceType(token, id, "r")
+}
+
+// recordDeviceTypeBaseline stores the stored definition of a device-type as first version, if it has none,
+// so that device-types published before the version history was enabled can be compared and rolled back
+func (this *Controller) recordDeviceTypeBaseline(stored models.DeviceType) {
+	if this.versions == nil {
+		return
+	}
+	_, err := this.versions.AddBaseline(stored)
+	if err != nil {
+		log.Println("ERROR: unable to record device-type baseline version", stored.Id, err)
+	}
+}
+
+// SubscribeDeviceTypeVersions records the device-types of the consumed device-type topic as versions, including those published by this instance.
+// the replicas consume as one group, so that each command is numbered once; commands are keyed by device-type id and stay in order.
+// does nothing if versions are disabled
+func (this *Controller) SubscribeDeviceTypeVersions(ctx context.Context, consumer bus.Consumer) error {
+	if this.versions == nil {
+		return nil
+	}
+	return consumer.Subscribe(ctx, this.config.DeviceTypeTopic, this.config.GroupId+"-device-type-versions", func(_ context.Context, topic string, msg []byte) error {
+		cmd := struct {
+			Command    string            `json:"command"`
+			Owner      string            `json:"owner"`
+			DeviceType models.DeviceType `json:"device_type"`
+		}{}
+		err := json.Unmarshal(msg, &cmd)
+		if err != nil {
+			log.Println("WARNING: unable to interpret device-type command for version history; ignore", err)
+			return nil
+		}
+		if cmd.Command != "PUT" || cmd.DeviceType.Id == "" {
+			return nil
+		}
+		_, _, err = this.versions.Add(cmd.Owner, cmd.DeviceType)
+		return err
+	}, func(err error) {
+		log.Println("ERROR: device-type version consumer stopped", err)
+	})
+}
@@ -0,0 +1,545 @@
+/*
+ * Copyright 2024 InfAI (CC SES)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package devmode
+
+import (
+	"errors"
+	"github.com/SENERGY-Platform/device-manager/lib/auth"
+	"github.com/SENERGY-Platform/device-manager/lib/controller/com"
+	"github.com/SENERGY-Platform/device-repository/lib/client"
+	"github.com/SENERGY-Platform/models/go/models"
+	"github.com/SENERGY-Platform/permissions-v2/pkg/model"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// Com implements controller.Com on a Store.
+// permission checks always succeed, validation only checks ids, names and references to other resources.
+type Com struct {
+	store *Store
+}
+
+func NewCom(store *Store) *Com {
+	return &Com{store: store}
+}
+
+func devices(data *Data) map[string]models.Device                 { return data.Devices }
+func hubs(data *Data) map[string]models.Hub                       { return data.Hubs }
+func deviceTypes(data *Data) map[string]models.DeviceType         { return data.DeviceTypes }
+func deviceGroups(data *Data) map[string]models.DeviceGroup       { return data.DeviceGroups }
+func locations(data *Data) map[string]models.Location             { return data.Locations }
+func protocols(data *Data) map[string]models.Protocol             { return data.Protocols }
+func aspects(data *Data) map[string]models.Aspect                 { return data.Aspects }
+func functions(data *Data) map[string]models.Function             { return data.Functions }
+func concepts(data *Data) map[string]models.Concept               { return data.Concepts }
+func characteristics(data *Data) map[string]models.Characteristic { return data.Characteristics }
+func deviceClasses(data *Data) map[string]models.DeviceClass      { return data.DeviceClasses }
+
+// permissions
+
+func (this *Com) ResourcesEffectedByUserDelete(token auth.Token, resource string) (deleteResourceIds []string, deleteUserFromResource []model.Resource, err error) {
+	userId := token.GetUserId()
+	for _, element := range this.store.listPermissions(resource) {
+		permissions, ok := element.UserPermissions[userId]
+		if !ok {
+			continue
+		}
+		otherAdmin := false
+		for user, p := range element.UserPermissions {
+			if user != userId && p.Administrate {
+				otherAdmin = true
+			}
+		}
+		if permissions.Administrate && !otherAdmin {
+			deleteResourceIds = append(deleteResourceIds, element.Id)
+		} else {
+			deleteUserFromResource = append(deleteUserFromResource, element)
+		}
+	}
+	return deleteResourceIds, deleteUserFromResource, nil
+}
+
+func (this *Com) GetResourceRights(token auth.Token, kind string, id string) (result model.Resource, err error, code int) {
+	permissions, ok := this.store.Permissions(kind, id)
+	if !ok {
+		return result, errors.New("not found"), http.StatusNotFound
+	}
+	return model.Resource{Id: id, TopicId: kind, ResourcePermissions: permissions}, nil, http.StatusOK
+}
+
+func (this *Com) SetPermission(token string, topicId string, id string, permissions model.ResourcePermissions) (result model.ResourcePermissions, err error, code int) {
+	if !permissions.Valid() {
+		return result, errors.New("invalid permissions: at least one admin user is required"), http.StatusBadRequest
+	}
+	this.store.setPermissions(topicId, id, permissions)
+	return permissions, nil, http.StatusOK
+}
+
+func (this *Com) PermissionCheckForDeviceList(token auth.Token, ids []string, rights string) (result map[string]bool, err error, code int) {
+	result = map[string]bool{}
+	for _, id := range ids {
+		result[id] = exists(this.store, devices, id)
+	}
+	return result, nil, http.StatusOK
+}
+
+func (this *Com) PermissionCheckForDevice(token auth.Token, id string, permission string) (err error, code int) {
+	return nil, http.StatusOK
+}
+
+func (this *Com) PermissionCheckForHub(token auth.Token, id string, permission string) (err error, code int) {
+	return nil, http.StatusOK
+}
+
+func (this *Com) PermissionCheckForDeviceGroup(token auth.Token, id string, permission string) (err error, code int) {
+	return nil, http.StatusOK
+}
+
+func (this *Com) PermissionCheckForDeviceType(token auth.Token, id string, permission string) (err error, code int) {
+	return nil, http.StatusOK
+}
+
+func (this *Com) PermissionCheckForConcept(token auth.Token, id string, permission string) (err error, code int) {
+	return nil, http.StatusOK
+}
+
+func (this *Com) PermissionCheckForCharacteristic(token auth.Token, id string, permission string) (err error, code int) {
+	return nil, http.StatusOK
+}
+
+func (this *Com) PermissionCheckForLocation(token auth.Token, id string, permission string) (err error, code int) {
+	return nil, http.StatusOK
+}
+
+// devices
+
+func (this *Com) ListDevicesByQuery(token auth.Token, query url.Values) (result []models.Device, err error, code int) {
+	options := listOptions{search: query.Get("search"), sortBy: query.Get("sort")}
+	if query.Has("ids") {
+		options.ids = strings.Split(query.Get("ids"), ",")
+	}
+	if query.Has("limit") {
+		options.limit, err = strconv.ParseInt(query.Get("limit"), 10, 64)
+		if err != nil {
+			return result, err, http.StatusBadRequest
+		}
+	}
+	if query.Has("offset") {
+		options.offset, err = strconv.ParseInt(query.Get("offset"), 10, 64)
+		if err != nil {
+			return result, err, http.StatusBadRequest
+		}
+	}
+	return list(this.store, devices, func(d models.Device) string { return d.Name }, options, nil), nil, http.StatusOK
+}
+
+func (this *Com) ListDevices(token string, options client.DeviceListOptions) (result []models.Device, err error, code int) {
+	owner := options.Owner
+	if owner == "" {
+		if parsed, err := auth.Parse(token); err == nil {
+			owner = parsed.GetUserId()
+		}
+	}
+	return list(this.store, devices, func(d models.Device) string { return d.Name }, listOptions{
+		ids:    options.Ids,
+		search: options.Search,
+		limit:  options.Limit,
+		offset: options.Offset,
+		sortBy: options.SortBy,
+	}, func(device models.Device) bool {
+		if options.LocalIds != nil && (!contains(options.LocalIds, device.LocalId) || device.OwnerId != owner) {
+			return false
+		}
+		if options.DeviceTypeIds != nil && !contains(options.DeviceTypeIds, device.DeviceTypeId) {
+			return false
+		}
+		return true
+	}), nil, http.StatusOK
+}
+
+func (this *Com) GetDevice(token auth.Token, id string) (models.Device, error, int) {
+	return get(this.store, devices, id)
+}
+
+func (this *Com) GetDeviceByLocalId(token auth.Token, ownerId string, localId string) (models.Device, error, int) {
+	if ownerId == "" {
+		ownerId = token.GetUserId()
+	}
+	result := list(this.store, devices, func(d models.Device) string { return d.Name }, listOptions{limit: 1}, func(device models.Device) bool {
+		return device.LocalId == localId && device.OwnerId == ownerId
+	})
+	if len(result) == 0 {
+		return models.Device{}, errors.New("not found"), http.StatusNotFound
+	}
+	return result[0], nil, http.StatusOK
+}
+
+func (this *Com) DeviceLocalIdToId(token auth.Token, localId string) (id string, err error, code int) {
+	device, err, code := this.GetDeviceByLocalId(token, token.GetUserId(), localId)
+	return device.Id, err, code
+}
+
+func (this *Com) DevicesOfTypeExist(token auth.Token, deviceTypeId string) (result bool, err error, code int) {
+	if !token.IsAdmin() {
+		return false, errors.New("only for admins allowed"), http.StatusForbidden
+	}
+	deviceTypeId = removeIdModifier(deviceTypeId)
+	found := list(this.store, devices, func(d models.Device) string { return d.Name }, listOptions{limit: 1}, func(device models.Device) bool {
+		return device.DeviceTypeId == deviceTypeId
+	})
+	return len(found) > 0, nil, http.StatusOK
+}
+
+func (this *Com) ValidateDevice(token auth.Token, device models.Device) (err error, code int) {
+	if err = com.PreventIdModifier(device.Id); err != nil {
+		return err, http.StatusBadRequest
+	}
+	if this.store.config.DisableValidation {
+		return nil, http.StatusOK
+	}
+	err, code = validateIdAndName(device.Id, device.Name)
+	if err != nil {
+		return err, code
+	}
+	if !exists(this.store, deviceTypes, device.DeviceTypeId) {
+		return errors.New("unknown device-type " + device.DeviceTypeId), http.StatusBadRequest
+	}
+	if device.LocalId != "" {
+		existing, err, _ := this.GetDeviceByLocalId(token, device.OwnerId, device.LocalId)
+		if err == nil && existing.Id != device.Id {
+			return errors.New("local id already in use"), http.StatusBadRequest
+		}
+	}
+	return nil, http.StatusOK
+}
+
+// hubs
+
+func (this *Com) ListHubs(token string, options client.HubListOptions) (result []models.Hub, err error, code int) {
+	return list(this.store, hubs, func(h models.Hub) string { return h.Name }, listOptions{
+		ids:    options.Ids,
+		search: options.Search,
+		limit:  options.Limit,
+		offset: options.Offset,
+		sortBy: options.SortBy,
+	}, nil), nil, http.StatusOK
+}
+
+func (this *Com) GetHub(token auth.Token, id string) (models.Hub, error, int) {
+	return get(this.store, hubs, id)
+}
+
+func (this *Com) ValidateHub(token auth.Token, hub models.Hub) (err error, code int) {
+	if err = com.PreventIdModifier(hub.Id); err != nil {
+		return err, http.StatusBadRequest
+	}
+	if this.store.config.DisableValidation {
+		return nil, http.StatusOK
+	}
+	return validateIdAndName(hub.Id, hub.Name)
+}
+
+// device-types
+
+func (this *Com) ListDeviceTypes(token string, options client.DeviceTypeListOptions) (result []models.DeviceType, err error, code int) {
+	return list(this.store, deviceTypes, func(dt models.DeviceType) string { return dt.Name }, listOptions{
+		ids:    options.Ids,
+		search: options.Search,
+		limit:  options.Limit,
+		offset: options.Offset,
+		sortBy: options.SortBy,
+	}, func(dt models.DeviceType) bool {
+		if options.ProtocolIds == nil {
+			return true
+		}
+		for _, service := range dt.Services {
+			if contains(options.ProtocolIds, service.ProtocolId) {
+				return true
+			}
+		}
+		return false
+	}), nil, http.StatusOK
+}
+
+func (this *Com) GetDeviceType(token auth.Token, id string) (models.DeviceType, error, int) {
+	return get(this.store, deviceTypes, id)
+}
+
+func (this *Com) ValidateDeviceType(token auth.Token, dt models.DeviceType) (err error, code int) {
+	if err = com.PreventIdModifier(dt.Id); err != nil {
+		return err, http.StatusBadRequest
+	}
+	if this.store.config.DisableValidation {
+		return nil, http.StatusOK
+	}
+	err, code = validateIdAndName(dt.Id, dt.Name)
+	if err != nil {
+		return err, code
+	}
+	localIds := map[string]bool{}
+	for _, service := range dt.Services {
+		if localIds[service.LocalId] {
+			return errors.New("duplicate service local id " + service.LocalId), http.StatusBadRequest
+		}
+		localIds[service.LocalId] = true
+		if !exists(this.store, protocols, service.ProtocolId) {
+			return errors.New("unknown protocol " + service.ProtocolId), http.StatusBadRequest
+		}
+	}
+	return nil, http.StatusOK
+}
+
+// device-groups
+
+func (this *Com) ListDeviceGroups(token string, options client.DeviceGroupListOptions) (result []models.DeviceGroup, err error, code int) {
+	return list(this.store, deviceGroups, func(dg models.DeviceGroup) string { return dg.Name }, listOptions{
+		ids:    options.Ids,
+		search: options.Search,
+		limit:  options.Limit,
+		offset: options.Offset,
+		sortBy: options.SortBy,
+	}, func(dg models.DeviceGroup) bool {
+		return !options.IgnoreGenerated || dg.AutoGeneratedByDevice == ""
+	}), nil, http.StatusOK
+}
+
+func (this *Com) GetTechnicalDeviceGroup(token auth.Token, id string) (models.DeviceGroup, error, int) {
+	return get(this.store, deviceGroups, id)
+}
+
+func (this *Com) ValidateDeviceGroup(token auth.Token, dg models.DeviceGroup) (err error, code int) {
+	if err = com.PreventIdModifier(dg.Id); err != nil {
+		return err, http.StatusBadRequest
+	}
+	if this.store.config.DisableValidation {
+		return nil, http.StatusOK
+	}
+	err, code = validateIdAndName(dg.Id, dg.Name)
+	if err != nil {
+		return err, code
+	}
+	for _, id := range dg.DeviceIds {
+		if !exists(this.store, devices, id) {
+			return errors.New("unknown device " + id), http.StatusBadRequest
+		}
+	}
+	return nil, http.StatusOK
+}
+
+func (this *Com) ValidateDeviceGroupDelete(token auth.Token, id string) (err error, code int) {
+	return nil, http.StatusOK
+}
+
+// locations
+
+func (this *Com) ListLocations(token string, options client.LocationListOptions) (result []models.Location, err error, code int) {
+	return list(this.store, locations, func(l models.Location) string { return l.Name }, listOptions{
+		ids:    options.Ids,
+		search: options.Search,
+		limit:  options.Limit,
+		offset: options.Offset,
+		sortBy: options.SortBy,
+	}, nil), nil, http.StatusOK
+}
+
+func (this *Com) GetLocation(token auth.Token, id string) (models.Location, error, int) {
+	return get(this.store, locations, id)
+}
+
+func (this *Com) ValidateLocation(token auth.Token, location models.Location) (err error, code int) {
+	if err = com.PreventIdModifier(location.Id); err != nil {
+		return err, http.StatusBadRequest
+	}
+	if this.store.config.DisableValidation {
+		return nil, http.StatusOK
+	}
+	return validateIdAndName(location.Id, location.Name)
+}
+
+// protocols
+
+func (this *Com) ListProtocols(token string, limit int64, offset int64, sort string) (result []models.Protocol, err error, code int) {
+	return list(this.store, protocols, func(p models.Protocol) string { return p.Name }, listOptions{limit: limit, offset: offset, sortBy: sort}, nil), nil, http.StatusOK
+}
+
+func (this *Com) GetProtocol(token auth.Token, id string) (models.Protocol, error, int) {
+	return get(this.store, protocols, id)
+}
+
+func (this *Com) ValidateProtocol(token auth.Token, protocol models.Protocol) (err error, code int) {
+	if this.store.config.DisableValidation {
+		return nil, http.StatusOK
+	}
+	return validateIdAndName(protocol.Id, protocol.Name)
+}
+
+// semantics
+
+func (this *Com) ListAspects(options client.AspectListOptions) (result []models.Aspect, err error, code int) {
+	return list(this.store, aspects, func(a models.Aspect) string { return a.Name }, listOptions{
+		ids:    options.Ids,
+		search: options.Search,
+		limit:  options.Limit,
+		offset: options.Offset,
+		sortBy: options.SortBy,
+	}, nil), nil, http.StatusOK
+}
+
+func (this *Com) GetAspect(token auth.Token, id string) (models.Aspect, error, int) {
+	return get(this.store, aspects, id)
+}
+
+func (this *Com) ValidateAspect(token auth.Token, aspect models.Aspect) (err error, code int) {
+	if this.store.config.DisableValidation {
+		return nil, http.StatusOK
+	}
+	return validateIdAndName(aspect.Id, aspect.Name)
+}
+
+func (this *Com) ValidateAspectDelete(token auth.Token, id string) (err error, code int) {
+	return nil, http.StatusOK
+}
+
+func (this *Com) ListFunctions(options client.FunctionListOptions) (result []models.Function, err error, code int) {
+	return list(this.store, functions, func(f models.Function) string { return f.Name }, listOptions{
+		ids:    options.Ids,
+		search: options.Search,
+		limit:  options.Limit,
+		offset: options.Offset,
+		sortBy: options.SortBy,
+	}, func(f models.Function) bool {
+		return options.RdfType == "" || f.RdfType == options.RdfType
+	}), nil, http.StatusOK
+}
+
+func (this *Com) GetFunction(token auth.Token, id string) (models.Function, error, int) {
+	return get(this.store, functions, id)
+}
+
+func (this *Com) ValidateFunction(token auth.Token, function models.Function) (err error, code int) {
+	if this.store.config.DisableValidation {
+		return nil, http.StatusOK
+	}
+	err, code = validateIdAndName(function.Id, function.Name)
+	if err != nil {
+		return err, code
+	}
+	if function.ConceptId != "" && !exists(this.store, concepts, function.ConceptId) {
+		return errors.New("unknown concept " + function.ConceptId), http.StatusBadRequest
+	}
+	return nil, http.StatusOK
+}
+
+func (this *Com) ValidateFunctionDelete(token auth.Token, id string) (err error, code int) {
+	return nil, http.StatusOK
+}
+
+func (this *Com) ListConcepts(options client.ConceptListOptions) (result []models.Concept, err error, code int) {
+	return list(this.store, concepts, func(c models.Concept) string { return c.Name }, listOptions{
+		ids:    options.Ids,
+		search: options.Search,
+		limit:  options.Limit,
+		offset: options.Offset,
+		sortBy: options.SortBy,
+	}, nil), nil, http.StatusOK
+}
+
+func (this *Com) GetConcept(token auth.Token, id string) (models.Concept, error, int) {
+	return get(this.store, concepts, id)
+}
+
+func (this *Com) ValidateConcept(token auth.Token, concept models.Concept) (err error, code int) {
+	if this.store.config.DisableValidation {
+		return nil, http.StatusOK
+	}
+	err, code = validateIdAndName(concept.Id, concept.Name)
+	if err != nil {
+		return err, code
+	}
+	for _, id := range concept.CharacteristicIds {
+		if !exists(this.store, characteristics, id) {
+			return errors.New("unknown characteristic " + id), http.StatusBadRequest
+		}
+	}
+	return nil, http.StatusOK
+}
+
+func (this *Com) ValidateConceptDelete(token auth.Token, id string) (err error, code int) {
+	return nil, http.StatusOK
+}
+
+func (this *Com) ListCharacteristics(options client.CharacteristicListOptions) (result []models.Characteristic, err error, code int) {
+	return list(this.store, characteristics, func(c models.Characteristic) string { return c.Name }, listOptions{
+		ids:    options.Ids,
+		search: options.Search,
+		limit:  options.Limit,
+		offset: options.Offset,
+		sortBy: options.SortBy,
+	}, nil), nil, http.StatusOK
+}
+
+func (this *Com) GetCharacteristic(token auth.Token, id string) (models.Characteristic, error, int) {
+	return get(this.store, characteristics, id)
+}
+
+func (this *Com) ValidateCharacteristic(token auth.Token, characteristic models.Characteristic) (err error, code int) {
+	if this.store.config.DisableValidation {
+		return nil, http.StatusOK
+	}
+	return validateIdAndName(characteristic.Id, characteristic.Name)
+}
+
+func (this *Com) ValidateCharacteristicDelete(token auth.Token, id string) (err error, code int) {
+	return nil, http.StatusOK
+}
+
+func (this *Com) ListDeviceClasses(options client.DeviceClassListOptions) (result []models.DeviceClass, err error, code int) {
+	return list(this.store, deviceClasses, func(dc models.DeviceClass) string { return dc.Name }, listOptions{
+		ids:    options.Ids,
+		search: options.Search,
+		limit:  options.Limit,
+		offset: options.Offset,
+		sortBy: options.SortBy,
+	}, nil), nil, http.StatusOK
+}
+
+func (this *Com) GetDeviceClass(token auth.Token, id string) (models.DeviceClass, error, int) {
+	return get(this.store, deviceClasses, id)
+}
+
+func (this *Com) ValidateDeviceClass(token auth.Token, deviceClass models.DeviceClass) (err error, code int) {
+	if this.store.config.DisableValidation {
+		return nil, http.StatusOK
+	}
+	return validateIdAndName(deviceClass.Id, deviceClass.Name)
+}
+
+func (this *Com) ValidateDeviceClassDelete(token auth.Token, id string) (err error, code int) {
+	return nil, http.StatusOK
+}
+
+func validateIdAndName(id string, name string) (err error, code int) {
+	if id == "" {
+		return errors.New("missing id"), http.StatusBadRequest
+	}
+	if name == "" {
+		return errors.New("missing name"), http.StatusBadRequest
+	}
+	return nil, http.StatusOK
+}
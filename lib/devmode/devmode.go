@@ -0,0 +1,43 @@
+/*
+ * Copyright 2024 InfAI (CC SES)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package devmode provides a self-contained backend for local development:
+// an in-process publisher, an in-memory repository and a permissive permission store.
+// it needs neither kafka nor the device-repository nor permissions-v2.
+package devmode
+
+import (
+	"github.com/SENERGY-Platform/device-manager/lib/config"
+	"github.com/SENERGY-Platform/device-manager/lib/controller"
+)
+
+// Config disables everything that needs external services
+func Config(conf config.Config) config.Config {
+	conf.EditForward = ""
+	conf.HandleDoneWait = false
+	conf.ReadModel = false
+	conf.ReadYourWritesTtl = ""
+	return conf
+}
+
+// NewController creates a controller on an in-memory store; file may be empty to keep the data only in memory
+func NewController(conf config.Config, file string) (*controller.Controller, error) {
+	store, err := NewStore(conf, file)
+	if err != nil {
+		return nil, err
+	}
+	return controller.NewWithDependencies(conf, NewPublisher(store), NewCom(store))
+}
@@ -0,0 +1,177 @@
+/*
+ * Copyright 2024 InfAI (CC SES)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package devmode
+
+import (
+	"errors"
+	"github.com/SENERGY-Platform/models/go/models"
+)
+
+// Publisher implements controller.Publisher by applying the commands directly to a Store,
+// like the device-repository would when consuming them.
+type Publisher struct {
+	store *Store
+}
+
+func NewPublisher(store *Store) *Publisher {
+	return &Publisher{store: store}
+}
+
+var errMissingOwner = errors.New("missing owner in command")
+
+func (this *Publisher) PublishDevice(device models.Device, userID string) (err error) {
+	if userID == "" {
+		return errMissingOwner
+	}
+	set(this.store, devices, this.store.config.DeviceTopic, device.Id, userID, device)
+	return nil
+}
+
+func (this *Publisher) PublishDeviceDelete(id string, userID string) error {
+	remove(this.store, devices, this.store.config.DeviceTopic, id)
+	return nil
+}
+
+func (this *Publisher) PublishDeviceType(dt models.DeviceType, userID string) (err error) {
+	if userID == "" {
+		return errMissingOwner
+	}
+	set(this.store, deviceTypes, this.store.config.DeviceTypeTopic, dt.Id, userID, dt)
+	return nil
+}
+
+func (this *Publisher) PublishDeviceTypeDelete(id string, userID string) error {
+	remove(this.store, deviceTypes, this.store.config.DeviceTypeTopic, id)
+	return nil
+}
+
+func (this *Publisher) PublishDeviceGroup(dg models.DeviceGroup, userID string) (err error) {
+	if userID == "" {
+		return errMissingOwner
+	}
+	set(this.store, deviceGroups, this.store.config.DeviceGroupTopic, dg.Id, userID, dg)
+	return nil
+}
+
+func (this *Publisher) PublishDeviceGroupDelete(id string, userID string) error {
+	remove(this.store, deviceGroups, this.store.config.DeviceGroupTopic, id)
+	return nil
+}
+
+func (this *Publisher) PublishProtocol(protocol models.Protocol, userID string) (err error) {
+	if userID == "" {
+		return errMissingOwner
+	}
+	set(this.store, protocols, this.store.config.ProtocolTopic, protocol.Id, userID, protocol)
+	return nil
+}
+
+func (this *Publisher) PublishProtocolDelete(id string, userID string) error {
+	remove(this.store, protocols, this.store.config.ProtocolTopic, id)
+	return nil
+}
+
+func (this *Publisher) PublishHub(hub models.Hub, userID string) (err error) {
+	if userID == "" {
+		return errMissingOwner
+	}
+	set(this.store, hubs, this.store.config.HubTopic, hub.Id, userID, hub)
+	return nil
+}
+
+func (this *Publisher) PublishHubDelete(id string, userID string) error {
+	remove(this.store, hubs, this.store.config.HubTopic, id)
+	return nil
+}
+
+func (this *Publisher) PublishConcept(concept models.Concept, userID string) (err error) {
+	if userID == "" {
+		return errMissingOwner
+	}
+	set(this.store, concepts, this.store.config.ConceptTopic, concept.Id, userID, concept)
+	return nil
+}
+
+func (this *Publisher) PublishConceptDelete(id string, userID string) error {
+	remove(this.store, concepts, this.store.config.ConceptTopic, id)
+	return nil
+}
+
+func (this *Publisher) PublishCharacteristic(characteristic models.Characteristic, userID string) (err error) {
+	if userID == "" {
+		return errMissingOwner
+	}
+	set(this.store, characteristics, this.store.config.CharacteristicTopic, characteristic.Id, userID, characteristic)
+	return nil
+}
+
+func (this *Publisher) PublishCharacteristicDelete(id string, userID string) error {
+	remove(this.store, characteristics, this.store.config.CharacteristicTopic, id)
+	return nil
+}
+
+func (this *Publisher) PublishAspect(aspect models.Aspect, userID string) (err error) {
+	if userID == "" {
+		return errMissingOwner
+	}
+	set(this.store, aspects, this.store.config.AspectTopic, aspect.Id, userID, aspect)
+	return nil
+}
+
+func (this *Publisher) PublishAspectDelete(id string, userID string) error {
+	remove(this.store, aspects, this.store.config.AspectTopic, id)
+	return nil
+}
+
+func (this *Publisher) PublishFunction(function models.Function, userID string) (err error) {
+	if userID == "" {
+		return errMissingOwner
+	}
+	set(this.store, functions, this.store.config.FunctionTopic, function.Id, userID, function)
+	return nil
+}
+
+func (this *Publisher) PublishFunctionDelete(id string, userID string) error {
+	remove(this.store, functions, this.store.config.FunctionTopic, id)
+	return nil
+}
+
+func (this *Publisher) PublishDeviceClass(deviceClass models.DeviceClass, userID string) (err error) {
+	if userID == "" {
+		return errMissingOwner
+	}
+	set(this.store, deviceClasses, this.store.config.DeviceClassTopic, deviceClass.Id, userID, deviceClass)
+	return nil
+}
+
+func (this *Publisher) PublishDeviceClassDelete(id string, userID string) error {
+	remove(this.store, deviceClasses, this.store.config.DeviceClassTopic, id)
+	return nil
+}
+
+func (this *Publisher) PublishLocation(location models.Location, userID string) (err error) {
+	if userID == "" {
+		return errMissingOwner
+	}
+	set(this.store, locations, this.store.config.LocationTopic, location.Id, userID, location)
+	return nil
+}
+
+func (this *Publisher) PublishLocationDelete(id string, userID string) error {
+	remove(this.store, locations, this.store.config.LocationTopic, id)
+	return nil
+}
@@ -0,0 +1,270 @@
+/*
+ * Copyright 2024 InfAI (CC SES)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package devmode
+
+import (
+	"encoding/json"
+	"errors"
+	"github.com/SENERGY-Platform/device-manager/lib/config"
+	"github.com/SENERGY-Platform/device-manager/lib/controller/com"
+	"github.com/SENERGY-Platform/models/go/models"
+	"github.com/SENERGY-Platform/permissions-v2/pkg/model"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Store holds all resources and permissions in memory.
+// if a file is given, the data is loaded from it on start and written to it after every change.
+type Store struct {
+	config config.Config
+	file   string
+	mux    sync.RWMutex
+	data   Data
+}
+
+type Data struct {
+	Devices         map[string]models.Device         `json:"devices"`
+	Hubs            map[string]models.Hub            `json:"hubs"`
+	DeviceTypes     map[string]models.DeviceType     `json:"device_types"`
+	DeviceGroups    map[string]models.DeviceGroup    `json:"device_groups"`
+	Locations       map[string]models.Location       `json:"locations"`
+	Protocols       map[string]models.Protocol       `json:"protocols"`
+	Aspects         map[string]models.Aspect         `json:"aspects"`
+	Functions       map[string]models.Function       `json:"functions"`
+	Concepts        map[string]models.Concept        `json:"concepts"`
+	Characteristics map[string]models.Characteristic `json:"characteristics"`
+	DeviceClasses   map[string]models.DeviceClass    `json:"device_classes"`
+
+	Permissions map[string]map[string]model.ResourcePermissions `json:"permissions"` //topic -> resource id -> permissions
+}
+
+// NewStore creates a store; file may be empty to keep the data only in memory
+func NewStore(conf config.Config, file string) (result *Store, err error) {
+	result = &Store{config: conf, file: file}
+	if file != "" {
+		buf, err := os.ReadFile(file)
+		if err != nil && !errors.Is(err, os.ErrNotExist) {
+			return result, err
+		}
+		if err == nil {
+			err = json.Unmarshal(buf, &result.data)
+			if err != nil {
+				return result, err
+			}
+		}
+	}
+	result.data.init()
+	return result, nil
+}
+
+func (this *Data) init() {
+	this.Devices = initMap(this.Devices)
+	this.Hubs = initMap(this.Hubs)
+	this.DeviceTypes = initMap(this.DeviceTypes)
+	this.DeviceGroups = initMap(this.DeviceGroups)
+	this.Locations = initMap(this.Locations)
+	this.Protocols = initMap(this.Protocols)
+	this.Aspects = initMap(this.Aspects)
+	this.Functions = initMap(this.Functions)
+	this.Concepts = initMap(this.Concepts)
+	this.Characteristics = initMap(this.Characteristics)
+	this.DeviceClasses = initMap(this.DeviceClasses)
+	this.Permissions = initMap(this.Permissions)
+}
+
+func initMap[T any](m map[string]T) map[string]T {
+	if m == nil {
+		return map[string]T{}
+	}
+	return m
+}
+
+// must be called while holding the write lock
+func (this *Store) save() {
+	if this.file == "" {
+		return
+	}
+	buf, err := json.MarshalIndent(this.data, "", "  ")
+	if err != nil {
+		log.Println("ERROR: unable to serialize dev data", err)
+		return
+	}
+	temp := filepath.Join(filepath.Dir(this.file), "."+filepath.Base(this.file)+".tmp")
+	err = os.WriteFile(temp, buf, 0644)
+	if err != nil {
+		log.Println("ERROR: unable to write dev data", err)
+		return
+	}
+	err = os.Rename(temp, this.file)
+	if err != nil {
+		log.Println("ERROR: unable to write dev data", err)
+	}
+}
+
+func get[T any](store *Store, collection func(data *Data) map[string]T, id string) (result T, err error, code int) {
+	store.mux.RLock()
+	defer store.mux.RUnlock()
+	result, ok := collection(&store.data)[removeIdModifier(id)]
+	if !ok {
+		return result, errors.New("not found"), http.StatusNotFound
+	}
+	return result, nil, http.StatusOK
+}
+
+func exists[T any](store *Store, collection func(data *Data) map[string]T, id string) bool {
+	_, err, _ := get(store, collection, id)
+	return err == nil
+}
+
+// set stores the element and, if no permissions exist yet, grants all rights to the owner
+func set[T any](store *Store, collection func(data *Data) map[string]T, topic string, id string, owner string, element T) {
+	store.mux.Lock()
+	defer store.mux.Unlock()
+	collection(&store.data)[id] = element
+	permissions := store.data.Permissions[topic]
+	if permissions == nil {
+		permissions = map[string]model.ResourcePermissions{}
+		store.data.Permissions[topic] = permissions
+	}
+	if _, ok := permissions[id]; !ok && owner != "" {
+		permissions[id] = model.ResourcePermissions{
+			UserPermissions:  map[string]model.PermissionsMap{owner: {Read: true, Write: true, Execute: true, Administrate: true}},
+			GroupPermissions: map[string]model.PermissionsMap{},
+			RolePermissions:  map[string]model.PermissionsMap{},
+		}
+	}
+	store.save()
+}
+
+func remove[T any](store *Store, collection func(data *Data) map[string]T, topic string, id string) {
+	store.mux.Lock()
+	defer store.mux.Unlock()
+	delete(collection(&store.data), id)
+	delete(store.data.Permissions[topic], id)
+	store.save()
+}
+
+type listOptions struct {
+	ids    []string
+	search string
+	limit  int64
+	offset int64
+	sortBy string
+}
+
+// list mirrors the device-repository list semantics: ids != nil ignores limit/offset, default limit is 100, default sort is name.asc;
+// sortBy "id.asc" or "id.desc" sorts by id
+func list[T any](store *Store, collection func(data *Data) map[string]T, name func(T) string, options listOptions, filter func(T) bool) (result []T) {
+	store.mux.RLock()
+	defer store.mux.RUnlock()
+	result = []T{}
+	search := strings.ToLower(options.search)
+	elements := collection(&store.data)
+	ids := make([]string, 0, len(elements))
+	for id := range elements {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids) //stable order for elements with equal names
+	for _, id := range ids {
+		element := elements[id]
+		if options.ids != nil && !contains(options.ids, id) {
+			continue
+		}
+		if search != "" && !strings.Contains(strings.ToLower(name(element)), search) {
+			continue
+		}
+		if filter != nil && !filter(element) {
+			continue
+		}
+		result = append(result, element)
+	}
+	desc := strings.HasSuffix(options.sortBy, ".desc")
+	if strings.HasPrefix(options.sortBy, "id.") {
+		//result is already in id order
+		if desc {
+			slices.Reverse(result)
+		}
+	} else {
+		sort.SliceStable(result, func(i, j int) bool {
+			if desc {
+				return name(result[i]) > name(result[j])
+			}
+			return name(result[i]) < name(result[j])
+		})
+	}
+	if options.ids != nil {
+		return result
+	}
+	limit := options.limit
+	if limit <= 0 {
+		limit = 100
+	}
+	if options.offset >= int64(len(result)) {
+		return []T{}
+	}
+	end := min(options.offset+limit, int64(len(result)))
+	return result[options.offset:end]
+}
+
+func contains(list []string, value string) bool {
+	for _, element := range list {
+		if element == value {
+			return true
+		}
+	}
+	return false
+}
+
+func removeIdModifier(id string) string {
+	return strings.SplitN(id, com.Seperator, 2)[0]
+}
+
+// Permissions returns the permissions of a resource; topic identifies the resource kind (e.g. config.DeviceTopic)
+func (this *Store) Permissions(topic string, id string) (result model.ResourcePermissions, ok bool) {
+	this.mux.RLock()
+	defer this.mux.RUnlock()
+	result, ok = this.data.Permissions[topic][removeIdModifier(id)]
+	return result, ok
+}
+
+func (this *Store) setPermissions(topic string, id string, permissions model.ResourcePermissions) {
+	this.mux.Lock()
+	defer this.mux.Unlock()
+	if this.data.Permissions[topic] == nil {
+		this.data.Permissions[topic] = map[string]model.ResourcePermissions{}
+	}
+	this.data.Permissions[topic][id] = permissions
+	this.save()
+}
+
+func (this *Store) listPermissions(topic string) (result []model.Resource) {
+	this.mux.RLock()
+	defer this.mux.RUnlock()
+	for id, permissions := range this.data.Permissions[topic] {
+		result = append(result, model.Resource{Id: id, TopicId: topic, ResourcePermissions: permissions})
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Id < result[j].Id
+	})
+	return result
+}
@@ -0,0 +1,600 @@
+/*
+ * Copyright 2024 InfAI (CC SES)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package fakes
+
+import (
+	"errors"
+	"github.com/SENERGY-Platform/device-manager/lib/auth"
+	"github.com/SENERGY-Platform/device-manager/lib/config"
+	"github.com/SENERGY-Platform/device-manager/lib/devmode"
+	"github.com/SENERGY-Platform/device-repository/lib/client"
+	"github.com/SENERGY-Platform/models/go/models"
+	"github.com/SENERGY-Platform/permissions-v2/pkg/model"
+	"net/http"
+	"net/url"
+	"sync"
+)
+
+// Com implements controller.Com on an in-memory store.
+// unlike devmode.Com, permissions are checked like permissions-v2 would check them:
+// admins may do everything, other users need the requested rights as user, group or role.
+type Com struct {
+	*devmode.Com
+	config config.Config
+	store  *devmode.Store
+
+	mux                sync.Mutex
+	repositoryFailures map[string]failure //method name -> failure; "" for all repository methods
+	permissionFailures map[string]failure //method name -> failure; "" for all permission methods
+	denied             map[denial]bool
+}
+
+type failure struct {
+	err  error
+	code int
+}
+
+type denial struct {
+	userId string
+	topic  string
+	id     string
+}
+
+func NewCom(conf config.Config, store *devmode.Store) *Com {
+	return &Com{
+		Com:                devmode.NewCom(store),
+		config:             conf,
+		store:              store,
+		repositoryFailures: map[string]failure{},
+		permissionFailures: map[string]failure{},
+		denied:             map[denial]bool{},
+	}
+}
+
+// FailRepository lets the repository method (e.g. "GetDevice" or "ValidateDevice") return err and code;
+// method "" lets all repository methods fail
+func (this *Com) FailRepository(method string, err error, code int) {
+	this.mux.Lock()
+	defer this.mux.Unlock()
+	this.repositoryFailures[method] = failure{err: err, code: code}
+}
+
+// FailPermissions lets all permission methods return err and code
+func (this *Com) FailPermissions(err error, code int) {
+	this.FailPermission("", err, code)
+}
+
+// FailPermission lets the permission method (e.g. "SetPermission" or "GetResourceRights") return err and code;
+// method "" lets all permission methods fail, "check" all permission checks
+func (this *Com) FailPermission(method string, err error, code int) {
+	this.mux.Lock()
+	defer this.mux.Unlock()
+	this.permissionFailures[method] = failure{err: err, code: code}
+}
+
+// DenyPermission lets all permission checks of userId for the resource fail with 403, even for admins;
+// topic identifies the resource kind (e.g. config.DeviceTopic), id "" denies all resources of the kind
+func (this *Com) DenyPermission(userId string, topic string, id string) {
+	this.mux.Lock()
+	defer this.mux.Unlock()
+	this.denied[denial{userId: userId, topic: topic, id: id}] = true
+}
+
+// Reset removes all simulated failures and denials
+func (this *Com) Reset() {
+	this.mux.Lock()
+	defer this.mux.Unlock()
+	this.repositoryFailures = map[string]failure{}
+	this.permissionFailures = map[string]failure{}
+	this.denied = map[denial]bool{}
+}
+
+func (this *Com) repositoryFailure(method string) (err error, code int) {
+	this.mux.Lock()
+	defer this.mux.Unlock()
+	if f, ok := this.repositoryFailures[method]; ok {
+		return f.err, f.code
+	}
+	if f, ok := this.repositoryFailures[""]; ok {
+		return f.err, f.code
+	}
+	return nil, http.StatusOK
+}
+
+func (this *Com) permissionsFailure(method string) (err error, code int) {
+	this.mux.Lock()
+	defer this.mux.Unlock()
+	if f, ok := this.permissionFailures[method]; ok {
+		return f.err, f.code
+	}
+	if f, ok := this.permissionFailures[""]; ok {
+		return f.err, f.code
+	}
+	return nil, http.StatusOK
+}
+
+func (this *Com) isDenied(userId string, topic string, id string) bool {
+	this.mux.Lock()
+	defer this.mux.Unlock()
+	return this.denied[denial{userId: userId, topic: topic, id: id}] || this.denied[denial{userId: userId, topic: topic}]
+}
+
+func (this *Com) check(token auth.Token, topic string, id string, permission string) (err error, code int) {
+	if err, code = this.permissionsFailure("check"); err != nil {
+		return err, code
+	}
+	if this.isDenied(token.GetUserId(), topic, id) {
+		return errors.New("access denied"), http.StatusForbidden
+	}
+	if token.IsAdmin() {
+		return nil, http.StatusOK
+	}
+	rights, err := model.PermissionListFromString(permission)
+	if err != nil {
+		return err, http.StatusInternalServerError
+	}
+	permissions, ok := this.store.Permissions(topic, id)
+	if !ok {
+		return errors.New("not found"), http.StatusNotFound
+	}
+	if !hasPermissions(token, permissions, rights) {
+		return errors.New("access denied"), http.StatusForbidden
+	}
+	return nil, http.StatusOK
+}
+
+func hasPermissions(token auth.Token, permissions model.ResourcePermissions, rights model.PermissionList) bool {
+	for _, right := range rights {
+		if !hasPermission(token, permissions, right) {
+			return false
+		}
+	}
+	return true
+}
+
+func hasPermission(token auth.Token, permissions model.ResourcePermissions, right model.Permission) bool {
+	if allows(permissions.UserPermissions[token.GetUserId()], right) {
+		return true
+	}
+	for _, group := range token.GetGroups() {
+		if allows(permissions.GroupPermissions[group], right) {
+			return true
+		}
+	}
+	for _, role := range token.GetRoles() {
+		if allows(permissions.RolePermissions[role], right) {
+			return true
+		}
+	}
+	return false
+}
+
+func allows(m model.PermissionsMap, right model.Permission) bool {
+	switch right {
+	case model.Read:
+		return m.Read
+	case model.Write:
+		return m.Write
+	case model.Execute:
+		return m.Execute
+	case model.Administrate:
+		return m.Administrate
+	}
+	return false
+}
+
+// readable filters list results like the device-repository, which only lists resources the user may read
+func readable[T any](this *Com, jwt string, topic string, list []T, id func(T) string) []T {
+	token, err := auth.Parse(jwt)
+	if err != nil {
+		return []T{}
+	}
+	result := []T{}
+	for _, element := range list {
+		if err, _ := this.check(token, topic, id(element), "r"); err == nil {
+			result = append(result, element)
+		}
+	}
+	return result
+}
+
+// permissions
+
+func (this *Com) ResourcesEffectedByUserDelete(token auth.Token, resource string) (deleteResourceIds []string, deleteUserFromResource []model.Resource, err error) {
+	if err, _ = this.permissionsFailure("ResourcesEffectedByUserDelete"); err != nil {
+		return nil, nil, err
+	}
+	return this.Com.ResourcesEffectedByUserDelete(token, resource)
+}
+
+func (this *Com) GetResourceRights(token auth.Token, kind string, id string) (result model.Resource, err error, code int) {
+	if err, code = this.permissionsFailure("GetResourceRights"); err != nil {
+		return result, err, code
+	}
+	return this.Com.GetResourceRights(token, kind, id)
+}
+
+func (this *Com) SetPermission(token string, topicId string, id string, permissions model.ResourcePermissions) (result model.ResourcePermissions, err error, code int) {
+	if err, code = this.permissionsFailure("SetPermission"); err != nil {
+		return result, err, code
+	}
+	return this.Com.SetPermission(token, topicId, id, permissions)
+}
+
+func (this *Com) PermissionCheckForDeviceList(token auth.Token, ids []string, rights string) (result map[string]bool, err error, code int) {
+	if err, code = this.permissionsFailure("PermissionCheckForDeviceList"); err != nil {
+		return result, err, code
+	}
+	result = map[string]bool{}
+	for _, id := range ids {
+		err, _ := this.check(token, this.config.DeviceTopic, id, rights)
+		result[id] = err == nil
+	}
+	return result, nil, http.StatusOK
+}
+
+func (this *Com) PermissionCheckForDevice(token auth.Token, id string, permission string) (err error, code int) {
+	return this.check(token, this.config.DeviceTopic, id, permission)
+}
+
+func (this *Com) PermissionCheckForHub(token auth.Token, id string, permission string) (err error, code int) {
+	return this.check(token, this.config.HubTopic, id, permission)
+}
+
+func (this *Com) PermissionCheckForDeviceGroup(token auth.Token, id string, permission string) (err error, code int) {
+	return this.check(token, this.config.DeviceGroupTopic, id, permission)
+}
+
+func (this *Com) PermissionCheckForDeviceType(token auth.Token, id string, permission string) (err error, code int) {
+	return this.check(token, this.config.DeviceTypeTopic, id, permission)
+}
+
+func (this *Com) PermissionCheckForConcept(token auth.Token, id string, permission string) (err error, code int) {
+	return this.check(token, this.config.ConceptTopic, id, permission)
+}
+
+func (this *Com) PermissionCheckForCharacteristic(token auth.Token, id string, permission string) (err error, code int) {
+	return this.check(token, this.config.CharacteristicTopic, id, permission)
+}
+
+func (this *Com) PermissionCheckForLocation(token auth.Token, id string, permission string) (err error, code int) {
+	return this.check(token, this.config.LocationTopic, id, permission)
+}
+
+// devices
+
+func (this *Com) ListDevicesByQuery(token auth.Token, query url.Values) (result []models.Device, err error, code int) {
+	if err, code = this.repositoryFailure("ListDevicesByQuery"); err != nil {
+		return result, err, code
+	}
+	result, err, code = this.Com.ListDevicesByQuery(token, query)
+	return readable(this, token.Jwt(), this.config.DeviceTopic, result, func(e models.Device) string { return e.Id }), err, code
+}
+
+func (this *Com) ListDevices(token string, options client.DeviceListOptions) (result []models.Device, err error, code int) {
+	if err, code = this.repositoryFailure("ListDevices"); err != nil {
+		return result, err, code
+	}
+	result, err, code = this.Com.ListDevices(token, options)
+	return readable(this, token, this.config.DeviceTopic, result, func(e models.Device) string { return e.Id }), err, code
+}
+
+func (this *Com) GetDevice(token auth.Token, id string) (result models.Device, err error, code int) {
+	if err, code = this.repositoryFailure("GetDevice"); err != nil {
+		return result, err, code
+	}
+	return this.Com.GetDevice(token, id)
+}
+
+func (this *Com) GetDeviceByLocalId(token auth.Token, ownerId string, localId string) (result models.Device, err error, code int) {
+	if err, code = this.repositoryFailure("GetDeviceByLocalId"); err != nil {
+		return result, err, code
+	}
+	return this.Com.GetDeviceByLocalId(token, ownerId, localId)
+}
+
+func (this *Com) DeviceLocalIdToId(token auth.Token, localId string) (id string, err error, code int) {
+	if err, code = this.repositoryFailure("DeviceLocalIdToId"); err != nil {
+		return id, err, code
+	}
+	return this.Com.DeviceLocalIdToId(token, localId)
+}
+
+func (this *Com) DevicesOfTypeExist(token auth.Token, deviceTypeId string) (result bool, err error, code int) {
+	if err, code = this.repositoryFailure("DevicesOfTypeExist"); err != nil {
+		return result, err, code
+	}
+	return this.Com.DevicesOfTypeExist(token, deviceTypeId)
+}
+
+func (this *Com) ValidateDevice(token auth.Token, device models.Device) (err error, code int) {
+	if err, code = this.repositoryFailure("ValidateDevice"); err != nil {
+		return err, code
+	}
+	//the device-repository requires local ids, devmode accepts devices without
+	if device.LocalId == "" {
+		return errors.New("missing local id"), http.StatusBadRequest
+	}
+	return this.Com.ValidateDevice(token, device)
+}
+
+// hubs
+
+func (this *Com) ListHubs(token string, options client.HubListOptions) (result []models.Hub, err error, code int) {
+	if err, code = this.repositoryFailure("ListHubs"); err != nil {
+		return result, err, code
+	}
+	result, err, code = this.Com.ListHubs(token, options)
+	return readable(this, token, this.config.HubTopic, result, func(e models.Hub) string { return e.Id }), err, code
+}
+
+func (this *Com) GetHub(token auth.Token, id string) (result models.Hub, err error, code int) {
+	if err, code = this.repositoryFailure("GetHub"); err != nil {
+		return result, err, code
+	}
+	return this.Com.GetHub(token, id)
+}
+
+func (this *Com) ValidateHub(token auth.Token, hub models.Hub) (err error, code int) {
+	if err, code = this.repositoryFailure("ValidateHub"); err != nil {
+		return err, code
+	}
+	return this.Com.ValidateHub(token, hub)
+}
+
+// device-types
+
+func (this *Com) ListDeviceTypes(token string, options client.DeviceTypeListOptions) (result []models.DeviceType, err error, code int) {
+	if err, code = this.repositoryFailure("ListDeviceTypes"); err != nil {
+		return result, err, code
+	}
+	return this.Com.ListDeviceTypes(token, options)
+}
+
+func (this *Com) GetDeviceType(token auth.Token, id string) (result models.DeviceType, err error, code int) {
+	if err, code = this.repositoryFailure("GetDeviceType"); err != nil {
+		return result, err, code
+	}
+	return this.Com.GetDeviceType(token, id)
+}
+
+func (this *Com) ValidateDeviceType(token auth.Token, dt models.DeviceType) (err error, code int) {
+	if err, code = this.repositoryFailure("ValidateDeviceType"); err != nil {
+		return err, code
+	}
+	return this.Com.ValidateDeviceType(token, dt)
+}
+
+// device-groups
+
+func (this *Com) ListDeviceGroups(token string, options client.DeviceGroupListOptions) (result []models.DeviceGroup, err error, code int) {
+	if err, code = this.repositoryFailure("ListDeviceGroups"); err != nil {
+		return result, err, code
+	}
+	result, err, code = this.Com.ListDeviceGroups(token, options)
+	return readable(this, token, this.config.DeviceGroupTopic, result, func(e models.DeviceGroup) string { return e.Id }), err, code
+}
+
+func (this *Com) GetTechnicalDeviceGroup(token auth.Token, id string) (result models.DeviceGroup, err error, code int) {
+	if err, code = this.repositoryFailure("GetTechnicalDeviceGroup"); err != nil {
+		return result, err, code
+	}
+	return this.Com.GetTechnicalDeviceGroup(token, id)
+}
+
+func (this *Com) ValidateDeviceGroup(token auth.Token, dg models.DeviceGroup) (err error, code int) {
+	if err, code = this.repositoryFailure("ValidateDeviceGroup"); err != nil {
+		return err, code
+	}
+	return this.Com.ValidateDeviceGroup(token, dg)
+}
+
+func (this *Com) ValidateDeviceGroupDelete(token auth.Token, id string) (err error, code int) {
+	if err, code = this.repositoryFailure("ValidateDeviceGroupDelete"); err != nil {
+		return err, code
+	}
+	return this.Com.ValidateDeviceGroupDelete(token, id)
+}
+
+// locations
+
+func (this *Com) ListLocations(token string, options client.LocationListOptions) (result []models.Location, err error, code int) {
+	if err, code = this.repositoryFailure("ListLocations"); err != nil {
+		return result, err, code
+	}
+	result, err, code = this.Com.ListLocations(token, options)
+	return readable(this, token, this.config.LocationTopic, result, func(e models.Location) string { return e.Id }), err, code
+}
+
+func (this *Com) GetLocation(token auth.Token, id string) (result models.Location, err error, code int) {
+	if err, code = this.repositoryFailure("GetLocation"); err != nil {
+		return result, err, code
+	}
+	return this.Com.GetLocation(token, id)
+}
+
+func (this *Com) ValidateLocation(token auth.Token, location models.Location) (err error, code int) {
+	if err, code = this.repositoryFailure("ValidateLocation"); err != nil {
+		return err, code
+	}
+	return this.Com.ValidateLocation(token, location)
+}
+
+// protocols
+
+func (this *Com) ListProtocols(token string, limit int64, offset int64, sort string) (result []models.Protocol, err error, code int) {
+	if err, code = this.repositoryFailure("ListProtocols"); err != nil {
+		return result, err, code
+	}
+	return this.Com.ListProtocols(token, limit, offset, sort)
+}
+
+func (this *Com) GetProtocol(token auth.Token, id string) (result models.Protocol, err error, code int) {
+	if err, code = this.repositoryFailure("GetProtocol"); err != nil {
+		return result, err, code
+	}
+	return this.Com.GetProtocol(token, id)
+}
+
+func (this *Com) ValidateProtocol(token auth.Token, protocol models.Protocol) (err error, code int) {
+	if err, code = this.repositoryFailure("ValidateProtocol"); err != nil {
+		return err, code
+	}
+	return this.Com.ValidateProtocol(token, protocol)
+}
+
+// semantics
+
+func (this *Com) ListAspects(options client.AspectListOptions) (result []models.Aspect, err error, code int) {
+	if err, code = this.repositoryFailure("ListAspects"); err != nil {
+		return result, err, code
+	}
+	return this.Com.ListAspects(options)
+}
+
+func (this *Com) GetAspect(token auth.Token, id string) (result models.Aspect, err error, code int) {
+	if err, code = this.repositoryFailure("GetAspect"); err != nil {
+		return result, err, code
+	}
+	return this.Com.GetAspect(token, id)
+}
+
+func (this *Com) ValidateAspect(token auth.Token, aspect models.Aspect) (err error, code int) {
+	if err, code = this.repositoryFailure("ValidateAspect"); err != nil {
+		return err, code
+	}
+	return this.Com.ValidateAspect(token, aspect)
+}
+
+func (this *Com) ValidateAspectDelete(token auth.Token, id string) (err error, code int) {
+	if err, code = this.repositoryFailure("ValidateAspectDelete"); err != nil {
+		return err, code
+	}
+	return this.Com.ValidateAspectDelete(token, id)
+}
+
+func (this *Com) ListFunctions(options client.FunctionListOptions) (result []models.Function, err error, code int) {
+	if err, code = this.repositoryFailure("ListFunctions"); err != nil {
+		return result, err, code
+	}
+	return this.Com.ListFunctions(options)
+}
+
+func (this *Com) GetFunction(token auth.Token, id string) (result models.Function, err error, code int) {
+	if err, code = this.repositoryFailure("GetFunction"); err != nil {
+		return result, err, code
+	}
+	return this.Com.GetFunction(token, id)
+}
+
+func (this *Com) ValidateFunction(token auth.Token, function models.Function) (err error, code int) {
+	if err, code = this.repositoryFailure("ValidateFunction"); err != nil {
+		return err, code
+	}
+	return this.Com.ValidateFunction(token, function)
+}
+
+func (this *Com) ValidateFunctionDelete(token auth.Token, id string) (err error, code int) {
+	if err, code = this.repositoryFailure("ValidateFunctionDelete"); err != nil {
+		return err, code
+	}
+	return this.Com.ValidateFunctionDelete(token, id)
+}
+
+func (this *Com) ListConcepts(options client.ConceptListOptions) (result []models.Concept, err error, code int) {
+	if err, code = this.repositoryFailure("ListConcepts"); err != nil {
+		return result, err, code
+	}
+	return this.Com.ListConcepts(options)
+}
+
+func (this *Com) GetConcept(token auth.Token, id string) (result models.Concept, err error, code int) {
+	if err, code = this.repositoryFailure("GetConcept"); err != nil {
+		return result, err, code
+	}
+	return this.Com.GetConcept(token, id)
+}
+
+func (this *Com) ValidateConcept(token auth.Token, concept models.Concept) (err error, code int) {
+	if err, code = this.repositoryFailure("ValidateConcept"); err != nil {
+		return err, code
+	}
+	return this.Com.ValidateConcept(token, concept)
+}
+
+func (this *Com) ValidateConceptDelete(token auth.Token, id string) (err error, code int) {
+	if err, code = this.repositoryFailure("ValidateConceptDelete"); err != nil {
+		return err, code
+	}
+	return this.Com.ValidateConceptDelete(token, id)
+}
+
+func (this *Com) ListCharacteristics(options client.CharacteristicListOptions) (result []models.Characteristic, err error, code int) {
+	if err, code = this.repositoryFailure("ListCharacteristics"); err != nil {
+		return result, err, code
+	}
+	return this.Com.ListCharacteristics(options)
+}
+
+func (this *Com) GetCharacteristic(token auth.Token, id string) (result models.Characteristic, err error, code int) {
+	if err, code = this.repositoryFailure("GetCharacteristic"); err != nil {
+		return result, err, code
+	}
+	return this.Com.GetCharacteristic(token, id)
+}
+
+func (this *Com) ValidateCharacteristic(token auth.Token, characteristic models.Characteristic) (err error, code int) {
+	if err, code = this.repositoryFailure("ValidateCharacteristic"); err != nil {
+		return err, code
+	}
+	return this.Com.ValidateCharacteristic(token, characteristic)
+}
+
+func (this *Com) ValidateCharacteristicDelete(token auth.Token, id string) (err error, code int) {
+	if err, code = this.repositoryFailure("ValidateCharacteristicDelete"); err != nil {
+		return err, code
+	}
+	return this.Com.ValidateCharacteristicDelete(token, id)
+}
+
+func (this *Com) ListDeviceClasses(options client.DeviceClassListOptions) (result []models.DeviceClass, err error, code int) {
+	if err, code = this.repositoryFailure("ListDeviceClasses"); err != nil {
+		return result, err, code
+	}
+	return this.Com.ListDeviceClasses(options)
+}
+
+func (this *Com) GetDeviceClass(token auth.Token, id string) (result models.DeviceClass, err error, code int) {
+	if err, code = this.repositoryFailure("GetDeviceClass"); err != nil {
+		return result, err, code
+	}
+	return this.Com.GetDeviceClass(token, id)
+}
+
+func (this *Com) ValidateDeviceClass(token auth.Token, deviceClass models.DeviceClass) (err error, code int) {
+	if err, code = this.repositoryFailure("ValidateDeviceClass"); err != nil {
+		return err, code
+	}
+	return this.Com.ValidateDeviceClass(token, deviceClass)
+}
+
+func (this *Com) ValidateDeviceClassDelete(token auth.Token, id string) (err error, code int) {
+	if err, code = this.repositoryFailure("ValidateDeviceClassDelete"); err != nil {
+		return err, code
+	}
+	return this.Com.ValidateDeviceClassDelete(token, id)
+}
@@ -0,0 +1,111 @@
+/*
+ * Copyright 2024 InfAI (CC SES)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package fakes provides in-memory implementations of controller.Com and controller.Publisher
+// to test the controller and the api without kafka, device-repository or permissions-v2.
+package fakes
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/SENERGY-Platform/device-manager/lib/auth"
+	"github.com/SENERGY-Platform/device-manager/lib/config"
+	"github.com/SENERGY-Platform/device-manager/lib/controller"
+	"github.com/SENERGY-Platform/device-manager/lib/devmode"
+	"log"
+	"net/http"
+)
+
+type Fakes struct {
+	Config    config.Config
+	Store     *devmode.Store
+	Com       *Com
+	Publisher *Publisher
+}
+
+// New creates fakes sharing one in-memory store.
+// the returned Fakes.Config has done-wait enabled, to be answered by the fake publisher.
+func New(conf config.Config) (*Fakes, error) {
+	conf = devmode.Config(conf)
+	conf.HandleDoneWait = len(conf.DoneHandler) > 0
+	store, err := devmode.NewStore(conf, "")
+	if err != nil {
+		return nil, err
+	}
+	return &Fakes{
+		Config:    conf,
+		Store:     store,
+		Com:       NewCom(conf, store),
+		Publisher: NewPublisher(conf, store, nil),
+	}, nil
+}
+
+// Controller creates a controller on the fakes, which consumes the device-type commands of the publisher for its version history
+func (this *Fakes) Controller() (*controller.Controller, error) {
+	ctrl, err := controller.NewWithDependencies(this.Config, this.Publisher, this.Com)
+	if err != nil {
+		return nil, err
+	}
+	err = ctrl.SubscribeDeviceTypeVersions(context.Background(), this.Publisher)
+	if err != nil {
+		return nil, err
+	}
+	return ctrl, nil
+}
+
+// Reset removes all simulated failures, denials and recorded commands; stored resources are kept
+func (this *Fakes) Reset() {
+	this.Com.Reset()
+	this.Publisher.Reset()
+}
+
+// RepositoryHandler serves GET /{kind}/{id} like the device-repository,
+// for tests which check the repository state directly (config.DeviceRepoUrl)
+func (this *Fakes) RepositoryHandler() http.Handler {
+	router := http.NewServeMux()
+	handle(router, "devices", this.Com.GetDevice)
+	handle(router, "hubs", this.Com.GetHub)
+	handle(router, "device-types", this.Com.GetDeviceType)
+	handle(router, "device-groups", this.Com.GetTechnicalDeviceGroup)
+	handle(router, "locations", this.Com.GetLocation)
+	handle(router, "protocols", this.Com.GetProtocol)
+	handle(router, "aspects", this.Com.GetAspect)
+	handle(router, "functions", this.Com.GetFunction)
+	handle(router, "concepts", this.Com.GetConcept)
+	handle(router, "characteristics", this.Com.GetCharacteristic)
+	handle(router, "device-classes", this.Com.GetDeviceClass)
+	return router
+}
+
+func handle[T any](router *http.ServeMux, path string, get func(token auth.Token, id string) (T, error, int)) {
+	router.HandleFunc("GET /"+path+"/{id}", func(writer http.ResponseWriter, request *http.Request) {
+		token, err := auth.GetParsedToken(request)
+		if err != nil {
+			http.Error(writer, err.Error(), http.StatusBadRequest)
+			return
+		}
+		result, err, code := get(token, request.PathValue("id"))
+		if err != nil {
+			http.Error(writer, err.Error(), code)
+			return
+		}
+		writer.Header().Set("Content-Type", "application/json; charset=utf-8")
+		err = json.NewEncoder(writer).Encode(result)
+		if err != nil {
+			log.Println("ERROR: unable to encode response", err)
+		}
+	})
+}
@@ -0,0 +1,364 @@
+/*
+ * Copyright 2024 InfAI (CC SES)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package fakes
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"github.com/SENERGY-Platform/device-manager/lib/bus"
+	"github.com/SENERGY-Platform/device-manager/lib/config"
+	"github.com/SENERGY-Platform/device-manager/lib/devmode"
+	"github.com/SENERGY-Platform/device-manager/lib/kafka/publisher"
+	"github.com/SENERGY-Platform/models/go/models"
+	"github.com/SENERGY-Platform/service-commons/pkg/donewait"
+	"github.com/SENERGY-Platform/service-commons/pkg/signal"
+	"log"
+	"slices"
+	"sync"
+	"time"
+)
+
+// DefaultDoneDelay is the time between applying a command and sending its done messages.
+// the delay gives donewait.AsyncWaitMultiple time to subscribe, like the kafka round trip would in production.
+var DefaultDoneDelay = 10 * time.Millisecond
+
+// Publisher implements controller.Publisher on an in-memory store.
+// commands are applied immediately and acknowledged with done messages for every config.DoneHandler on the signal broker,
+// where they are received by the controllers done-wait.
+// Publisher is also a bus.Consumer, which delivers the applied commands like the kafka topics would.
+type Publisher struct {
+	*devmode.Publisher
+	config config.Config
+	broker *signal.Broker
+
+	mux        sync.Mutex
+	doneDelay  time.Duration
+	applyDelay time.Duration
+	failures   map[string]error //topic -> error; "" for all topics
+	dropDone   map[string]bool  //topic -> true; "" for all topics
+	commands   []Command
+	handlers   map[string][]subscriber //topic -> subscribers
+}
+
+type subscriber struct {
+	ctx     context.Context
+	group   string
+	handler bus.Handler
+	onError func(err error)
+}
+
+// Command is a published command, recorded for assertions
+type Command struct {
+	Topic   string
+	Command string //PUT | DELETE
+	Id      string
+	Owner   string
+}
+
+// NewPublisher creates a publisher; broker may be nil (defaults to signal.DefaultBroker)
+func NewPublisher(conf config.Config, store *devmode.Store, broker *signal.Broker) *Publisher {
+	if broker == nil {
+		broker = signal.DefaultBroker
+	}
+	return &Publisher{
+		Publisher: devmode.NewPublisher(store),
+		config:    conf,
+		broker:    broker,
+		doneDelay: DefaultDoneDelay,
+		failures:  map[string]error{},
+		dropDone:  map[string]bool{},
+		handlers:  map[string][]subscriber{},
+	}
+}
+
+// FailPublish lets all publishes to topic fail; topic "" lets all publishes fail
+func (this *Publisher) FailPublish(topic string, err error) {
+	this.mux.Lock()
+	defer this.mux.Unlock()
+	this.failures[topic] = err
+}
+
+// DropDone prevents done messages for topic, like a stalled consumer would; topic "" drops all done messages
+func (this *Publisher) DropDone(topic string) {
+	this.mux.Lock()
+	defer this.mux.Unlock()
+	this.dropDone[topic] = true
+}
+
+// SetDoneDelay sets the time between applying a command and sending its done messages
+func (this *Publisher) SetDoneDelay(delay time.Duration) {
+	this.mux.Lock()
+	defer this.mux.Unlock()
+	this.doneDelay = delay
+}
+
+// SetApplyDelay lets commands be applied asynchronously after delay, like consumers of the kafka topics would;
+// done messages follow the application. 0 applies commands before the publish returns
+func (this *Publisher) SetApplyDelay(delay time.Duration) {
+	this.mux.Lock()
+	defer this.mux.Unlock()
+	this.applyDelay = delay
+}
+
+// Reset removes all simulated failures and delays and the recorded commands
+func (this *Publisher) Reset() {
+	this.mux.Lock()
+	defer this.mux.Unlock()
+	this.applyDelay = 0
+	this.failures = map[string]error{}
+	this.dropDone = map[string]bool{}
+	this.commands = nil
+}
+
+// Commands returns all successfully published commands
+func (this *Publisher) Commands() []Command {
+	this.mux.Lock()
+	defer this.mux.Unlock()
+	return slices.Clone(this.commands)
+}
+
+// Subscribe lets handler receive the commands of topic, synchronously after they are applied; of each group, only the first active member receives them
+func (this *Publisher) Subscribe(ctx context.Context, topic string, group string, handler bus.Handler, onError func(err error)) error {
+	this.mux.Lock()
+	defer this.mux.Unlock()
+	this.handlers[topic] = append(this.handlers[topic], subscriber{ctx: ctx, group: group, handler: handler, onError: onError})
+	return nil
+}
+
+func (this *Publisher) publish(topic string, command string, id string, owner string, message interface{}, apply func() error) error {
+	this.mux.Lock()
+	err, ok := this.failures[topic]
+	if !ok {
+		err = this.failures[""]
+	}
+	this.mux.Unlock()
+	if err != nil {
+		return err
+	}
+	if owner == "" {
+		return errors.New("missing owner in command")
+	}
+	this.mux.Lock()
+	applyDelay := this.applyDelay
+	this.mux.Unlock()
+	if applyDelay > 0 {
+		go func() {
+			time.Sleep(applyDelay)
+			err := this.applyAndSendDone(topic, command, id, owner, message, apply)
+			if err != nil {
+				log.Println("ERROR: fake publisher unable to apply delayed command", topic, command, id, err)
+			}
+		}()
+		return nil
+	}
+	return this.applyAndSendDone(topic, command, id, owner, message, apply)
+}
+
+func (this *Publisher) applyAndSendDone(topic string, command string, id string, owner string, message interface{}, apply func() error) error {
+	err := apply()
+	if err != nil {
+		return err
+	}
+	err = this.deliver(topic, message)
+	if err != nil {
+		return err
+	}
+	this.mux.Lock()
+	defer this.mux.Unlock()
+	this.commands = append(this.commands, Command{Topic: topic, Command: command, Id: id, Owner: owner})
+	if this.dropDone[topic] || this.dropDone[""] {
+		return nil
+	}
+	messages := []string{}
+	for _, handler := range this.config.DoneHandler {
+		messages = append(messages, donewait.SerializeDoneMsg(donewait.DoneMsg{
+			ResourceKind: topic,
+			ResourceId:   id,
+			Command:      command,
+			Handler:      handler,
+		}))
+	}
+	delay := this.doneDelay
+	go func() {
+		time.Sleep(delay)
+		for _, msg := range messages {
+			this.broker.Pub(signal.Known.UpdateDone, msg)
+		}
+	}()
+	return nil
+}
+
+// deliver passes the serialized command to the subscribers of topic; handler errors are reported to their onError
+func (this *Publisher) deliver(topic string, message interface{}) error {
+	this.mux.Lock()
+	subscribers := slices.Clone(this.handlers[topic])
+	this.mux.Unlock()
+	if len(subscribers) == 0 {
+		return nil
+	}
+	msg, err := json.Marshal(message)
+	if err != nil {
+		return err
+	}
+	served := map[string]bool{}
+	for _, sub := range subscribers {
+		if sub.ctx.Err() != nil || served[sub.group] {
+			continue
+		}
+		if sub.group != "" {
+			served[sub.group] = true
+		}
+		err = sub.handler(sub.ctx, topic, msg)
+		if err != nil && sub.onError != nil {
+			sub.onError(err)
+		}
+	}
+	return nil
+}
+
+func (this *Publisher) PublishDevice(device models.Device, userID string) error {
+	return this.publish(this.config.DeviceTopic, "PUT", device.Id, userID, publisher.DeviceCommand{Command: "PUT", Id: device.Id, Owner: userID, Device: device}, func() error {
+		return this.Publisher.PublishDevice(device, userID)
+	})
+}
+
+func (this *Publisher) PublishDeviceDelete(id string, userID string) error {
+	return this.publish(this.config.DeviceTopic, "DELETE", id, userID, publisher.DeviceCommand{Command: "DELETE", Id: id, Owner: userID}, func() error {
+		return this.Publisher.PublishDeviceDelete(id, userID)
+	})
+}
+
+func (this *Publisher) PublishDeviceType(dt models.DeviceType, userID string) error {
+	return this.publish(this.config.DeviceTypeTopic, "PUT", dt.Id, userID, publisher.DeviceTypeCommand{Command: "PUT", Id: dt.Id, Owner: userID, DeviceType: dt}, func() error {
+		return this.Publisher.PublishDeviceType(dt, userID)
+	})
+}
+
+func (this *Publisher) PublishDeviceTypeDelete(id string, userID string) error {
+	return this.publish(this.config.DeviceTypeTopic, "DELETE", id, userID, publisher.DeviceTypeCommand{Command: "DELETE", Id: id, Owner: userID}, func() error {
+		return this.Publisher.PublishDeviceTypeDelete(id, userID)
+	})
+}
+
+func (this *Publisher) PublishDeviceGroup(dg models.DeviceGroup, userID string) error {
+	return this.publish(this.config.DeviceGroupTopic, "PUT", dg.Id, userID, publisher.DeviceGroupCommand{Command: "PUT", Id: dg.Id, Owner: userID, DeviceGroup: dg}, func() error {
+		return this.Publisher.PublishDeviceGroup(dg, userID)
+	})
+}
+
+func (this *Publisher) PublishDeviceGroupDelete(id string, userID string) error {
+	return this.publish(this.config.DeviceGroupTopic, "DELETE", id, userID, publisher.DeviceGroupCommand{Command: "DELETE", Id: id, Owner: userID}, func() error {
+		return this.Publisher.PublishDeviceGroupDelete(id, userID)
+	})
+}
+
+func (this *Publisher) PublishProtocol(protocol models.Protocol, userID string) error {
+	return this.publish(this.config.ProtocolTopic, "PUT", protocol.Id, userID, publisher.ProtocolCommand{Command: "PUT", Id: protocol.Id, Owner: userID, Protocol: protocol}, func() error {
+		return this.Publisher.PublishProtocol(protocol, userID)
+	})
+}
+
+func (this *Publisher) PublishProtocolDelete(id string, userID string) error {
+	return this.publish(this.config.ProtocolTopic, "DELETE", id, userID, publisher.ProtocolCommand{Command: "DELETE", Id: id, Owner: userID}, func() error {
+		return this.Publisher.PublishProtocolDelete(id, userID)
+	})
+}
+
+func (this *Publisher) PublishHub(hub models.Hub, userID string) error {
+	return this.publish(this.config.HubTopic, "PUT", hub.Id, userID, publisher.HubCommand{Command: "PUT", Id: hub.Id, Owner: userID, Hub: hub}, func() error {
+		return this.Publisher.PublishHub(hub, userID)
+	})
+}
+
+func (this *Publisher) PublishHubDelete(id string, userID string) error {
+	return this.publish(this.config.HubTopic, "DELETE", id, userID, publisher.HubCommand{Command: "DELETE", Id: id, Owner: userID}, func() error {
+		return this.Publisher.PublishHubDelete(id, userID)
+	})
+}
+
+func (this *Publisher) PublishConcept(concept models.Concept, userID string) error {
+	return this.publish(this.config.ConceptTopic, "PUT", concept.Id, userID, publisher.ConceptCommand{Command: "PUT", Id: concept.Id, Owner: userID, Concept: concept}, func() error {
+		return this.Publisher.PublishConcept(concept, userID)
+	})
+}
+
+func (this *Publisher) PublishConceptDelete(id string, userID string) error {
+	return this.publish(this.config.ConceptTopic, "DELETE", id, userID, publisher.ConceptCommand{Command: "DELETE", Id: id, Owner: userID}, func() error {
+		return this.Publisher.PublishConceptDelete(id, userID)
+	})
+}
+
+func (this *Publisher) PublishCharacteristic(characteristic models.Characteristic, userID string) error {
+	return this.publish(this.config.CharacteristicTopic, "PUT", characteristic.Id, userID, publisher.CharacteristicCommand{Command: "PUT", Id: characteristic.Id, Owner: userID, Characteristic: characteristic}, func() error {
+		return this.Publisher.PublishCharacteristic(characteristic, userID)
+	})
+}
+
+func (this *Publisher) PublishCharacteristicDelete(id string, userID string) error {
+	return this.publish(this.config.CharacteristicTopic, "DELETE", id, userID, publisher.CharacteristicCommand{Command: "DELETE", Id: id, Owner: userID}, func() error {
+		return this.Publisher.PublishCharacteristicDelete(id, userID)
+	})
+}
+
+func (this *Publisher) PublishAspect(aspect models.Aspect, userID string) error {
+	return this.publish(this.config.AspectTopic, "PUT", aspect.Id, userID, publisher.AspectCommand{Command: "PUT", Id: aspect.Id, Owner: userID, Aspect: aspect}, func() error {
+		return this.Publisher.PublishAspect(aspect, userID)
+	})
+}
+
+func (this *Publisher) PublishAspectDelete(id string, userID string) error {
+	return this.publish(this.config.AspectTopic, "DELETE", id, userID, publisher.AspectCommand{Command: "DELETE", Id: id, Owner: userID}, func() error {
+		return this.Publisher.PublishAspectDelete(id, userID)
+	})
+}
+
+func (this *Publisher) PublishFunction(function models.Function, userID string) error {
+	return this.publish(this.config.FunctionTopic, "PUT", function.Id, userID, publisher.FunctionCommand{Command: "PUT", Id: function.Id, Owner: userID, Function: function}, func() error {
+		return this.Publisher.PublishFunction(function, userID)
+	})
+}
+
+func (this *Publisher) PublishFunctionDelete(id string, userID string) error {
+	return this.publish(this.config.FunctionTopic, "DELETE", id, userID, publisher.FunctionCommand{Command: "DELETE", Id: id, Owner: userID}, func() error {
+		return this.Publisher.PublishFunctionDelete(id, userID)
+	})
+}
+
+func (this *Publisher) PublishDeviceClass(deviceClass models.DeviceClass, userID string) error {
+	return this.publish(this.config.DeviceClassTopic, "PUT", deviceClass.Id, userID, publisher.DeviceClassCommand{Command: "PUT", Id: deviceClass.Id, Owner: userID, DeviceClass: deviceClass}, func() error {
+		return this.Publisher.PublishDeviceClass(deviceClass, userID)
+	})
+}
+
+func (this *Publisher) PublishDeviceClassDelete(id string, userID string) error {
+	return this.publish(this.config.DeviceClassTopic, "DELETE", id, userID, publisher.DeviceClassCommand{Command: "DELETE", Id: id, Owner: userID}, func() error {
+		return this.Publisher.PublishDeviceClassDelete(id, userID)
+	})
+}
+
+func (this *Publisher) PublishLocation(location models.Location, userID string) error {
+	return this.publish(this.config.LocationTopic, "PUT", location.Id, userID, publisher.LocationCommand{Command: "PUT", Id: location.Id, Owner: userID, Location: location}, func() error {
+		return this.Publisher.PublishLocation(location, userID)
+	})
+}
+
+func (this *Publisher) PublishLocationDelete(id string, userID string) error {
+	return this.publish(this.config.LocationTopic, "DELETE", id, userID, publisher.LocationCommand{Command: "DELETE", Id: id, Owner: userID}, func() error {
+		return this.Publisher.PublishLocationDelete(id, userID)
+	})
+}
@@ -0,0 +1,125 @@
+/*
+ * Copyright 2024 InfAI (CC SES)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package jsonstore keeps a list of json elements in a file, which may be shared by replicas, e.g. on a shared volume.
+// writes replace the file atomically; Lock serializes read-modify-write cycles across processes with an flock on "<file>.lock".
+// Map implements these read-modify-write cycles for elements identified by a key.
+package jsonstore
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"syscall"
+)
+
+// File is the json file of a store; the zero location keeps nothing and reports no changes, for in-memory stores
+type File[T any] struct {
+	location string
+	info     os.FileInfo //of the last Read or Write
+}
+
+func New[T any](location string) *File[T] {
+	return &File[T]{location: location}
+}
+
+// Shared reports if the file is stored, as opposed to an in-memory store
+func (this *File[T]) Shared() bool {
+	return this.location != ""
+}
+
+// Read returns the elements of the file if it was replaced since the last Read or Write; changed is false otherwise.
+// a missing file is read as empty list.
+func (this *File[T]) Read() (list []T, changed bool, err error) {
+	if this.location == "" {
+		return nil, false, nil
+	}
+	info, err := os.Stat(this.location)
+	if errors.Is(err, os.ErrNotExist) {
+		changed = this.info != nil
+		this.info = nil
+		return []T{}, changed, nil
+	}
+	if err != nil {
+		return nil, false, err
+	}
+	if this.info != nil && os.SameFile(this.info, info) && this.info.ModTime().Equal(info.ModTime()) && this.info.Size() == info.Size() {
+		return nil, false, nil
+	}
+	data, err := os.ReadFile(this.location)
+	if err != nil {
+		return nil, false, err
+	}
+	list = []T{}
+	err = json.Unmarshal(data, &list)
+	if err != nil {
+		return nil, false, err
+	}
+	this.info = info
+	return list, true, nil
+}
+
+// Write replaces the file with list
+func (this *File[T]) Write(list []T) error {
+	if this.location == "" {
+		return nil
+	}
+	data, err := json.MarshalIndent(list, "", "  ")
+	if err != nil {
+		return err
+	}
+	temp, err := os.CreateTemp(filepath.Dir(this.location), filepath.Base(this.location)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(temp.Name())
+	_, err = temp.Write(data)
+	if err != nil {
+		temp.Close()
+		return err
+	}
+	err = temp.Close()
+	if err != nil {
+		return err
+	}
+	err = os.Rename(temp.Name(), this.location)
+	if err != nil {
+		return err
+	}
+	this.info, err = os.Stat(this.location)
+	return err
+}
+
+// Lock blocks until no other process holds the lock of the file; read-modify-write cycles Read after Lock and Write before unlock
+func (this *File[T]) Lock() (unlock func(), err error) {
+	if this.location == "" {
+		return func() {}, nil
+	}
+	lockFile, err := os.OpenFile(this.location+".lock", os.O_CREATE|os.O_RDWR, 0o644)
+	if err != nil {
+		return nil, err
+	}
+	err = syscall.Flock(int(lockFile.Fd()), syscall.LOCK_EX)
+	if err != nil {
+		lockFile.Close()
+		return nil, err
+	}
+	return func() {
+		syscall.Flock(int(lockFile.Fd()), syscall.LOCK_UN)
+		lockFile.Close()
+	}, nil
+}
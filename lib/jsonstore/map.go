@@ -0,0 +1,109 @@
+/*
+ * Copyright 2024 InfAI (CC SES)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package jsonstore
+
+import (
+	"errors"
+	"log"
+	"maps"
+	"sort"
+	"sync"
+)
+
+// SkipWrite may be returned by the change of Map.Update, if nothing changed; Update returns nil without writing the file
+var SkipWrite = errors.New("skip write")
+
+// Map keeps the elements of a File in memory by key and reloads them, if another process replaced the file
+type Map[T any] struct {
+	file     *File[T]
+	key      func(element T) string
+	less     func(a T, b T) bool
+	mux      sync.Mutex
+	elements map[string]T
+}
+
+// NewMap loads the elements of the json file at location, if it exists; key identifies elements and less orders them in the file
+func NewMap[T any](location string, key func(element T) string, less func(a T, b T) bool) (*Map[T], error) {
+	result := &Map[T]{file: New[T](location), key: key, less: less, elements: map[string]T{}}
+	err := result.sync()
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// View calls f with the elements, including the changes of other processes; on read errors the elements in memory are used.
+// f must not change the map
+func (this *Map[T]) View(f func(elements map[string]T)) {
+	this.mux.Lock()
+	defer this.mux.Unlock()
+	err := this.sync()
+	if err != nil {
+		log.Println("WARNING: unable to read", this.file.location, "--> use elements in memory", err)
+	}
+	f(this.elements)
+}
+
+// Update calls change with a copy of the latest elements of the file, locked against other processes, and writes the copy if change returns nil.
+// if change or the write fail, the elements remain unchanged
+func (this *Map[T]) Update(change func(elements map[string]T) error) error {
+	this.mux.Lock()
+	defer this.mux.Unlock()
+	unlock, err := this.file.Lock()
+	if err != nil {
+		return err
+	}
+	defer unlock()
+	err = this.sync()
+	if err != nil {
+		return err
+	}
+	elements := maps.Clone(this.elements)
+	err = change(elements)
+	if errors.Is(err, SkipWrite) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	list := []T{}
+	for _, element := range elements {
+		list = append(list, element)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		return this.less(list[i], list[j])
+	})
+	err = this.file.Write(list)
+	if err != nil {
+		return err
+	}
+	this.elements = elements
+	return nil
+}
+
+// sync replaces the elements in memory with those of the file, if it changed; the caller must hold the lock
+func (this *Map[T]) sync() error {
+	list, changed, err := this.file.Read()
+	if err != nil || !changed {
+		return err
+	}
+	this.elements = map[string]T{}
+	for _, element := range list {
+		this.elements[this.key(element)] = element
+	}
+	return nil
+}
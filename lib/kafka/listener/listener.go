@@ -18,31 +18,33 @@ package listener
 
 import (
 	"context"
+	"github.com/SENERGY-Platform/device-manager/lib/bus"
 	"github.com/SENERGY-Platform/device-manager/lib/config"
 	"log"
 )
 
-type Listener func(msg []byte) (err error)
+type Listener func(ctx context.Context, msg []byte) (err error)
 
 var Factories = []func(config config.Config, control Controller) (topic string, listener Listener, err error){}
 
 type Controller interface {
-	DeleteUser(userId string) error
+	DeleteUser(ctx context.Context, userId string) error
 }
 
-func Start(ctx context.Context, config config.Config, control Controller) (err error) {
+// Start subscribes all listeners on the bus, as members of config.GroupId
+func Start(ctx context.Context, config config.Config, consumer bus.Consumer, control Controller) (err error) {
 	for _, factory := range Factories {
 		topic, handler, err := factory(config, control)
 		if err != nil {
 			log.Println("ERROR: listener.factory", topic, err)
 			return err
 		}
-		_, err = NewConsumer(ctx, config.KafkaUrl, config.GroupId, topic, func(topic string, msg []byte) error {
+		err = consumer.Subscribe(ctx, topic, config.GroupId, func(ctx context.Context, topic string, msg []byte) error {
 			if config.Debug {
 				log.Println("DEBUG: consume", topic, string(msg))
 			}
-			return handler(msg)
-		}, func(err error, consumer *Consumer) {
+			return handler(ctx, msg)
+		}, func(err error) {
 			log.Fatal(err)
 		})
 		if err != nil {
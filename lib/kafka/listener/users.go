@@ -17,6 +17,7 @@
 package listener
 
 import (
+	"context"
 	"encoding/json"
 	"github.com/SENERGY-Platform/device-manager/lib/config"
 )
@@ -31,14 +32,14 @@ type UserCommandMsg struct {
 }
 
 func UsersListenerFactory(config config.Config, control Controller) (topic string, listener Listener, err error) {
-	return config.UserTopic, func(msg []byte) (err error) {
+	return config.UserTopic, func(ctx context.Context, msg []byte) (err error) {
 		command := UserCommandMsg{}
 		err = json.Unmarshal(msg, &command)
 		if err != nil {
 			return err
 		}
 		if command.Command == "DELETE" {
-			return control.DeleteUser(command.Id)
+			return control.DeleteUser(ctx, command.Id)
 		}
 		return nil
 	}, nil
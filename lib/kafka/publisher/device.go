@@ -17,14 +17,11 @@
 package publisher
 
 import (
-	"context"
 	"encoding/json"
 	"errors"
 	"github.com/SENERGY-Platform/models/go/models"
-	"github.com/segmentio/kafka-go"
 	"log"
 	"runtime/debug"
-	"time"
 )
 
 type DeviceCommand struct {
@@ -59,14 +56,7 @@ func (this *Publisher) PublishDeviceCommand(cmd DeviceCommand) error {
 		debug.PrintStack()
 		return err
 	}
-	err = this.devices.WriteMessages(
-		context.Background(),
-		kafka.Message{
-			Key:   []byte(cmd.Id),
-			Value: message,
-			Time:  time.Now(),
-		},
-	)
+	err = this.publish(this.config.DeviceTopic, cmd.Id, message)
 	if err != nil {
 		debug.PrintStack()
 	}
@@ -17,14 +17,11 @@
 package publisher
 
 import (
-	"context"
 	"encoding/json"
 	"errors"
 	"github.com/SENERGY-Platform/models/go/models"
-	"github.com/segmentio/kafka-go"
 	"log"
 	"runtime/debug"
-	"time"
 )
 
 type HubCommand struct {
@@ -59,14 +56,7 @@ func (this *Publisher) PublishHubCommand(cmd HubCommand) error {
 	if this.config.Debug {
 		log.Printf("DEBUG: produce hub %v\n", string(message))
 	}
-	err = this.hubs.WriteMessages(
-		context.Background(),
-		kafka.Message{
-			Key:   []byte(cmd.Id),
-			Value: message,
-			Time:  time.Now(),
-		},
-	)
+	err = this.publish(this.config.HubTopic, cmd.Id, message)
 	if err != nil {
 		debug.PrintStack()
 	}
@@ -0,0 +1,188 @@
+/*
+ * Copyright 2024 InfAI (CC SES)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package metrics holds the prometheus collectors of the service, served by Handler.
+package metrics
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+const namespace = "device_manager"
+
+var registry = prometheus.NewRegistry()
+
+var (
+	HttpRequests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "http_requests_total",
+		Help:      "handled http requests by route, method and status",
+	}, []string{"route", "method", "status"})
+
+	HttpRequestDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "http_request_duration_seconds",
+		Help:      "latency of http requests by route and method",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"route", "method"})
+
+	PublishDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "publish_duration_seconds",
+		Help:      "latency of command publishes by topic",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"topic"})
+
+	PublishErrors = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "publish_errors_total",
+		Help:      "failed command publishes by topic",
+	}, []string{"topic"})
+
+	DoneWait = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "done_wait_total",
+		Help:      "done-wait results by handler and outcome (success | timeout | error)",
+	}, []string{"handler", "outcome"})
+
+	ComDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "com_duration_seconds",
+		Help:      "latency of upstream calls (device-repository, permissions-v2, converter) by method and outcome (ok | error)",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"method", "outcome"})
+
+	ListenerLag = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "listener_lag_seconds",
+		Help:      "time between publishing and consuming the last message by topic; only for bus backends with message timestamps",
+	}, []string{"topic"})
+
+	ListenerRetries = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "listener_retries_total",
+		Help:      "retried message handlings by topic",
+	}, []string{"topic"})
+
+	CacheRequests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "cache_requests_total",
+		Help:      "reads of cached resources by kind (topic) and result (hit | miss)",
+	}, []string{"kind", "result"})
+
+	UpstreamRetries = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "upstream_retries_total",
+		Help:      "retried upstream requests by host",
+	}, []string{"host"})
+
+	UpstreamCircuitOpen = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "upstream_circuit_open",
+		Help:      "1 while the circuit breaker of the upstream host is open",
+	}, []string{"host"})
+)
+
+func init() {
+	registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		HttpRequests,
+		HttpRequestDuration,
+		PublishDuration,
+		PublishErrors,
+		DoneWait,
+		ComDuration,
+		ListenerLag,
+		ListenerRetries,
+		CacheRequests,
+		UpstreamRetries,
+		UpstreamCircuitOpen,
+	)
+}
+
+func Handler() http.Handler {
+	return promhttp.HandlerFor(registry, promhttp.HandlerOpts{})
+}
+
+// ObservePublish records the latency and the outcome of a publish started at start
+func ObservePublish(topic string, start time.Time, err error) {
+	PublishDuration.WithLabelValues(topic).Observe(time.Since(start).Seconds())
+	if err != nil {
+		PublishErrors.WithLabelValues(topic).Inc()
+	}
+}
+
+// ObserveCom records the latency and the outcome of an upstream call started at start
+func ObserveCom(method string, start time.Time, err error) {
+	outcome := "ok"
+	if err != nil {
+		outcome = "error"
+	}
+	ComDuration.WithLabelValues(method, outcome).Observe(time.Since(start).Seconds())
+}
+
+// ObserveListenerLag records the lag of a consumed message; messageTime may be zero if the backend has no timestamps
+func ObserveListenerLag(topic string, messageTime time.Time) {
+	if messageTime.IsZero() {
+		return
+	}
+	ListenerLag.WithLabelValues(topic).Set(time.Since(messageTime).Seconds())
+}
+
+// NewHttpMiddleware counts requests by the pattern of the matched route; unmatched or forwarded requests use the route "other"
+func NewHttpMiddleware(handler http.Handler) http.Handler {
+	return http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		start := time.Now()
+		recorder := &statusRecorder{ResponseWriter: writer, status: http.StatusOK}
+		handler.ServeHTTP(recorder, request)
+		route := request.Pattern
+		if route == "" {
+			route = "other"
+		}
+		HttpRequests.WithLabelValues(route, request.Method, strconv.Itoa(recorder.status)).Inc()
+		HttpRequestDuration.WithLabelValues(route, request.Method).Observe(time.Since(start).Seconds())
+	})
+}
+
+type statusRecorder struct {
+	http.ResponseWriter
+	status      int
+	wroteHeader bool
+}
+
+func (this *statusRecorder) WriteHeader(status int) {
+	if !this.wroteHeader {
+		this.status = status
+		this.wroteHeader = true
+	}
+	this.ResponseWriter.WriteHeader(status)
+}
+
+func (this *statusRecorder) Unwrap() http.ResponseWriter {
+	return this.ResponseWriter
+}
+
+func (this *statusRecorder) Flush() {
+	if flusher, ok := this.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
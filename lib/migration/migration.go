@@ -0,0 +1,100 @@
+/*
+ * Copyright 2024 InfAI (CC SES)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package migration stores the progress of device-type migrations in a json file, which is shared by the replicas of the service (e.g. on a shared volume).
+// jobs are removed JobRetention after their last update.
+package migration
+
+import (
+	"errors"
+	"github.com/SENERGY-Platform/device-manager/lib/jsonstore"
+	"github.com/SENERGY-Platform/device-manager/lib/model"
+	"github.com/google/uuid"
+	"time"
+)
+
+var ErrNotFound = errors.New("migration job not found")
+
+var JobRetention = 7 * 24 * time.Hour
+
+type Store struct {
+	jobs *jsonstore.Map[model.DeviceTypeMigrationJob]
+	now  func() time.Time
+}
+
+// New loads the jobs of the json file at location, if it exists.
+// a job started by one replica may be polled from any other, so location is required.
+func New(location string) (*Store, error) {
+	if location == "" || location == "-" {
+		return nil, errors.New("migration jobs need a file shared by all replicas")
+	}
+	jobs, err := jsonstore.NewMap[model.DeviceTypeMigrationJob](location, func(job model.DeviceTypeMigrationJob) string {
+		return job.Id
+	}, func(a model.DeviceTypeMigrationJob, b model.DeviceTypeMigrationJob) bool {
+		return a.CreatedAt.Before(b.CreatedAt)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &Store{jobs: jobs, now: time.Now}, nil
+}
+
+// Create stores job with a new id and creation time
+func (this *Store) Create(job model.DeviceTypeMigrationJob) (model.DeviceTypeMigrationJob, error) {
+	job.Id = uuid.NewString()
+	job.CreatedAt = this.now()
+	job.UpdatedAt = job.CreatedAt
+	err := this.jobs.Update(func(jobs map[string]model.DeviceTypeMigrationJob) error {
+		this.removeExpired(jobs)
+		jobs[job.Id] = job
+		return nil
+	})
+	return job, err
+}
+
+func (this *Store) Get(id string) (result model.DeviceTypeMigrationJob, err error) {
+	err = ErrNotFound
+	this.jobs.View(func(jobs map[string]model.DeviceTypeMigrationJob) {
+		if job, ok := jobs[id]; ok {
+			result, err = job, nil
+		}
+	})
+	return result, err
+}
+
+// Update applies change to the stored job and sets its update time
+func (this *Store) Update(id string, change func(job *model.DeviceTypeMigrationJob)) error {
+	return this.jobs.Update(func(jobs map[string]model.DeviceTypeMigrationJob) error {
+		job, ok := jobs[id]
+		if !ok {
+			return ErrNotFound
+		}
+		change(&job)
+		job.UpdatedAt = this.now()
+		jobs[id] = job
+		return nil
+	})
+}
+
+// removeExpired drops jobs, which were not updated since JobRetention
+func (this *Store) removeExpired(jobs map[string]model.DeviceTypeMigrationJob) {
+	limit := this.now().Add(-JobRetention)
+	for id, job := range jobs {
+		if job.UpdatedAt.Before(limit) {
+			delete(jobs, id)
+		}
+	}
+}
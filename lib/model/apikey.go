@@ -0,0 +1,51 @@
+/*
+ * Copyright 2024 InfAI (CC SES)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package model
+
+import "time"
+
+// ApiKey is an alternative to user tokens for machine clients, sent as "Authorization: ApiKey <key>".
+// the secret key is only returned on creation (ApiKeyCreated); the service stores its hash.
+type ApiKey struct {
+	Id        string        `json:"id"`
+	Name      string        `json:"name"`
+	UserId    string        `json:"user_id"` //identity of requests with this key: the creating user or a service account
+	Roles     []string      `json:"roles"`   //realm roles of requests with this key
+	Scopes    []ApiKeyScope `json:"scopes"`
+	CreatedBy string        `json:"created_by"`
+	CreatedAt time.Time     `json:"created_at"`
+	ExpiresAt time.Time     `json:"expires_at"`
+	RevokedAt *time.Time    `json:"revoked_at,omitempty"`
+}
+
+type ApiKeyScope struct {
+	Kind    string   `json:"kind"`    //resource kind, e.g. "devices" or "device-types"; "*" for all
+	Actions []string `json:"actions"` //read | create | update | delete | read-permissions | update-permissions; "*" for all
+}
+
+type ApiKeyCreateRequest struct {
+	Name      string        `json:"name"`
+	UserId    string        `json:"user_id,omitempty"`    //only admins may create keys for other users or service accounts; defaults to the requesting user
+	Roles     []string      `json:"roles,omitempty"`      //must be roles of the requesting user, never admin; defaults to the non-admin roles of the requesting user for own keys
+	Scopes    []ApiKeyScope `json:"scopes"`               //at least one scope
+	ExpiresAt *time.Time    `json:"expires_at,omitempty"` //defaults to and is limited by config.ApiKeyMaxTtl
+}
+
+type ApiKeyCreated struct {
+	ApiKey
+	Key string `json:"key"`
+}
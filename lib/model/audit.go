@@ -0,0 +1,51 @@
+/*
+ * Copyright 2024 InfAI (CC SES)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package model
+
+import "time"
+
+// AuditRecord describes a write operation: who did what to which resource, with which outcome
+type AuditRecord struct {
+	Time       time.Time     `json:"time"`
+	RequestId  string        `json:"request_id,omitempty"`
+	Actor      string        `json:"actor"`
+	Roles      []string      `json:"roles"`
+	Kind       string        `json:"kind"` //topic of the resource, e.g. "devices"
+	ResourceId string        `json:"resource_id"`
+	Operation  string        `json:"operation"` //see lib/audit
+	Diff       []AuditChange `json:"diff,omitempty"`
+	Outcome    string        `json:"outcome"` //success | error
+	Code       int           `json:"code"`
+	Error      string        `json:"error,omitempty"`
+}
+
+// AuditChange is a changed json field of the resource; lists are compared as a whole
+type AuditChange struct {
+	Path   string      `json:"path"`
+	Before interface{} `json:"before,omitempty"`
+	After  interface{} `json:"after,omitempty"`
+}
+
+type AuditQuery struct {
+	Resource string    //resource id
+	Kind     string    //topic of the resource
+	Actor    string    //user id
+	From     time.Time //inclusive
+	To       time.Time //exclusive
+	Limit    int64
+	Offset   int64
+}
@@ -0,0 +1,26 @@
+/*
+ * Copyright 2024 InfAI (CC SES)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package model
+
+import "github.com/SENERGY-Platform/models/go/models"
+
+// CloneRequest describes overrides applied to a copy of a device or device-type
+type CloneRequest struct {
+	Name       string             `json:"name,omitempty"`       //defaults to the name of the source
+	LocalId    string             `json:"local_id,omitempty"`   //devices only; local ids are unique per owner; defaults to a random uuid
+	Attributes []models.Attribute `json:"attributes,omitempty"` //replaces source attributes with the same key, other source attributes are kept
+}
@@ -0,0 +1,52 @@
+/*
+ * Copyright 2024 InfAI (CC SES)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package model
+
+// DeviceTypeChange is a change of a device-type update, which may affect devices and processes using the device-type
+type DeviceTypeChange struct {
+	Type     string      `json:"type"` //see DeviceTypeChange* constants
+	Path     string      `json:"path"` //e.g. "services[<service-id>].outputs[<content-id>].content_variable.sub_content_variables[<variable-id>]"
+	Before   interface{} `json:"before,omitempty"`
+	After    interface{} `json:"after,omitempty"`
+	Breaking bool        `json:"breaking"`
+}
+
+const (
+	DeviceTypeChangeServiceAdded          = "service_added"
+	DeviceTypeChangeServiceRemoved        = "service_removed"
+	DeviceTypeChangeLocalId               = "local_id_changed"
+	DeviceTypeChangeInteraction           = "interaction_changed"
+	DeviceTypeChangeProtocol              = "protocol_changed"
+	DeviceTypeChangeContentAdded          = "content_added"
+	DeviceTypeChangeContentRemoved        = "content_removed"
+	DeviceTypeChangeSerialization         = "serialization_changed"
+	DeviceTypeChangeProtocolSegment       = "protocol_segment_changed"
+	DeviceTypeChangeVariableAdded         = "variable_added"
+	DeviceTypeChangeVariableRemoved       = "variable_removed"
+	DeviceTypeChangeVariableName          = "variable_name_changed"
+	DeviceTypeChangeVariableType          = "variable_type_changed"
+	DeviceTypeChangeCharacteristicBinding = "characteristic_changed"
+	DeviceTypeChangeFunctionBinding       = "function_changed"
+	DeviceTypeChangeAspectBinding         = "aspect_changed"
+)
+
+type DeviceTypeUpdateAnalysis struct {
+	Changes       []DeviceTypeChange `json:"changes"`
+	Breaking      bool               `json:"breaking"`
+	DevicesExist  bool               `json:"devices_exist"`  //only checked for breaking changes
+	ForceRequired bool               `json:"force_required"` //breaking changes of a device-type with devices need DeviceTypeUpdateOptions.Force
+}
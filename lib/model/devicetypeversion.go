@@ -0,0 +1,38 @@
+/*
+ * Copyright 2024 InfAI (CC SES)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package model
+
+import (
+	"github.com/SENERGY-Platform/models/go/models"
+	"time"
+)
+
+// DeviceTypeVersion is a published definition of a device-type
+type DeviceTypeVersion struct {
+	DeviceTypeId string            `json:"device_type_id"`
+	Version      int               `json:"version"` //starts at 1 and increments with each changed definition
+	UserId       string            `json:"user_id"` //publishing user
+	PublishedAt  time.Time         `json:"published_at"`
+	DeviceType   models.DeviceType `json:"device_type"`
+}
+
+type DeviceTypeVersionDiff struct {
+	DeviceTypeId string        `json:"device_type_id"`
+	From         int           `json:"from"`
+	To           int           `json:"to"`
+	Changes      []AuditChange `json:"changes"`
+}
@@ -16,6 +16,8 @@
 
 package model
 
+import "time"
+
 type DeviceUpdateOptions struct {
 	UpdateOnlySameOriginAttributes []string
 	Wait                           bool
@@ -30,7 +32,8 @@ type DeviceDeleteOptions struct {
 }
 
 type DeviceTypeUpdateOptions struct {
-	Wait bool
+	Wait  bool
+	Force bool //allows breaking changes of device-types with existing devices
 }
 
 type DeviceTypeDeleteOptions struct {
@@ -108,3 +111,49 @@ type ProtocolUpdateOptions struct {
 type ProtocolDeleteOptions struct {
 	Wait bool
 }
+
+type ReplayOptions struct {
+	Ids           []string //filter; ignored if nil
+	DryRun        bool     //only count matching resources, nothing is published
+	RatePerSecond float64  //max published commands per second; 0 = unlimited
+	BatchSize     int64    //page size used to read from the device-repository; defaults to 1000
+}
+
+type ReplayProgress struct {
+	Kind      string   `json:"kind"`
+	DryRun    bool     `json:"dry_run"`
+	Listed    int64    `json:"listed"`
+	Published int64    `json:"published"`
+	Failed    []string `json:"failed"`
+	Done      bool     `json:"done"`
+}
+
+type DeviceTypeMigrationRequest struct {
+	TargetDeviceTypeId string `json:"target_device_type_id"`
+	DeleteSource       bool   `json:"delete_source"` //deletes the source device-type, once no devices of it exist
+	DryRun             bool   `json:"dry_run"`       //only checks the target and counts the devices, nothing is published
+	BatchSize          int64  `json:"batch_size"`    //page size used to read devices from the device-repository and to report progress; defaults to 1000
+}
+
+type DeviceTypeMigrationProgress struct {
+	SourceDeviceTypeId string   `json:"source_device_type_id"`
+	TargetDeviceTypeId string   `json:"target_device_type_id"`
+	DryRun             bool     `json:"dry_run"`
+	Listed             int64    `json:"listed"`
+	Migrated           int64    `json:"migrated"`
+	Failed             []string `json:"failed"`
+	SourceDeleted      bool     `json:"source_deleted"`
+	SourceDeleteError  string   `json:"source_delete_error,omitempty"`
+	Done               bool     `json:"done"`
+}
+
+// DeviceTypeMigrationJob is a migration running in the background; Progress is updated after every batch
+type DeviceTypeMigrationJob struct {
+	Id        string                      `json:"id"`
+	Request   DeviceTypeMigrationRequest  `json:"request"`
+	Progress  DeviceTypeMigrationProgress `json:"progress"`
+	Error     string                      `json:"error,omitempty"` //set if the migration was aborted
+	CreatedBy string                      `json:"created_by"`
+	CreatedAt time.Time                   `json:"created_at"`
+	UpdatedAt time.Time                   `json:"updated_at"` //a job which is not done and not updated for long was interrupted, e.g. by a restart
+}
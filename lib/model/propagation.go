@@ -0,0 +1,35 @@
+/*
+ * Copyright 2024 InfAI (CC SES)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package model
+
+// Propagation of the user permissions of a location or device-group to its member devices ("propagate sharing")
+type Propagation struct {
+	Kind    string                                `json:"kind"` //topic of the container, e.g. "locations"
+	Id      string                                `json:"id"`
+	Enabled bool                                  `json:"enabled"`
+	Grants  map[string]map[string]PropagatedGrant `json:"grants"` //device id -> user id -> grant
+}
+
+// PropagatedGrant records rights granted on a member device; only the added rights are removed on revocation
+type PropagatedGrant struct {
+	Granted PermissionsMap `json:"granted"`
+	Added   PermissionsMap `json:"added"` //rights the user did not have on the device before the grant
+}
+
+type PropagationRequest struct {
+	Enabled bool `json:"enabled"`
+}
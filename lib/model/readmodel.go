@@ -0,0 +1,30 @@
+/*
+ * Copyright 2024 InfAI (CC SES)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package model
+
+import "time"
+
+type ReadModelTopicStatus struct {
+	Topic          string    `json:"topic"`
+	Elements       int       `json:"elements"`
+	LastMessageAt  time.Time `json:"last_message_at"`  //kafka timestamp of the last consumed message
+	LastConsumedAt time.Time `json:"last_consumed_at"` //local time of the last consumption
+	CheckedAt      time.Time `json:"checked_at"`       //local time of the last high-water mark check
+	Lag            int64     `json:"lag"`              //messages between the consumed offsets and the high-water marks of the partitions
+	Fresh          bool      `json:"fresh"`            //if false, reads fall back to the device-repository
+	Error          string    `json:"error,omitempty"`
+}
@@ -0,0 +1,45 @@
+/*
+ * Copyright 2024 InfAI (CC SES)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package model
+
+import "time"
+
+// Share is a time-limited grant of rights on a device, device-group or location to a user
+type Share struct {
+	Id         string         `json:"id"`
+	Kind       string         `json:"kind"` //topic of the resource, e.g. "devices"
+	ResourceId string         `json:"resource_id"`
+	UserId     string         `json:"user_id"`
+	Rights     PermissionsMap `json:"rights"`
+	Added      PermissionsMap `json:"added"` //rights the user did not have before the share, removed on revocation
+	CreatedBy  string         `json:"created_by"`
+	CreatedAt  time.Time      `json:"created_at"`
+	ExpiresAt  time.Time      `json:"expires_at"`
+	RevokedAt  *time.Time     `json:"revoked_at,omitempty"`
+	RevokedBy  string         `json:"revoked_by,omitempty"` //user id, ShareRevokedByReaper or ShareRevokedByReplacement
+}
+
+const (
+	ShareRevokedByReaper      = "expired"
+	ShareRevokedByReplacement = "replaced"
+)
+
+type ShareRequest struct {
+	UserId    string         `json:"user_id"`
+	Rights    PermissionsMap `json:"rights"` //read, write and execute; administrate may not be shared
+	ExpiresAt time.Time      `json:"expires_at"`
+}
@@ -0,0 +1,37 @@
+/*
+ * Copyright 2024 InfAI (CC SES)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package model
+
+import "time"
+
+// Transfer is a pending ownership transfer of a device or hub, which the recipient accepts with POST /transfers/{id}/accept
+type Transfer struct {
+	Id             string    `json:"id"`
+	Kind           string    `json:"kind"` //topic of the resource, e.g. "devices" or "hubs"
+	ResourceId     string    `json:"resource_id"`
+	From           string    `json:"from"` //owner at the creation of the transfer
+	To             string    `json:"to"`
+	RemoveOldOwner bool      `json:"remove_old_owner"`
+	CreatedBy      string    `json:"created_by"`
+	CreatedAt      time.Time `json:"created_at"`
+	ExpiresAt      time.Time `json:"expires_at"`
+}
+
+type TransferRequest struct {
+	UserId         string `json:"user_id"`          //recipient and new owner
+	RemoveOldOwner bool   `json:"remove_old_owner"` //removes all permissions of the old owner on acceptance
+}
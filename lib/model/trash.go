@@ -0,0 +1,34 @@
+/*
+ * Copyright 2024 InfAI (CC SES)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package model
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// TrashEntry is a snapshot of a soft deleted resource, which may be restored until ExpiresAt
+type TrashEntry struct {
+	Id          string               `json:"id"`
+	Kind        string               `json:"kind"` //topic of the resource, e.g. "devices"
+	ResourceId  string               `json:"resource_id"`
+	Resource    json.RawMessage      `json:"resource"`
+	Permissions *ResourcePermissions `json:"permissions,omitempty"` //nil if permissions-v2 has no permissions for the resource
+	DeletedBy   string               `json:"deleted_by"`
+	DeletedAt   time.Time            `json:"deleted_at"`
+	ExpiresAt   time.Time            `json:"expires_at"`
+}
@@ -0,0 +1,262 @@
+/*
+ * Copyright 2024 InfAI (CC SES)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package overlay
+
+import (
+	"container/heap"
+	"context"
+	"encoding/json"
+	"github.com/SENERGY-Platform/service-commons/pkg/signal"
+	"log"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Overlay remembers resources and deletes recently published by a user, until the expected done messages arrive or the ttl expires.
+// it is used to answer reads of the same user directly after a write, while the device-repository has not yet consumed the command.
+type Overlay struct {
+	ttl      time.Duration
+	handlers []string
+	now      func() time.Time
+	mux      sync.Mutex
+	entries  map[key]entry
+	expiries expiries //min-heap of entry expiry times, so that writes do not scan all entries
+	version  uint64
+}
+
+type key struct {
+	kind string
+	id   string
+}
+
+type entry struct {
+	userId  string
+	command string // PUT | DELETE
+	value   []byte //json, to prevent callers from modifying the stored value
+	expires time.Time
+	version uint64 //distinguishes the entry from earlier and later entries of the same key
+
+	//handler -> publishes of the key, which the handler has not yet reported, in publish order.
+	//done messages carry no sequence, so each done message is matched to the oldest unreported publish with the same command;
+	//the entry is removed once no handler is missing its own publish, not on the done message of an earlier publish.
+	pending map[string][]publish
+}
+
+type publish struct {
+	command string
+	version uint64
+}
+
+type expiry struct {
+	at      time.Time
+	key     key
+	version uint64
+}
+
+type expiries []expiry
+
+func (h expiries) Len() int           { return len(h) }
+func (h expiries) Less(i, j int) bool { return h[i].at.Before(h[j].at) }
+func (h expiries) Swap(i, j int)      { h[i], h[j] = h[j], h[i] }
+func (h *expiries) Push(x any)        { *h = append(*h, x.(expiry)) }
+func (h *expiries) Pop() any {
+	old := *h
+	last := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return last
+}
+
+// New creates an overlay; handlers are the done handlers (config.DoneHandler) which must report a command before its entry is removed.
+// without handlers, entries are only removed by ttl.
+func New(ttl time.Duration, handlers []string) *Overlay {
+	return &Overlay{
+		ttl:      ttl,
+		handlers: handlers,
+		now:      time.Now,
+		entries:  map[key]entry{},
+	}
+}
+
+// Subscribe removes entries on done messages received by donewait.StartDoneWaitListener (default signal broker)
+func (this *Overlay) Subscribe(ctx context.Context) {
+	id := signal.Sub("", signal.Known.UpdateDone, func(value string, _ *sync.WaitGroup) {
+		this.HandleDone(value)
+	})
+	go func() {
+		<-ctx.Done()
+		signal.Unsub(id)
+	}()
+}
+
+// HandleDone interprets a done message serialized by donewait.SerializeDoneMsg ("command|kind|id|handler")
+func (this *Overlay) HandleDone(value string) {
+	parts := strings.SplitN(value, "|", 4)
+	if len(parts) != 4 {
+		return
+	}
+	command, kind, id, handler := parts[0], parts[1], parts[2], parts[3]
+	this.mux.Lock()
+	defer this.mux.Unlock()
+	k := key{kind: kind, id: id}
+	e, ok := this.entries[k]
+	if !ok {
+		return
+	}
+	index := slices.IndexFunc(e.pending[handler], func(p publish) bool { return p.command == command })
+	if index < 0 {
+		return
+	}
+	e.pending = clonePending(e.pending)
+	e.pending[handler] = slices.Delete(e.pending[handler], index, index+1)
+	if !e.isPending() {
+		delete(this.entries, k)
+	} else {
+		this.entries[k] = e
+	}
+}
+
+// isPending is true if a handler has not yet reported the publish of the entry
+func (this entry) isPending() bool {
+	for _, list := range this.pending {
+		if slices.ContainsFunc(list, func(p publish) bool { return p.version == this.version }) {
+			return true
+		}
+	}
+	return false
+}
+
+// withoutPublish returns the entry without the publish of version, e.g. because it failed
+func (this entry) withoutPublish(version uint64) entry {
+	this.pending = clonePending(this.pending)
+	for handler, list := range this.pending {
+		this.pending[handler] = slices.DeleteFunc(list, func(p publish) bool { return p.version == version })
+	}
+	return this
+}
+
+func clonePending(pending map[string][]publish) map[string][]publish {
+	result := map[string][]publish{}
+	for handler, list := range pending {
+		result[handler] = slices.Clone(list)
+	}
+	return result
+}
+
+// Put remembers a resource published by userId; kind is the topic of the resource.
+// undo restores the previous entry of the resource, e.g. if the publish failed.
+func (this *Overlay) Put(userId string, kind string, id string, value interface{}) (undo func()) {
+	temp, err := json.Marshal(value)
+	if err != nil {
+		log.Println("WARNING: unable to remember resource in read-your-writes overlay", kind, id, err)
+		this.Forget(kind, id)
+		return func() {}
+	}
+	return this.set(key{kind: kind, id: id}, entry{userId: userId, command: "PUT", value: temp})
+}
+
+// Delete remembers a delete published by userId; kind is the topic of the resource.
+// undo restores the previous entry of the resource, e.g. if the publish failed.
+func (this *Overlay) Delete(userId string, kind string, id string) (undo func()) {
+	return this.set(key{kind: kind, id: id}, entry{userId: userId, command: "DELETE"})
+}
+
+// Forget removes the entry, e.g. if its value is unknown
+func (this *Overlay) Forget(kind string, id string) {
+	this.mux.Lock()
+	defer this.mux.Unlock()
+	delete(this.entries, key{kind: kind, id: id})
+}
+
+func (this *Overlay) set(k key, e entry) (undo func()) {
+	this.mux.Lock()
+	defer this.mux.Unlock()
+	now := this.now()
+	this.expire(now)
+	previous, hasPrevious := this.entries[k]
+	this.version++
+	e.version = this.version
+	e.expires = now.Add(this.ttl)
+	//done messages of earlier publishes, which are still expected, must not remove this entry
+	e.pending = map[string][]publish{}
+	if hasPrevious {
+		e.pending = clonePending(previous.pending)
+	}
+	for _, handler := range this.handlers {
+		e.pending[handler] = append(e.pending[handler], publish{command: e.command, version: e.version})
+	}
+	this.entries[k] = e
+	heap.Push(&this.expiries, expiry{at: e.expires, key: k, version: e.version})
+	return func() {
+		this.mux.Lock()
+		defer this.mux.Unlock()
+		current, ok := this.entries[k]
+		if !ok {
+			//already done or expired
+			return
+		}
+		if current.version != e.version {
+			//replaced by a later write, which must not wait for the done messages of the failed publish
+			this.entries[k] = current.withoutPublish(e.version)
+			return
+		}
+		if hasPrevious && previous.expires.After(this.now()) {
+			this.entries[k] = previous
+		} else {
+			delete(this.entries, k)
+		}
+	}
+}
+
+// expire removes entries whose expiry time has passed; the caller must hold the lock
+func (this *Overlay) expire(now time.Time) {
+	for len(this.expiries) > 0 && !this.expiries[0].at.After(now) {
+		next := heap.Pop(&this.expiries).(expiry)
+		if e, ok := this.entries[next.key]; ok && e.version == next.version {
+			delete(this.entries, next.key)
+		}
+	}
+}
+
+// Get returns the resource, if userId has published it recently and the entry is neither done nor expired.
+// deleted is true if the last remembered command of userId is a delete.
+func Get[T any](overlay *Overlay, userId string, kind string, id string) (result T, found bool, deleted bool) {
+	overlay.mux.Lock()
+	defer overlay.mux.Unlock()
+	k := key{kind: kind, id: id}
+	e, ok := overlay.entries[k]
+	if !ok {
+		return result, false, false
+	}
+	if !e.expires.After(overlay.now()) {
+		delete(overlay.entries, k)
+		return result, false, false
+	}
+	if e.userId != userId {
+		return result, false, false
+	}
+	if e.command == "DELETE" {
+		return result, true, true
+	}
+	err := json.Unmarshal(e.value, &result)
+	if err != nil {
+		log.Println("WARNING: unable to read resource from read-your-writes overlay", kind, id, err)
+		return result, false, false
+	}
+	return result, true, false
+}
@@ -0,0 +1,178 @@
+/*
+ * Copyright 2024 InfAI (CC SES)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package policy decides which realm roles may create, update or delete which kind of resource.
+// it is evaluated before permissions-v2 checks and validation; without policy file every action is allowed.
+//
+// example policy file:
+//
+//	{
+//	  "default": ["*"],
+//	  "kinds": {
+//	    "device-types": {"create": ["admin", "device-type-editor"]},
+//	    "protocols":    {"create": ["admin"], "update": ["admin"], "delete": ["admin"]},
+//	    "*":            {"delete": ["admin", "user"]}
+//	  }
+//	}
+//
+// kinds are the topic names of the resources (e.g. config.DeviceTypeTopic), "*" matches all kinds;
+// the role "*" matches every user. actions without rule use the roles of "default".
+package policy
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"slices"
+	"sync"
+	"time"
+)
+
+const (
+	Create = "create"
+	Update = "update"
+	Delete = "delete"
+)
+
+const Any = "*"
+
+var ErrForbidden = errors.New("forbidden by policy")
+
+type Policy struct {
+	Default []string                       `json:"default"`
+	Kinds   map[string]map[string][]string `json:"kinds"`
+}
+
+// Roles returns the roles allowed to do action on kind
+func (this Policy) Roles(kind string, action string) []string {
+	if roles, ok := this.Kinds[kind][action]; ok {
+		return roles
+	}
+	if roles, ok := this.Kinds[Any][action]; ok {
+		return roles
+	}
+	return this.Default
+}
+
+// Allowed reports if one of roles may do action on kind
+func (this Policy) Allowed(roles []string, kind string, action string) bool {
+	allowed := this.Roles(kind, action)
+	if slices.Contains(allowed, Any) {
+		return true
+	}
+	return slices.ContainsFunc(roles, func(role string) bool { return slices.Contains(allowed, role) })
+}
+
+// Check returns an error wrapping ErrForbidden if none of roles may do action on kind
+func (this Policy) Check(roles []string, kind string, action string) error {
+	if !this.Allowed(roles, kind, action) {
+		return fmt.Errorf("%w: %v %v needs one of the roles %v", ErrForbidden, action, kind, this.Roles(kind, action))
+	}
+	return nil
+}
+
+func Load(location string) (result Policy, err error) {
+	data, err := os.ReadFile(location)
+	if err != nil {
+		return result, err
+	}
+	err = json.Unmarshal(data, &result)
+	if err != nil {
+		return result, fmt.Errorf("invalid policy file %v: %w", location, err)
+	}
+	return result, nil
+}
+
+// Store holds the current policy of a file and reloads it when the file changes
+type Store struct {
+	location string
+	mux      sync.RWMutex
+	policy   Policy
+	modTime  time.Time
+}
+
+// NewStore loads the policy file; an invalid file is an error on start, but is ignored on reload to keep the last valid policy
+func NewStore(location string) (*Store, error) {
+	result := &Store{location: location}
+	err := result.Reload()
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// Get returns the current policy
+func (this *Store) Get() Policy {
+	this.mux.RLock()
+	defer this.mux.RUnlock()
+	return this.policy
+}
+
+// Reload reads the policy file
+func (this *Store) Reload() error {
+	info, err := os.Stat(this.location)
+	if err != nil {
+		return err
+	}
+	p, err := Load(this.location)
+	if err != nil {
+		return err
+	}
+	this.mux.Lock()
+	defer this.mux.Unlock()
+	this.policy = p
+	this.modTime = info.ModTime()
+	return nil
+}
+
+// Watch reloads the policy file every interval if its modification time changed, until ctx is done
+func (this *Store) Watch(ctx context.Context, interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+			info, err := os.Stat(this.location)
+			if err != nil {
+				log.Println("WARNING: unable to check policy file --> keep last policy", err)
+				continue
+			}
+			this.mux.RLock()
+			changed := !info.ModTime().Equal(this.modTime)
+			this.mux.RUnlock()
+			if !changed {
+				continue
+			}
+			err = this.Reload()
+			if err != nil {
+				log.Println("ERROR: unable to reload policy file --> keep last policy", err)
+				//retry only after the next change
+				this.mux.Lock()
+				this.modTime = info.ModTime()
+				this.mux.Unlock()
+				continue
+			}
+			log.Println("reloaded policy file", this.location)
+		}
+	}()
+}
@@ -0,0 +1,85 @@
+/*
+ * Copyright 2024 InfAI (CC SES)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package propagation stores the propagate-sharing flags of locations and device-groups
+// and the rights granted on their member devices in a json file, which is shared by the replicas of the service (e.g. on a shared volume).
+package propagation
+
+import (
+	"errors"
+	"github.com/SENERGY-Platform/device-manager/lib/jsonstore"
+	"github.com/SENERGY-Platform/device-manager/lib/model"
+	"maps"
+)
+
+type Store struct {
+	propagations *jsonstore.Map[model.Propagation]
+}
+
+// New loads the propagations of the json file at location, if it exists.
+// members and permissions of a container may be changed on any replica, so location is required.
+func New(location string) (*Store, error) {
+	if location == "" || location == "-" {
+		return nil, errors.New("propagation needs a file shared by all replicas")
+	}
+	propagations, err := jsonstore.NewMap[model.Propagation](location, func(propagation model.Propagation) string {
+		return key(propagation.Kind, propagation.Id)
+	}, func(a model.Propagation, b model.Propagation) bool {
+		return key(a.Kind, a.Id) < key(b.Kind, b.Id)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &Store{propagations: propagations}, nil
+}
+
+func key(kind string, id string) string {
+	return kind + "/" + id
+}
+
+// Get returns the propagation of a container; ok is false if it was never enabled
+func (this *Store) Get(kind string, id string) (result model.Propagation, ok bool) {
+	this.propagations.View(func(propagations map[string]model.Propagation) {
+		result, ok = propagations[key(kind, id)]
+	})
+	return result, ok
+}
+
+// Update calls f with the propagation of a container and stores the result, also if f returns an error,
+// because f may have applied a part of its grants. updates are locked against other replicas, so that grants are not recorded concurrently.
+// a propagation which is disabled and without grants is removed.
+func (this *Store) Update(kind string, id string, f func(propagation *model.Propagation) error) (err error) {
+	writeErr := this.propagations.Update(func(propagations map[string]model.Propagation) error {
+		propagation, ok := propagations[key(kind, id)]
+		if !ok {
+			propagation = model.Propagation{Kind: kind, Id: id}
+		}
+		//f gets a copy, because the stored grants are shared with concurrent readers
+		grants := map[string]map[string]model.PropagatedGrant{}
+		for deviceId, users := range propagation.Grants {
+			grants[deviceId] = maps.Clone(users)
+		}
+		propagation.Grants = grants
+		err = f(&propagation)
+		if !propagation.Enabled && len(propagation.Grants) == 0 {
+			delete(propagations, key(kind, id))
+		} else {
+			propagations[key(kind, id)] = propagation
+		}
+		return nil
+	})
+	return errors.Join(err, writeErr)
+}
@@ -0,0 +1,311 @@
+/*
+ * Copyright 2024 InfAI (CC SES)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package readmodel
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"github.com/SENERGY-Platform/device-manager/lib/config"
+	"github.com/SENERGY-Platform/device-manager/lib/kafka/publisher"
+	"github.com/SENERGY-Platform/device-manager/lib/model"
+	commonskafka "github.com/SENERGY-Platform/service-commons/pkg/kafka"
+	"github.com/segmentio/kafka-go"
+	"log"
+	"sort"
+	"sync"
+	"time"
+)
+
+// ReadModel is a local query side, built by consuming the command topics this service publishes to.
+// Elements are only returned while the topic is fresh, callers are expected to fall back to the device-repository otherwise.
+type ReadModel struct {
+	config       config.Config
+	maxStaleness time.Duration
+	now          func() time.Time
+	mux          sync.RWMutex
+	topics       map[string]*topic
+}
+
+type topic struct {
+	decode         func(msg []byte) (cmd command, value interface{}, err error)
+	elements       map[string]interface{}
+	consumed       map[int]int64 //next offset to consume per partition
+	highWaterMarks map[int]int64 //next offset to be written per partition, as seen by the last check
+	checkedAt      time.Time     //time of the last high-water mark check
+	lastMessageAt  time.Time
+	lastConsumedAt time.Time
+	err            error
+}
+
+type command struct {
+	Command string `json:"command"`
+	Id      string `json:"id"`
+}
+
+const DefaultMaxStaleness = 10 * time.Second
+
+func New(conf config.Config) (result *ReadModel) {
+	maxStaleness := DefaultMaxStaleness
+	if conf.ReadModelMaxStaleness != "" {
+		var err error
+		maxStaleness, err = time.ParseDuration(conf.ReadModelMaxStaleness)
+		if err != nil || maxStaleness <= 0 {
+			log.Println("WARNING: invalid read_model_max_staleness --> use default", DefaultMaxStaleness, err)
+			maxStaleness = DefaultMaxStaleness
+		}
+	}
+	result = &ReadModel{
+		config:       conf,
+		maxStaleness: maxStaleness,
+		now:          time.Now,
+		topics:       map[string]*topic{},
+	}
+	result.addTopic(conf.DeviceTopic, decoder[publisher.DeviceCommand](func(cmd publisher.DeviceCommand) interface{} { return cmd.Device }))
+	result.addTopic(conf.HubTopic, decoder[publisher.HubCommand](func(cmd publisher.HubCommand) interface{} { return cmd.Hub }))
+	result.addTopic(conf.DeviceTypeTopic, decoder[publisher.DeviceTypeCommand](func(cmd publisher.DeviceTypeCommand) interface{} { return cmd.DeviceType }))
+	result.addTopic(conf.DeviceGroupTopic, decoder[publisher.DeviceGroupCommand](func(cmd publisher.DeviceGroupCommand) interface{} { return cmd.DeviceGroup }))
+	result.addTopic(conf.LocationTopic, decoder[publisher.LocationCommand](func(cmd publisher.LocationCommand) interface{} { return cmd.Location }))
+	result.addTopic(conf.ProtocolTopic, decoder[publisher.ProtocolCommand](func(cmd publisher.ProtocolCommand) interface{} { return cmd.Protocol }))
+	result.addTopic(conf.AspectTopic, decoder[publisher.AspectCommand](func(cmd publisher.AspectCommand) interface{} { return cmd.Aspect }))
+	result.addTopic(conf.FunctionTopic, decoder[publisher.FunctionCommand](func(cmd publisher.FunctionCommand) interface{} { return cmd.Function }))
+	result.addTopic(conf.ConceptTopic, decoder[publisher.ConceptCommand](func(cmd publisher.ConceptCommand) interface{} { return cmd.Concept }))
+	result.addTopic(conf.CharacteristicTopic, decoder[publisher.CharacteristicCommand](func(cmd publisher.CharacteristicCommand) interface{} { return cmd.Characteristic }))
+	result.addTopic(conf.DeviceClassTopic, decoder[publisher.DeviceClassCommand](func(cmd publisher.DeviceClassCommand) interface{} { return cmd.DeviceClass }))
+	return result
+}
+
+// Start consumes all command topics from the first offset, without consumer group,
+// and checks the high-water marks of their partitions every maxStaleness/2
+func (this *ReadModel) Start(ctx context.Context) error {
+	err := commonskafka.NewMultiConsumer(ctx, commonskafka.Config{
+		KafkaUrl:    this.config.KafkaUrl,
+		StartOffset: commonskafka.FirstOffset,
+		Debug:       this.config.Debug,
+		OnError: func(err error) {
+			log.Println("ERROR: read model consumer stopped --> fall back to device-repository", err)
+			this.setError(err)
+		},
+	}, this.Topics(), func(delivery commonskafka.Message) error {
+		return this.Handle(delivery.Topic, delivery.Partition, delivery.Offset, delivery.Value, delivery.Time)
+	})
+	if err != nil {
+		return err
+	}
+	go this.watchHighWaterMarks(ctx)
+	return nil
+}
+
+func (this *ReadModel) Topics() (result []string) {
+	this.mux.RLock()
+	defer this.mux.RUnlock()
+	for name := range this.topics {
+		result = append(result, name)
+	}
+	sort.Strings(result)
+	return result
+}
+
+// Handle applies a command message of the given topic; messageTime is the time the message has been published
+func (this *ReadModel) Handle(topicName string, partition int, offset int64, msg []byte, messageTime time.Time) error {
+	this.mux.Lock()
+	defer this.mux.Unlock()
+	t, ok := this.topics[topicName]
+	if !ok {
+		return nil
+	}
+	t.consumed[partition] = offset + 1
+	t.lastMessageAt = messageTime
+	t.lastConsumedAt = this.now()
+	cmd, value, err := t.decode(msg)
+	if err != nil {
+		log.Println("ERROR: unable to interpret message in read model; ignore", topicName, err, string(msg))
+		return nil
+	}
+	switch cmd.Command {
+	case "PUT":
+		t.elements[cmd.Id] = value
+	case "DELETE":
+		delete(t.elements, cmd.Id)
+	}
+	return nil
+}
+
+// SetHighWaterMarks stores the next offset to be written per partition of the topic and marks the topic as checked
+func (this *ReadModel) SetHighWaterMarks(topicName string, highWaterMarks map[int]int64) {
+	this.mux.Lock()
+	defer this.mux.Unlock()
+	t, ok := this.topics[topicName]
+	if !ok {
+		return
+	}
+	t.highWaterMarks = highWaterMarks
+	t.checkedAt = this.now()
+}
+
+// Get returns the element with the given id if the read model is fresh for the topic and contains the element
+func Get[T any](readModel *ReadModel, topicName string, id string) (result T, ok bool) {
+	readModel.mux.RLock()
+	defer readModel.mux.RUnlock()
+	t, exists := readModel.topics[topicName]
+	if !exists || !readModel.isFresh(t) {
+		return result, false
+	}
+	value, exists := t.elements[id]
+	if !exists {
+		return result, false
+	}
+	result, ok = value.(T)
+	return result, ok
+}
+
+func (this *ReadModel) Status() (result []model.ReadModelTopicStatus) {
+	this.mux.RLock()
+	defer this.mux.RUnlock()
+	result = []model.ReadModelTopicStatus{}
+	for name, t := range this.topics {
+		status := model.ReadModelTopicStatus{
+			Topic:          name,
+			Elements:       len(t.elements),
+			LastMessageAt:  t.lastMessageAt,
+			LastConsumedAt: t.lastConsumedAt,
+			CheckedAt:      t.checkedAt,
+			Lag:            t.lag(),
+			Fresh:          this.isFresh(t),
+		}
+		if t.err != nil {
+			status.Error = t.err.Error()
+		}
+		result = append(result, status)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Topic < result[j].Topic
+	})
+	return result
+}
+
+// a topic is fresh if the consumer has reached the high-water marks of all partitions
+// and the high-water marks have been checked at most maxStaleness ago.
+// a stalled or disconnected consumer falls behind the high-water marks of new messages,
+// a failing check lets checkedAt expire.
+func (this *ReadModel) isFresh(t *topic) bool {
+	if t.err != nil || t.checkedAt.IsZero() {
+		return false
+	}
+	if this.now().Sub(t.checkedAt) > this.maxStaleness {
+		return false
+	}
+	return t.lag() == 0
+}
+
+// lag is the number of messages between the consumed offsets and the high-water marks
+func (t *topic) lag() (result int64) {
+	for partition, highWaterMark := range t.highWaterMarks {
+		if behind := highWaterMark - t.consumed[partition]; behind > 0 {
+			result += behind
+		}
+	}
+	return result
+}
+
+func (this *ReadModel) watchHighWaterMarks(ctx context.Context) {
+	ticker := time.NewTicker(this.maxStaleness / 2)
+	defer ticker.Stop()
+	for {
+		err := this.checkHighWaterMarks(ctx)
+		if err != nil {
+			log.Println("WARNING: unable to check read model high-water marks --> fall back to device-repository until the next successful check", err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (this *ReadModel) checkHighWaterMarks(ctx context.Context) error {
+	ctx, cancel := context.WithTimeout(ctx, this.maxStaleness/2)
+	defer cancel()
+	client := &kafka.Client{Addr: kafka.TCP(this.config.KafkaUrl)}
+	metadata, err := client.Metadata(ctx, &kafka.MetadataRequest{Topics: this.Topics()})
+	if err != nil {
+		return err
+	}
+	request := map[string][]kafka.OffsetRequest{}
+	for _, t := range metadata.Topics {
+		if t.Error != nil {
+			return t.Error
+		}
+		for _, partition := range t.Partitions {
+			request[t.Name] = append(request[t.Name], kafka.LastOffsetOf(partition.ID))
+		}
+	}
+	offsets, err := client.ListOffsets(ctx, &kafka.ListOffsetsRequest{Topics: request})
+	if err != nil {
+		return err
+	}
+	for name, partitions := range offsets.Topics {
+		highWaterMarks := map[int]int64{}
+		for _, partition := range partitions {
+			if partition.Error != nil {
+				return partition.Error
+			}
+			highWaterMarks[partition.Partition] = partition.LastOffset
+		}
+		if len(highWaterMarks) != len(request[name]) {
+			return errors.New("missing partition offsets for topic " + name)
+		}
+		this.SetHighWaterMarks(name, highWaterMarks)
+	}
+	return nil
+}
+
+func (this *ReadModel) setError(err error) {
+	this.mux.Lock()
+	defer this.mux.Unlock()
+	for _, t := range this.topics {
+		t.err = err
+	}
+}
+
+func (this *ReadModel) addTopic(name string, decode func(msg []byte) (cmd command, value interface{}, err error)) {
+	if name == "" || name == "-" {
+		return
+	}
+	this.topics[name] = &topic{
+		decode:         decode,
+		elements:       map[string]interface{}{},
+		consumed:       map[int]int64{},
+		highWaterMarks: map[int]int64{},
+	}
+}
+
+func decoder[T any](getValue func(T) interface{}) func(msg []byte) (cmd command, value interface{}, err error) {
+	return func(msg []byte) (cmd command, value interface{}, err error) {
+		err = json.Unmarshal(msg, &cmd)
+		if err != nil {
+			return cmd, value, err
+		}
+		var temp T
+		err = json.Unmarshal(msg, &temp)
+		if err != nil {
+			return cmd, value, err
+		}
+		return cmd, getValue(temp), nil
+	}
+}
@@ -0,0 +1,160 @@
+/*
+ * Copyright 2024 InfAI (CC SES)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package share stores time-limited share grants in a json file, which is shared by the replicas of the service (e.g. on a shared volume).
+// revoked shares are kept as record for HistoryRetention.
+package share
+
+import (
+	"errors"
+	"github.com/SENERGY-Platform/device-manager/lib/jsonstore"
+	"github.com/SENERGY-Platform/device-manager/lib/model"
+	"github.com/google/uuid"
+	"sort"
+	"time"
+)
+
+var ErrNotFound = errors.New("share not found")
+
+var HistoryRetention = 30 * 24 * time.Hour
+
+type Store struct {
+	shares *jsonstore.Map[model.Share]
+	now    func() time.Time
+}
+
+// New loads the shares of the json file at location, if it exists.
+// the grants of shares must be revoked after a restart and by any replica, so location is required.
+func New(location string) (*Store, error) {
+	if location == "" || location == "-" {
+		return nil, errors.New("shares need a file shared by all replicas")
+	}
+	shares, err := jsonstore.NewMap[model.Share](location, func(share model.Share) string {
+		return share.Id
+	}, func(a model.Share, b model.Share) bool {
+		return a.CreatedAt.Before(b.CreatedAt)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &Store{shares: shares, now: time.Now}, nil
+}
+
+// Add stores share with a new id and creation time
+func (this *Store) Add(share model.Share) (model.Share, error) {
+	share.Id = uuid.NewString()
+	share.CreatedAt = this.now()
+	err := this.shares.Update(func(shares map[string]model.Share) error {
+		shares[share.Id] = share
+		return nil
+	})
+	return share, err
+}
+
+// Remove deletes the record of a share, e.g. if its grant could not be applied
+func (this *Store) Remove(id string) error {
+	return this.shares.Update(func(shares map[string]model.Share) error {
+		if _, ok := shares[id]; !ok {
+			return ErrNotFound
+		}
+		delete(shares, id)
+		return nil
+	})
+}
+
+// Active returns the not revoked share of userId on the resource
+func (this *Store) Active(kind string, resourceId string, userId string) (result model.Share, found bool) {
+	this.shares.View(func(shares map[string]model.Share) {
+		for _, share := range shares {
+			if share.RevokedAt == nil && share.Kind == kind && share.ResourceId == resourceId && share.UserId == userId {
+				result, found = share, true
+				return
+			}
+		}
+	})
+	return result, found
+}
+
+// List returns the shares of a resource, including revoked shares, ordered by creation
+func (this *Store) List(kind string, resourceId string) (result []model.Share) {
+	result = []model.Share{}
+	this.shares.View(func(shares map[string]model.Share) {
+		for _, share := range shares {
+			if share.Kind == kind && share.ResourceId == resourceId {
+				result = append(result, share)
+			}
+		}
+	})
+	sortByCreation(result)
+	return result
+}
+
+func (this *Store) Get(id string) (share model.Share, err error) {
+	var ok bool
+	this.shares.View(func(shares map[string]model.Share) {
+		share, ok = shares[id]
+	})
+	if !ok {
+		return share, ErrNotFound
+	}
+	return share, nil
+}
+
+// Expired returns the not revoked shares which expired at or before now
+func (this *Store) Expired() (result []model.Share) {
+	now := this.now()
+	this.shares.View(func(shares map[string]model.Share) {
+		for _, share := range shares {
+			if share.RevokedAt == nil && !share.ExpiresAt.After(now) {
+				result = append(result, share)
+			}
+		}
+	})
+	sortByCreation(result)
+	return result
+}
+
+// MarkRevoked records the revocation of a share and removes records older than HistoryRetention.
+// a share revoked in the meantime, e.g. by another replica, keeps its first revocation.
+func (this *Store) MarkRevoked(id string, by string) (share model.Share, err error) {
+	err = this.shares.Update(func(shares map[string]model.Share) error {
+		var ok bool
+		share, ok = shares[id]
+		if !ok {
+			return ErrNotFound
+		}
+		if share.RevokedAt != nil {
+			return jsonstore.SkipWrite
+		}
+		now := this.now()
+		share.RevokedAt = &now
+		share.RevokedBy = by
+		shares[id] = share
+		for id, element := range shares {
+			if element.RevokedAt != nil && element.RevokedAt.Before(now.Add(-HistoryRetention)) {
+				delete(shares, id)
+			}
+		}
+		return nil
+	})
+	return share, err
+}
+
+func sortByCreation(list []model.Share) {
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].CreatedAt.Before(list[j].CreatedAt)
+	})
+}
@@ -0,0 +1,353 @@
+/*
+ * Copyright 2024 InfAI (CC SES)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package tests
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/SENERGY-Platform/device-manager/lib/api"
+	"github.com/SENERGY-Platform/device-manager/lib/apikey"
+	"github.com/SENERGY-Platform/device-manager/lib/auth"
+	"github.com/SENERGY-Platform/device-manager/lib/bus"
+	"github.com/SENERGY-Platform/device-manager/lib/config"
+	"github.com/SENERGY-Platform/device-manager/lib/controller"
+	"github.com/SENERGY-Platform/device-manager/lib/model"
+	"github.com/SENERGY-Platform/device-manager/lib/tests/helper"
+	"github.com/SENERGY-Platform/models/go/models"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestApiKeys(t *testing.T) {
+	conf, err := config.Load("./../../config.json")
+	if err != nil {
+		t.Fatal("ERROR: unable to load config", err)
+	}
+	conf.BusBackend = bus.BackendChannel
+	conf.HandleDoneWait = false
+	conf.DisableValidation = true
+	conf.ApiKeyFile = filepath.Join(t.TempDir(), "api_keys.json")
+	conf.ApiKeyMaxTtl = "1h"
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	_, err = controller.New(ctx, conf)
+	if err == nil {
+		t.Fatal("expected error for api keys without signing key")
+	}
+
+	conf.ApiKeySigningKeyFile = testKeyFile
+	conf.ApiKeySigningKeyId = "api-keys"
+	ctrl, err := controller.New(ctx, conf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := httptest.NewServer(api.GetRouter(conf, ctrl))
+	defer server.Close()
+
+	user, err := auth.CreateTokenWithRoles("test", "user1", []string{"user"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	status := func(resp *http.Response, err error) int {
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		return resp.StatusCode
+	}
+
+	var key model.ApiKeyCreated
+	t.Run("create", func(t *testing.T) {
+		if code := status(helper.Jwtpost(user.Token, server.URL+"/api-keys", model.ApiKeyCreateRequest{
+			Name:   "admin-key",
+			Roles:  []string{"admin"},
+			Scopes: []model.ApiKeyScope{{Kind: apikey.Any, Actions: []string{apikey.Any}}},
+		})); code != http.StatusForbidden {
+			t.Error("expected 403 for roles the user does not have, got", code)
+		}
+		admin, err := auth.CreateTokenWithRoles("test", "admin1", []string{"admin", "user"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if code := status(helper.Jwtpost(admin.Token, server.URL+"/api-keys", model.ApiKeyCreateRequest{
+			Name:   "admin-key",
+			Roles:  []string{"admin"},
+			Scopes: []model.ApiKeyScope{{Kind: apikey.Any, Actions: []string{apikey.Any}}},
+		})); code != http.StatusForbidden {
+			t.Error("expected 403 for admin role, got", code)
+		}
+		if code := status(helper.Jwtpost(user.Token, server.URL+"/api-keys", model.ApiKeyCreateRequest{
+			Name:   "unknown-key",
+			Scopes: []model.ApiKeyScope{{Kind: "api-keys", Actions: []string{apikey.ActionCreate}}},
+		})); code != http.StatusBadRequest {
+			t.Error("expected 400 for unknown scope kind, got", code)
+		}
+		tooLate := time.Now().Add(2 * time.Hour)
+		if code := status(helper.Jwtpost(user.Token, server.URL+"/api-keys", model.ApiKeyCreateRequest{
+			Name:      "long-key",
+			Scopes:    []model.ApiKeyScope{{Kind: "concepts", Actions: []string{apikey.ActionRead}}},
+			ExpiresAt: &tooLate,
+		})); code != http.StatusBadRequest {
+			t.Error("expected 400 for expiry beyond api_key_max_ttl, got", code)
+		}
+
+		resp, err := helper.Jwtpost(user.Token, server.URL+"/api-keys", model.ApiKeyCreateRequest{
+			Name:   "ci",
+			Scopes: []model.ApiKeyScope{{Kind: "concepts", Actions: []string{apikey.ActionCreate, apikey.ActionRead}}},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			t.Fatal(resp.StatusCode)
+		}
+		err = json.NewDecoder(resp.Body).Decode(&key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if key.Key == "" || key.UserId != "user1" || len(key.Roles) != 1 || key.Roles[0] != "user" {
+			t.Errorf("%#v", key)
+		}
+		if key.ExpiresAt.After(time.Now().Add(time.Hour)) {
+			t.Error("expiry exceeds api_key_max_ttl", key.ExpiresAt)
+		}
+	})
+
+	t.Run("use", func(t *testing.T) {
+		if code := status(helper.Jwtpost("ApiKey "+key.Key, server.URL+"/concepts", models.Concept{Name: "c"})); code != http.StatusOK {
+			t.Error("expected 200 for scoped create, got", code)
+		}
+		if code := status(helper.Jwtdelete("ApiKey "+key.Key, server.URL+"/concepts/foo")); code != http.StatusForbidden {
+			t.Error("expected 403 for delete outside of the scopes, got", code)
+		}
+		if code := status(helper.Jwtpost("ApiKey "+key.Key, server.URL+"/aspects", models.Aspect{Name: "a"})); code != http.StatusForbidden {
+			t.Error("expected 403 for kind outside of the scopes, got", code)
+		}
+		if code := status(helper.Jwtput("ApiKey "+key.Key, server.URL+"/concepts/foo/permissions", model.PermissionsMap{})); code != http.StatusForbidden {
+			t.Error("expected 403 for unknown route, got", code)
+		}
+		if code := status(helper.Jwtget("ApiKey "+key.Key, server.URL+"/api-keys")); code != http.StatusForbidden {
+			t.Error("expected 403 for api key management with api key, got", code)
+		}
+		if code := status(helper.Jwtpost("ApiKey "+key.Id+".wrong", server.URL+"/concepts", models.Concept{Name: "c"})); code != http.StatusUnauthorized {
+			t.Error("expected 401 for wrong secret, got", code)
+		}
+	})
+
+	t.Run("scopes are operations", func(t *testing.T) {
+		resp, err := helper.Jwtpost(user.Token, server.URL+"/api-keys", model.ApiKeyCreateRequest{
+			Name:   "dt-writer",
+			Scopes: []model.ApiKeyScope{{Kind: "device-types", Actions: []string{apikey.ActionUpdate}}},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		writer := model.ApiKeyCreated{}
+		err = json.NewDecoder(resp.Body).Decode(&writer)
+		resp.Body.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if code := status(helper.Jwtput("ApiKey "+writer.Key, server.URL+"/device-types/dt1/permissions", model.ResourcePermissions{})); code != http.StatusForbidden {
+			t.Error("expected 403 for permission change with update scope, got", code)
+		}
+		if code := status(helper.Jwtdelete("ApiKey "+writer.Key, server.URL+"/device-types/dt1/permissions/users/user2")); code != http.StatusForbidden {
+			t.Error("expected 403 for permission change with update scope, got", code)
+		}
+	})
+
+	t.Run("signed token", func(t *testing.T) {
+		token, _, err, _ := ctrl.AuthenticateApiKey(key.Key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		verifyConf := conf
+		verifyConf.JwtPublicKeyFile = testKeyFile
+		verifyConf.JwtIssuer = controller.ApiKeyIssuer
+		verifier, err := auth.NewVerifier(verifyConf)
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = verifier.Verify(token.Token)
+		if err != nil {
+			t.Error(err)
+		}
+		if token.GetUserId() != "user1" || token.IsAdmin() {
+			t.Errorf("%#v", token)
+		}
+	})
+
+	t.Run("forward", func(t *testing.T) {
+		forwarded := make(chan string, 10)
+		upstream := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+			forwarded <- request.Header.Get("Authorization")
+		}))
+		defer upstream.Close()
+		forwardConf := conf
+		forwardConf.EditForward = upstream.URL
+		forwardCtrl, err := controller.New(ctx, forwardConf)
+		if err != nil {
+			t.Fatal(err)
+		}
+		forwardServer := httptest.NewServer(api.GetRouter(forwardConf, forwardCtrl))
+		defer forwardServer.Close()
+
+		if code := status(helper.Jwtpost("ApiKey "+key.Key, forwardServer.URL+"/aspects", models.Aspect{Name: "a"})); code != http.StatusForbidden {
+			t.Error("expected 403 for kind outside of the scopes, got", code)
+		}
+		if code := status(helper.Jwtdelete("ApiKey "+key.Key, forwardServer.URL+"/concepts/foo")); code != http.StatusForbidden {
+			t.Error("expected 403 for delete outside of the scopes, got", code)
+		}
+		select {
+		case header := <-forwarded:
+			t.Fatal("out of scope request was forwarded", header)
+		default:
+		}
+
+		if code := status(helper.Jwtpost("ApiKey "+key.Key, forwardServer.URL+"/concepts", models.Concept{Name: "c"})); code != http.StatusOK {
+			t.Error("expected 200 for scoped create, got", code)
+		}
+		select {
+		case header := <-forwarded:
+			verifyConf := conf
+			verifyConf.JwtPublicKeyFile = testKeyFile
+			verifyConf.JwtIssuer = controller.ApiKeyIssuer
+			verifier, err := auth.NewVerifier(verifyConf)
+			if err != nil {
+				t.Fatal(err)
+			}
+			err = verifier.Verify(header)
+			if err != nil {
+				t.Error("forwarded token is not the signed token of the key", header, err)
+			}
+		default:
+			t.Error("scoped request was not forwarded")
+		}
+	})
+
+	t.Run("list", func(t *testing.T) {
+		resp, err := helper.Jwtget(user.Token, server.URL+"/api-keys")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		keys := []model.ApiKey{}
+		err = json.NewDecoder(resp.Body).Decode(&keys)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(keys) != 2 || keys[0].Id != key.Id {
+			t.Errorf("%#v", keys)
+		}
+	})
+
+	t.Run("persisted hashed", func(t *testing.T) {
+		store, err := apikey.New(conf.ApiKeyFile)
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, err = store.Authenticate(key.Key)
+		if err != nil {
+			t.Error(err)
+		}
+	})
+
+	t.Run("revoke", func(t *testing.T) {
+		other, err := auth.CreateTokenWithRoles("test", "user2", []string{"user"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if code := status(helper.Jwtdelete(other.Token, server.URL+"/api-keys/"+key.Id)); code != http.StatusNotFound {
+			t.Error("expected 404 for foreign key, got", code)
+		}
+		if code := status(helper.Jwtdelete(user.Token, server.URL+"/api-keys/"+key.Id)); code != http.StatusOK {
+			t.Error("expected 200, got", code)
+		}
+		if code := status(helper.Jwtpost("ApiKey "+key.Key, server.URL+"/concepts", models.Concept{Name: "c"})); code != http.StatusUnauthorized {
+			t.Error("expected 401 for revoked key, got", code)
+		}
+	})
+
+	t.Run("migration deleting the source", func(t *testing.T) {
+		resp, err := helper.Jwtpost(user.Token, server.URL+"/api-keys", model.ApiKeyCreateRequest{
+			Name:   "device-writer",
+			Scopes: []model.ApiKeyScope{{Kind: "devices", Actions: []string{apikey.ActionUpdate}}},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		writer := model.ApiKeyCreated{}
+		err = json.NewDecoder(resp.Body).Decode(&writer)
+		resp.Body.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+		request := model.DeviceTypeMigrationRequest{TargetDeviceTypeId: "dt2", DeleteSource: true}
+		if code := status(helper.Jwtpost("ApiKey "+writer.Key, server.URL+"/device-types/dt1/migrate", request)); code != http.StatusForbidden {
+			t.Error("expected 403 for delete_source without device-types delete scope, got", code)
+		}
+		request.DeleteSource = false
+		if code := status(helper.Jwtpost("ApiKey "+writer.Key, server.URL+"/device-types/dt1/migrate", request)); code == http.StatusForbidden {
+			t.Error("unexpected 403 for migration without delete_source")
+		}
+	})
+
+	t.Run("replicas", func(t *testing.T) {
+		location := filepath.Join(t.TempDir(), "keys.json")
+		replica1, err := apikey.New(location)
+		if err != nil {
+			t.Fatal(err)
+		}
+		replica2, err := apikey.New(location)
+		if err != nil {
+			t.Fatal(err)
+		}
+		expires := time.Now().Add(time.Hour)
+		key1, err := replica1.Create(model.ApiKey{Name: "k1", UserId: "user1", ExpiresAt: expires})
+		if err != nil {
+			t.Fatal(err)
+		}
+		key2, err := replica2.Create(model.ApiKey{Name: "k2", UserId: "user1", ExpiresAt: expires})
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, replica := range []*apikey.Store{replica1, replica2} {
+			for _, key := range []model.ApiKeyCreated{key1, key2} {
+				if _, err = replica.Authenticate(key.Key); err != nil {
+					t.Error(key.Name, err)
+				}
+			}
+		}
+		err = replica2.Revoke(key1.Id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err = replica1.Authenticate(key1.Key); err == nil {
+			t.Error("expected key revoked by the other replica to be rejected")
+		}
+		if _, err = replica1.Authenticate(key2.Key); err != nil {
+			t.Error(err)
+		}
+	})
+}
@@ -0,0 +1,168 @@
+/*
+ * Copyright 2024 InfAI (CC SES)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package tests
+
+import (
+	"bytes"
+	"encoding/json"
+	"github.com/SENERGY-Platform/device-manager/lib/api"
+	"github.com/SENERGY-Platform/device-manager/lib/audit"
+	"github.com/SENERGY-Platform/device-manager/lib/auth"
+	"github.com/SENERGY-Platform/device-manager/lib/config"
+	"github.com/SENERGY-Platform/device-manager/lib/fakes"
+	"github.com/SENERGY-Platform/device-manager/lib/model"
+	"github.com/SENERGY-Platform/device-manager/lib/tests/helper"
+	"github.com/SENERGY-Platform/models/go/models"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+)
+
+func TestAudit(t *testing.T) {
+	conf, err := config.Load("./../../config.json")
+	if err != nil {
+		t.Fatal("ERROR: unable to load config", err)
+	}
+	conf.AuditFile = t.TempDir() + "/audit.jsonl"
+	f, err := fakes.New(conf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctrl, err := f.Controller()
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := httptest.NewServer(api.GetRouter(f.Config, ctrl))
+	defer server.Close()
+
+	owner, err := auth.CreateTokenWithRoles("test", "owner", []string{"user"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	other, err := auth.CreateTokenWithRoles("test", "other", []string{"user"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	admin, err := auth.CreateTokenWithRoles("test", "admin", []string{"admin"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = f.Publisher.PublishLocation(models.Location{Id: "l1", Name: "before", DeviceIds: []string{}}, "owner")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	query := func(token string, params url.Values) (result []model.AuditRecord, code int) {
+		resp, err := helper.Jwtget(token, server.URL+"/audit?"+params.Encode())
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			return nil, resp.StatusCode
+		}
+		err = json.NewDecoder(resp.Body).Decode(&result)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return result, resp.StatusCode
+	}
+
+	t.Run("update", func(t *testing.T) {
+		body, _ := json.Marshal(models.Location{Id: "l1", Name: "after", DeviceIds: []string{}})
+		req, err := http.NewRequest(http.MethodPut, server.URL+"/locations/l1", bytes.NewReader(body))
+		if err != nil {
+			t.Fatal(err)
+		}
+		req.Header.Set("Authorization", owner.Token)
+		req.Header.Set(audit.RequestIdHeader, "req-1")
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			t.Fatal(resp.StatusCode)
+		}
+		if id := resp.Header.Get(audit.RequestIdHeader); id != "req-1" {
+			t.Error(id)
+		}
+	})
+
+	t.Run("permissions", func(t *testing.T) {
+		resp, err := helper.Jwtput(owner.Token, server.URL+"/locations/l1/permissions/users/viewer", model.PermissionsMap{Read: true})
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			t.Fatal(resp.StatusCode)
+		}
+		if resp.Header.Get(audit.RequestIdHeader) == "" {
+			t.Error("missing generated request id")
+		}
+	})
+
+	t.Run("denied delete", func(t *testing.T) {
+		resp, err := helper.Jwtdelete(other.Token, server.URL+"/locations/l1")
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode == http.StatusOK {
+			t.Fatal(resp.StatusCode)
+		}
+	})
+
+	t.Run("query", func(t *testing.T) {
+		if _, code := query(owner.Token, url.Values{}); code != http.StatusForbidden {
+			t.Error("expected 403 for non admin, got", code)
+		}
+		records, code := query(admin.Token, url.Values{"resource": {"l1"}})
+		if code != http.StatusOK {
+			t.Fatal(code)
+		}
+		if len(records) != 3 {
+			t.Fatalf("%#v", records)
+		}
+		update := records[0]
+		if update.Operation != audit.Update || update.Actor != "owner" || update.RequestId != "req-1" || update.Outcome != audit.OutcomeSuccess || update.Kind != f.Config.LocationTopic {
+			t.Errorf("%#v", update)
+		}
+		if len(update.Diff) != 1 || update.Diff[0].Path != "name" || update.Diff[0].Before != "before" || update.Diff[0].After != "after" {
+			t.Errorf("%#v", update.Diff)
+		}
+		permissions := records[1]
+		if permissions.Operation != audit.Permissions || len(permissions.Diff) != 1 || permissions.Diff[0].Path != "user_permissions.viewer" {
+			t.Errorf("%#v", permissions)
+		}
+		denied := records[2]
+		if denied.Operation != audit.Delete || denied.Actor != "other" || denied.Outcome != audit.OutcomeError || denied.Error == "" {
+			t.Errorf("%#v", denied)
+		}
+
+		records, _ = query(admin.Token, url.Values{"actor": {"other"}})
+		if len(records) != 1 || records[0].Actor != "other" {
+			t.Errorf("%#v", records)
+		}
+		records, _ = query(admin.Token, url.Values{"resource": {"l1"}, "from": {update.Time.Add(1).Format("2006-01-02T15:04:05.999999999Z07:00")}})
+		if len(records) != 2 {
+			t.Errorf("%#v", records)
+		}
+	})
+}
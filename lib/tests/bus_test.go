@@ -0,0 +1,256 @@
+/*
+ * Copyright 2024 InfAI (CC SES)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package tests
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/SENERGY-Platform/device-manager/lib/bus"
+	"github.com/SENERGY-Platform/device-manager/lib/config"
+	"github.com/SENERGY-Platform/device-manager/lib/kafka/listener"
+	"github.com/SENERGY-Platform/device-manager/lib/kafka/publisher"
+	"github.com/SENERGY-Platform/models/go/models"
+	"github.com/SENERGY-Platform/service-commons/pkg/donewait"
+	"github.com/SENERGY-Platform/service-commons/pkg/signal"
+	natsserver "github.com/nats-io/nats-server/v2/server"
+	"sync"
+	"testing"
+	"time"
+)
+
+type userDeleteRecorder struct {
+	mux     sync.Mutex
+	deleted []string
+}
+
+func (this *userDeleteRecorder) DeleteUser(_ context.Context, userId string) error {
+	this.mux.Lock()
+	defer this.mux.Unlock()
+	this.deleted = append(this.deleted, userId)
+	return nil
+}
+
+func (this *userDeleteRecorder) get() []string {
+	this.mux.Lock()
+	defer this.mux.Unlock()
+	return append([]string{}, this.deleted...)
+}
+
+func TestChannelBus(t *testing.T) {
+	conf, err := config.Load("./../../config.json")
+	if err != nil {
+		t.Fatal("ERROR: unable to load config", err)
+	}
+	conf.BusBackend = bus.BackendChannel
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	b := bus.NewChannel()
+
+	t.Run("groups", func(t *testing.T) {
+		mux := sync.Mutex{}
+		received := map[string]int{}
+		count := func(name string) bus.Handler {
+			return func(_ context.Context, topic string, msg []byte) error {
+				mux.Lock()
+				defer mux.Unlock()
+				received[name]++
+				return nil
+			}
+		}
+		for _, sub := range []struct{ name, group string }{{"a1", "a"}, {"a2", "a"}, {"b", "b"}, {"all", ""}} {
+			err := b.Subscribe(ctx, "group-test", sub.group, count(sub.name), nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+		}
+		for i := 0; i < 10; i++ {
+			err := b.Publish(ctx, "group-test", "", []byte("msg"))
+			if err != nil {
+				t.Fatal(err)
+			}
+		}
+		time.Sleep(100 * time.Millisecond)
+		mux.Lock()
+		if received["a1"] != 5 || received["a2"] != 5 || received["b"] != 10 || received["all"] != 10 {
+			t.Error(received)
+		}
+		mux.Unlock()
+
+		//messages with the same key go to the same group member, to be handled in order
+		for i := 0; i < 10; i++ {
+			err := b.Publish(ctx, "group-test", "key", []byte("msg"))
+			if err != nil {
+				t.Fatal(err)
+			}
+		}
+		time.Sleep(100 * time.Millisecond)
+		mux.Lock()
+		defer mux.Unlock()
+		if received["a1"]+received["a2"] != 20 || (received["a1"] != 15 && received["a2"] != 15) || received["b"] != 20 || received["all"] != 20 {
+			t.Error(received)
+		}
+	})
+
+	t.Run("publisher", func(t *testing.T) {
+		commands := make(chan publisher.DeviceCommand, 1)
+		err := b.Subscribe(ctx, conf.DeviceTopic, "repo", func(_ context.Context, topic string, msg []byte) error {
+			cmd := publisher.DeviceCommand{}
+			err := json.Unmarshal(msg, &cmd)
+			if err != nil {
+				return err
+			}
+			commands <- cmd
+			return nil
+		}, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = publisher.NewWithBus(conf, b).PublishDevice(models.Device{Id: "d1", Name: "d1"}, "owner")
+		if err != nil {
+			t.Fatal(err)
+		}
+		select {
+		case cmd := <-commands:
+			if cmd.Command != "PUT" || cmd.Id != "d1" || cmd.Owner != "owner" || cmd.Device.Name != "d1" {
+				t.Error(cmd)
+			}
+		case <-time.After(time.Second):
+			t.Error("timeout")
+		}
+	})
+
+	t.Run("user listener", func(t *testing.T) {
+		recorder := &userDeleteRecorder{}
+		err := listener.Start(ctx, conf, b, recorder)
+		if err != nil {
+			t.Fatal(err)
+		}
+		msg, err := json.Marshal(listener.UserCommandMsg{Command: "DELETE", Id: "u1"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = b.Publish(ctx, conf.UserTopic, "u1", msg)
+		if err != nil {
+			t.Fatal(err)
+		}
+		time.Sleep(100 * time.Millisecond)
+		if deleted := recorder.get(); len(deleted) != 1 || deleted[0] != "u1" {
+			t.Error(deleted)
+		}
+	})
+
+	t.Run("done wait", func(t *testing.T) {
+		broker := &signal.Broker{}
+		err := bus.StartDoneWaitListener(ctx, b, conf.DoneTopics, broker, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		done := donewait.DoneMsg{ResourceKind: conf.DeviceTopic, ResourceId: "d1", Command: "PUT", Handler: conf.DoneHandler[0]}
+		waitCtx, waitCancel := context.WithTimeout(ctx, time.Second)
+		defer waitCancel()
+		wait := donewait.AsyncWait(waitCtx, done, broker)
+		msg, err := json.Marshal(done)
+		if err != nil {
+			t.Fatal(err)
+		}
+		time.Sleep(50 * time.Millisecond)
+		err = b.Publish(ctx, conf.DoneTopics[0], "d1", msg)
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = wait()
+		if err != nil {
+			t.Error(err)
+		}
+	})
+}
+
+func TestNatsJetStreamBus(t *testing.T) {
+	conf, err := config.Load("./../../config.json")
+	if err != nil {
+		t.Fatal("ERROR: unable to load config", err)
+	}
+	server, err := natsserver.NewServer(&natsserver.Options{Host: "127.0.0.1", Port: -1, JetStream: true, StoreDir: t.TempDir()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	go server.Start()
+	defer server.Shutdown()
+	if !server.ReadyForConnections(10 * time.Second) {
+		t.Fatal("nats server not ready")
+	}
+	conf.BusBackend = bus.BackendNats
+	conf.NatsUrl = server.ClientURL()
+	conf.NatsJetStream = true
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	b, err := bus.New(ctx, conf, "jetstream-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	mux := sync.Mutex{}
+	received := []string{}
+	handler := func(_ context.Context, topic string, msg []byte) error {
+		mux.Lock()
+		defer mux.Unlock()
+		received = append(received, string(msg))
+		return nil
+	}
+	get := func() []string {
+		mux.Lock()
+		defer mux.Unlock()
+		return append([]string{}, received...)
+	}
+
+	t.Run("deliver messages published before the first subscription", func(t *testing.T) {
+		err = b.Publish(ctx, "jetstream-test", "k", []byte("m1"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		subCtx, subCancel := context.WithCancel(ctx)
+		err = b.Subscribe(subCtx, "jetstream-test", "group", handler, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		time.Sleep(time.Second)
+		subCancel()
+		time.Sleep(200 * time.Millisecond)
+		if result := get(); len(result) != 1 || result[0] != "m1" {
+			t.Error(result)
+		}
+	})
+
+	t.Run("deliver messages published while the group is down", func(t *testing.T) {
+		err = b.Publish(ctx, "jetstream-test", "k", []byte("m2"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = b.Subscribe(ctx, "jetstream-test", "group", handler, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		time.Sleep(time.Second)
+		if result := get(); len(result) != 2 || result[1] != "m2" {
+			t.Error(result)
+		}
+	})
+}
@@ -0,0 +1,202 @@
+/*
+ * Copyright 2024 InfAI (CC SES)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package tests
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"github.com/SENERGY-Platform/device-manager/lib/api"
+	"github.com/SENERGY-Platform/device-manager/lib/bus"
+	"github.com/SENERGY-Platform/device-manager/lib/cache"
+	"github.com/SENERGY-Platform/device-manager/lib/config"
+	"github.com/SENERGY-Platform/device-manager/lib/controller"
+	"github.com/SENERGY-Platform/device-manager/lib/kafka/publisher"
+	"github.com/SENERGY-Platform/device-manager/lib/tests/helper"
+	"github.com/SENERGY-Platform/models/go/models"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestResourceCache(t *testing.T) {
+	conf, err := config.Load("./../../config.json")
+	if err != nil {
+		t.Fatal("ERROR: unable to load config", err)
+	}
+	conf.BusBackend = bus.BackendChannel
+	conf.HandleDoneWait = false
+	conf.ReadYourWritesTtl = "-"
+	conf.ResourceCacheTtl = "1m"
+
+	calls := atomic.Int64{}
+	repo := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		switch request.URL.Path {
+		case "/aspects/a1":
+			calls.Add(1)
+			json.NewEncoder(writer).Encode(models.Aspect{Id: "a1", Name: "a1"})
+		case "/device-types/dt1":
+			json.NewEncoder(writer).Encode(models.DeviceType{Id: "dt1", Name: "dt1"})
+		default:
+			http.NotFound(writer, request)
+		}
+	}))
+	defer repo.Close()
+	conf.DeviceRepoUrl = repo.URL
+
+	//only admins may read dt1
+	permissions := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		json.NewEncoder(writer).Encode(map[string]bool{"dt1": false})
+	}))
+	defer permissions.Close()
+	conf.PermissionsV2Url = permissions.URL
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	ctrl, err := controller.New(ctx, conf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := httptest.NewServer(api.GetRouter(conf, ctrl))
+	defer server.Close()
+
+	get := func(id string) int {
+		resp, err := helper.Jwtget(adminjwt, server.URL+"/aspects/"+id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		return resp.StatusCode
+	}
+
+	t.Run("hit", func(t *testing.T) {
+		for i := 0; i < 3; i++ {
+			if code := get("a1"); code != http.StatusOK {
+				t.Fatal(code)
+			}
+		}
+		if calls.Load() != 1 {
+			t.Error(calls.Load())
+		}
+	})
+
+	t.Run("device-type hit checks permissions", func(t *testing.T) {
+		resp, err := helper.Jwtget(adminjwt, server.URL+"/device-types/dt1")
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			t.Fatal(resp.StatusCode)
+		}
+		resp, err = helper.Jwtget(userjwt, server.URL+"/device-types/dt1")
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusForbidden {
+			t.Error("expected cached device-type to be denied for user", resp.StatusCode)
+		}
+	})
+
+	t.Run("errors are not cached", func(t *testing.T) {
+		get("unknown")
+		if code := get("unknown"); code != http.StatusNotFound {
+			t.Error(code)
+		}
+	})
+
+	t.Run("invalidate by command", func(t *testing.T) {
+		err = publisher.NewWithBus(conf, bus.DefaultChannel).PublishAspect(models.Aspect{Id: "a1", Name: "changed"}, "owner")
+		if err != nil {
+			t.Fatal(err)
+		}
+		time.Sleep(100 * time.Millisecond)
+		get("a1")
+		if calls.Load() != 2 {
+			t.Error(calls.Load())
+		}
+	})
+}
+
+func TestCache(t *testing.T) {
+	type value struct {
+		Id string
+	}
+	getter := func(calls *int, id string) func() (value, error, int) {
+		return func() (value, error, int) {
+			*calls++
+			return value{Id: id}, nil, http.StatusOK
+		}
+	}
+
+	t.Run("size", func(t *testing.T) {
+		c := cache.New(time.Minute, 2)
+		calls := 0
+		cache.Use(c, "test", "a", getter(&calls, "a"))
+		cache.Use(c, "test", "b", getter(&calls, "b"))
+		cache.Use(c, "test", "a", getter(&calls, "a"))
+		cache.Use(c, "test", "c", getter(&calls, "c")) //removes b as least recently used
+		cache.Use(c, "test", "a", getter(&calls, "a"))
+		if calls != 3 || c.Len() != 2 {
+			t.Error(calls, c.Len())
+		}
+		cache.Use(c, "test", "b", getter(&calls, "b"))
+		if calls != 4 {
+			t.Error(calls)
+		}
+	})
+
+	t.Run("ttl", func(t *testing.T) {
+		c := cache.New(50*time.Millisecond, 0)
+		calls := 0
+		cache.Use(c, "test", "a", getter(&calls, "a"))
+		cache.Use(c, "test", "a", getter(&calls, "a"))
+		time.Sleep(100 * time.Millisecond)
+		cache.Use(c, "test", "a", getter(&calls, "a"))
+		if calls != 2 {
+			t.Error(calls)
+		}
+	})
+
+	t.Run("invalidate while reading", func(t *testing.T) {
+		c := cache.New(time.Minute, 0)
+		calls := 0
+		cache.Use(c, "test", "a", func() (value, error, int) {
+			calls++
+			c.Invalidate("test", "a")
+			return value{Id: "stale"}, nil, http.StatusOK
+		})
+		result, _, _ := cache.Use(c, "test", "a", getter(&calls, "a"))
+		if calls != 2 || result.Id != "a" {
+			t.Error(calls, result)
+		}
+	})
+
+	t.Run("error", func(t *testing.T) {
+		c := cache.New(time.Minute, 0)
+		_, err, code := cache.Use(c, "test", "a", func() (value, error, int) {
+			return value{}, errors.New("not found"), http.StatusNotFound
+		})
+		if err == nil || code != http.StatusNotFound || c.Len() != 0 {
+			t.Error(err, code, c.Len())
+		}
+	})
+}
@@ -0,0 +1,171 @@
+/*
+ * Copyright 2024 InfAI (CC SES)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package tests
+
+import (
+	"encoding/json"
+	"github.com/SENERGY-Platform/device-manager/lib/api"
+	"github.com/SENERGY-Platform/device-manager/lib/auth"
+	"github.com/SENERGY-Platform/device-manager/lib/config"
+	"github.com/SENERGY-Platform/device-manager/lib/controller"
+	"github.com/SENERGY-Platform/device-manager/lib/fakes"
+	"github.com/SENERGY-Platform/device-manager/lib/model"
+	"github.com/SENERGY-Platform/device-manager/lib/tests/helper"
+	"github.com/SENERGY-Platform/models/go/models"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestClone(t *testing.T) {
+	conf, err := config.Load("./../../config.json")
+	if err != nil {
+		t.Fatal("ERROR: unable to load config", err)
+	}
+	f, err := fakes.New(conf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctrl, err := f.Controller()
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := httptest.NewServer(api.GetRouter(f.Config, ctrl))
+	defer server.Close()
+
+	admin, err := auth.CreateTokenWithRoles("test", "admin", []string{"admin"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = f.Publisher.PublishProtocol(models.Protocol{Id: "p1", Name: "p1", Handler: "p1"}, "admin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	source, err, _ := ctrl.PublishDeviceTypeCreate(admin, models.DeviceType{
+		Name:       "source",
+		Attributes: []models.Attribute{{Key: "vendor", Value: "a"}, {Key: "model", Value: "m1"}},
+		Services: []models.Service{{
+			LocalId:     "get",
+			Name:        "get",
+			Interaction: models.REQUEST,
+			ProtocolId:  "p1",
+			Outputs: []models.Content{{
+				Serialization: models.JSON,
+				ContentVariable: models.ContentVariable{
+					Name:                "value",
+					Type:                models.Structure,
+					SubContentVariables: []models.ContentVariable{{Name: "temperature", Type: models.Float}},
+				},
+			}},
+		}},
+	}, model.DeviceTypeUpdateOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	clone := func(path string, body model.CloneRequest, result interface{}) int {
+		resp, err := helper.Jwtpost(admin.Token, server.URL+path, body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode == http.StatusOK {
+			err = json.NewDecoder(resp.Body).Decode(result)
+			if err != nil {
+				t.Fatal(err)
+			}
+		}
+		return resp.StatusCode
+	}
+
+	attribute := func(attributes []models.Attribute, key string) string {
+		for _, attr := range attributes {
+			if attr.Key == key {
+				return attr.Value
+			}
+		}
+		return ""
+	}
+
+	t.Run("device-type", func(t *testing.T) {
+		result := models.DeviceType{}
+		code := clone("/device-types/"+source.Id+"/clone", model.CloneRequest{Name: "variant", Attributes: []models.Attribute{{Key: "model", Value: "m2"}}}, &result)
+		if code != http.StatusOK {
+			t.Fatal(code)
+		}
+		if result.Id == "" || result.Id == source.Id || result.Name != "variant" {
+			t.Errorf("%#v", result)
+		}
+		if attribute(result.Attributes, "vendor") != "a" || attribute(result.Attributes, "model") != "m2" || attribute(result.Attributes, controller.CloneSourceAttributeKey) != source.Id {
+			t.Errorf("%#v", result.Attributes)
+		}
+		if len(result.Services) != 1 || len(result.Services[0].Outputs) != 1 {
+			t.Fatalf("%#v", result.Services)
+		}
+		service, content := result.Services[0], result.Services[0].Outputs[0]
+		sourceService, sourceContent := source.Services[0], source.Services[0].Outputs[0]
+		if service.Id == "" || service.Id == sourceService.Id || service.LocalId != "get" {
+			t.Errorf("%#v", service)
+		}
+		if content.Id == "" || content.Id == sourceContent.Id || content.ContentVariable.Id == "" || content.ContentVariable.Id == sourceContent.ContentVariable.Id {
+			t.Errorf("%#v", content)
+		}
+		sub := content.ContentVariable.SubContentVariables
+		if len(sub) != 1 || sub[0].Id == "" || sub[0].Id == sourceContent.ContentVariable.SubContentVariables[0].Id || sub[0].Name != "temperature" {
+			t.Errorf("%#v", sub)
+		}
+		stored, err, _ := ctrl.ReadDeviceType(admin, result.Id)
+		if err != nil || stored.Name != "variant" {
+			t.Error(err, stored)
+		}
+		unchanged, err, _ := ctrl.ReadDeviceType(admin, source.Id)
+		if err != nil || unchanged.Name != "source" || unchanged.Services[0].Id != sourceService.Id || attribute(unchanged.Attributes, controller.CloneSourceAttributeKey) != "" {
+			t.Error(err, unchanged)
+		}
+	})
+
+	t.Run("device", func(t *testing.T) {
+		device, err, _ := ctrl.PublishDeviceCreate(admin, models.Device{Name: "d", LocalId: "d", DeviceTypeId: source.Id, Attributes: []models.Attribute{{Key: "room", Value: "kitchen"}}}, model.DeviceCreateOptions{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		result := models.Device{}
+		code := clone("/devices/"+device.Id+"/clone", model.CloneRequest{Name: "d2", LocalId: "d2"}, &result)
+		if code != http.StatusOK {
+			t.Fatal(code)
+		}
+		if result.Id == "" || result.Id == device.Id || result.Name != "d2" || result.LocalId != "d2" || result.DeviceTypeId != source.Id || result.OwnerId != "admin" {
+			t.Errorf("%#v", result)
+		}
+		if attribute(result.Attributes, "room") != "kitchen" || attribute(result.Attributes, controller.CloneSourceAttributeKey) != device.Id {
+			t.Errorf("%#v", result.Attributes)
+		}
+
+		generated := models.Device{}
+		code = clone("/devices/"+device.Id+"/clone", model.CloneRequest{}, &generated)
+		if code != http.StatusOK {
+			t.Fatal(code)
+		}
+		if generated.LocalId == "" || generated.LocalId == device.LocalId || generated.Name != "d" {
+			t.Errorf("%#v", generated)
+		}
+
+		if code = clone("/devices/unknown/clone", model.CloneRequest{}, &models.Device{}); code != http.StatusNotFound {
+			t.Error(code)
+		}
+	})
+}
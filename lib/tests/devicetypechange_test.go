@@ -0,0 +1,229 @@
+/*
+ * Copyright 2024 InfAI (CC SES)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package tests
+
+import (
+	"encoding/json"
+	"github.com/SENERGY-Platform/device-manager/lib/api"
+	"github.com/SENERGY-Platform/device-manager/lib/auth"
+	"github.com/SENERGY-Platform/device-manager/lib/config"
+	"github.com/SENERGY-Platform/device-manager/lib/fakes"
+	"github.com/SENERGY-Platform/device-manager/lib/model"
+	"github.com/SENERGY-Platform/device-manager/lib/tests/helper"
+	"github.com/SENERGY-Platform/models/go/models"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestDeviceTypeBreakingChanges(t *testing.T) {
+	conf, err := config.Load("./../../config.json")
+	if err != nil {
+		t.Fatal("ERROR: unable to load config", err)
+	}
+	f, err := fakes.New(conf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctrl, err := f.Controller()
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := httptest.NewServer(api.GetRouter(f.Config, ctrl))
+	defer server.Close()
+
+	admin, err := auth.CreateTokenWithRoles("test", "admin", []string{"admin"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = f.Publisher.PublishProtocol(models.Protocol{Id: "p1", Name: "p1", Handler: "p1", ProtocolSegments: []models.ProtocolSegment{{Id: "seg1", Name: "payload"}}}, "admin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	dt, err, _ := ctrl.PublishDeviceTypeCreate(admin, models.DeviceType{
+		Name: "dt",
+		Services: []models.Service{{
+			LocalId:     "get",
+			Name:        "get",
+			Interaction: models.REQUEST,
+			ProtocolId:  "p1",
+			Outputs: []models.Content{{
+				Serialization:     models.JSON,
+				ProtocolSegmentId: "seg1",
+				ContentVariable: models.ContentVariable{
+					Name: "value",
+					Type: models.Structure,
+					SubContentVariables: []models.ContentVariable{{
+						Name:             "temperature",
+						Type:             models.Float,
+						CharacteristicId: "celsius",
+					}},
+				},
+			}},
+		}},
+	}, model.DeviceTypeUpdateOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	update := func(dt models.DeviceType, query string) int {
+		resp, err := helper.Jwtput(admin.Token, server.URL+"/device-types/"+dt.Id+query, dt)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		return resp.StatusCode
+	}
+	analyze := func(dt models.DeviceType) (result model.DeviceTypeUpdateAnalysis) {
+		resp, err := helper.Jwtpost(admin.Token, server.URL+"/device-types/"+dt.Id+"/analysis", dt)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			t.Fatal(resp.StatusCode)
+		}
+		err = json.NewDecoder(resp.Body).Decode(&result)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return result
+	}
+	changed := func(change func(dt *models.DeviceType)) models.DeviceType {
+		temp, _ := json.Marshal(dt)
+		result := models.DeviceType{}
+		_ = json.Unmarshal(temp, &result)
+		change(&result)
+		return result
+	}
+	variablePath := "services[" + dt.Services[0].Id + "].outputs[" + dt.Services[0].Outputs[0].Id + "].content_variable.sub_content_variables[" + dt.Services[0].Outputs[0].ContentVariable.SubContentVariables[0].Id + "]"
+
+	characteristicChange := changed(func(dt *models.DeviceType) {
+		dt.Services[0].Outputs[0].ContentVariable.SubContentVariables[0].CharacteristicId = "fahrenheit"
+	})
+
+	t.Run("without devices", func(t *testing.T) {
+		analysis := analyze(characteristicChange)
+		if !analysis.Breaking || analysis.DevicesExist || analysis.ForceRequired {
+			t.Errorf("%#v", analysis)
+		}
+	})
+
+	err = f.Publisher.PublishDevice(models.Device{Id: "d1", Name: "d1", LocalId: "d1", DeviceTypeId: dt.Id, OwnerId: "admin"}, "admin")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Run("analysis", func(t *testing.T) {
+		analysis := analyze(characteristicChange)
+		if !analysis.Breaking || !analysis.DevicesExist || !analysis.ForceRequired || len(analysis.Changes) != 1 {
+			t.Fatalf("%#v", analysis)
+		}
+		change := analysis.Changes[0]
+		if change.Type != model.DeviceTypeChangeCharacteristicBinding || change.Path != variablePath+".characteristic_id" || change.Before != "celsius" || change.After != "fahrenheit" || !change.Breaking {
+			t.Errorf("%#v", change)
+		}
+		analysis = analyze(changed(func(dt *models.DeviceType) {
+			dt.Services[0].LocalId = "get-v2"
+			dt.Services[0].Outputs[0].ProtocolSegmentId = "seg2"
+			dt.Services[0].Outputs[0].ContentVariable.SubContentVariables = nil
+		}))
+		types := map[string]bool{}
+		for _, change := range analysis.Changes {
+			types[change.Type] = change.Breaking
+		}
+		if len(types) != 3 || !types[model.DeviceTypeChangeLocalId] || !types[model.DeviceTypeChangeProtocolSegment] || !types[model.DeviceTypeChangeVariableRemoved] {
+			t.Errorf("%#v", analysis.Changes)
+		}
+	})
+
+	t.Run("non admin", func(t *testing.T) {
+		user, err := auth.CreateTokenWithRoles("test", "user1", []string{"user"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp, err := helper.Jwtput(admin.Token, server.URL+"/device-types/"+dt.Id+"/permissions/users/user1", model.PermissionsMap{Read: true, Write: true})
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			t.Fatal(resp.StatusCode)
+		}
+		resp, err = helper.Jwtpost(user.Token, server.URL+"/device-types/"+dt.Id+"/analysis", characteristicChange)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			t.Fatal("expected 200 for analysis of non admin, got", resp.StatusCode)
+		}
+		analysis := model.DeviceTypeUpdateAnalysis{}
+		err = json.NewDecoder(resp.Body).Decode(&analysis)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !analysis.DevicesExist || !analysis.ForceRequired {
+			t.Errorf("%#v", analysis)
+		}
+		resp, err = helper.Jwtput(user.Token, server.URL+"/device-types/"+dt.Id, characteristicChange)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusConflict {
+			t.Error("expected 409 without force for non admin, got", resp.StatusCode)
+		}
+	})
+
+	t.Run("non breaking", func(t *testing.T) {
+		nonBreaking := changed(func(dt *models.DeviceType) {
+			dt.Services[0].Name = "get temperature"
+			dt.Services[0].Outputs[0].ContentVariable.SubContentVariables[0].FunctionId = "getTemperature"
+			dt.Services = append(dt.Services, models.Service{LocalId: "set", Name: "set", Interaction: models.REQUEST, ProtocolId: "p1"})
+		})
+		analysis := analyze(nonBreaking)
+		if analysis.Breaking || len(analysis.Changes) != 2 {
+			t.Errorf("%#v", analysis)
+		}
+		if code := update(nonBreaking, ""); code != http.StatusOK {
+			t.Fatal(code)
+		}
+		dt, err, _ = ctrl.ReadDeviceType(admin, dt.Id)
+		if err != nil {
+			t.Fatal(err)
+		}
+	})
+
+	t.Run("breaking", func(t *testing.T) {
+		removed := changed(func(dt *models.DeviceType) {
+			dt.Services = dt.Services[:1]
+		})
+		if code := update(removed, ""); code != http.StatusConflict {
+			t.Error("expected 409 without force, got", code)
+		}
+		if current, _, _ := ctrl.ReadDeviceType(admin, dt.Id); len(current.Services) != 2 {
+			t.Errorf("%#v", current.Services)
+		}
+		if code := update(removed, "?force=true"); code != http.StatusOK {
+			t.Error(code)
+		}
+		if current, _, _ := ctrl.ReadDeviceType(admin, dt.Id); len(current.Services) != 1 {
+			t.Errorf("%#v", current.Services)
+		}
+	})
+}
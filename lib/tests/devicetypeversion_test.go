@@ -0,0 +1,320 @@
+/*
+ * Copyright 2024 InfAI (CC SES)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package tests
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/SENERGY-Platform/device-manager/lib/api"
+	"github.com/SENERGY-Platform/device-manager/lib/auth"
+	"github.com/SENERGY-Platform/device-manager/lib/bus"
+	"github.com/SENERGY-Platform/device-manager/lib/config"
+	"github.com/SENERGY-Platform/device-manager/lib/controller"
+	"github.com/SENERGY-Platform/device-manager/lib/fakes"
+	"github.com/SENERGY-Platform/device-manager/lib/kafka/publisher"
+	"github.com/SENERGY-Platform/device-manager/lib/model"
+	"github.com/SENERGY-Platform/device-manager/lib/tests/helper"
+	"github.com/SENERGY-Platform/models/go/models"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestDeviceTypeVersions(t *testing.T) {
+	conf, err := config.Load("./../../config.json")
+	if err != nil {
+		t.Fatal("ERROR: unable to load config", err)
+	}
+	conf.DeviceTypeVersionFile = t.TempDir() + "/versions.json"
+	conf.DeviceTypeVersionLimit = 3
+	f, err := fakes.New(conf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctrl, err := f.Controller()
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := httptest.NewServer(api.GetRouter(f.Config, ctrl))
+	defer server.Close()
+
+	admin, err := auth.CreateTokenWithRoles("test", "admin", []string{"admin"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	get := func(path string, result interface{}) int {
+		resp, err := helper.Jwtget(admin.Token, server.URL+path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode == http.StatusOK && result != nil {
+			err = json.NewDecoder(resp.Body).Decode(result)
+			if err != nil {
+				t.Fatal(err)
+			}
+		}
+		return resp.StatusCode
+	}
+
+	err = f.Publisher.PublishProtocol(models.Protocol{Id: "p1", Name: "p1", Handler: "p1"}, "admin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	dt, err, _ := ctrl.PublishDeviceTypeCreate(admin, models.DeviceType{
+		Name:     "dt",
+		Services: []models.Service{{LocalId: "s1", Name: "s1", Interaction: models.REQUEST, ProtocolId: "p1"}},
+	}, model.DeviceTypeUpdateOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	serviceId := dt.Services[0].Id
+
+	t.Run("record", func(t *testing.T) {
+		dt.Services[0].Name = "broken"
+		dt, err, _ = ctrl.PublishDeviceTypeUpdate(admin, dt.Id, dt, model.DeviceTypeUpdateOptions{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		//unchanged definitions are no new version
+		_, err, _ = ctrl.PublishDeviceTypeUpdate(admin, dt.Id, dt, model.DeviceTypeUpdateOptions{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		versions := []model.DeviceTypeVersion{}
+		if code := get("/device-types/"+dt.Id+"/versions", &versions); code != http.StatusOK {
+			t.Fatal(code)
+		}
+		if len(versions) != 2 || versions[0].Version != 1 || versions[1].Version != 2 || versions[1].UserId != "admin" {
+			t.Fatalf("%#v", versions)
+		}
+		version := model.DeviceTypeVersion{}
+		if code := get("/device-types/"+dt.Id+"/versions/1", &version); code != http.StatusOK {
+			t.Fatal(code)
+		}
+		if version.DeviceType.Services[0].Name != "s1" {
+			t.Errorf("%#v", version)
+		}
+		if code := get("/device-types/"+dt.Id+"/versions/42", nil); code != http.StatusNotFound {
+			t.Error("expected 404 for unknown version, got", code)
+		}
+	})
+
+	t.Run("diff", func(t *testing.T) {
+		diff := model.DeviceTypeVersionDiff{}
+		if code := get("/device-types/"+dt.Id+"/versions/1/diff", &diff); code != http.StatusOK {
+			t.Fatal(code)
+		}
+		if diff.From != 1 || diff.To != 2 || len(diff.Changes) != 1 {
+			t.Fatalf("%#v", diff)
+		}
+		change := diff.Changes[0]
+		if change.Path != "services["+serviceId+"].name" || change.Before != "s1" || change.After != "broken" {
+			t.Errorf("%#v", change)
+		}
+		if code := get("/device-types/"+dt.Id+"/versions/2/diff?to=2", &diff); code != http.StatusOK || len(diff.Changes) != 0 {
+			t.Errorf("%v %#v", code, diff)
+		}
+	})
+
+	t.Run("rollback", func(t *testing.T) {
+		resp, err := helper.Jwtpost(admin.Token, server.URL+"/device-types/"+dt.Id+"/versions/1/rollback", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			t.Fatal(resp.StatusCode)
+		}
+		current, err, _ := ctrl.ReadDeviceType(admin, dt.Id)
+		if err != nil || current.Services[0].Name != "s1" {
+			t.Fatalf("%#v %v", current, err)
+		}
+		versions := []model.DeviceTypeVersion{}
+		get("/device-types/"+dt.Id+"/versions", &versions)
+		if len(versions) != 3 || versions[2].DeviceType.Services[0].Name != "s1" {
+			t.Fatalf("%#v", versions)
+		}
+	})
+
+	t.Run("limit", func(t *testing.T) {
+		dt.Name = "dt-4"
+		_, err, _ = ctrl.PublishDeviceTypeUpdate(admin, dt.Id, dt, model.DeviceTypeUpdateOptions{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		versions := []model.DeviceTypeVersion{}
+		get("/device-types/"+dt.Id+"/versions", &versions)
+		if len(versions) != 3 || versions[0].Version != 2 || versions[2].Version != 4 {
+			t.Fatalf("%#v", versions)
+		}
+		if code := get("/device-types/"+dt.Id+"/versions/1", nil); code != http.StatusNotFound {
+			t.Error("expected 404 for dropped version, got", code)
+		}
+	})
+
+	t.Run("reload", func(t *testing.T) {
+		reloaded, err := f.Controller()
+		if err != nil {
+			t.Fatal(err)
+		}
+		versions, err, _ := reloaded.ListDeviceTypeVersions(admin, dt.Id)
+		if err != nil || len(versions) != 3 {
+			t.Fatal(err, len(versions))
+		}
+		for i, v := range versions {
+			if v.Version != i+2 {
+				t.Error(strconv.Itoa(i), v.Version)
+			}
+		}
+	})
+
+	t.Run("consumed commands", func(t *testing.T) {
+		//published by another instance or client of the device-type topic
+		dt.Name = "external"
+		err = f.Publisher.PublishDeviceType(dt, "other")
+		if err != nil {
+			t.Fatal(err)
+		}
+		versions, err, _ := ctrl.ListDeviceTypeVersions(admin, dt.Id)
+		if err != nil || len(versions) != 3 {
+			t.Fatal(err, len(versions))
+		}
+		if latest := versions[2]; latest.Version != 5 || latest.UserId != "other" || latest.DeviceType.Name != "external" {
+			t.Errorf("%#v", latest)
+		}
+	})
+
+	t.Run("baseline", func(t *testing.T) {
+		//stored before the version history was enabled
+		legacy := models.DeviceType{
+			Name:     "legacy",
+			Services: []models.Service{{LocalId: "s1", Name: "s1", Interaction: models.REQUEST, ProtocolId: "p1"}},
+		}
+		legacy.GenerateId()
+		err = f.Publisher.Publisher.PublishDeviceType(legacy, "admin")
+		if err != nil {
+			t.Fatal(err)
+		}
+		versions, err, _ := ctrl.ListDeviceTypeVersions(admin, legacy.Id)
+		if err != nil || len(versions) != 0 {
+			t.Fatal(err, len(versions))
+		}
+		update := legacy
+		update.Name = "updated"
+		_, err, _ = ctrl.PublishDeviceTypeUpdate(admin, legacy.Id, update, model.DeviceTypeUpdateOptions{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		versions, err, _ = ctrl.ListDeviceTypeVersions(admin, legacy.Id)
+		if err != nil || len(versions) != 2 {
+			t.Fatal(err, len(versions))
+		}
+		if versions[0].Version != 1 || versions[0].DeviceType.Name != "legacy" || versions[0].UserId != "" {
+			t.Errorf("%#v", versions[0])
+		}
+		if versions[1].Version != 2 || versions[1].DeviceType.Name != "updated" || versions[1].UserId != "admin" {
+			t.Errorf("%#v", versions[1])
+		}
+	})
+
+	t.Run("disabled", func(t *testing.T) {
+		conf := f.Config
+		conf.DeviceTypeVersionFile = ""
+		disabled, err := controller.NewWithDependencies(conf, f.Publisher, f.Com)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, _, code := disabled.ListDeviceTypeVersions(admin, dt.Id); code != http.StatusNotFound {
+			t.Error("expected 404 without device_type_version_file, got", code)
+		}
+	})
+}
+
+// slowConsumer delays each message, like a replica lagging behind
+type slowConsumer struct {
+	bus.Consumer
+	delay time.Duration
+}
+
+func (this slowConsumer) Subscribe(ctx context.Context, topic string, group string, handler bus.Handler, onError func(err error)) error {
+	return this.Consumer.Subscribe(ctx, topic, group, func(ctx context.Context, topic string, msg []byte) error {
+		time.Sleep(this.delay)
+		return handler(ctx, topic, msg)
+	}, onError)
+}
+
+func TestDeviceTypeVersionReplicas(t *testing.T) {
+	conf, err := config.Load("./../../config.json")
+	if err != nil {
+		t.Fatal("ERROR: unable to load config", err)
+	}
+	conf.DeviceTypeVersionFile = t.TempDir() + "/versions.json"
+	f, err := fakes.New(conf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	admin, err := auth.CreateTokenWithRoles("test", "admin", []string{"admin"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	b := bus.NewChannel()
+	replicas := []*controller.Controller{}
+	for _, delay := range []time.Duration{0, 20 * time.Millisecond} {
+		replica, err := controller.NewWithDependencies(f.Config, f.Publisher, f.Com)
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = replica.SubscribeDeviceTypeVersions(ctx, slowConsumer{Consumer: b, delay: delay})
+		if err != nil {
+			t.Fatal(err)
+		}
+		replicas = append(replicas, replica)
+	}
+
+	//y, x, y and x again are four changed definitions
+	names := []string{"y", "x", "y", "x"}
+	producer := publisher.NewWithBus(f.Config, b)
+	for _, name := range names {
+		err = producer.PublishDeviceType(models.DeviceType{Id: "dt", Name: name}, "admin")
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	time.Sleep(200 * time.Millisecond)
+
+	for i, replica := range replicas {
+		versions, err, _ := replica.ListDeviceTypeVersions(admin, "dt")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(versions) != len(names) {
+			t.Fatalf("replica %v: %#v", i, versions)
+		}
+		for j, v := range versions {
+			if v.Version != j+1 || v.DeviceType.Name != names[j] {
+				t.Errorf("replica %v: %v %v %v", i, j, v.Version, v.DeviceType.Name)
+			}
+		}
+	}
+}
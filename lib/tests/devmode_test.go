@@ -0,0 +1,149 @@
+/*
+ * Copyright 2024 InfAI (CC SES)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package tests
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/SENERGY-Platform/device-manager/lib/api"
+	"github.com/SENERGY-Platform/device-manager/lib/config"
+	"github.com/SENERGY-Platform/device-manager/lib/devmode"
+	"github.com/SENERGY-Platform/device-manager/lib/tests/helper"
+	"github.com/SENERGY-Platform/models/go/models"
+	"net/http"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestDevMode(t *testing.T) {
+	conf, err := config.Load("./../../config.json")
+	if err != nil {
+		t.Fatal("ERROR: unable to load config", err)
+	}
+	conf = devmode.Config(conf)
+	file := filepath.Join(t.TempDir(), "dev.json")
+
+	start := func() (stop func()) {
+		port, err := helper.GetFreePort()
+		if err != nil {
+			t.Fatal(err)
+		}
+		conf.ServerPort = strconv.Itoa(port)
+		ctrl, err := devmode.NewController(conf, file)
+		if err != nil {
+			t.Fatal(err)
+		}
+		srv, err := api.Start(conf, ctrl)
+		if err != nil {
+			t.Fatal(err)
+		}
+		time.Sleep(200 * time.Millisecond)
+		return func() { srv.Shutdown(context.Background()) }
+	}
+
+	stop := start()
+
+	protocol := models.Protocol{Name: "p", Handler: "p", ProtocolSegments: []models.ProtocolSegment{{Name: "data"}}}
+	resp, err := helper.Jwtpost(AdminToken, "http://localhost:"+conf.ServerPort+"/protocols", protocol)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		t.Fatal(resp.Status)
+	}
+	err = json.NewDecoder(resp.Body).Decode(&protocol)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dt := models.DeviceType{Name: "dt", Services: []models.Service{{Name: "s", LocalId: "s", ProtocolId: protocol.Id}}}
+	resp, err = helper.Jwtpost(Userjwt, "http://localhost:"+conf.ServerPort+"/device-types", dt)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		t.Fatal(resp.Status)
+	}
+	err = json.NewDecoder(resp.Body).Decode(&dt)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Run("unknown device-type", func(t *testing.T) {
+		resp, err := helper.Jwtpost(Userjwt, "http://localhost:"+conf.ServerPort+"/devices", models.Device{Name: "d", LocalId: "d", DeviceTypeId: "unknown"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if resp.StatusCode != http.StatusBadRequest {
+			t.Error(resp.Status)
+		}
+	})
+
+	device := models.Device{Name: "d", LocalId: "d", DeviceTypeId: dt.Id}
+	resp, err = helper.Jwtpost(Userjwt, "http://localhost:"+conf.ServerPort+"/devices", device)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		t.Fatal(resp.Status)
+	}
+	err = json.NewDecoder(resp.Body).Decode(&device)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	stop()
+	stop = start()
+	defer stop()
+
+	t.Run("read persisted device", func(t *testing.T) {
+		resp, err := helper.Jwtget(Userjwt, "http://localhost:"+conf.ServerPort+"/devices/"+device.Id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if resp.StatusCode != http.StatusOK {
+			t.Fatal(resp.Status)
+		}
+		result := models.Device{}
+		err = json.NewDecoder(resp.Body).Decode(&result)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if result.Name != "d" || result.OwnerId != Userid {
+			t.Error(result)
+		}
+	})
+
+	t.Run("owner is admin", func(t *testing.T) {
+		resp, err := helper.Jwtdelete(Userjwt, "http://localhost:"+conf.ServerPort+"/devices/"+device.Id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if resp.StatusCode != http.StatusOK {
+			t.Fatal(resp.Status)
+		}
+		resp, err = helper.Jwtget(Userjwt, "http://localhost:"+conf.ServerPort+"/devices/"+device.Id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if resp.StatusCode != http.StatusNotFound {
+			t.Error(resp.Status)
+		}
+	})
+}
@@ -0,0 +1,174 @@
+/*
+ * Copyright 2024 InfAI (CC SES)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package tests
+
+import (
+	"github.com/SENERGY-Platform/device-manager/lib/api/util"
+	"github.com/SENERGY-Platform/device-manager/lib/config"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestConditionalForward(t *testing.T) {
+	local := http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		writer.Write([]byte("local"))
+	})
+	isEdit := func(r *http.Request) bool {
+		return r.Method == http.MethodPost || r.Method == http.MethodPut || r.Method == http.MethodDelete
+	}
+
+	echo := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		body, _ := io.ReadAll(request.Body)
+		writer.Header().Set("X-Seen-Custom", request.Header.Get("X-Custom"))
+		writer.Header().Set("X-Seen-Forwarded-For", request.Header.Get("X-Forwarded-For"))
+		writer.Header().Set("X-Seen-Forwarded-Host", request.Header.Get("X-Forwarded-Host"))
+		writer.Header().Set("X-Seen-Auth", request.Header.Get("Authorization"))
+		writer.WriteHeader(http.StatusOK)
+		writer.Write([]byte(request.Method + " " + request.URL.RequestURI() + " " + string(body)))
+	}))
+	defer echo.Close()
+
+	var unavailableCalls atomic.Int64
+	unavailable := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		unavailableCalls.Add(1)
+		io.ReadAll(request.Body)
+		http.Error(writer, "unavailable", http.StatusServiceUnavailable)
+	}))
+	defer unavailable.Close()
+
+	slow := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		time.Sleep(time.Second)
+		writer.WriteHeader(http.StatusOK)
+	}))
+	defer slow.Close()
+
+	closed := httptest.NewServer(http.NotFoundHandler())
+	closedUrl := closed.URL
+	closed.Close()
+
+	options := util.DefaultForwardOptions
+	options.Timeout = 200 * time.Millisecond
+
+	do := func(t *testing.T, handler http.Handler, method string, path string, body string, header http.Header) (code int, respBody string, respHeader http.Header) {
+		server := httptest.NewServer(handler)
+		defer server.Close()
+		req, err := http.NewRequest(method, server.URL+path, strings.NewReader(body))
+		if err != nil {
+			t.Fatal(err)
+		}
+		for key, values := range header {
+			req.Header[key] = values
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		temp, _ := io.ReadAll(resp.Body)
+		return resp.StatusCode, string(temp), resp.Header
+	}
+
+	t.Run("condition", func(t *testing.T) {
+		handler := util.NewConditionalForwardWithOptions(local, echo.URL, options, isEdit)
+		_, body, _ := do(t, handler, http.MethodGet, "/devices", "", nil)
+		if body != "local" {
+			t.Error(body)
+		}
+	})
+
+	t.Run("headers and body", func(t *testing.T) {
+		handler := util.NewConditionalForwardWithOptions(local, echo.URL, options, isEdit)
+		code, body, header := do(t, handler, http.MethodPut, "/devices/d1?wait=true", "payload", http.Header{
+			"Connection":      {"X-Custom"},
+			"X-Custom":        {"hop"},
+			"X-Forwarded-For": {"10.0.0.1"},
+			"Authorization":   {"Bearer token"},
+		})
+		if code != http.StatusOK || body != "PUT /devices/d1?wait=true payload" {
+			t.Error(code, body)
+		}
+		if header.Get("X-Seen-Custom") != "" {
+			t.Error("hop-by-hop header forwarded", header.Get("X-Seen-Custom"))
+		}
+		if !strings.HasPrefix(header.Get("X-Seen-Forwarded-For"), "10.0.0.1, ") {
+			t.Error(header.Get("X-Seen-Forwarded-For"))
+		}
+		if header.Get("X-Seen-Forwarded-Host") == "" || header.Get("X-Seen-Auth") != "Bearer token" {
+			t.Error(header)
+		}
+	})
+
+	t.Run("failover on dial error", func(t *testing.T) {
+		handler := util.NewConditionalForwardWithOptions(local, closedUrl+","+echo.URL, options, isEdit)
+		code, body, _ := do(t, handler, http.MethodPost, "/devices", "payload", nil)
+		if code != http.StatusOK || body != "POST /devices payload" {
+			t.Error(code, body)
+		}
+	})
+
+	t.Run("retry idempotent on 503", func(t *testing.T) {
+		unavailableCalls.Store(0)
+		handler := util.NewConditionalForwardWithOptions(local, unavailable.URL+","+echo.URL, options, isEdit)
+		code, body, _ := do(t, handler, http.MethodDelete, "/devices/d1", "", nil)
+		if code != http.StatusOK || body != "DELETE /devices/d1 " {
+			t.Error(code, body)
+		}
+		if unavailableCalls.Load() != 1 {
+			t.Error(unavailableCalls.Load())
+		}
+
+		//the unhealthy upstream is skipped afterward
+		code, _, _ = do(t, handler, http.MethodPut, "/devices/d1", "payload", nil)
+		if code != http.StatusOK || unavailableCalls.Load() != 1 {
+			t.Error(code, unavailableCalls.Load())
+		}
+	})
+
+	t.Run("no retry for post", func(t *testing.T) {
+		unavailableCalls.Store(0)
+		handler := util.NewConditionalForwardWithOptions(local, unavailable.URL+","+echo.URL, options, isEdit)
+		code, _, _ := do(t, handler, http.MethodPost, "/devices", "payload", nil)
+		if code != http.StatusServiceUnavailable || unavailableCalls.Load() != 1 {
+			t.Error(code, unavailableCalls.Load())
+		}
+	})
+
+	t.Run("timeout", func(t *testing.T) {
+		handler := util.NewConditionalForwardWithOptions(local, slow.URL, options, isEdit)
+		code, _, _ := do(t, handler, http.MethodPost, "/devices", "payload", nil)
+		if code != http.StatusGatewayTimeout {
+			t.Error(code)
+		}
+	})
+}
+
+func TestForwardOptionsFromConfig(t *testing.T) {
+	if retries := util.ForwardOptionsFromConfig(config.Config{}).Retries; retries != util.DefaultForwardOptions.Retries {
+		t.Error("expected default retries for unset edit_forward_retries, got", retries)
+	}
+	if retries := util.ForwardOptionsFromConfig(config.Config{EditForwardRetries: 5}).Retries; retries != 5 {
+		t.Error(retries)
+	}
+	if retries := util.ForwardOptionsFromConfig(config.Config{EditForwardRetries: -1}).Retries; retries != 0 {
+		t.Error("expected disabled retries for negative edit_forward_retries, got", retries)
+	}
+}
@@ -0,0 +1,126 @@
+/*
+ * Copyright 2024 InfAI (CC SES)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package tests
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/SENERGY-Platform/device-manager/lib/api"
+	"github.com/SENERGY-Platform/device-manager/lib/bus"
+	"github.com/SENERGY-Platform/device-manager/lib/config"
+	"github.com/SENERGY-Platform/device-manager/lib/controller"
+	"github.com/SENERGY-Platform/device-manager/lib/model"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+)
+
+func TestHealth(t *testing.T) {
+	conf, err := config.Load("./../../config.json")
+	if err != nil {
+		t.Fatal("ERROR: unable to load config", err)
+	}
+	conf.BusBackend = bus.BackendChannel
+	conf.HandleDoneWait = true
+
+	repo := httptest.NewServer(http.NotFoundHandler())
+	defer repo.Close()
+	conf.DeviceRepoUrl = repo.URL
+
+	var permissionsDown atomic.Bool
+	permissionsDown.Store(true)
+	permissions := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		if permissionsDown.Load() {
+			writer.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		writer.WriteHeader(http.StatusOK)
+	}))
+	defer permissions.Close()
+	conf.PermissionsV2Url = permissions.URL
+
+	converter := httptest.NewServer(http.NotFoundHandler())
+	conf.ConverterUrl = converter.URL
+	converter.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	ctrl, err := controller.New(ctx, conf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := httptest.NewServer(api.GetRouter(conf, ctrl))
+	defer server.Close()
+
+	get := func(t *testing.T, path string) (code int, result model.HealthStatus) {
+		resp, err := http.Get(server.URL + path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		if path != "/health/live" {
+			err = json.NewDecoder(resp.Body).Decode(&result)
+			if err != nil {
+				t.Fatal(err)
+			}
+		}
+		return resp.StatusCode, result
+	}
+
+	t.Run("live", func(t *testing.T) {
+		code, _ := get(t, "/health/live")
+		if code != http.StatusOK {
+			t.Error(code)
+		}
+	})
+
+	t.Run("dependencies", func(t *testing.T) {
+		code, result := get(t, "/health/dependencies")
+		if code != http.StatusOK || result.Ready {
+			t.Error(code, result)
+		}
+		expected := map[string]bool{"device-repository": true, "permissions-v2": false, "converter": false, "done-wait": true}
+		if len(result.Dependencies) != len(expected) {
+			t.Error(result.Dependencies)
+		}
+		for _, dependency := range result.Dependencies {
+			if ok, known := expected[dependency.Name]; !known || ok != dependency.Ok {
+				t.Error(dependency)
+			}
+			if dependency.Name == "converter" && dependency.Required {
+				t.Error("converter should be optional", dependency)
+			}
+		}
+	})
+
+	t.Run("not ready", func(t *testing.T) {
+		code, result := get(t, "/health/ready")
+		if code != http.StatusServiceUnavailable || result.Ready {
+			t.Error(code, result)
+		}
+	})
+
+	t.Run("ready with optional converter down", func(t *testing.T) {
+		permissionsDown.Store(false)
+		code, result := get(t, "/health/ready")
+		if code != http.StatusOK || !result.Ready {
+			t.Error(code, result)
+		}
+	})
+}
@@ -0,0 +1,224 @@
+/*
+ * Copyright 2024 InfAI (CC SES)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package tests
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"github.com/SENERGY-Platform/device-manager/lib/api"
+	"github.com/SENERGY-Platform/device-manager/lib/auth"
+	"github.com/SENERGY-Platform/device-manager/lib/bus"
+	"github.com/SENERGY-Platform/device-manager/lib/config"
+	"github.com/SENERGY-Platform/device-manager/lib/controller"
+	"github.com/SENERGY-Platform/device-manager/lib/tests/helper"
+	gojwt "github.com/golang-jwt/jwt"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+const testKeyFile = "./resources/jwt_test_key.pem"
+
+func TestJwtVerification(t *testing.T) {
+	conf, err := config.Load("./../../config.json")
+	if err != nil {
+		t.Fatal("ERROR: unable to load config", err)
+	}
+	conf.BusBackend = bus.BackendChannel
+	conf.HandleDoneWait = false
+	conf.JwtPublicKeyFile = testKeyFile
+	conf.JwtIssuer = "test-issuer"
+
+	repo := httptest.NewServer(http.NotFoundHandler())
+	defer repo.Close()
+	conf.DeviceRepoUrl = repo.URL
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	ctrl, err := controller.New(ctx, conf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	router, err := api.NewRouter(conf, ctrl)
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := httptest.NewServer(router)
+	defer server.Close()
+
+	key, err := auth.LoadPemPrivateKey(testKeyFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	get := func(token string) int {
+		resp, err := helper.Jwtget(token, server.URL+"/devices/unknown")
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		return resp.StatusCode
+	}
+
+	t.Run("signed", func(t *testing.T) {
+		token, err := auth.CreateSignedTokenWithRoles("test-issuer", "user1", []string{"user"}, key, "")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if code := get(token.Token); code != http.StatusNotFound {
+			t.Error(code)
+		}
+	})
+
+	t.Run("unsigned", func(t *testing.T) {
+		token, err := auth.CreateTokenWithRoles("test-issuer", "user1", []string{"user"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if code := get(token.Token); code != http.StatusUnauthorized {
+			t.Error(code)
+		}
+	})
+
+	t.Run("other key", func(t *testing.T) {
+		if code := get(userjwt); code != http.StatusUnauthorized {
+			t.Error(code)
+		}
+	})
+
+	t.Run("wrong issuer", func(t *testing.T) {
+		token, err := auth.CreateSignedTokenWithRoles("other-issuer", "user1", []string{"user"}, key, "")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if code := get(token.Token); code != http.StatusUnauthorized {
+			t.Error(code)
+		}
+	})
+
+	t.Run("no token", func(t *testing.T) {
+		resp, err := http.Get(server.URL + "/health/live")
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			t.Error(resp.StatusCode)
+		}
+	})
+}
+
+func TestJwtLeeway(t *testing.T) {
+	key, err := auth.LoadPemPrivateKey(testKeyFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	verifier, err := auth.NewVerifier(config.Config{JwtPublicKeyFile: testKeyFile, JwtLeeway: "1m", JwtAudience: []string{"frontend"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	sign := func(claims gojwt.MapClaims) string {
+		token, err := gojwt.NewWithClaims(gojwt.SigningMethodRS256, claims).SignedString(key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return token
+	}
+	if err := verifier.Verify(sign(gojwt.MapClaims{"exp": time.Now().Add(-30 * time.Second).Unix(), "aud": "frontend"})); err != nil {
+		t.Error("expired within leeway", err)
+	}
+	if err := verifier.Verify(sign(gojwt.MapClaims{"exp": time.Now().Add(-2 * time.Minute).Unix(), "aud": "frontend"})); err == nil {
+		t.Error("expected expired token to be rejected")
+	}
+	if err := verifier.Verify(sign(gojwt.MapClaims{"exp": time.Now().Add(time.Minute).Unix(), "aud": []string{"account", "frontend"}})); err != nil {
+		t.Error("audience list", err)
+	}
+	if err := verifier.Verify(sign(gojwt.MapClaims{"exp": time.Now().Add(time.Minute).Unix(), "aud": "account"})); err == nil {
+		t.Error("expected wrong audience to be rejected")
+	}
+}
+
+func TestJwksRotation(t *testing.T) {
+	oldKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	newKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	jwk := func(kid string, key *rsa.PrivateKey) map[string]string {
+		return map[string]string{
+			"kid": kid,
+			"kty": "RSA",
+			"use": "sig",
+			"n":   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+			"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+		}
+	}
+	rotated := atomic.Bool{}
+	fetches := atomic.Int64{}
+	jwks := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		fetches.Add(1)
+		keys := []map[string]string{jwk("old", oldKey)}
+		if rotated.Load() {
+			keys = []map[string]string{jwk("new", newKey)}
+		}
+		json.NewEncoder(writer).Encode(map[string]interface{}{"keys": keys})
+	}))
+	defer jwks.Close()
+
+	minRefresh := auth.JwksMinRefreshInterval
+	auth.JwksMinRefreshInterval = 0
+	defer func() { auth.JwksMinRefreshInterval = minRefresh }()
+
+	verifier, err := auth.NewVerifier(config.Config{JwksUrl: jwks.URL})
+	if err != nil {
+		t.Fatal(err)
+	}
+	oldToken, err := auth.CreateSignedTokenWithRoles("", "user1", nil, oldKey, "old")
+	if err != nil {
+		t.Fatal(err)
+	}
+	newToken, err := auth.CreateSignedTokenWithRoles("", "user1", nil, newKey, "new")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = verifier.Verify(oldToken.Token); err != nil {
+		t.Error(err)
+	}
+	if err = verifier.Verify(oldToken.Token); err != nil || fetches.Load() != 1 {
+		t.Error("expected cached keys", err, fetches.Load())
+	}
+	if err = verifier.Verify(newToken.Token); err == nil {
+		t.Error("expected unknown key to be rejected")
+	}
+	rotated.Store(true)
+	if err = verifier.Verify(newToken.Token); err != nil {
+		t.Error("expected refresh on unknown key id", err)
+	}
+	if err = verifier.Verify(oldToken.Token); err == nil {
+		t.Error("expected removed key to be rejected")
+	}
+}
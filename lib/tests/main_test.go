@@ -21,9 +21,11 @@ import (
 	"github.com/SENERGY-Platform/device-manager/lib/api"
 	"github.com/SENERGY-Platform/device-manager/lib/config"
 	"github.com/SENERGY-Platform/device-manager/lib/controller"
+	"github.com/SENERGY-Platform/device-manager/lib/fakes"
 	"github.com/SENERGY-Platform/device-manager/lib/tests/docker"
 	"github.com/SENERGY-Platform/device-manager/lib/tests/helper"
 	"github.com/SENERGY-Platform/models/go/models"
+	"net/http/httptest"
 	"strconv"
 	"sync"
 	"testing"
@@ -95,6 +97,42 @@ func TestWithDocker(t *testing.T) {
 	tests(t, conf, false)
 }
 
+func TestWithFakes(t *testing.T) {
+	conf, err := config.Load("./../../config.json")
+	if err != nil {
+		t.Fatal("ERROR: unable to load config", err)
+	}
+
+	port, err := helper.GetFreePort()
+	if err != nil {
+		t.Fatal(err)
+	}
+	conf.ServerPort = strconv.Itoa(port)
+
+	f, err := fakes.New(conf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	repo := httptest.NewServer(f.RepositoryHandler())
+	defer repo.Close()
+	f.Config.DeviceRepoUrl = repo.URL
+
+	ctrl, err := f.Controller()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	srv, err := api.Start(f.Config, ctrl)
+	if err != nil {
+		t.Fatal("ERROR: unable to start api", err)
+	}
+	defer srv.Shutdown(context.Background())
+
+	time.Sleep(200 * time.Millisecond)
+
+	tests(t, f.Config, true)
+}
+
 const a1Id = models.URN_PREFIX + "aspect:a1"
 const f1Id = models.URN_PREFIX + "controlling-function:f1"
 
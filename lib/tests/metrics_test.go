@@ -0,0 +1,86 @@
+/*
+ * Copyright 2024 InfAI (CC SES)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package tests
+
+import (
+	"context"
+	"github.com/SENERGY-Platform/device-manager/lib/api"
+	"github.com/SENERGY-Platform/device-manager/lib/bus"
+	"github.com/SENERGY-Platform/device-manager/lib/config"
+	"github.com/SENERGY-Platform/device-manager/lib/controller"
+	"github.com/SENERGY-Platform/device-manager/lib/kafka/publisher"
+	"github.com/SENERGY-Platform/device-manager/lib/tests/helper"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestMetrics(t *testing.T) {
+	conf, err := config.Load("./../../config.json")
+	if err != nil {
+		t.Fatal("ERROR: unable to load config", err)
+	}
+	conf.BusBackend = bus.BackendChannel
+	conf.HandleDoneWait = false
+
+	repo := httptest.NewServer(http.NotFoundHandler())
+	defer repo.Close()
+	conf.DeviceRepoUrl = repo.URL
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	ctrl, err := controller.New(ctx, conf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := httptest.NewServer(api.GetRouter(conf, ctrl))
+	defer server.Close()
+
+	resp, err := helper.Jwtget(userjwt, server.URL+"/devices/unknown")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+
+	err = publisher.NewWithBus(conf, bus.NewChannel()).PublishDeviceDelete("unknown", "owner")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	resp, err = http.Get(server.URL + "/metrics")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	temp, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	text := string(temp)
+	for _, expected := range []string{
+		`device_manager_http_requests_total{method="GET",route="GET /devices/{id}",status="404"}`,
+		`device_manager_com_duration_seconds_count{method="GetDevice",outcome="error"}`,
+		`device_manager_publish_duration_seconds_count{topic="devices"}`,
+	} {
+		if !strings.Contains(text, expected) {
+			t.Error("missing", expected)
+		}
+	}
+}
@@ -0,0 +1,357 @@
+/*
+ * Copyright 2024 InfAI (CC SES)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package tests
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/SENERGY-Platform/device-manager/lib/api"
+	"github.com/SENERGY-Platform/device-manager/lib/auth"
+	"github.com/SENERGY-Platform/device-manager/lib/config"
+	"github.com/SENERGY-Platform/device-manager/lib/fakes"
+	"github.com/SENERGY-Platform/device-manager/lib/model"
+	"github.com/SENERGY-Platform/device-manager/lib/tests/helper"
+	"github.com/SENERGY-Platform/models/go/models"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestDeviceTypeMigration(t *testing.T) {
+	conf, err := config.Load("./../../config.json")
+	if err != nil {
+		t.Fatal("ERROR: unable to load config", err)
+	}
+	conf.MigrationJobFile = filepath.Join(t.TempDir(), "migrations.json")
+	f, err := fakes.New(conf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctrl, err := f.Controller()
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := httptest.NewServer(api.GetRouter(f.Config, ctrl))
+	defer server.Close()
+
+	admin, err := auth.CreateTokenWithRoles("test", "admin", []string{"admin"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = f.Publisher.PublishProtocol(models.Protocol{Id: "p1", Name: "p1", Handler: "p1"}, "admin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	deviceType := func(name string, localIds ...string) models.DeviceType {
+		dt := models.DeviceType{Name: name}
+		for _, localId := range localIds {
+			dt.Services = append(dt.Services, models.Service{LocalId: localId, Name: localId, Interaction: models.REQUEST, ProtocolId: "p1"})
+		}
+		result, err, _ := ctrl.PublishDeviceTypeCreate(admin, dt, model.DeviceTypeUpdateOptions{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return result
+	}
+	source := deviceType("source", "get", "set")
+	target := deviceType("target", "get", "set", "toggle")
+	incompatible := deviceType("incompatible", "get")
+	for i := 0; i < 3; i++ {
+		id := "d" + strconv.Itoa(i)
+		err = f.Publisher.PublishDevice(models.Device{Id: id, Name: id, LocalId: id, DeviceTypeId: source.Id, OwnerId: "admin"}, "admin")
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	migrate := func(request model.DeviceTypeMigrationRequest) (result model.DeviceTypeMigrationProgress, code int) {
+		resp, err := helper.Jwtpost(admin.Token, server.URL+"/device-types/"+source.Id+"/migrate", request)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			return result, resp.StatusCode
+		}
+		err = json.NewDecoder(resp.Body).Decode(&result)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return result, resp.StatusCode
+	}
+
+	t.Run("missing services", func(t *testing.T) {
+		if _, code := migrate(model.DeviceTypeMigrationRequest{TargetDeviceTypeId: incompatible.Id}); code != http.StatusBadRequest {
+			t.Error("expected 400 for missing service local ids, got", code)
+		}
+		if _, code := migrate(model.DeviceTypeMigrationRequest{TargetDeviceTypeId: source.Id}); code != http.StatusBadRequest {
+			t.Error("expected 400 for source as target, got", code)
+		}
+	})
+
+	t.Run("dry run", func(t *testing.T) {
+		result, code := migrate(model.DeviceTypeMigrationRequest{TargetDeviceTypeId: target.Id, DryRun: true})
+		if code != http.StatusOK || result.Listed != 3 || result.Migrated != 0 || !result.Done {
+			t.Fatalf("%v %#v", code, result)
+		}
+		if device, _, _ := ctrl.ReadDevice(admin, "d0"); device.DeviceTypeId != source.Id {
+			t.Errorf("%#v", device)
+		}
+	})
+
+	t.Run("disabled", func(t *testing.T) {
+		disabledConf := conf
+		disabledConf.MigrationJobFile = ""
+		disabled, err := fakes.New(disabledConf)
+		if err != nil {
+			t.Fatal(err)
+		}
+		disabledCtrl, err := disabled.Controller()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err, code := disabledCtrl.StartDeviceTypeMigration(admin, source.Id, model.DeviceTypeMigrationRequest{TargetDeviceTypeId: target.Id}); code != http.StatusNotFound {
+			t.Error("expected 404 without migration_job_file", err, code)
+		}
+	})
+
+	t.Run("invalid device", func(t *testing.T) {
+		invalidSource := deviceType("invalid-source", "get")
+		//the device-repository rejects devices without local id
+		err = f.Publisher.PublishDevice(models.Device{Id: "invalid", Name: "invalid", DeviceTypeId: invalidSource.Id, OwnerId: "admin"}, "admin")
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = f.Publisher.PublishDevice(models.Device{Id: "valid", Name: "valid", LocalId: "valid", DeviceTypeId: invalidSource.Id, OwnerId: "admin"}, "admin")
+		if err != nil {
+			t.Fatal(err)
+		}
+		result, err, _ := ctrl.MigrateDeviceType(admin, invalidSource.Id, model.DeviceTypeMigrationRequest{TargetDeviceTypeId: target.Id, DeleteSource: true}, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if result.Listed != 2 || result.Migrated != 1 || len(result.Failed) != 1 || result.Failed[0] != "invalid" || result.SourceDeleted || result.SourceDeleteError == "" {
+			t.Fatalf("%#v", result)
+		}
+		if device, _, _ := ctrl.ReadDevice(admin, "invalid"); device.DeviceTypeId != invalidSource.Id {
+			t.Errorf("%#v", device)
+		}
+		if _, err, _ := ctrl.ReadDeviceType(admin, invalidSource.Id); err != nil {
+			t.Error("expected kept source device-type", err)
+		}
+	})
+
+	t.Run("job", func(t *testing.T) {
+		jobSource := deviceType("job-source", "get")
+		err = f.Publisher.PublishDevice(models.Device{Id: "job-device", Name: "job-device", LocalId: "job-device", DeviceTypeId: jobSource.Id, OwnerId: "admin"}, "admin")
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp, err := helper.Jwtpost(admin.Token, server.URL+"/device-types/"+jobSource.Id+"/migrate", model.DeviceTypeMigrationRequest{TargetDeviceTypeId: target.Id})
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode != http.StatusAccepted {
+			t.Fatal(resp.StatusCode)
+		}
+		job := model.DeviceTypeMigrationJob{}
+		err = json.NewDecoder(resp.Body).Decode(&job)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if job.Id == "" || job.CreatedBy != admin.GetUserId() || job.Progress.Done {
+			t.Fatalf("%#v", job)
+		}
+		deadline := time.Now().Add(10 * time.Second)
+		for !job.Progress.Done && time.Now().Before(deadline) {
+			time.Sleep(50 * time.Millisecond)
+			resp, err := helper.Jwtget(admin.Token, server.URL+"/device-types/"+jobSource.Id+"/migrations/"+job.Id)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if resp.StatusCode != http.StatusOK {
+				resp.Body.Close()
+				t.Fatal(resp.StatusCode)
+			}
+			err = json.NewDecoder(resp.Body).Decode(&job)
+			resp.Body.Close()
+			if err != nil {
+				t.Fatal(err)
+			}
+		}
+		if !job.Progress.Done || job.Progress.Listed != 1 || job.Progress.Migrated != 1 || job.Error != "" {
+			t.Fatalf("%#v", job)
+		}
+		if device, _, _ := ctrl.ReadDevice(admin, "job-device"); device.DeviceTypeId != target.Id {
+			t.Errorf("%#v", device)
+		}
+		user, err := auth.CreateToken("test", "user1")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err, code := ctrl.ReadDeviceTypeMigration(user, jobSource.Id, job.Id); code != http.StatusNotFound {
+			t.Error("expected 404 for other users", err, code)
+		}
+		if _, err, code := ctrl.ReadDeviceTypeMigration(admin, source.Id, job.Id); code != http.StatusNotFound {
+			t.Error("expected 404 for other device-types", err, code)
+		}
+	})
+
+	t.Run("migrate", func(t *testing.T) {
+		progress := []model.DeviceTypeMigrationProgress{}
+		result, err, _ := ctrl.MigrateDeviceType(admin, source.Id, model.DeviceTypeMigrationRequest{TargetDeviceTypeId: target.Id, DeleteSource: true, BatchSize: 2}, func(p model.DeviceTypeMigrationProgress) {
+			progress = append(progress, p)
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if result.Listed != 3 || result.Migrated != 3 || len(result.Failed) != 0 || !result.SourceDeleted || !result.Done {
+			t.Fatalf("%#v", result)
+		}
+		if len(progress) != 3 || progress[0].Migrated != 2 {
+			t.Errorf("%#v", progress)
+		}
+		for i := 0; i < 3; i++ {
+			device, err, _ := ctrl.ReadDevice(admin, "d"+strconv.Itoa(i))
+			if err != nil || device.DeviceTypeId != target.Id || device.OwnerId != "admin" {
+				t.Errorf("%#v %v", device, err)
+			}
+		}
+		if _, err, code := ctrl.ReadDeviceType(admin, source.Id); code != http.StatusNotFound {
+			t.Error("expected deleted source device-type", err, code)
+		}
+	})
+
+	t.Run("equal names", func(t *testing.T) {
+		//equal names, so that pages ordered by name would not be unique
+		namesSource := deviceType("names-source", "get")
+		for i := 0; i < 7; i++ {
+			id := "same-" + strconv.Itoa(i)
+			err = f.Publisher.PublishDevice(models.Device{Id: id, Name: "same", LocalId: id, DeviceTypeId: namesSource.Id, OwnerId: "admin"}, "admin")
+			if err != nil {
+				t.Fatal(err)
+			}
+		}
+		result, err, _ := ctrl.MigrateDeviceType(admin, namesSource.Id, model.DeviceTypeMigrationRequest{TargetDeviceTypeId: target.Id, BatchSize: 2}, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if result.Listed != 7 || result.Migrated != 7 || len(result.Failed) != 0 {
+			t.Fatalf("%#v", result)
+		}
+		for i := 0; i < 7; i++ {
+			if device, _, _ := ctrl.ReadDevice(admin, "same-"+strconv.Itoa(i)); device.DeviceTypeId != target.Id {
+				t.Errorf("%#v", device)
+			}
+		}
+	})
+
+	t.Run("non admin", func(t *testing.T) {
+		user, err := auth.CreateTokenWithRoles("test", "user1", []string{"user"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		userSource := deviceType("user-source", "get")
+		err = f.Publisher.PublishDevice(models.Device{Id: "user-device", Name: "user-device", LocalId: "user-device", DeviceTypeId: userSource.Id, OwnerId: "user1"}, "user1")
+		if err != nil {
+			t.Fatal(err)
+		}
+		grant := func(id string, rights model.PermissionsMap) {
+			resp, err := helper.Jwtput(admin.Token, server.URL+"/device-types/"+id+"/permissions/users/user1", rights)
+			if err != nil {
+				t.Fatal(err)
+			}
+			resp.Body.Close()
+			if resp.StatusCode != http.StatusOK {
+				t.Fatal(resp.StatusCode)
+			}
+		}
+		grant(userSource.Id, model.PermissionsMap{Read: true, Write: true})
+		grant(target.Id, model.PermissionsMap{Read: true})
+
+		request := model.DeviceTypeMigrationRequest{TargetDeviceTypeId: target.Id, DeleteSource: true}
+		if _, err, code := ctrl.StartDeviceTypeMigration(user, userSource.Id, request); code != http.StatusForbidden {
+			t.Fatal("expected 403 before the job starts, if the source may not be deleted", err, code)
+		}
+
+		grant(userSource.Id, model.PermissionsMap{Read: true, Write: true, Administrate: true})
+		job, err, _ := ctrl.StartDeviceTypeMigration(user, userSource.Id, request)
+		if err != nil {
+			t.Fatal(err)
+		}
+		deadline := time.Now().Add(10 * time.Second)
+		for !job.Progress.Done && time.Now().Before(deadline) {
+			time.Sleep(50 * time.Millisecond)
+			job, err, _ = ctrl.ReadDeviceTypeMigration(user, userSource.Id, job.Id)
+			if err != nil {
+				t.Fatal(err)
+			}
+		}
+		if !job.Progress.Done || job.Progress.Migrated != 1 || !job.Progress.SourceDeleted || job.Error != "" {
+			t.Fatalf("%#v", job)
+		}
+		if device, _, _ := ctrl.ReadDevice(admin, "user-device"); device.DeviceTypeId != target.Id || device.OwnerId != "user1" {
+			t.Errorf("%#v", device)
+		}
+	})
+
+	t.Run("aborted", func(t *testing.T) {
+		abortSource := deviceType("abort-source", "get")
+		for i := 0; i < 3; i++ {
+			id := "abort-" + strconv.Itoa(i)
+			err = f.Publisher.PublishDevice(models.Device{Id: id, Name: id, LocalId: id, DeviceTypeId: abortSource.Id, OwnerId: "admin"}, "admin")
+			if err != nil {
+				t.Fatal(err)
+			}
+		}
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		//the fake publisher delivers synchronously, so that the job is cancelled while its first batch is published
+		err = f.Publisher.Subscribe(ctx, conf.DeviceTopic, "abort", func(context.Context, string, []byte) error {
+			cancel()
+			return nil
+		}, func(err error) {})
+		if err != nil {
+			t.Fatal(err)
+		}
+		job, err, _ := ctrl.WithContext(ctx).StartDeviceTypeMigration(admin, abortSource.Id, model.DeviceTypeMigrationRequest{TargetDeviceTypeId: target.Id, DeleteSource: true, BatchSize: 1})
+		if err != nil {
+			t.Fatal(err)
+		}
+		deadline := time.Now().Add(10 * time.Second)
+		for !job.Progress.Done && time.Now().Before(deadline) {
+			time.Sleep(50 * time.Millisecond)
+			job, err, _ = ctrl.ReadDeviceTypeMigration(admin, abortSource.Id, job.Id)
+			if err != nil {
+				t.Fatal(err)
+			}
+		}
+		if !job.Progress.Done || job.Error == "" || job.Progress.Listed != 3 || job.Progress.Migrated != 1 || job.Progress.SourceDeleted {
+			t.Fatalf("%#v", job)
+		}
+		if device, _, _ := ctrl.ReadDevice(admin, "abort-2"); device.DeviceTypeId != abortSource.Id {
+			t.Errorf("%#v", device)
+		}
+		if _, err, _ := ctrl.ReadDeviceType(admin, abortSource.Id); err != nil {
+			t.Error("expected kept source device-type", err)
+		}
+	})
+}
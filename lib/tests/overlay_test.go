@@ -0,0 +1,132 @@
+/*
+ * Copyright 2024 InfAI (CC SES)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package tests
+
+import (
+	"github.com/SENERGY-Platform/device-manager/lib/overlay"
+	"github.com/SENERGY-Platform/models/go/models"
+	"github.com/SENERGY-Platform/service-commons/pkg/donewait"
+	"testing"
+	"time"
+)
+
+func TestReadYourWritesOverlay(t *testing.T) {
+	handler := "github.com/SENERGY-Platform/device-repository"
+	o := overlay.New(200*time.Millisecond, []string{handler})
+
+	t.Run("read own write", func(t *testing.T) {
+		o.Put("user1", "devices", "d1", models.Device{Id: "d1", Name: "d1"})
+		device, found, deleted := overlay.Get[models.Device](o, "user1", "devices", "d1")
+		if !found || deleted || device.Name != "d1" {
+			t.Error(found, deleted, device)
+		}
+	})
+
+	t.Run("ignore write of other user", func(t *testing.T) {
+		_, found, _ := overlay.Get[models.Device](o, "user2", "devices", "d1")
+		if found {
+			t.Error("unexpected overlay entry for other user")
+		}
+	})
+
+	t.Run("ignore done of other handler", func(t *testing.T) {
+		o.HandleDone(donewait.SerializeDoneMsg(donewait.DoneMsg{ResourceKind: "devices", ResourceId: "d1", Command: "PUT", Handler: "other"}))
+		_, found, _ := overlay.Get[models.Device](o, "user1", "devices", "d1")
+		if !found {
+			t.Error("expected overlay entry")
+		}
+	})
+
+	t.Run("remove on done", func(t *testing.T) {
+		o.HandleDone(donewait.SerializeDoneMsg(donewait.DoneMsg{ResourceKind: "devices", ResourceId: "d1", Command: "PUT", Handler: handler}))
+		_, found, _ := overlay.Get[models.Device](o, "user1", "devices", "d1")
+		if found {
+			t.Error("unexpected overlay entry after done")
+		}
+	})
+
+	t.Run("done of earlier write keeps later write", func(t *testing.T) {
+		o.Put("user1", "devices", "d5", models.Device{Id: "d5", Name: "v1"})
+		o.Put("user1", "devices", "d5", models.Device{Id: "d5", Name: "v2"})
+		o.HandleDone(donewait.SerializeDoneMsg(donewait.DoneMsg{ResourceKind: "devices", ResourceId: "d5", Command: "PUT", Handler: handler}))
+		device, found, _ := overlay.Get[models.Device](o, "user1", "devices", "d5")
+		if !found || device.Name != "v2" {
+			t.Error(found, device)
+		}
+		o.HandleDone(donewait.SerializeDoneMsg(donewait.DoneMsg{ResourceKind: "devices", ResourceId: "d5", Command: "PUT", Handler: handler}))
+		_, found, _ = overlay.Get[models.Device](o, "user1", "devices", "d5")
+		if found {
+			t.Error("unexpected overlay entry after done of the later write")
+		}
+	})
+
+	t.Run("failed earlier write is not awaited", func(t *testing.T) {
+		undo := o.Put("user1", "devices", "d6", models.Device{Id: "d6", Name: "v1"})
+		o.Put("user1", "devices", "d6", models.Device{Id: "d6", Name: "v2"})
+		undo()
+		o.HandleDone(donewait.SerializeDoneMsg(donewait.DoneMsg{ResourceKind: "devices", ResourceId: "d6", Command: "PUT", Handler: handler}))
+		_, found, _ := overlay.Get[models.Device](o, "user1", "devices", "d6")
+		if found {
+			t.Error("unexpected overlay entry after done of the later write")
+		}
+	})
+
+	t.Run("delete", func(t *testing.T) {
+		o.Delete("user1", "devices", "d2")
+		_, found, deleted := overlay.Get[models.Device](o, "user1", "devices", "d2")
+		if !found || !deleted {
+			t.Error(found, deleted)
+		}
+	})
+
+	t.Run("ttl", func(t *testing.T) {
+		time.Sleep(250 * time.Millisecond)
+		_, found, _ := overlay.Get[models.Device](o, "user1", "devices", "d2")
+		if found {
+			t.Error("unexpected overlay entry after ttl")
+		}
+	})
+
+	t.Run("undo restores previous entry", func(t *testing.T) {
+		o.Put("user1", "devices", "d3", models.Device{Id: "d3", Name: "v1"})
+		undo := o.Put("user1", "devices", "d3", models.Device{Id: "d3", Name: "v2"})
+		undo()
+		device, found, _ := overlay.Get[models.Device](o, "user1", "devices", "d3")
+		if !found || device.Name != "v1" {
+			t.Error(found, device)
+		}
+	})
+
+	t.Run("undo keeps later write", func(t *testing.T) {
+		undo := o.Delete("user1", "devices", "d3")
+		o.Put("user1", "devices", "d3", models.Device{Id: "d3", Name: "v3"})
+		undo()
+		device, found, deleted := overlay.Get[models.Device](o, "user1", "devices", "d3")
+		if !found || deleted || device.Name != "v3" {
+			t.Error(found, deleted, device)
+		}
+	})
+
+	t.Run("undo without previous entry", func(t *testing.T) {
+		undo := o.Put("user1", "devices", "d4", models.Device{Id: "d4", Name: "d4"})
+		undo()
+		_, found, _ := overlay.Get[models.Device](o, "user1", "devices", "d4")
+		if found {
+			t.Error("unexpected overlay entry after undo")
+		}
+	})
+}
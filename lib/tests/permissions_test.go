@@ -0,0 +1,151 @@
+/*
+ * Copyright 2024 InfAI (CC SES)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package tests
+
+import (
+	"encoding/json"
+	"github.com/SENERGY-Platform/device-manager/lib/api"
+	"github.com/SENERGY-Platform/device-manager/lib/auth"
+	"github.com/SENERGY-Platform/device-manager/lib/config"
+	"github.com/SENERGY-Platform/device-manager/lib/fakes"
+	"github.com/SENERGY-Platform/device-manager/lib/model"
+	"github.com/SENERGY-Platform/device-manager/lib/tests/helper"
+	"github.com/SENERGY-Platform/models/go/models"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestPermissionManagement(t *testing.T) {
+	conf, err := config.Load("./../../config.json")
+	if err != nil {
+		t.Fatal("ERROR: unable to load config", err)
+	}
+	f, err := fakes.New(conf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctrl, err := f.Controller()
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := httptest.NewServer(api.GetRouter(f.Config, ctrl))
+	defer server.Close()
+
+	owner, err := auth.CreateTokenWithRoles("test", "owner", []string{"user"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	other, err := auth.CreateTokenWithRoles("test", "other", []string{"user"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = f.Publisher.PublishLocation(models.Location{Id: "l1", Name: "l1"}, "owner")
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = f.Publisher.PublishHub(models.Hub{Id: "h1", Name: "h1", OwnerId: "owner"}, "owner")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	//code is 0 for request and decoding errors
+	read := func(resp *http.Response, err error) (result model.ResourcePermissions, code int) {
+		if err != nil {
+			return result, 0
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode == http.StatusOK {
+			err = json.NewDecoder(resp.Body).Decode(&result)
+			if err != nil {
+				return result, 0
+			}
+		}
+		return result, resp.StatusCode
+	}
+
+	t.Run("get", func(t *testing.T) {
+		result, code := read(helper.Jwtget(owner.Token, server.URL+"/locations/l1/permissions"))
+		if code != http.StatusOK || !result.UserPermissions["owner"].Administrate {
+			t.Errorf("%v %#v", code, result)
+		}
+		_, code = read(helper.Jwtget(other.Token, server.URL+"/locations/l1/permissions"))
+		if code != http.StatusForbidden {
+			t.Error("expected 403 without administrate rights, got", code)
+		}
+	})
+
+	t.Run("add user and group", func(t *testing.T) {
+		_, code := read(helper.Jwtput(owner.Token, server.URL+"/locations/l1/permissions/users/other", model.PermissionsMap{Read: true}))
+		if code != http.StatusOK {
+			t.Fatal(code)
+		}
+		result, code := read(helper.Jwtput(owner.Token, server.URL+"/locations/l1/permissions/groups/g1", model.PermissionsMap{Read: true, Execute: true}))
+		if code != http.StatusOK {
+			t.Fatal(code)
+		}
+		if !result.UserPermissions["other"].Read || !result.UserPermissions["owner"].Administrate || !result.GroupPermissions["g1"].Execute {
+			t.Errorf("%#v", result)
+		}
+	})
+
+	t.Run("remove user", func(t *testing.T) {
+		result, code := read(helper.Jwtdelete(owner.Token, server.URL+"/locations/l1/permissions/users/other"))
+		if code != http.StatusOK {
+			t.Fatal(code)
+		}
+		if _, ok := result.UserPermissions["other"]; ok || !result.GroupPermissions["g1"].Read {
+			t.Errorf("%#v", result)
+		}
+	})
+
+	t.Run("last admin", func(t *testing.T) {
+		_, code := read(helper.Jwtdelete(owner.Token, server.URL+"/locations/l1/permissions/users/owner"))
+		if code != http.StatusBadRequest {
+			t.Error("expected 400 for removal of the last admin, got", code)
+		}
+		_, code = read(helper.Jwtput(owner.Token, server.URL+"/locations/l1/permissions", model.ResourcePermissions{
+			UserPermissions: map[string]model.PermissionsMap{"owner": {Read: true}},
+		}))
+		if code != http.StatusBadRequest {
+			t.Error("expected 400 for permissions without admin, got", code)
+		}
+	})
+
+	t.Run("hub owner", func(t *testing.T) {
+		_, code := read(helper.Jwtput(owner.Token, server.URL+"/hubs/h1/permissions", model.ResourcePermissions{
+			UserPermissions: map[string]model.PermissionsMap{"other": {Read: true, Write: true, Execute: true, Administrate: true}},
+		}))
+		if code != http.StatusBadRequest {
+			t.Error("expected 400 if the owner loses administrate rights, got", code)
+		}
+		result, code := read(helper.Jwtput(owner.Token, server.URL+"/hubs/h1/permissions", model.ResourcePermissions{
+			UserPermissions: map[string]model.PermissionsMap{
+				"owner": {Read: true, Write: true, Execute: true, Administrate: true},
+				"other": {Read: true, Write: true, Execute: true, Administrate: true},
+			},
+		}))
+		if code != http.StatusOK || !result.UserPermissions["other"].Administrate {
+			t.Errorf("%v %#v", code, result)
+		}
+		_, code = read(helper.Jwtdelete(other.Token, server.URL+"/hubs/h1/permissions/users/owner"))
+		if code != http.StatusBadRequest {
+			t.Error("expected 400 for removal of the owner, got", code)
+		}
+	})
+}
@@ -0,0 +1,122 @@
+/*
+ * Copyright 2024 InfAI (CC SES)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package tests
+
+import (
+	"context"
+	"github.com/SENERGY-Platform/device-manager/lib/api"
+	"github.com/SENERGY-Platform/device-manager/lib/auth"
+	"github.com/SENERGY-Platform/device-manager/lib/bus"
+	"github.com/SENERGY-Platform/device-manager/lib/config"
+	"github.com/SENERGY-Platform/device-manager/lib/controller"
+	"github.com/SENERGY-Platform/device-manager/lib/tests/helper"
+	"github.com/SENERGY-Platform/models/go/models"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestPolicy(t *testing.T) {
+	policyFile := filepath.Join(t.TempDir(), "policy.json")
+	err := os.WriteFile(policyFile, []byte(`{"default": ["*"], "kinds": {"concepts": {"create": ["concept-editor"]}}}`), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	conf, err := config.Load("./../../config.json")
+	if err != nil {
+		t.Fatal("ERROR: unable to load config", err)
+	}
+	conf.BusBackend = bus.BackendChannel
+	conf.HandleDoneWait = false
+	conf.DisableValidation = true
+	conf.PolicyFile = policyFile
+	conf.PolicyReloadInterval = "50ms"
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	ctrl, err := controller.New(ctx, conf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := httptest.NewServer(api.GetRouter(conf, ctrl))
+	defer server.Close()
+
+	user, err := auth.CreateTokenWithRoles("test", "user1", []string{"user"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	editor, err := auth.CreateTokenWithRoles("test", "user2", []string{"user", "concept-editor"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	create := func(token auth.Token) int {
+		resp, err := helper.Jwtpost(token.Token, server.URL+"/concepts", models.Concept{Name: "c"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		return resp.StatusCode
+	}
+
+	t.Run("forbidden", func(t *testing.T) {
+		if code := create(user); code != http.StatusForbidden {
+			t.Error(code)
+		}
+	})
+
+	t.Run("allowed", func(t *testing.T) {
+		if code := create(editor); code != http.StatusOK {
+			t.Error(code)
+		}
+	})
+
+	t.Run("reload", func(t *testing.T) {
+		err := os.WriteFile(policyFile, []byte(`{"default": ["*"]}`), 0644)
+		if err != nil {
+			t.Fatal(err)
+		}
+		//explicit modification time, for file systems with coarse timestamps
+		err = os.Chtimes(policyFile, time.Now(), time.Now().Add(time.Second))
+		if err != nil {
+			t.Fatal(err)
+		}
+		time.Sleep(200 * time.Millisecond)
+		if code := create(user); code != http.StatusOK {
+			t.Error(code)
+		}
+	})
+
+	t.Run("invalid reload keeps policy", func(t *testing.T) {
+		err := os.WriteFile(policyFile, []byte(`{`), 0644)
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = os.Chtimes(policyFile, time.Now(), time.Now().Add(2*time.Second))
+		if err != nil {
+			t.Fatal(err)
+		}
+		time.Sleep(200 * time.Millisecond)
+		if code := create(user); code != http.StatusOK {
+			t.Error(code)
+		}
+	})
+}
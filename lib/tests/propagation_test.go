@@ -0,0 +1,184 @@
+/*
+ * Copyright 2024 InfAI (CC SES)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package tests
+
+import (
+	"github.com/SENERGY-Platform/device-manager/lib/api"
+	"github.com/SENERGY-Platform/device-manager/lib/auth"
+	"github.com/SENERGY-Platform/device-manager/lib/config"
+	"github.com/SENERGY-Platform/device-manager/lib/fakes"
+	"github.com/SENERGY-Platform/device-manager/lib/model"
+	"github.com/SENERGY-Platform/device-manager/lib/tests/helper"
+	"github.com/SENERGY-Platform/models/go/models"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+)
+
+func TestPropagateSharing(t *testing.T) {
+	conf, err := config.Load("./../../config.json")
+	if err != nil {
+		t.Fatal("ERROR: unable to load config", err)
+	}
+	disabled, err := fakes.New(conf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	disabledCtrl, err := disabled.Controller()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err, code := disabledCtrl.ReadPropagation(auth.Token{}, conf.LocationTopic, "l1"); err == nil || code != http.StatusNotFound {
+		t.Error("expected 404 without propagation_file, got", code, err)
+	}
+
+	conf.PropagationFile = filepath.Join(t.TempDir(), "propagation.json")
+	f, err := fakes.New(conf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctrl, err := f.Controller()
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := httptest.NewServer(api.GetRouter(f.Config, ctrl))
+	defer server.Close()
+
+	owner, err := auth.CreateTokenWithRoles("test", "owner", []string{"user"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, device := range []models.Device{
+		{Id: "d1", Name: "d1", LocalId: "d1", OwnerId: "owner"},
+		{Id: "d2", Name: "d2", LocalId: "d2", OwnerId: "owner"},
+		{Id: "foreign", Name: "foreign", LocalId: "foreign", OwnerId: "stranger"},
+	} {
+		err = f.Publisher.PublishDevice(device, device.OwnerId)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	//the owner may read the foreign device and add it to the location, but not administrate it
+	_, err, _ = ctrl.SetSubjectPermissions(auth.Token{Sub: "stranger"}, f.Config.DeviceTopic, "foreign", model.PermissionSubjectUsers, "owner", &model.PermissionsMap{Read: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	location := models.Location{Id: "l1", Name: "l1", DeviceIds: []string{"d1", "foreign"}}
+	err = f.Publisher.PublishLocation(location, "owner")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	status := func(resp *http.Response, err error) int {
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		return resp.StatusCode
+	}
+	viewer := func(deviceId string) (model.PermissionsMap, bool) {
+		permissions, _ := f.Store.Permissions(f.Config.DeviceTopic, deviceId)
+		result, ok := permissions.UserPermissions["viewer"]
+		return result, ok
+	}
+
+	t.Run("without propagation", func(t *testing.T) {
+		if code := status(helper.Jwtput(owner.Token, server.URL+"/locations/l1/permissions/users/viewer", model.PermissionsMap{Read: true})); code != http.StatusOK {
+			t.Fatal(code)
+		}
+		if _, ok := viewer("d1"); ok {
+			t.Error("unexpected propagation")
+		}
+	})
+
+	t.Run("enable", func(t *testing.T) {
+		if code := status(helper.Jwtput(owner.Token, server.URL+"/locations/l1/propagation", model.PropagationRequest{Enabled: true})); code != http.StatusOK {
+			t.Fatal(code)
+		}
+		if p, _ := viewer("d1"); !p.Read || p.Write || p.Administrate {
+			t.Errorf("%#v", p)
+		}
+		if p, ok := viewer("foreign"); ok {
+			t.Errorf("propagated to device without administrate rights: %#v", p)
+		}
+		if p, _ := f.Store.Permissions(f.Config.DeviceTopic, "d1"); !p.UserPermissions["owner"].Administrate {
+			t.Errorf("owner rights changed: %#v", p)
+		}
+	})
+
+	t.Run("changed rights", func(t *testing.T) {
+		if code := status(helper.Jwtput(owner.Token, server.URL+"/locations/l1/permissions/users/viewer", model.PermissionsMap{Read: true, Execute: true})); code != http.StatusOK {
+			t.Fatal(code)
+		}
+		if p, _ := viewer("d1"); !p.Read || !p.Execute {
+			t.Errorf("%#v", p)
+		}
+	})
+
+	t.Run("device added later", func(t *testing.T) {
+		location.DeviceIds = []string{"d1", "d2", "foreign"}
+		if code := status(helper.Jwtput(owner.Token, server.URL+"/locations/l1", location)); code != http.StatusOK {
+			t.Fatal(code)
+		}
+		if p, _ := viewer("d2"); !p.Read || !p.Execute {
+			t.Errorf("%#v", p)
+		}
+	})
+
+	t.Run("device removed", func(t *testing.T) {
+		//granted independent of the propagation, must be kept on revocation
+		_, err, _ = ctrl.SetSubjectPermissions(owner, f.Config.DeviceTopic, "d1", model.PermissionSubjectUsers, "viewer", &model.PermissionsMap{Read: true, Write: true, Execute: true})
+		if err != nil {
+			t.Fatal(err)
+		}
+		location.DeviceIds = []string{"d2"}
+		if code := status(helper.Jwtput(owner.Token, server.URL+"/locations/l1", location)); code != http.StatusOK {
+			t.Fatal(code)
+		}
+		if p, _ := viewer("d1"); p != (model.PermissionsMap{Write: true}) {
+			t.Errorf("expected only the propagated rights to be removed: %#v", p)
+		}
+		if _, ok := viewer("d2"); !ok {
+			t.Error("missing propagated rights")
+		}
+	})
+
+	t.Run("user removed", func(t *testing.T) {
+		if code := status(helper.Jwtdelete(owner.Token, server.URL+"/locations/l1/permissions/users/viewer")); code != http.StatusOK {
+			t.Fatal(code)
+		}
+		if p, ok := viewer("d2"); ok {
+			t.Errorf("not revoked: %#v", p)
+		}
+	})
+
+	t.Run("disable", func(t *testing.T) {
+		if code := status(helper.Jwtput(owner.Token, server.URL+"/locations/l1/permissions/users/viewer", model.PermissionsMap{Read: true})); code != http.StatusOK {
+			t.Fatal(code)
+		}
+		if _, ok := viewer("d2"); !ok {
+			t.Fatal("missing propagated rights")
+		}
+		if code := status(helper.Jwtput(owner.Token, server.URL+"/locations/l1/propagation", model.PropagationRequest{Enabled: false})); code != http.StatusOK {
+			t.Fatal(code)
+		}
+		if p, ok := viewer("d2"); ok {
+			t.Errorf("not revoked: %#v", p)
+		}
+	})
+}
@@ -0,0 +1,121 @@
+/*
+ * Copyright 2024 InfAI (CC SES)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package tests
+
+import (
+	"encoding/json"
+	"github.com/SENERGY-Platform/device-manager/lib/config"
+	"github.com/SENERGY-Platform/device-manager/lib/kafka/publisher"
+	"github.com/SENERGY-Platform/device-manager/lib/readmodel"
+	"github.com/SENERGY-Platform/models/go/models"
+	"testing"
+	"time"
+)
+
+func TestReadModel(t *testing.T) {
+	conf, err := config.Load("./../../config.json")
+	if err != nil {
+		t.Fatal("ERROR: unable to load config", err)
+	}
+	conf.ReadModelMaxStaleness = "200ms"
+
+	rm := readmodel.New(conf)
+
+	var offset int64 = 0
+	put := func(device models.Device) {
+		msg, err := json.Marshal(publisher.DeviceCommand{Command: "PUT", Id: device.Id, Owner: "owner", Device: device})
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = rm.Handle(conf.DeviceTopic, 0, offset, msg, time.Now())
+		if err != nil {
+			t.Fatal(err)
+		}
+		offset++
+	}
+
+	t.Run("stale before first high-water mark check", func(t *testing.T) {
+		put(models.Device{Id: "d1", Name: "d1"})
+		_, ok := readmodel.Get[models.Device](rm, conf.DeviceTopic, "d1")
+		if ok {
+			t.Error("expected stale read model")
+		}
+	})
+
+	t.Run("stale while consuming backlog", func(t *testing.T) {
+		rm.SetHighWaterMarks(conf.DeviceTopic, map[int]int64{0: 2})
+		_, ok := readmodel.Get[models.Device](rm, conf.DeviceTopic, "d1")
+		if ok {
+			t.Error("expected stale read model")
+		}
+	})
+
+	t.Run("fresh after backlog", func(t *testing.T) {
+		put(models.Device{Id: "d1", Name: "d1-updated"})
+		device, ok := readmodel.Get[models.Device](rm, conf.DeviceTopic, "d1")
+		if !ok || device.Name != "d1-updated" {
+			t.Error(ok, device)
+		}
+	})
+
+	t.Run("stale if the consumer stalls", func(t *testing.T) {
+		rm.SetHighWaterMarks(conf.DeviceTopic, map[int]int64{0: 3})
+		time.Sleep(250 * time.Millisecond)
+		rm.SetHighWaterMarks(conf.DeviceTopic, map[int]int64{0: 3})
+		_, ok := readmodel.Get[models.Device](rm, conf.DeviceTopic, "d1")
+		if ok {
+			t.Error("expected stale read model")
+		}
+		put(models.Device{Id: "d1", Name: "d1"})
+		if _, ok = readmodel.Get[models.Device](rm, conf.DeviceTopic, "d1"); !ok {
+			t.Error("expected fresh read model")
+		}
+	})
+
+	t.Run("stale without recent high-water mark check", func(t *testing.T) {
+		time.Sleep(250 * time.Millisecond)
+		_, ok := readmodel.Get[models.Device](rm, conf.DeviceTopic, "d1")
+		if ok {
+			t.Error("expected stale read model")
+		}
+		rm.SetHighWaterMarks(conf.DeviceTopic, map[int]int64{0: 3})
+	})
+
+	t.Run("delete", func(t *testing.T) {
+		msg, err := json.Marshal(publisher.DeviceCommand{Command: "DELETE", Id: "d1", Owner: "owner"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = rm.Handle(conf.DeviceTopic, 0, offset, msg, time.Now())
+		if err != nil {
+			t.Fatal(err)
+		}
+		offset++
+		_, ok := readmodel.Get[models.Device](rm, conf.DeviceTopic, "d1")
+		if ok {
+			t.Error("expected deleted device to be missing")
+		}
+	})
+
+	t.Run("status", func(t *testing.T) {
+		for _, status := range rm.Status() {
+			if status.Topic == conf.DeviceTopic && (!status.Fresh || status.Elements != 0 || status.Lag != 0) {
+				t.Errorf("%#v", status)
+			}
+		}
+	})
+}
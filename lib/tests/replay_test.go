@@ -0,0 +1,147 @@
+/*
+ * Copyright 2024 InfAI (CC SES)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package tests
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/SENERGY-Platform/device-manager/lib/api"
+	"github.com/SENERGY-Platform/device-manager/lib/auth"
+	"github.com/SENERGY-Platform/device-manager/lib/config"
+	"github.com/SENERGY-Platform/device-manager/lib/fakes"
+	"github.com/SENERGY-Platform/device-manager/lib/model"
+	"github.com/SENERGY-Platform/device-manager/lib/tests/helper"
+	"github.com/SENERGY-Platform/models/go/models"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strconv"
+	"testing"
+)
+
+func TestReplay(t *testing.T) {
+	conf, err := config.Load("./../../config.json")
+	if err != nil {
+		t.Fatal("ERROR: unable to load config", err)
+	}
+	f, err := fakes.New(conf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctrl, err := f.Controller()
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := httptest.NewServer(api.GetRouter(f.Config, ctrl))
+	defer server.Close()
+
+	admin, err := auth.CreateTokenWithRoles("test", "admin", []string{"admin"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	user, err := auth.CreateToken("test", "user")
+	if err != nil {
+		t.Fatal(err)
+	}
+	//equal names, so that a page order by name would not be unique
+	for i := 0; i < 7; i++ {
+		id := "d" + strconv.Itoa(i)
+		err = f.Publisher.PublishDevice(models.Device{Id: id, Name: "device", LocalId: id, DeviceTypeId: "dt", OwnerId: "admin"}, "admin")
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	replay := func(token string, query string) (result model.ReplayProgress, code int) {
+		f.Reset()
+		resp, err := helper.Jwtpost(token, server.URL+"/admin/replay/"+f.Config.DeviceTopic+query, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			return result, resp.StatusCode
+		}
+		err = json.NewDecoder(resp.Body).Decode(&result)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return result, resp.StatusCode
+	}
+	publishedIds := func() (ids []string) {
+		for _, command := range f.Publisher.Commands() {
+			if command.Topic == f.Config.DeviceTopic && command.Command == "PUT" {
+				ids = append(ids, command.Id)
+			}
+		}
+		slices.Sort(ids)
+		return ids
+	}
+
+	t.Run("only admins", func(t *testing.T) {
+		if _, code := replay(user.Token, ""); code != http.StatusForbidden {
+			t.Error("expected 403, got", code)
+		}
+	})
+
+	t.Run("all", func(t *testing.T) {
+		result, code := replay(admin.Token, "?batch-size=3")
+		if code != http.StatusOK || !result.Done || result.Listed != 7 || result.Published != 7 || len(result.Failed) != 0 {
+			t.Fatalf("%v %#v", code, result)
+		}
+		if ids := publishedIds(); !slices.Equal(ids, []string{"d0", "d1", "d2", "d3", "d4", "d5", "d6"}) {
+			t.Error(ids)
+		}
+	})
+
+	t.Run("ids", func(t *testing.T) {
+		result, code := replay(admin.Token, "?batch-size=2&ids=d1,d4,d6,unknown")
+		if code != http.StatusOK || !result.Done || result.Listed != 3 || result.Published != 3 {
+			t.Fatalf("%v %#v", code, result)
+		}
+		if ids := publishedIds(); !slices.Equal(ids, []string{"d1", "d4", "d6"}) {
+			t.Error(ids)
+		}
+	})
+
+	t.Run("dry run", func(t *testing.T) {
+		result, code := replay(admin.Token, "?dry-run=true")
+		if code != http.StatusOK || result.Listed != 7 || result.Published != 0 {
+			t.Fatalf("%v %#v", code, result)
+		}
+		if ids := publishedIds(); len(ids) != 0 {
+			t.Error(ids)
+		}
+	})
+
+	t.Run("canceled", func(t *testing.T) {
+		f.Reset()
+		ctx, cancel := context.WithCancel(context.Background())
+		result, err, _ := ctrl.WithContext(ctx).Replay(admin, f.Config.DeviceTopic, model.ReplayOptions{BatchSize: 2, RatePerSecond: 10}, func(progress model.ReplayProgress) {
+			if progress.Published >= 2 {
+				cancel()
+			}
+		})
+		cancel()
+		if err == nil || result.Done || result.Published != 2 {
+			t.Fatalf("%v %#v", err, result)
+		}
+		if ids := publishedIds(); len(ids) != 2 {
+			t.Error(ids)
+		}
+	})
+}
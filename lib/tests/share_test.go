@@ -0,0 +1,186 @@
+/*
+ * Copyright 2024 InfAI (CC SES)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package tests
+
+import (
+	"encoding/json"
+	"errors"
+	"github.com/SENERGY-Platform/device-manager/lib/api"
+	"github.com/SENERGY-Platform/device-manager/lib/auth"
+	"github.com/SENERGY-Platform/device-manager/lib/config"
+	"github.com/SENERGY-Platform/device-manager/lib/fakes"
+	"github.com/SENERGY-Platform/device-manager/lib/model"
+	sharestore "github.com/SENERGY-Platform/device-manager/lib/share"
+	"github.com/SENERGY-Platform/device-manager/lib/tests/helper"
+	"github.com/SENERGY-Platform/models/go/models"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestShares(t *testing.T) {
+	conf, err := config.Load("./../../config.json")
+	if err != nil {
+		t.Fatal("ERROR: unable to load config", err)
+	}
+	disabled, err := fakes.New(conf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	disabledCtrl, err := disabled.Controller()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err, code := disabledCtrl.ListShares(auth.Token{}, conf.DeviceTopic, "d1"); err == nil || code != http.StatusNotFound {
+		t.Error("expected 404 without share_file, got", code, err)
+	}
+
+	conf.ShareFile = filepath.Join(t.TempDir(), "shares.json")
+	f, err := fakes.New(conf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctrl, err := f.Controller()
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := httptest.NewServer(api.GetRouter(f.Config, ctrl))
+	defer server.Close()
+
+	owner, err := auth.CreateTokenWithRoles("test", "owner", []string{"user"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = f.Publisher.PublishDevice(models.Device{Id: "d1", Name: "d1", LocalId: "d1", OwnerId: "owner"}, "owner")
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err, _ = ctrl.SetSubjectPermissions(owner, f.Config.DeviceTopic, "d1", model.PermissionSubjectUsers, "viewer", &model.PermissionsMap{Read: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	share := func(userId string, rights model.PermissionsMap, expiresAt time.Time) int {
+		resp, err := helper.Jwtpost(owner.Token, server.URL+"/devices/d1/shares", model.ShareRequest{UserId: userId, Rights: rights, ExpiresAt: expiresAt})
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		return resp.StatusCode
+	}
+	userPermissions := func(userId string) (model.PermissionsMap, bool) {
+		permissions, _ := f.Store.Permissions(f.Config.DeviceTopic, "d1")
+		result, ok := permissions.UserPermissions[userId]
+		return result, ok
+	}
+
+	t.Run("invalid", func(t *testing.T) {
+		if code := share("installer", model.PermissionsMap{Administrate: true}, time.Now().Add(time.Hour)); code != http.StatusBadRequest {
+			t.Error("expected 400 for shared administrate rights, got", code)
+		}
+		if code := share("installer", model.PermissionsMap{Write: true}, time.Now().Add(-time.Hour)); code != http.StatusBadRequest {
+			t.Error("expected 400 for past expiry, got", code)
+		}
+	})
+
+	t.Run("failed grant", func(t *testing.T) {
+		f.Com.FailPermission("SetPermission", errors.New("unavailable"), http.StatusServiceUnavailable)
+		defer f.Com.Reset()
+		if code := share("installer", model.PermissionsMap{Read: true}, time.Now().Add(time.Hour)); code != http.StatusServiceUnavailable {
+			t.Error("expected 503, got", code)
+		}
+		list, err, _ := ctrl.ListShares(owner, f.Config.DeviceTopic, "d1")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(list) != 0 {
+			t.Errorf("expected no record of the failed share: %#v", list)
+		}
+	})
+
+	t.Run("grant", func(t *testing.T) {
+		expiresAt := time.Now().Add(200 * time.Millisecond)
+		if code := share("installer", model.PermissionsMap{Read: true, Write: true}, expiresAt); code != http.StatusOK {
+			t.Fatal(code)
+		}
+		if code := share("viewer", model.PermissionsMap{Write: true}, expiresAt); code != http.StatusOK {
+			t.Fatal(code)
+		}
+		if p, _ := userPermissions("installer"); !p.Write || p.Administrate {
+			t.Errorf("%#v", p)
+		}
+		//granted independent of the share, must be kept on revocation
+		_, err, _ = ctrl.SetSubjectPermissions(owner, f.Config.DeviceTopic, "d1", model.PermissionSubjectUsers, "installer", &model.PermissionsMap{Read: true, Write: true, Execute: true})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if p, _ := userPermissions("viewer"); !p.Write || !p.Read {
+			t.Errorf("%#v", p)
+		}
+	})
+
+	t.Run("reap", func(t *testing.T) {
+		revoked, err := ctrl.RevokeExpiredShares()
+		if err != nil || len(revoked) != 0 {
+			t.Fatal(err, revoked)
+		}
+		time.Sleep(300 * time.Millisecond)
+		restarted, err := sharestore.New(conf.ShareFile)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if expired := restarted.Expired(); len(expired) != 2 {
+			t.Errorf("expected stored shares after restart: %#v", expired)
+		}
+		revoked, err = ctrl.RevokeExpiredShares()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(revoked) != 2 {
+			t.Errorf("%#v", revoked)
+		}
+		if p, _ := userPermissions("installer"); p != (model.PermissionsMap{Execute: true}) {
+			t.Errorf("expected only the shared rights to be removed: %#v", p)
+		}
+		if p, _ := userPermissions("viewer"); p.Write || !p.Read {
+			t.Errorf("viewer permissions not restored: %#v", p)
+		}
+	})
+
+	t.Run("record", func(t *testing.T) {
+		resp, err := helper.Jwtget(owner.Token, server.URL+"/devices/d1/shares")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		list := []model.Share{}
+		err = json.NewDecoder(resp.Body).Decode(&list)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(list) != 2 {
+			t.Fatalf("%#v", list)
+		}
+		for _, element := range list {
+			if element.RevokedAt == nil || element.RevokedBy != model.ShareRevokedByReaper {
+				t.Errorf("%#v", element)
+			}
+		}
+	})
+}
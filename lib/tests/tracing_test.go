@@ -0,0 +1,189 @@
+/*
+ * Copyright 2024 InfAI (CC SES)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package tests
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"github.com/SENERGY-Platform/device-manager/lib/api"
+	"github.com/SENERGY-Platform/device-manager/lib/bus"
+	"github.com/SENERGY-Platform/device-manager/lib/config"
+	"github.com/SENERGY-Platform/device-manager/lib/controller"
+	"github.com/SENERGY-Platform/device-manager/lib/kafka/publisher"
+	"github.com/SENERGY-Platform/device-manager/lib/tracing"
+	"github.com/SENERGY-Platform/service-commons/pkg/accesslog"
+	"go.opentelemetry.io/otel"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestTracing(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	previous := otel.GetTracerProvider()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+	defer otel.SetTracerProvider(previous)
+
+	conf, err := config.Load("./../../config.json")
+	if err != nil {
+		t.Fatal("ERROR: unable to load config", err)
+	}
+	conf.BusBackend = bus.BackendChannel
+	conf.HandleDoneWait = false
+
+	repoTraceparent := make(chan string, 10)
+	repo := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		repoTraceparent <- request.Header.Get("traceparent")
+		http.NotFound(writer, request)
+	}))
+	defer repo.Close()
+	conf.DeviceRepoUrl = repo.URL
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	ctrl, err := controller.New(ctx, conf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := httptest.NewServer(api.GetRouter(conf, ctrl))
+	defer server.Close()
+
+	spanByName := func(name string) sdktrace.ReadOnlySpan {
+		for _, span := range recorder.Ended() {
+			if span.Name() == name {
+				return span
+			}
+		}
+		return nil
+	}
+
+	t.Run("http and com", func(t *testing.T) {
+		const traceId = "4bf92f3577b34da6a3ce929d0e0e4736"
+		req, err := http.NewRequest(http.MethodGet, server.URL+"/devices/unknown", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		req.Header.Set("Authorization", userjwt)
+		req.Header.Set("traceparent", "00-"+traceId+"-00f067aa0ba902b7-01")
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+
+		server := spanByName("GET /devices/{id}")
+		if server == nil {
+			t.Fatal("missing server span")
+		}
+		if server.SpanContext().TraceID().String() != traceId {
+			t.Error("server span does not continue incoming trace", server.SpanContext().TraceID())
+		}
+		com := spanByName("Com.GetDevice")
+		if com == nil {
+			t.Fatal("missing com span")
+		}
+		if com.Parent().SpanID() != server.SpanContext().SpanID() {
+			t.Error("com span is not a child of the server span")
+		}
+		select {
+		case traceparent := <-repoTraceparent:
+			if !strings.HasPrefix(traceparent, "00-"+traceId+"-") {
+				t.Error("device-repository request does not continue the trace", traceparent)
+			}
+		default:
+			t.Error("missing device-repository request")
+		}
+	})
+
+	t.Run("bus", func(t *testing.T) {
+		b := bus.NewChannel()
+		consumed := make(chan trace.SpanContext, 1)
+		err := b.Subscribe(ctx, conf.DeviceTopic, "", func(ctx context.Context, topic string, msg []byte) error {
+			consumed <- trace.SpanContextFromContext(ctx)
+			return nil
+		}, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		spanCtx, span := tracing.Start(ctx, "test")
+		err = publisher.NewWithBus(conf, b).WithContext(spanCtx).PublishDeviceDelete("unknown", "owner")
+		span.End()
+		if err != nil {
+			t.Fatal(err)
+		}
+		var handlerSpan trace.SpanContext
+		select {
+		case handlerSpan = <-consumed:
+		case <-time.After(10 * time.Second):
+			t.Fatal("timeout")
+		}
+		time.Sleep(100 * time.Millisecond)
+
+		publish := spanByName("publish " + conf.DeviceTopic)
+		consume := spanByName("consume " + conf.DeviceTopic)
+		if publish == nil || consume == nil {
+			t.Fatal("missing publish or consume span")
+		}
+		if publish.Parent().SpanID() != span.SpanContext().SpanID() {
+			t.Error("publish span is not a child of the caller span")
+		}
+		if consume.Parent().SpanID() != publish.SpanContext().SpanID() {
+			t.Error("consume span is not a child of the publish span")
+		}
+		if handlerSpan.SpanID() != consume.SpanContext().SpanID() {
+			t.Error("handler context does not carry the consume span")
+		}
+	})
+}
+
+// the tracing middleware must not replace the status of responses written with http.Error for the access log outside of it
+func TestTracingResponseStatus(t *testing.T) {
+	router := http.NewServeMux()
+	router.HandleFunc("GET /devices/{id}", func(writer http.ResponseWriter, request *http.Request) {
+		http.Error(writer, "not found", http.StatusNotFound)
+	})
+	logs := &bytes.Buffer{}
+	server := httptest.NewServer(accesslog.NewWithLogger(tracing.NewHttpMiddleware(router), slog.New(slog.NewJSONHandler(logs, nil))))
+	defer server.Close()
+
+	resp, err := http.Get(server.URL + "/devices/unknown")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusNotFound {
+		t.Fatal(resp.StatusCode)
+	}
+	entry := struct {
+		Status int `json:"response-status-code"`
+	}{}
+	err = json.Unmarshal(logs.Bytes(), &entry)
+	if err != nil {
+		t.Fatal(err, logs.String())
+	}
+	if entry.Status != http.StatusNotFound {
+		t.Error("expected logged status 404, got", entry.Status)
+	}
+}
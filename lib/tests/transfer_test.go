@@ -0,0 +1,222 @@
+/*
+ * Copyright 2024 InfAI (CC SES)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package tests
+
+import (
+	"encoding/json"
+	"errors"
+	"github.com/SENERGY-Platform/device-manager/lib/api"
+	"github.com/SENERGY-Platform/device-manager/lib/auth"
+	"github.com/SENERGY-Platform/device-manager/lib/config"
+	"github.com/SENERGY-Platform/device-manager/lib/fakes"
+	"github.com/SENERGY-Platform/device-manager/lib/model"
+	"github.com/SENERGY-Platform/device-manager/lib/tests/helper"
+	"github.com/SENERGY-Platform/device-manager/lib/transfer"
+	"github.com/SENERGY-Platform/models/go/models"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestOwnershipTransfer(t *testing.T) {
+	conf, err := config.Load("./../../config.json")
+	if err != nil {
+		t.Fatal("ERROR: unable to load config", err)
+	}
+	conf.TransferFile = filepath.Join(t.TempDir(), "transfers.json")
+	f, err := fakes.New(conf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctrl, err := f.Controller()
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := httptest.NewServer(api.GetRouter(f.Config, ctrl))
+	defer server.Close()
+
+	owner, err := auth.CreateTokenWithRoles("test", "owner", []string{"user"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	recipient, err := auth.CreateTokenWithRoles("test", "recipient", []string{"user"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = f.Publisher.PublishDevice(models.Device{Id: "d1", Name: "d1", LocalId: "d1", OwnerId: "owner"}, "owner")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	status := func(resp *http.Response, err error) int {
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		return resp.StatusCode
+	}
+
+	pending := model.Transfer{}
+	t.Run("create", func(t *testing.T) {
+		if code := status(helper.Jwtpost(recipient.Token, server.URL+"/devices/d1/transfer", model.TransferRequest{UserId: "recipient"})); code != http.StatusForbidden {
+			t.Error("expected 403 without administrate rights, got", code)
+		}
+		resp, err := helper.Jwtpost(owner.Token, server.URL+"/devices/d1/transfer", model.TransferRequest{UserId: "recipient", RemoveOldOwner: true})
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			t.Fatal(resp.StatusCode)
+		}
+		err = json.NewDecoder(resp.Body).Decode(&pending)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if pending.From != "owner" || pending.To != "recipient" || pending.ResourceId != "d1" {
+			t.Errorf("%#v", pending)
+		}
+	})
+
+	t.Run("list", func(t *testing.T) {
+		resp, err := helper.Jwtget(recipient.Token, server.URL+"/transfers")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		list := []model.Transfer{}
+		err = json.NewDecoder(resp.Body).Decode(&list)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(list) != 1 || list[0].Id != pending.Id {
+			t.Errorf("%#v", list)
+		}
+	})
+
+	t.Run("accept", func(t *testing.T) {
+		if code := status(helper.Jwtpost(owner.Token, server.URL+"/transfers/"+pending.Id+"/accept", nil)); code != http.StatusNotFound {
+			t.Error("expected 404 for accept by other user than the recipient, got", code)
+		}
+		if code := status(helper.Jwtpost(recipient.Token, server.URL+"/transfers/"+pending.Id+"/accept", nil)); code != http.StatusOK {
+			t.Fatal(code)
+		}
+		device, err, _ := f.Com.GetDevice(recipient, "d1")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if device.OwnerId != "recipient" {
+			t.Error(device.OwnerId)
+		}
+		permissions, _ := f.Store.Permissions(f.Config.DeviceTopic, "d1")
+		if !permissions.UserPermissions["recipient"].Administrate {
+			t.Errorf("%#v", permissions)
+		}
+		if _, ok := permissions.UserPermissions["owner"]; ok {
+			t.Errorf("old owner not removed: %#v", permissions)
+		}
+		if code := status(helper.Jwtpost(recipient.Token, server.URL+"/transfers/"+pending.Id+"/accept", nil)); code != http.StatusNotFound {
+			t.Error("expected 404 for second accept, got", code)
+		}
+	})
+
+	t.Run("failed publish keeps transfer and permissions", func(t *testing.T) {
+		resp, err := helper.Jwtpost(recipient.Token, server.URL+"/devices/d1/transfer", model.TransferRequest{UserId: "owner"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		next := model.Transfer{}
+		err = json.NewDecoder(resp.Body).Decode(&next)
+		resp.Body.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+		f.Publisher.FailPublish(f.Config.DeviceTopic, errors.New("test"))
+		if code := status(helper.Jwtpost(owner.Token, server.URL+"/transfers/"+next.Id+"/accept", nil)); code != http.StatusInternalServerError {
+			t.Error("expected 500, got", code)
+		}
+		f.Reset()
+		permissions, _ := f.Store.Permissions(f.Config.DeviceTopic, "d1")
+		if _, ok := permissions.UserPermissions["owner"]; ok {
+			t.Errorf("permissions not restored: %#v", permissions)
+		}
+		if code := status(helper.Jwtpost(owner.Token, server.URL+"/transfers/"+next.Id+"/accept", nil)); code != http.StatusOK {
+			t.Error("expected 200 after restore, got", code)
+		}
+	})
+}
+
+func TestTransferExpiry(t *testing.T) {
+	store, err := transfer.New(filepath.Join(t.TempDir(), "transfers.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	expired, err := store.Create(model.Transfer{Kind: "devices", ResourceId: "d1", From: "a", To: "b", ExpiresAt: time.Now().Add(-time.Second)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = store.Get(expired.Id)
+	if !errors.Is(err, transfer.ErrNotFound) {
+		t.Error(err)
+	}
+	first, err := store.Create(model.Transfer{Kind: "devices", ResourceId: "d1", From: "a", To: "b", ExpiresAt: time.Now().Add(time.Hour)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := store.Create(model.Transfer{Kind: "devices", ResourceId: "d1", From: "a", To: "c", ExpiresAt: time.Now().Add(time.Hour)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = store.Get(first.Id); !errors.Is(err, transfer.ErrNotFound) {
+		t.Error("expected replaced transfer to be removed", err)
+	}
+	if list := store.List("a"); len(list) != 1 || list[0].Id != second.Id {
+		t.Errorf("%#v", list)
+	}
+}
+
+func TestTransferSharedByReplicas(t *testing.T) {
+	if _, err := transfer.New(""); err == nil {
+		t.Error("expected error for in-memory transfers")
+	}
+	location := filepath.Join(t.TempDir(), "transfers.json")
+	replica1, err := transfer.New(location)
+	if err != nil {
+		t.Fatal(err)
+	}
+	replica2, err := transfer.New(location)
+	if err != nil {
+		t.Fatal(err)
+	}
+	created, err := replica1.Create(model.Transfer{Kind: "devices", ResourceId: "d1", From: "a", To: "b", ExpiresAt: time.Now().Add(time.Hour)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if list := replica2.List("b"); len(list) != 1 || list[0].Id != created.Id {
+		t.Errorf("%#v", list)
+	}
+	_, err = replica2.Take(created.Id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = replica1.Take(created.Id); !errors.Is(err, transfer.ErrNotFound) {
+		t.Error("expected transfer taken by the other replica to be gone", err)
+	}
+}
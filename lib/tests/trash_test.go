@@ -0,0 +1,242 @@
+/*
+ * Copyright 2024 InfAI (CC SES)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package tests
+
+import (
+	"encoding/json"
+	"errors"
+	"github.com/SENERGY-Platform/device-manager/lib/api"
+	"github.com/SENERGY-Platform/device-manager/lib/auth"
+	"github.com/SENERGY-Platform/device-manager/lib/config"
+	"github.com/SENERGY-Platform/device-manager/lib/fakes"
+	"github.com/SENERGY-Platform/device-manager/lib/model"
+	"github.com/SENERGY-Platform/device-manager/lib/tests/helper"
+	"github.com/SENERGY-Platform/device-manager/lib/trash"
+	"github.com/SENERGY-Platform/models/go/models"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestSoftDelete(t *testing.T) {
+	conf, err := config.Load("./../../config.json")
+	if err != nil {
+		t.Fatal("ERROR: unable to load config", err)
+	}
+	conf.SoftDelete = true
+	conf.TrashFile = t.TempDir() + "/trash.json"
+	f, err := fakes.New(conf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctrl, err := f.Controller()
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := httptest.NewServer(api.GetRouter(f.Config, ctrl))
+	defer server.Close()
+
+	owner, err := auth.CreateTokenWithRoles("test", "owner", []string{"user"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	other, err := auth.CreateTokenWithRoles("test", "other", []string{"user"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = f.Publisher.PublishDevice(models.Device{Id: "d1", Name: "d1", LocalId: "d1", OwnerId: "owner"}, "owner")
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err, _ = ctrl.SetSubjectPermissions(owner, f.Config.DeviceTopic, "d1", model.PermissionSubjectUsers, "viewer", &model.PermissionsMap{Read: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	list := func(token auth.Token) (result []model.TrashEntry) {
+		resp, err := helper.Jwtget(token.Token, server.URL+"/trash?kind="+f.Config.DeviceTopic)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			t.Fatal(resp.StatusCode)
+		}
+		err = json.NewDecoder(resp.Body).Decode(&result)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return result
+	}
+	restore := func(token auth.Token, id string) int {
+		resp, err := helper.Jwtpost(token.Token, server.URL+"/trash/"+id+"/restore", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		return resp.StatusCode
+	}
+
+	t.Run("failed delete", func(t *testing.T) {
+		f.Publisher.FailPublish(f.Config.DeviceTopic, errors.New("test"))
+		defer f.Publisher.Reset()
+		if _, code := ctrl.PublishDeviceDelete(owner, "d1", model.DeviceDeleteOptions{}); code != http.StatusInternalServerError {
+			t.Fatal(code)
+		}
+		if entries := list(owner); len(entries) != 0 {
+			t.Fatalf("%#v", entries)
+		}
+	})
+
+	var entry model.TrashEntry
+	t.Run("delete", func(t *testing.T) {
+		resp, err := helper.Jwtdelete(owner.Token, server.URL+"/devices/d1")
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			t.Fatal(resp.StatusCode)
+		}
+		if _, err, _ := ctrl.ReadDevice(owner, "d1"); err == nil {
+			t.Fatal("expected deleted device")
+		}
+		entries := list(owner)
+		if len(entries) != 1 {
+			t.Fatalf("%#v", entries)
+		}
+		entry = entries[0]
+		if entry.ResourceId != "d1" || entry.DeletedBy != "owner" || entry.Permissions == nil || !entry.Permissions.UserPermissions["viewer"].Read {
+			t.Errorf("%#v", entry)
+		}
+		if entries := list(other); len(entries) != 0 {
+			t.Errorf("%#v", entries)
+		}
+	})
+
+	t.Run("restore", func(t *testing.T) {
+		if code := restore(other, entry.Id); code != http.StatusNotFound {
+			t.Error("expected 404 for other user, got", code)
+		}
+		f.Publisher.FailPublish(f.Config.DeviceTopic, errors.New("test"))
+		if code := restore(owner, entry.Id); code != http.StatusInternalServerError {
+			t.Error("expected 500 for failed publish, got", code)
+		}
+		f.Publisher.Reset()
+		if entries := list(owner); len(entries) != 1 {
+			t.Fatalf("entry should be kept after failed restore: %#v", entries)
+		}
+		if code := restore(owner, entry.Id); code != http.StatusOK {
+			t.Fatal(code)
+		}
+		device, err, _ := ctrl.ReadDevice(owner, "d1")
+		if err != nil || device.Name != "d1" || device.OwnerId != "owner" {
+			t.Fatalf("%#v %v", device, err)
+		}
+		permissions, _ := f.Store.Permissions(f.Config.DeviceTopic, "d1")
+		if !permissions.UserPermissions["viewer"].Read || !permissions.UserPermissions["owner"].Administrate {
+			t.Errorf("%#v", permissions)
+		}
+		if code := restore(owner, entry.Id); code != http.StatusNotFound {
+			t.Error("expected 404 for restored entry, got", code)
+		}
+		if entries := list(owner); len(entries) != 0 {
+			t.Errorf("%#v", entries)
+		}
+	})
+
+	t.Run("retention", func(t *testing.T) {
+		store, err := trash.New(filepath.Join(t.TempDir(), "trash.json"), 10*time.Millisecond)
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, err = store.Add(model.TrashEntry{Kind: f.Config.DeviceTopic, ResourceId: "d2"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		time.Sleep(20 * time.Millisecond)
+		purged, err := store.Purge()
+		if err != nil || purged != 1 {
+			t.Error(purged, err)
+		}
+	})
+}
+
+func TestSoftDeleteRestoreWithoutDoneWait(t *testing.T) {
+	conf, err := config.Load("./../../config.json")
+	if err != nil {
+		t.Fatal("ERROR: unable to load config", err)
+	}
+	conf.SoftDelete = true
+	conf.TrashFile = ""
+	f, err := fakes.New(conf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = f.Controller(); err == nil {
+		t.Error("expected error for soft delete without trash_file")
+	}
+
+	conf.TrashFile = filepath.Join(t.TempDir(), "trash.json")
+	f, err = fakes.New(conf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.Config.HandleDoneWait = false
+	ctrl, err := f.Controller()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	owner, err := auth.CreateTokenWithRoles("test", "owner", []string{"user"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = f.Publisher.PublishDevice(models.Device{Id: "d1", Name: "d1", LocalId: "d1", OwnerId: "owner"}, "owner")
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err, _ = ctrl.SetSubjectPermissions(owner, f.Config.DeviceTopic, "d1", model.PermissionSubjectUsers, "viewer", &model.PermissionsMap{Read: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, code := ctrl.PublishDeviceDelete(owner, "d1", model.DeviceDeleteOptions{})
+	if code != http.StatusOK {
+		t.Fatal(code)
+	}
+	entries, _, _ := ctrl.ListTrash(owner, f.Config.DeviceTopic)
+	if len(entries) != 1 {
+		t.Fatalf("%#v", entries)
+	}
+
+	//the device-repository creates the restored device after the publish returned
+	f.Publisher.SetApplyDelay(300 * time.Millisecond)
+	defer f.Publisher.Reset()
+	_, err, code = ctrl.RestoreTrash(owner, entries[0].Id)
+	if err != nil {
+		t.Fatal(err, code)
+	}
+	if _, err, _ := ctrl.ReadDevice(owner, "d1"); err != nil {
+		t.Error("expected restore to wait for the creation before setting the permissions:", err)
+	}
+	permissions, _ := f.Store.Permissions(f.Config.DeviceTopic, "d1")
+	if !permissions.UserPermissions["viewer"].Read {
+		t.Errorf("expected restored permissions after the creation: %#v", permissions)
+	}
+}
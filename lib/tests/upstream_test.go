@@ -0,0 +1,202 @@
+/*
+ * Copyright 2024 InfAI (CC SES)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package tests
+
+import (
+	"github.com/SENERGY-Platform/device-manager/lib/auth"
+	"github.com/SENERGY-Platform/device-manager/lib/config"
+	"github.com/SENERGY-Platform/device-manager/lib/controller/com"
+	"github.com/SENERGY-Platform/device-manager/lib/upstream"
+	devicerepo "github.com/SENERGY-Platform/device-repository/lib/client"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestUpstreamClient(t *testing.T) {
+	options := upstream.DefaultOptions
+	options.RetryBackoff = time.Millisecond
+	options.BreakerThreshold = 3
+	options.BreakerOpenFor = 200 * time.Millisecond
+	options.EndpointTimeouts = map[string]time.Duration{"/slow": 50 * time.Millisecond}
+
+	t.Run("retry get", func(t *testing.T) {
+		calls := atomic.Int64{}
+		server := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+			if calls.Add(1) < 3 {
+				writer.WriteHeader(http.StatusServiceUnavailable)
+				return
+			}
+			writer.WriteHeader(http.StatusOK)
+		}))
+		defer server.Close()
+		resp, err := upstream.New(options).Get(server.URL)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusOK || calls.Load() != 3 {
+			t.Error(resp.StatusCode, calls.Load())
+		}
+	})
+
+	t.Run("no retry of put", func(t *testing.T) {
+		calls := atomic.Int64{}
+		server := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+			calls.Add(1)
+			writer.WriteHeader(http.StatusServiceUnavailable)
+		}))
+		defer server.Close()
+		req, err := http.NewRequest(http.MethodPut, server.URL, strings.NewReader("{}"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp, err := upstream.New(options).Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusServiceUnavailable || calls.Load() != 1 {
+			t.Error(resp.StatusCode, calls.Load())
+		}
+	})
+
+	t.Run("endpoint timeout", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+			time.Sleep(time.Second)
+			writer.WriteHeader(http.StatusOK)
+		}))
+		defer server.Close()
+		noRetry := options
+		noRetry.Retries = 0
+		start := time.Now()
+		_, err := upstream.New(noRetry).Get(server.URL + "/slow/foo")
+		if err == nil {
+			t.Fatal("expected timeout")
+		}
+		if time.Since(start) > 500*time.Millisecond {
+			t.Error("endpoint timeout not used", time.Since(start))
+		}
+	})
+
+	t.Run("circuit breaker", func(t *testing.T) {
+		calls := atomic.Int64{}
+		healthy := atomic.Bool{}
+		server := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+			calls.Add(1)
+			if !healthy.Load() {
+				writer.WriteHeader(http.StatusBadGateway)
+				return
+			}
+			writer.WriteHeader(http.StatusOK)
+		}))
+		defer server.Close()
+		noRetry := options
+		noRetry.Retries = 0
+		client := upstream.New(noRetry)
+		get := func() int {
+			resp, err := client.Get(server.URL)
+			if err != nil {
+				t.Fatal(err)
+			}
+			resp.Body.Close()
+			return resp.StatusCode
+		}
+		for i := 0; i < 3; i++ {
+			get()
+		}
+		if code := get(); code != http.StatusServiceUnavailable || calls.Load() != 3 {
+			t.Error("expected fast fail", code, calls.Load())
+		}
+		healthy.Store(true)
+		time.Sleep(250 * time.Millisecond)
+		if code := get(); code != http.StatusOK || calls.Load() != 4 {
+			t.Error("expected probe", code, calls.Load())
+		}
+		if code := get(); code != http.StatusOK || calls.Load() != 5 {
+			t.Error("expected closed breaker", code, calls.Load())
+		}
+	})
+}
+
+func TestUpstreamOptionsFromConfig(t *testing.T) {
+	conf := config.Config{}
+	options := upstream.OptionsFromConfig(conf)
+	if options.Retries != upstream.DefaultOptions.Retries || options.BreakerThreshold != upstream.DefaultOptions.BreakerThreshold {
+		t.Errorf("expected defaults for unset fields %#v", options)
+	}
+	conf.UpstreamRetries = -1
+	conf.UpstreamBreakerThreshold = -1
+	options = upstream.OptionsFromConfig(conf)
+	if options.Retries != 0 || options.BreakerThreshold != 0 {
+		t.Errorf("expected disabled retries and breaker %#v", options)
+	}
+	conf.UpstreamRetries = 4
+	conf.UpstreamBreakerThreshold = 7
+	options = upstream.OptionsFromConfig(conf)
+	if options.Retries != 4 || options.BreakerThreshold != 7 {
+		t.Errorf("%#v", options)
+	}
+}
+
+func TestComUsesUpstreamClient(t *testing.T) {
+	calls := map[string]*atomic.Int64{}
+	for _, path := range []string{"/check/devices", "/devices"} {
+		calls[path] = &atomic.Int64{}
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		counter, ok := calls[request.URL.Path]
+		if !ok {
+			writer.WriteHeader(http.StatusNotFound)
+			return
+		}
+		if counter.Add(1) < 2 {
+			writer.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		writer.Header().Set("Content-Type", "application/json")
+		if request.URL.Path == "/check/devices" {
+			writer.Write([]byte(`{"d1":true}`))
+		} else {
+			writer.Write([]byte(`[]`))
+		}
+	}))
+	defer server.Close()
+
+	conf := config.Config{PermissionsV2Url: server.URL, DeviceRepoUrl: server.URL, DeviceTopic: "devices", UpstreamRetryBackoff: "1ms"}
+	c := com.New(conf)
+	token, err := auth.CreateToken("test", "user")
+	if err != nil {
+		t.Fatal(err)
+	}
+	err, code := c.PermissionCheckForDevice(token, "d1", "r")
+	if err != nil {
+		t.Error(err, code)
+	}
+	_, err, code = c.ListDevices(token.Jwt(), devicerepo.DeviceListOptions{Limit: 1})
+	if err != nil {
+		t.Error(err, code)
+	}
+	for path, counter := range calls {
+		if counter.Load() != 2 {
+			t.Error("expected a retry of", path, counter.Load())
+		}
+	}
+}
@@ -0,0 +1,122 @@
+/*
+ * Copyright 2024 InfAI (CC SES)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package tracing sets up opentelemetry tracing.
+// without config.OtlpEndpoint the global no-op tracer provider is kept and spans cost next to nothing.
+package tracing
+
+import (
+	"context"
+	"github.com/SENERGY-Platform/device-manager/lib/config"
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+	"log"
+	"net/http"
+)
+
+const instrumentationName = "github.com/SENERGY-Platform/device-manager"
+
+const ServiceName = "device-manager"
+
+func init() {
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}))
+}
+
+// Init exports spans to config.OtlpEndpoint (OTLP over http); returns a shutdown function which flushes pending spans.
+// the exporter may be further configured with the OTEL_EXPORTER_OTLP_* environment variables.
+func Init(ctx context.Context, conf config.Config) (shutdown func(ctx context.Context) error, err error) {
+	if conf.OtlpEndpoint == "" || conf.OtlpEndpoint == "-" {
+		return func(ctx context.Context) error { return nil }, nil
+	}
+	exporter, err := otlptracehttp.New(ctx, otlptracehttp.WithEndpointURL(conf.OtlpEndpoint))
+	if err != nil {
+		return nil, err
+	}
+	res, err := resource.Merge(resource.Default(), resource.NewWithAttributes(semconv.SchemaURL, semconv.ServiceName(ServiceName)))
+	if err != nil {
+		return nil, err
+	}
+	sampleRatio := conf.TraceSampleRatio
+	if sampleRatio <= 0 {
+		sampleRatio = 1
+	}
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(res),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(sampleRatio))),
+	)
+	otel.SetTracerProvider(provider)
+	log.Println("export traces to", conf.OtlpEndpoint)
+	return provider.Shutdown, nil
+}
+
+func Tracer() trace.Tracer {
+	return otel.Tracer(instrumentationName)
+}
+
+// Start starts a span; ctx may be nil
+func Start(ctx context.Context, name string, opts ...trace.SpanStartOption) (context.Context, trace.Span) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	return Tracer().Start(ctx, name, opts...)
+}
+
+// End records err on span and ends it
+func End(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
+
+// Inject writes the trace context of ctx to headers
+func Inject(ctx context.Context, headers map[string]string) {
+	otel.GetTextMapPropagator().Inject(ctx, propagation.MapCarrier(headers))
+}
+
+// Extract returns a context with the trace context found in headers; headers may be nil
+func Extract(ctx context.Context, headers map[string]string) context.Context {
+	if headers == nil {
+		return ctx
+	}
+	return otel.GetTextMapPropagator().Extract(ctx, propagation.MapCarrier(headers))
+}
+
+// NewHttpMiddleware starts a server span for each request, named by the pattern of the matched route
+func NewHttpMiddleware(handler http.Handler) http.Handler {
+	return otelhttp.NewHandler(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		handler.ServeHTTP(writer, request)
+		if request.Pattern != "" {
+			trace.SpanFromContext(request.Context()).SetName(request.Pattern)
+		}
+	}), "http", otelhttp.WithSpanNameFormatter(func(operation string, request *http.Request) string {
+		return request.Method
+	}))
+}
+
+// NewHttpTransport starts a client span for each request as child of the span in the request context and sends its trace context in the traceparent header
+func NewHttpTransport(base http.RoundTripper) http.RoundTripper {
+	return otelhttp.NewTransport(base)
+}
@@ -0,0 +1,145 @@
+/*
+ * Copyright 2024 InfAI (CC SES)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package transfer stores pending ownership transfers in a json file, which is shared by the replicas of the service (e.g. on a shared volume).
+// a resource has at most one pending transfer; expired transfers are removed on access.
+package transfer
+
+import (
+	"errors"
+	"github.com/SENERGY-Platform/device-manager/lib/jsonstore"
+	"github.com/SENERGY-Platform/device-manager/lib/model"
+	"github.com/google/uuid"
+	"sort"
+	"time"
+)
+
+var ErrNotFound = errors.New("transfer not found")
+
+type Store struct {
+	transfers *jsonstore.Map[model.Transfer]
+	now       func() time.Time
+}
+
+// New loads the transfers of the json file at location, if it exists.
+// transfers are accepted by the recipient, possibly on another replica, so location is required.
+func New(location string) (*Store, error) {
+	if location == "" || location == "-" {
+		return nil, errors.New("transfers need a file shared by all replicas")
+	}
+	transfers, err := jsonstore.NewMap[model.Transfer](location, func(transfer model.Transfer) string {
+		return transfer.Id
+	}, func(a model.Transfer, b model.Transfer) bool {
+		return a.CreatedAt.Before(b.CreatedAt)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &Store{transfers: transfers, now: time.Now}, nil
+}
+
+// Create stores transfer with a new id and creation time and replaces a pending transfer of the same resource
+func (this *Store) Create(transfer model.Transfer) (model.Transfer, error) {
+	transfer.Id = uuid.NewString()
+	transfer.CreatedAt = this.now()
+	err := this.update(func(transfers map[string]model.Transfer) error {
+		for id, pending := range transfers {
+			if pending.Kind == transfer.Kind && pending.ResourceId == transfer.ResourceId {
+				delete(transfers, id)
+			}
+		}
+		transfers[transfer.Id] = transfer
+		return nil
+	})
+	return transfer, err
+}
+
+// List returns the pending transfers from or to userId, ordered by creation
+func (this *Store) List(userId string) (result []model.Transfer) {
+	result = []model.Transfer{}
+	now := this.now()
+	this.transfers.View(func(transfers map[string]model.Transfer) {
+		for _, transfer := range transfers {
+			if transfer.ExpiresAt.After(now) && (transfer.From == userId || transfer.To == userId || transfer.CreatedBy == userId) {
+				result = append(result, transfer)
+			}
+		}
+	})
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].CreatedAt.Before(result[j].CreatedAt)
+	})
+	return result
+}
+
+func (this *Store) Get(id string) (transfer model.Transfer, err error) {
+	var ok bool
+	this.transfers.View(func(transfers map[string]model.Transfer) {
+		transfer, ok = transfers[id]
+	})
+	if !ok || !transfer.ExpiresAt.After(this.now()) {
+		return model.Transfer{}, ErrNotFound
+	}
+	return transfer, nil
+}
+
+// Take removes the pending transfer and returns it, so that it is executed at most once, even by different replicas.
+// use Restore if the execution fails.
+func (this *Store) Take(id string) (transfer model.Transfer, err error) {
+	err = this.update(func(transfers map[string]model.Transfer) error {
+		var ok bool
+		transfer, ok = transfers[id]
+		if !ok {
+			return ErrNotFound
+		}
+		delete(transfers, id)
+		return nil
+	})
+	return transfer, err
+}
+
+// Restore stores a taken transfer again, unless it expired or the resource got a new transfer in the meantime
+func (this *Store) Restore(transfer model.Transfer) error {
+	return this.update(func(transfers map[string]model.Transfer) error {
+		if !transfer.ExpiresAt.After(this.now()) {
+			return jsonstore.SkipWrite
+		}
+		for _, pending := range transfers {
+			if pending.Kind == transfer.Kind && pending.ResourceId == transfer.ResourceId {
+				return jsonstore.SkipWrite
+			}
+		}
+		transfers[transfer.Id] = transfer
+		return nil
+	})
+}
+
+func (this *Store) Delete(id string) error {
+	_, err := this.Take(id)
+	return err
+}
+
+// update runs change with the latest not expired transfers; expired transfers are removed from the file with the change
+func (this *Store) update(change func(transfers map[string]model.Transfer) error) error {
+	return this.transfers.Update(func(transfers map[string]model.Transfer) error {
+		now := this.now()
+		for id, transfer := range transfers {
+			if !transfer.ExpiresAt.After(now) {
+				delete(transfers, id)
+			}
+		}
+		return change(transfers)
+	})
+}
@@ -0,0 +1,150 @@
+/*
+ * Copyright 2024 InfAI (CC SES)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package trash stores snapshots of soft deleted resources in a json file, which is shared by the replicas of the service (e.g. on a shared volume).
+// entries are purged after the retention.
+package trash
+
+import (
+	"errors"
+	"github.com/SENERGY-Platform/device-manager/lib/jsonstore"
+	"github.com/SENERGY-Platform/device-manager/lib/model"
+	"github.com/google/uuid"
+	"sort"
+	"time"
+)
+
+var ErrNotFound = errors.New("trash entry not found")
+
+type Store struct {
+	entries   *jsonstore.Map[model.TrashEntry]
+	retention time.Duration
+	now       func() time.Time
+}
+
+// New loads the entries of the json file at location, if it exists.
+// resources deleted by one replica are restored by any other, so location is required.
+func New(location string, retention time.Duration) (*Store, error) {
+	if location == "" || location == "-" {
+		return nil, errors.New("the trash needs a file shared by all replicas")
+	}
+	entries, err := jsonstore.NewMap[model.TrashEntry](location, func(entry model.TrashEntry) string {
+		return entry.Id
+	}, func(a model.TrashEntry, b model.TrashEntry) bool {
+		return a.DeletedAt.Before(b.DeletedAt)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &Store{entries: entries, retention: retention, now: time.Now}, nil
+}
+
+// Add stores entry with a new id, deletion time and expiration
+func (this *Store) Add(entry model.TrashEntry) (model.TrashEntry, error) {
+	entry.Id = uuid.NewString()
+	entry.DeletedAt = this.now()
+	entry.ExpiresAt = entry.DeletedAt.Add(this.retention)
+	err := this.entries.Update(func(entries map[string]model.TrashEntry) error {
+		this.removeExpired(entries)
+		entries[entry.Id] = entry
+		return nil
+	})
+	return entry, err
+}
+
+// List returns the entries, for which filter returns true, ordered by deletion
+func (this *Store) List(filter func(entry model.TrashEntry) bool) (result []model.TrashEntry) {
+	result = []model.TrashEntry{}
+	now := this.now()
+	this.entries.View(func(entries map[string]model.TrashEntry) {
+		for _, entry := range entries {
+			if entry.ExpiresAt.After(now) && filter(entry) {
+				result = append(result, entry)
+			}
+		}
+	})
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].DeletedAt.Before(result[j].DeletedAt)
+	})
+	return result
+}
+
+func (this *Store) Get(id string) (entry model.TrashEntry, err error) {
+	var ok bool
+	this.entries.View(func(entries map[string]model.TrashEntry) {
+		entry, ok = entries[id]
+	})
+	if !ok || !entry.ExpiresAt.After(this.now()) {
+		return model.TrashEntry{}, ErrNotFound
+	}
+	return entry, nil
+}
+
+// Take removes the entry and returns it, so that it is restored at most once, even by different replicas.
+// use Put if the restore fails.
+func (this *Store) Take(id string) (entry model.TrashEntry, err error) {
+	err = this.entries.Update(func(entries map[string]model.TrashEntry) error {
+		this.removeExpired(entries)
+		var ok bool
+		entry, ok = entries[id]
+		if !ok {
+			return ErrNotFound
+		}
+		delete(entries, id)
+		return nil
+	})
+	return entry, err
+}
+
+// Put stores a taken entry again, unless it expired
+func (this *Store) Put(entry model.TrashEntry) error {
+	return this.entries.Update(func(entries map[string]model.TrashEntry) error {
+		if !entry.ExpiresAt.After(this.now()) {
+			return jsonstore.SkipWrite
+		}
+		entries[entry.Id] = entry
+		return nil
+	})
+}
+
+func (this *Store) Delete(id string) error {
+	_, err := this.Take(id)
+	return err
+}
+
+// Purge removes expired entries from the file
+func (this *Store) Purge() (purged int, err error) {
+	err = this.entries.Update(func(entries map[string]model.TrashEntry) error {
+		purged = this.removeExpired(entries)
+		if purged == 0 {
+			return jsonstore.SkipWrite
+		}
+		return nil
+	})
+	return purged, err
+}
+
+// removeExpired drops expired entries from entries
+func (this *Store) removeExpired(entries map[string]model.TrashEntry) (count int) {
+	now := this.now()
+	for id, entry := range entries {
+		if !entry.ExpiresAt.After(now) {
+			delete(entries, id)
+			count++
+		}
+	}
+	return count
+}
@@ -0,0 +1,86 @@
+/*
+ * Copyright 2024 InfAI (CC SES)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package upstream
+
+import (
+	"github.com/SENERGY-Platform/device-manager/lib/metrics"
+	"log"
+	"sync"
+	"time"
+)
+
+// breaker is the circuit breaker of one host:
+// closed until threshold consecutive failures, then open for openFor, then half-open with one probe request
+type breaker struct {
+	host      string
+	threshold int
+	openFor   time.Duration
+
+	mux       sync.Mutex
+	failures  int
+	openUntil time.Time
+	probing   bool
+}
+
+// allow reports if a request may be sent; in the half-open state only one probe is allowed at a time
+func (this *breaker) allow() bool {
+	if this.threshold <= 0 {
+		return true
+	}
+	this.mux.Lock()
+	defer this.mux.Unlock()
+	if this.failures < this.threshold {
+		return true
+	}
+	if time.Now().Before(this.openUntil) || this.probing {
+		return false
+	}
+	this.probing = true
+	return true
+}
+
+// release frees the probe slot of a request without result
+func (this *breaker) release() {
+	this.mux.Lock()
+	defer this.mux.Unlock()
+	this.probing = false
+}
+
+func (this *breaker) record(success bool) {
+	if this.threshold <= 0 {
+		return
+	}
+	this.mux.Lock()
+	defer this.mux.Unlock()
+	this.probing = false
+	if success {
+		if this.failures >= this.threshold {
+			log.Println("upstream", this.host, "available again: close circuit breaker")
+			metrics.UpstreamCircuitOpen.WithLabelValues(this.host).Set(0)
+		}
+		this.failures = 0
+		return
+	}
+	this.failures++
+	if this.failures >= this.threshold {
+		if this.failures == this.threshold {
+			log.Println("WARNING: upstream", this.host, "failed", this.failures, "times: open circuit breaker for", this.openFor)
+		}
+		this.openUntil = time.Now().Add(this.openFor)
+		metrics.UpstreamCircuitOpen.WithLabelValues(this.host).Set(1)
+	}
+}
@@ -0,0 +1,266 @@
+/*
+ * Copyright 2024 InfAI (CC SES)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package upstream provides the http client for calls to other services (device-repository, permissions-v2, converter):
+// per-endpoint timeouts, bounded retries of idempotent requests, a circuit breaker per upstream host and connection pool settings.
+// while the breaker of a host is open, requests fail fast with a 503 response and are not sent.
+package upstream
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/SENERGY-Platform/device-manager/lib/config"
+	"github.com/SENERGY-Platform/device-manager/lib/metrics"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+type Options struct {
+	Timeout          time.Duration            //default timeout of one attempt; 0 for no timeout
+	EndpointTimeouts map[string]time.Duration //timeouts by "<path-prefix>" or "<METHOD> <path-prefix>"; the longest matching prefix wins
+	Retries          int                      //additional attempts of GET and HEAD requests after connection errors and 502, 503 or 504 responses
+	RetryBackoff     time.Duration            //wait before the first retry, doubled for each further retry
+	BreakerThreshold int                      //consecutive failures of a host which open its circuit breaker; 0 disables the breaker
+	BreakerOpenFor   time.Duration            //time requests to a host fail fast, before one probe request is let through
+
+	MaxIdleConns        int
+	MaxIdleConnsPerHost int
+	MaxConnsPerHost     int //0 for no limit
+	IdleConnTimeout     time.Duration
+}
+
+var DefaultOptions = Options{
+	Timeout:             30 * time.Second,
+	Retries:             2,
+	RetryBackoff:        100 * time.Millisecond,
+	BreakerThreshold:    5,
+	BreakerOpenFor:      30 * time.Second,
+	MaxIdleConns:        100,
+	MaxIdleConnsPerHost: 10,
+	IdleConnTimeout:     90 * time.Second,
+}
+
+// OptionsFromConfig reads the upstream_* config fields; missing, zero or invalid values use DefaultOptions.
+// negative upstream_retries and upstream_breaker_threshold disable retries and the circuit breaker.
+func OptionsFromConfig(conf config.Config) Options {
+	result := DefaultOptions
+	result.Timeout = parseDuration("http_client_timeout", conf.HttpClientTimeout, result.Timeout)
+	result.EndpointTimeouts = map[string]time.Duration{}
+	for endpoint, timeout := range conf.UpstreamTimeouts {
+		d, err := time.ParseDuration(timeout)
+		if err != nil {
+			log.Println("WARNING: invalid upstream_timeouts value for", endpoint, "-->", err)
+			continue
+		}
+		result.EndpointTimeouts[endpoint] = d
+	}
+	if conf.UpstreamRetries < 0 {
+		result.Retries = 0
+	} else if conf.UpstreamRetries > 0 {
+		result.Retries = int(conf.UpstreamRetries)
+	}
+	result.RetryBackoff = parseDuration("upstream_retry_backoff", conf.UpstreamRetryBackoff, result.RetryBackoff)
+	if conf.UpstreamBreakerThreshold < 0 {
+		result.BreakerThreshold = 0
+	} else if conf.UpstreamBreakerThreshold > 0 {
+		result.BreakerThreshold = int(conf.UpstreamBreakerThreshold)
+	}
+	result.BreakerOpenFor = parseDuration("upstream_breaker_open_for", conf.UpstreamBreakerOpenFor, result.BreakerOpenFor)
+	if conf.UpstreamMaxIdleConns > 0 {
+		result.MaxIdleConns = int(conf.UpstreamMaxIdleConns)
+	}
+	if conf.UpstreamMaxIdleConnsPerHost > 0 {
+		result.MaxIdleConnsPerHost = int(conf.UpstreamMaxIdleConnsPerHost)
+	}
+	if conf.UpstreamMaxConnsPerHost > 0 {
+		result.MaxConnsPerHost = int(conf.UpstreamMaxConnsPerHost)
+	}
+	result.IdleConnTimeout = parseDuration("upstream_idle_conn_timeout", conf.UpstreamIdleConnTimeout, result.IdleConnTimeout)
+	return result
+}
+
+func parseDuration(field string, value string, fallback time.Duration) time.Duration {
+	if value == "" {
+		return fallback
+	}
+	result, err := time.ParseDuration(value)
+	if err != nil {
+		log.Println("WARNING: invalid", field, "-->", fallback, err)
+		return fallback
+	}
+	return result
+}
+
+// New returns a client using NewTransport; timeouts are handled by the transport per attempt
+func New(options Options) *http.Client {
+	return &http.Client{Transport: NewTransport(options)}
+}
+
+// NewTransport wraps a new http.Transport with the pool settings of options
+func NewTransport(options Options) *Transport {
+	base := http.DefaultTransport.(*http.Transport).Clone()
+	base.MaxIdleConns = options.MaxIdleConns
+	base.MaxIdleConnsPerHost = options.MaxIdleConnsPerHost
+	base.MaxConnsPerHost = options.MaxConnsPerHost
+	base.IdleConnTimeout = options.IdleConnTimeout
+	return &Transport{
+		Base:     base,
+		options:  options,
+		breakers: map[string]*breaker{},
+	}
+}
+
+type Transport struct {
+	Base     http.RoundTripper
+	options  Options
+	mux      sync.Mutex
+	breakers map[string]*breaker
+}
+
+func (this *Transport) RoundTrip(req *http.Request) (resp *http.Response, err error) {
+	b := this.getBreaker(req.URL.Host)
+	attempts := 1
+	if isRetryable(req) {
+		attempts += this.options.Retries
+	}
+	wait := this.options.RetryBackoff
+	for attempt := 0; attempt < attempts; attempt++ {
+		if attempt > 0 {
+			metrics.UpstreamRetries.WithLabelValues(req.URL.Host).Inc()
+			select {
+			case <-req.Context().Done():
+				return nil, req.Context().Err()
+			case <-time.After(wait):
+			}
+			wait = wait * 2
+		}
+		if !b.allow() {
+			return circuitOpenResponse(req), nil
+		}
+		resp, err = this.roundTrip(req)
+		if req.Context().Err() != nil {
+			//canceled by the caller; says nothing about the upstream
+			b.release()
+			return resp, err
+		}
+		failed := err != nil || isUnavailable(resp.StatusCode)
+		b.record(!failed)
+		if !failed {
+			return resp, nil
+		}
+		if attempt < attempts-1 && resp != nil {
+			io.Copy(io.Discard, resp.Body)
+			resp.Body.Close()
+		}
+	}
+	return resp, err
+}
+
+// roundTrip sends one attempt with the timeout of the endpoint; the timeout ends when the response body is closed
+func (this *Transport) roundTrip(req *http.Request) (*http.Response, error) {
+	timeout := this.timeout(req)
+	if timeout <= 0 {
+		return this.Base.RoundTrip(req)
+	}
+	ctx, cancel := context.WithTimeout(req.Context(), timeout)
+	resp, err := this.Base.RoundTrip(req.WithContext(ctx))
+	if err != nil {
+		cancel()
+		if errors.Is(err, context.DeadlineExceeded) {
+			err = fmt.Errorf("upstream timeout after %v: %w", timeout, err)
+		}
+		return nil, err
+	}
+	resp.Body = &cancelOnClose{ReadCloser: resp.Body, cancel: cancel}
+	return resp, nil
+}
+
+func (this *Transport) timeout(req *http.Request) time.Duration {
+	result := this.options.Timeout
+	longest := -1
+	for endpoint, timeout := range this.options.EndpointTimeouts {
+		method, prefix, found := strings.Cut(endpoint, " ")
+		if !found {
+			method, prefix = "", endpoint
+		}
+		if method != "" && method != req.Method {
+			continue
+		}
+		if !strings.HasPrefix(req.URL.Path, prefix) {
+			continue
+		}
+		//on equal prefixes the method specific entry wins
+		weight := len(prefix) * 2
+		if method != "" {
+			weight++
+		}
+		if weight > longest {
+			longest = weight
+			result = timeout
+		}
+	}
+	return result
+}
+
+func (this *Transport) getBreaker(host string) *breaker {
+	this.mux.Lock()
+	defer this.mux.Unlock()
+	result, ok := this.breakers[host]
+	if !ok {
+		result = &breaker{host: host, threshold: this.options.BreakerThreshold, openFor: this.options.BreakerOpenFor}
+		this.breakers[host] = result
+	}
+	return result
+}
+
+func isRetryable(req *http.Request) bool {
+	return (req.Method == http.MethodGet || req.Method == http.MethodHead) && (req.Body == nil || req.Body == http.NoBody)
+}
+
+func isUnavailable(code int) bool {
+	return code == http.StatusBadGateway || code == http.StatusServiceUnavailable || code == http.StatusGatewayTimeout
+}
+
+func circuitOpenResponse(req *http.Request) *http.Response {
+	body := "upstream " + req.URL.Host + " unavailable: circuit breaker open"
+	return &http.Response{
+		Status:        strconv.Itoa(http.StatusServiceUnavailable) + " " + http.StatusText(http.StatusServiceUnavailable),
+		StatusCode:    http.StatusServiceUnavailable,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        http.Header{"Content-Type": {"text/plain; charset=utf-8"}},
+		Body:          io.NopCloser(strings.NewReader(body)),
+		ContentLength: int64(len(body)),
+		Request:       req,
+	}
+}
+
+type cancelOnClose struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+func (this *cancelOnClose) Close() error {
+	defer this.cancel()
+	return this.ReadCloser.Close()
+}
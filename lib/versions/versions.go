@@ -0,0 +1,177 @@
+/*
+ * Copyright 2024 InfAI (CC SES)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package versions stores the published versions of device-types in a json file, which is shared by the replicas of the service (e.g. on a shared volume).
+package versions
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"github.com/SENERGY-Platform/device-manager/lib/jsonstore"
+	"github.com/SENERGY-Platform/device-manager/lib/model"
+	"github.com/SENERGY-Platform/models/go/models"
+	"sort"
+	"strconv"
+	"time"
+)
+
+var ErrNotFound = errors.New("device-type version not found")
+
+type Store struct {
+	versions *jsonstore.Map[model.DeviceTypeVersion]
+	limit    int //max number of kept versions per device-type; 0 for no limit
+	now      func() time.Time
+}
+
+// New loads the versions of the json file at location, if it exists.
+// any replica of the consumer group may number a version and any replica may read it, so location is required.
+func New(location string, limit int) (*Store, error) {
+	if location == "" || location == "-" {
+		return nil, errors.New("device-type versions need a file shared by all replicas")
+	}
+	versions, err := jsonstore.NewMap[model.DeviceTypeVersion](location, func(version model.DeviceTypeVersion) string {
+		return key(version.DeviceTypeId, version.Version)
+	}, func(a model.DeviceTypeVersion, b model.DeviceTypeVersion) bool {
+		if a.DeviceTypeId != b.DeviceTypeId {
+			return a.DeviceTypeId < b.DeviceTypeId
+		}
+		return a.Version < b.Version
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &Store{versions: versions, limit: limit, now: time.Now}, nil
+}
+
+func key(id string, version int) string {
+	return id + "/" + strconv.Itoa(version)
+}
+
+// Add stores a copy of dt as new version, unless it equals the latest version (e.g. an update without changes).
+// versions are numbered in the order of the calls, so the commands of a device-type must be added once and in order.
+func (this *Store) Add(userId string, dt models.DeviceType) (result model.DeviceTypeVersion, added bool, err error) {
+	definition, err := json.Marshal(dt)
+	if err != nil {
+		return result, false, err
+	}
+	dt = models.DeviceType{}
+	err = json.Unmarshal(definition, &dt)
+	if err != nil {
+		return result, false, err
+	}
+	err = this.versions.Update(func(versions map[string]model.DeviceTypeVersion) error {
+		list := listOf(versions, dt.Id)
+		if len(list) > 0 {
+			latest := list[len(list)-1]
+			latestDefinition, err := json.Marshal(latest.DeviceType)
+			if err != nil {
+				return err
+			}
+			if bytes.Equal(latestDefinition, definition) {
+				result = clone(latest)
+				return jsonstore.SkipWrite
+			}
+			result.Version = latest.Version + 1
+		} else {
+			result.Version = 1
+		}
+		result.DeviceTypeId = dt.Id
+		result.UserId = userId
+		result.PublishedAt = this.now()
+		result.DeviceType = dt
+		versions[key(dt.Id, result.Version)] = result
+		list = append(list, result)
+		if this.limit > 0 && len(list) > this.limit {
+			for _, dropped := range list[:len(list)-this.limit] {
+				delete(versions, key(dt.Id, dropped.Version))
+			}
+		}
+		added = true
+		return nil
+	})
+	return result, added, err
+}
+
+// AddBaseline stores dt as first version, if the device-type has no version yet;
+// device-types published before the version history was enabled get the stored definition as baseline
+func (this *Store) AddBaseline(dt models.DeviceType) (added bool, err error) {
+	err = this.versions.Update(func(versions map[string]model.DeviceTypeVersion) error {
+		if len(listOf(versions, dt.Id)) > 0 {
+			return jsonstore.SkipWrite
+		}
+		versions[key(dt.Id, 1)] = clone(model.DeviceTypeVersion{DeviceTypeId: dt.Id, Version: 1, PublishedAt: this.now(), DeviceType: dt})
+		added = true
+		return nil
+	})
+	return added, err
+}
+
+// List returns copies of the kept versions of the device-type, oldest first
+func (this *Store) List(id string) (result []model.DeviceTypeVersion) {
+	result = []model.DeviceTypeVersion{}
+	this.versions.View(func(versions map[string]model.DeviceTypeVersion) {
+		for _, v := range listOf(versions, id) {
+			result = append(result, clone(v))
+		}
+	})
+	return result
+}
+
+func (this *Store) Get(id string, version int) (result model.DeviceTypeVersion, err error) {
+	err = ErrNotFound
+	this.versions.View(func(versions map[string]model.DeviceTypeVersion) {
+		if v, ok := versions[key(id, version)]; ok {
+			result, err = clone(v), nil
+		}
+	})
+	return result, err
+}
+
+// Latest returns the newest version of the device-type
+func (this *Store) Latest(id string) (result model.DeviceTypeVersion, err error) {
+	err = ErrNotFound
+	this.versions.View(func(versions map[string]model.DeviceTypeVersion) {
+		if list := listOf(versions, id); len(list) > 0 {
+			result, err = clone(list[len(list)-1]), nil
+		}
+	})
+	return result, err
+}
+
+// listOf returns the versions of the device-type, oldest first
+func listOf(versions map[string]model.DeviceTypeVersion, id string) (result []model.DeviceTypeVersion) {
+	for _, v := range versions {
+		if v.DeviceTypeId == id {
+			result = append(result, v)
+		}
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Version < result[j].Version
+	})
+	return result
+}
+
+// clone copies the slices and maps of the device-type, so that callers may change the result
+func clone(v model.DeviceTypeVersion) model.DeviceTypeVersion {
+	definition, err := json.Marshal(v.DeviceType)
+	if err != nil {
+		return v
+	}
+	v.DeviceType = models.DeviceType{}
+	_ = json.Unmarshal(definition, &v.DeviceType)
+	return v
+}
@@ -22,6 +22,8 @@ import (
 	"github.com/SENERGY-Platform/device-manager/lib/api"
 	"github.com/SENERGY-Platform/device-manager/lib/config"
 	"github.com/SENERGY-Platform/device-manager/lib/controller"
+	"github.com/SENERGY-Platform/device-manager/lib/devmode"
+	"github.com/SENERGY-Platform/device-manager/lib/tracing"
 	"log"
 	"os"
 	"os/signal"
@@ -30,6 +32,8 @@ import (
 
 func main() {
 	configLocation := flag.String("config", "config.json", "configuration file")
+	dev := flag.Bool("dev", false, "development mode with in-memory repository and permissive permissions; needs no kafka, device-repository or permissions-v2")
+	devData := flag.String("dev-data", "", "optional json file to persist the data of the development mode")
 	flag.Parse()
 
 	conf, err := config.Load(*configLocation)
@@ -37,8 +41,23 @@ func main() {
 		log.Fatal("ERROR: unable to load config", err)
 	}
 
+	if flag.Arg(0) == "replay" {
+		os.Exit(replay(conf, flag.Args()[1:]))
+	}
+
 	ctx, cancel := context.WithCancel(context.Background())
-	ctrl, err := controller.New(ctx, conf)
+	shutdownTracing, err := tracing.Init(ctx, conf)
+	if err != nil {
+		log.Fatal("ERROR: unable to init tracing", err)
+	}
+	var ctrl *controller.Controller
+	if *dev {
+		log.Println("WARNING: start in development mode; all users have all permissions")
+		conf = devmode.Config(conf)
+		ctrl, err = devmode.NewController(conf, *devData)
+	} else {
+		ctrl, err = controller.New(ctx, conf)
+	}
 	if err != nil {
 		log.Fatal("ERROR: unable to start controller", err)
 	}
@@ -54,4 +73,5 @@ func main() {
 	cancel()
 	log.Println("received shutdown signal", sig)
 	log.Println(srv.Shutdown(context.Background()))
+	log.Println(shutdownTracing(context.Background()))
 }
@@ -0,0 +1,103 @@
+/*
+ * Copyright 2024 InfAI (CC SES)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"github.com/SENERGY-Platform/device-manager/lib/auth"
+	"github.com/SENERGY-Platform/device-manager/lib/config"
+	"github.com/SENERGY-Platform/device-manager/lib/controller"
+	"github.com/SENERGY-Platform/device-manager/lib/kafka/publisher"
+	"github.com/SENERGY-Platform/device-manager/lib/model"
+	"log"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+)
+
+// replay republishes resources read from the device-repository
+// usage: app -config=config.json replay -kind=devices [-ids=id1,id2] [-rate=10] [-batch-size=1000] [-dry-run]
+func replay(conf config.Config, args []string) (exitCode int) {
+	flags := flag.NewFlagSet("replay", flag.ContinueOnError)
+	kind := flags.String("kind", "", "resource kind (topic name, e.g. "+conf.DeviceTopic+")")
+	ids := flags.String("ids", "", "optional comma-separated id filter")
+	rate := flags.Float64("rate", 0, "max published commands per second; 0 = unlimited")
+	batchSize := flags.Int64("batch-size", controller.ReplayDefaultBatchSize, "page size used to read from the device-repository")
+	dryRun := flags.Bool("dry-run", false, "only count resources, nothing is published")
+	err := flags.Parse(args)
+	if err != nil {
+		return 2
+	}
+	if *kind == "" {
+		flags.Usage()
+		return 2
+	}
+
+	options := model.ReplayOptions{
+		DryRun:        *dryRun,
+		RatePerSecond: *rate,
+		BatchSize:     *batchSize,
+	}
+	if *ids != "" {
+		options.Ids = []string{}
+		for _, id := range strings.Split(*ids, ",") {
+			if id = strings.TrimSpace(id); id != "" {
+				options.Ids = append(options.Ids, id)
+			}
+		}
+	}
+
+	//interrupting the replay stops it after the current command
+	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer cancel()
+
+	var publ controller.Publisher = publisher.Void{}
+	if !options.DryRun {
+		publ, err = publisher.New(conf, ctx)
+		if err != nil {
+			log.Println("ERROR: unable to create publisher", err)
+			return 1
+		}
+	}
+	ctrl, err := controller.NewWithPublisher(conf, publ)
+	if err != nil {
+		log.Println("ERROR: unable to create controller", err)
+		return 1
+	}
+
+	token, err := auth.CreateTokenWithRoles("device-manager", "device-manager", []string{"admin"})
+	if err != nil {
+		log.Println("ERROR: unable to create token", err)
+		return 1
+	}
+
+	result, err, _ := ctrl.WithContext(ctx).Replay(token, *kind, options, func(progress model.ReplayProgress) {
+		fmt.Printf("%v: listed=%v published=%v failed=%v\n", progress.Kind, progress.Listed, progress.Published, len(progress.Failed))
+	})
+	if err != nil {
+		log.Println("ERROR:", err)
+		return 1
+	}
+	if len(result.Failed) > 0 {
+		fmt.Println("failed:", strings.Join(result.Failed, ","))
+		return 1
+	}
+	return 0
+}